@@ -0,0 +1,148 @@
+// Package avatar turns uploaded profile pictures into square thumbnails.
+// Images are decoded and encoded again, so metadata such as EXIF never
+// reaches the thumbnails.
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	// registers the WebP decoder, image/jpeg and image/png register theirs.
+	_ "golang.org/x/image/webp"
+)
+
+// MaxPixels bounds width times height of an upload, so a small file cannot
+// decode into a huge image: 4096x4096, 8 times the side of the largest of
+// DefaultSizes, decodes into at most 64 MiB.
+const MaxPixels = 4096 * 4096
+
+// jpegQuality is the quality thumbnails are encoded with.
+const jpegQuality = 85
+
+var (
+	ErrUnsupportedFormat = errors.New("image must be a JPEG, PNG or WebP")
+	ErrInvalidImage      = errors.New("image is invalid")
+	ErrImageTooLarge     = fmt.Errorf("image must be at most %d pixels", MaxPixels)
+)
+
+// DefaultSizes are the thumbnail sizes in pixels, largest first.
+var DefaultSizes = []int{512, 256, 128, 64}
+
+// contentTypes are the supported content types by image.Decode format name.
+var contentTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"webp": "image/webp",
+}
+
+// A Thumbnail is a square encoded image.
+type Thumbnail struct {
+	// Size is the width and height in pixels.
+	Size        int
+	ContentType string
+	// Ext is the file extension of ContentType, without the dot.
+	Ext  string
+	Data []byte
+}
+
+// DetectType return the content type sniffed from the leading bytes of data,
+// the client declared type is not trusted. Empty when not supported.
+func DetectType(data []byte) string {
+	switch contentType := http.DetectContentType(data); contentType {
+	case "image/jpeg", "image/png", "image/webp":
+		return contentType
+	}
+	return ""
+}
+
+// Process decode data and return a center cropped thumbnail per size, in
+// the order of sizes. JPEGs are first turned upright by their EXIF
+// orientation. Opaque images are encoded as JPEG, others as PNG.
+func Process(data []byte, sizes []int) ([]Thumbnail, error) {
+	contentType := DetectType(data)
+	if contentType == "" {
+		return nil, ErrUnsupportedFormat
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || contentTypes[format] != contentType {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width < 1 || cfg.Height < 1 {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	opaque := isOpaque(img)
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	// the center square of an upright image is the upright center square,
+	// so only the thumbnails are turned and the upload is never copied.
+	square := cropSquare(img)
+
+	thumbnails := make([]Thumbnail, 0, len(sizes))
+	for _, size := range sizes {
+		scaled := image.NewNRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), square, square.Bounds(), draw.Src, nil)
+		dst := orient(scaled, orientation)
+
+		thumbnail := Thumbnail{Size: size}
+		var buf bytes.Buffer
+		if opaque {
+			thumbnail.ContentType, thumbnail.Ext = "image/jpeg", "jpg"
+			err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+		} else {
+			thumbnail.ContentType, thumbnail.Ext = "image/png", "png"
+			err = png.Encode(&buf, dst)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("encode thumbnail: %w", err)
+		}
+		thumbnail.Data = buf.Bytes()
+		thumbnails = append(thumbnails, thumbnail)
+	}
+
+	return thumbnails, nil
+}
+
+// isOpaque reports whether img has no transparent pixels.
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// cropSquare return the centered square of img, as large as its shorter side.
+// It shares the pixels of img, the decoders all return images supporting
+// SubImage, others are copied.
+func cropSquare(img image.Image) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+	rect := image.Rect(x, y, x+side, y+side)
+
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect)
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, side, side))
+	draw.Copy(dst, image.Point{}, img, rect, draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,238 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	red  = color.NRGBA{R: 255, A: 255}
+	blue = color.NRGBA{B: 255, A: 255}
+)
+
+// newHalves return a w by h image, red on its left half and blue on its right.
+func newHalves(w, h int, alpha uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := red
+			if x >= w/2 {
+				c = blue
+			}
+			c.A = alpha
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodeJPEG(img image.Image) []byte {
+	var buf bytes.Buffer
+	_ = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100})
+	return buf.Bytes()
+}
+
+func encodePNG(img image.Image) []byte {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// withOrientation insert an EXIF segment with orientation after the SOI
+// marker of a JPEG.
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big endian header, IFD at 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, byte(orientation >> 8), byte(orientation), 0, 0, // orientation, SHORT
+		0, 0, 0, 0, // no next IFD
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	size := len(payload) + 2
+	segment := append([]byte{0xFF, 0xE1, byte(size >> 8), byte(size)}, payload...)
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+// withSize rewrite the width and height of the IHDR chunk of a PNG, so its
+// header claims a size its data does not have.
+func withSize(data []byte, w, h uint32) []byte {
+	data = append([]byte{}, data...)
+	// the 8 byte signature, then the length and type of IHDR.
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:], w)
+	binary.BigEndian.PutUint32(ihdr[4:], h)
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+	return data
+}
+
+// isRed reports whether c is closer to red than to blue.
+func isRed(c color.Color) bool {
+	r, _, b, _ := c.RGBA()
+	return r > b
+}
+
+func TestProcess(t *testing.T) {
+	t.Run("TestProcess", func(t *testing.T) {
+		Convey("TestProcess", t, func(c C) {
+			testCases := []struct {
+				testID          int
+				testDesc        string
+				data            []byte
+				wantErr         error
+				wantContentType string
+				// wantTopRed and wantBottomRed are the colors of the center
+				// top and bottom of the thumbnails.
+				wantTopRed    bool
+				wantBottomRed bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - not an image",
+					data:     []byte("GIF89a is not supported"),
+					wantErr:  ErrUnsupportedFormat,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - truncated image",
+					data:     encodePNG(newHalves(64, 32, 255))[:40],
+					wantErr:  ErrInvalidImage,
+				},
+				{
+					testID:          3,
+					testDesc:        "Success - opaque PNG becomes JPEG, center cropped",
+					data:            encodePNG(newHalves(64, 32, 255)),
+					wantContentType: "image/jpeg",
+					wantTopRed:      true,
+					wantBottomRed:   true,
+				},
+				{
+					testID:          4,
+					testDesc:        "Success - transparent PNG stays PNG",
+					data:            encodePNG(newHalves(64, 32, 128)),
+					wantContentType: "image/png",
+					wantTopRed:      true,
+					wantBottomRed:   true,
+				},
+				{
+					testID:          5,
+					testDesc:        "Success - JPEG turned upright by its EXIF orientation",
+					data:            withOrientation(encodeJPEG(newHalves(64, 32, 255)), 6),
+					wantContentType: "image/jpeg",
+					wantTopRed:      true,
+					wantBottomRed:   false,
+				},
+				{
+					testID:   6,
+					testDesc: "Failed - image over MaxPixels is not decoded",
+					data:     withSize(encodePNG(newHalves(64, 32, 255)), 4097, 4096),
+					wantErr:  ErrImageTooLarge,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					thumbnails, err := Process(tc.data, []int{32, 16})
+
+					// assert
+					if tc.wantErr != nil {
+						So(errors.Is(err, tc.wantErr), ShouldBeTrue)
+						return
+					}
+					So(err, ShouldBeNil)
+					So(thumbnails, ShouldHaveLength, 2)
+					for i, size := range []int{32, 16} {
+						thumbnail := thumbnails[i]
+						So(thumbnail.Size, ShouldEqual, size)
+						So(thumbnail.ContentType, ShouldEqual, tc.wantContentType)
+						So(DetectType(thumbnail.Data), ShouldEqual, tc.wantContentType)
+						So(bytes.Contains(thumbnail.Data, []byte("Exif")), ShouldBeFalse)
+
+						img, _, err := image.Decode(bytes.NewReader(thumbnail.Data))
+						So(err, ShouldBeNil)
+						So(img.Bounds().Dx(), ShouldEqual, size)
+						So(img.Bounds().Dy(), ShouldEqual, size)
+						So(isRed(img.At(size/4, size/8)), ShouldEqual, tc.wantTopRed)
+						So(isRed(img.At(size/4, size-1-size/8)), ShouldEqual, tc.wantBottomRed)
+					}
+				})
+			}
+		})
+	})
+}
+
+func TestCropSquare(t *testing.T) {
+	t.Run("TestCropSquare", func(t *testing.T) {
+		Convey("TestCropSquare", t, func(c C) {
+			img := newHalves(64, 32, 255)
+
+			square := cropSquare(img)
+
+			// assert the center square shares the pixels of img.
+			So(square.Bounds(), ShouldResemble, image.Rect(16, 0, 48, 32))
+			img.SetNRGBA(16, 0, blue)
+			So(isRed(square.At(16, 0)), ShouldBeFalse)
+		})
+	})
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	t.Run("TestJPEGOrientation", func(t *testing.T) {
+		Convey("TestJPEGOrientation", t, func(c C) {
+			data := encodeJPEG(newHalves(8, 8, 255))
+
+			So(jpegOrientation(data), ShouldEqual, 1)
+			for orientation := uint16(1); orientation <= 8; orientation++ {
+				So(jpegOrientation(withOrientation(data, orientation)), ShouldEqual, orientation)
+			}
+			So(jpegOrientation(withOrientation(data, 9)), ShouldEqual, 1)
+			So(jpegOrientation(data[:3]), ShouldEqual, 1)
+		})
+	})
+}
+
+func TestOrient(t *testing.T) {
+	t.Run("TestOrient", func(t *testing.T) {
+		Convey("TestOrient", t, func(c C) {
+			// a 2 by 1 image, red then blue.
+			img := newHalves(2, 1, 255)
+
+			testCases := []struct {
+				orientation int
+				// wantRed is the position of the red pixel after orient.
+				wantBounds image.Rectangle
+				wantRed    image.Point
+			}{
+				{orientation: 1, wantBounds: image.Rect(0, 0, 2, 1), wantRed: image.Pt(0, 0)},
+				{orientation: 2, wantBounds: image.Rect(0, 0, 2, 1), wantRed: image.Pt(1, 0)},
+				{orientation: 3, wantBounds: image.Rect(0, 0, 2, 1), wantRed: image.Pt(1, 0)},
+				{orientation: 4, wantBounds: image.Rect(0, 0, 2, 1), wantRed: image.Pt(0, 0)},
+				{orientation: 5, wantBounds: image.Rect(0, 0, 1, 2), wantRed: image.Pt(0, 0)},
+				{orientation: 6, wantBounds: image.Rect(0, 0, 1, 2), wantRed: image.Pt(0, 0)},
+				{orientation: 7, wantBounds: image.Rect(0, 0, 1, 2), wantRed: image.Pt(0, 1)},
+				{orientation: 8, wantBounds: image.Rect(0, 0, 1, 2), wantRed: image.Pt(0, 1)},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("orientation %d", tc.orientation), func() {
+					got := orient(img, tc.orientation)
+
+					// assert
+					So(got.Bounds(), ShouldResemble, tc.wantBounds)
+					So(isRed(got.At(tc.wantRed.X, tc.wantRed.Y)), ShouldBeTrue)
+				})
+			}
+		})
+	})
+}
@@ -0,0 +1,467 @@
+// This file contains the typed client of the service, built on the client
+// generated from api.yml. See the Makefile for more information.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is the number of retries when MaxRetries is zero.
+	DefaultMaxRetries = 2
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 5 * time.Second
+)
+
+// Errors matched by the Error of a failed request with errors.Is.
+var (
+	ErrBadRequest         = errors.New("bad request")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
+	ErrNotFound           = errors.New("not found")
+	ErrConflict           = errors.New("conflict")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrUnprocessable      = errors.New("unprocessable request")
+	ErrTooManyRequests    = errors.New("too many requests")
+	ErrUnavailable        = errors.New("service unavailable")
+)
+
+var statusErrors = map[int]error{
+	http.StatusBadRequest:          ErrBadRequest,
+	http.StatusUnauthorized:        ErrUnauthorized,
+	http.StatusForbidden:           ErrForbidden,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusConflict:            ErrConflict,
+	http.StatusPreconditionFailed:  ErrPreconditionFailed,
+	http.StatusUnprocessableEntity: ErrUnprocessable,
+	http.StatusTooManyRequests:     ErrTooManyRequests,
+	http.StatusServiceUnavailable:  ErrUnavailable,
+}
+
+// An Error represents a response the operation did not succeed with.
+type Error struct {
+	StatusCode int
+	Message    string
+	// RequestID identifies the request in the service logs.
+	RequestID string
+	// RetryAfter is how long to wait before retrying, zero when not told.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("user service: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("user service: %d %s", e.StatusCode, e.Message)
+}
+
+// Unwrap return the error of the status code, nil for other status codes.
+func (e *Error) Unwrap() error {
+	return statusErrors[e.StatusCode]
+}
+
+// A TokenSource return the bearer token of a request, no token is sent when empty.
+type TokenSource func(ctx context.Context) (string, error)
+
+// StaticToken return a TokenSource always returning token.
+func StaticToken(token string) TokenSource {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+// An API calls the service, each operation of api.yml is a method.
+type API struct {
+	raw   *ClientWithResponses
+	token TokenSource
+	// apiKey authorizes the requests instead of token, when set.
+	apiKey string
+}
+
+type NewAPIOptions struct {
+	// BaseURL is the address of the service, like http://localhost:1323.
+	BaseURL string
+	// Token authorizes the requests, requests are anonymous when nil.
+	Token TokenSource
+	// HTTPClient sends the requests, http.DefaultClient when nil.
+	HTTPClient HttpRequestDoer
+	// MaxRetries is how many times a failed request is retried,
+	// DefaultMaxRetries when zero and no retry when negative.
+	MaxRetries int
+	// Backoff is the wait before the first retry, doubled on each retry,
+	// 100ms when zero.
+	Backoff time.Duration
+	// MaxBackoff caps the wait between retries, 5s when zero. A Retry-After
+	// longer than MaxBackoff is not waited for.
+	MaxBackoff time.Duration
+}
+
+func NewAPI(opts NewAPIOptions) (*API, error) {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = DefaultMaxRetries
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultMaxBackoff
+	}
+
+	doer := opts.HTTPClient
+	if opts.MaxRetries > 0 {
+		doer = &retryDoer{
+			next:       opts.HTTPClient,
+			maxRetries: opts.MaxRetries,
+			backoff:    opts.Backoff,
+			maxBackoff: opts.MaxBackoff,
+		}
+	}
+	raw, err := NewClientWithResponses(opts.BaseURL, WithHTTPClient(doer))
+	if err != nil {
+		return nil, fmt.Errorf("new client: %w", err)
+	}
+
+	return &API{
+		raw:   raw,
+		token: opts.Token,
+	}, nil
+}
+
+// WithToken return a copy of the API authorizing requests with token.
+func (a *API) WithToken(token TokenSource) *API {
+	return &API{
+		raw:   a.raw,
+		token: token,
+	}
+}
+
+// WithAPIKey return a copy of the API authorizing requests with an API key,
+// issued by an administrator, instead of a token.
+func (a *API) WithAPIKey(key string) *API {
+	return &API{
+		raw:    a.raw,
+		apiKey: key,
+	}
+}
+
+// UserRegister create a new user.
+func (a *API) UserRegister(ctx context.Context, params *UserRegisterParams, body RegisterRequest) (*RegisterResponse, error) {
+	resp, err := a.raw.UserRegisterWithResponse(ctx, params, body, a.authorize)
+	if err != nil {
+		return nil, err
+	}
+	if resp.JSON200 == nil {
+		return nil, newError(resp.HTTPResponse, resp.Body)
+	}
+	return resp.JSON200, nil
+}
+
+// Login log in the user, opening a session.
+func (a *API) Login(ctx context.Context, body LoginRequest) (*LoginResponse, error) {
+	resp, err := a.raw.LoginWithResponse(ctx, body, a.authorize)
+	if err != nil {
+		return nil, err
+	}
+	if resp.JSON200 == nil {
+		return nil, newError(resp.HTTPResponse, resp.Body)
+	}
+	return resp.JSON200, nil
+}
+
+// LoginOIDC log in the user of the ID token of an external provider,
+// opening a session. created reports whether the user was signed up.
+func (a *API) LoginOIDC(ctx context.Context, body OIDCLoginRequest) (login *LoginResponse, created bool, err error) {
+	resp, err := a.raw.LoginOIDCWithResponse(ctx, body, a.authorize)
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.JSON201 != nil {
+		return resp.JSON201, true, nil
+	}
+	if resp.JSON200 == nil {
+		return nil, false, newError(resp.HTTPResponse, resp.Body)
+	}
+	return resp.JSON200, false, nil
+}
+
+// GetUser return the user of the token and its ETag. user is nil when
+// params.IfNoneMatch still matches the user.
+func (a *API) GetUser(ctx context.Context, params *GetUserParams) (user *UserResponse, etag string, err error) {
+	resp, err := a.raw.GetUserWithResponse(ctx, params, a.authorize)
+	if err != nil {
+		return nil, "", err
+	}
+	etag = resp.HTTPResponse.Header.Get("ETag")
+	if resp.StatusCode() == http.StatusNotModified {
+		return nil, etag, nil
+	}
+	if resp.JSON200 == nil {
+		return nil, "", newError(resp.HTTPResponse, resp.Body)
+	}
+	return resp.JSON200, etag, nil
+}
+
+// UpdateUser update the present fields of the user of the token, return the
+// updated user and its ETag.
+func (a *API) UpdateUser(ctx context.Context, params *UpdateUserParams, body UpdateUserRequest) (user *UserResponse, etag string, err error) {
+	resp, err := a.raw.UpdateUserWithResponse(ctx, params, body, a.authorize)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.JSON200 == nil {
+		return nil, "", newError(resp.HTTPResponse, resp.Body)
+	}
+	return resp.JSON200, resp.HTTPResponse.Header.Get("ETag"), nil
+}
+
+// ListUserEvents return recent security events of the user of the token.
+func (a *API) ListUserEvents(ctx context.Context, params *ListUserEventsParams) (*UserEventsResponse, error) {
+	resp, err := a.raw.ListUserEventsWithResponse(ctx, params, a.authorize)
+	if err != nil {
+		return nil, err
+	}
+	if resp.JSON200 == nil {
+		return nil, newError(resp.HTTPResponse, resp.Body)
+	}
+	return resp.JSON200, nil
+}
+
+// ListUserLogins return a page of the login history of the user of the token.
+func (a *API) ListUserLogins(ctx context.Context, params *ListUserLoginsParams) (*UserLoginsResponse, error) {
+	resp, err := a.raw.ListUserLoginsWithResponse(ctx, params, a.authorize)
+	if err != nil {
+		return nil, err
+	}
+	if resp.JSON200 == nil {
+		return nil, newError(resp.HTTPResponse, resp.Body)
+	}
+	return resp.JSON200, nil
+}
+
+// ListUserSessions return active sessions of the user of the token.
+func (a *API) ListUserSessions(ctx context.Context) (*UserSessionsResponse, error) {
+	resp, err := a.raw.ListUserSessionsWithResponse(ctx, a.authorize)
+	if err != nil {
+		return nil, err
+	}
+	if resp.JSON200 == nil {
+		return nil, newError(resp.HTTPResponse, resp.Body)
+	}
+	return resp.JSON200, nil
+}
+
+// RevokeUserSession revoke a session of the user of the token.
+func (a *API) RevokeUserSession(ctx context.Context, id string) error {
+	resp, err := a.raw.RevokeUserSessionWithResponse(ctx, id, a.authorize)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() != http.StatusNoContent {
+		return newError(resp.HTTPResponse, resp.Body)
+	}
+	return nil
+}
+
+// ListUserIdentities return the external identities linked to the user of
+// the token.
+func (a *API) ListUserIdentities(ctx context.Context) (*UserIdentitiesResponse, error) {
+	resp, err := a.raw.ListUserIdentitiesWithResponse(ctx, a.authorize)
+	if err != nil {
+		return nil, err
+	}
+	if resp.JSON200 == nil {
+		return nil, newError(resp.HTTPResponse, resp.Body)
+	}
+	return resp.JSON200, nil
+}
+
+// LinkUserIdentity link the identity of an ID token to the user of the token.
+func (a *API) LinkUserIdentity(ctx context.Context, body OIDCLoginRequest) (*UserIdentity, error) {
+	resp, err := a.raw.LinkUserIdentityWithResponse(ctx, body, a.authorize)
+	if err != nil {
+		return nil, err
+	}
+	if resp.JSON201 == nil {
+		return nil, newError(resp.HTTPResponse, resp.Body)
+	}
+	return resp.JSON201, nil
+}
+
+// UnlinkUserIdentity unlink the identity of provider from the user of the token.
+func (a *API) UnlinkUserIdentity(ctx context.Context, provider string) error {
+	resp, err := a.raw.UnlinkUserIdentityWithResponse(ctx, provider, a.authorize)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() != http.StatusNoContent {
+		return newError(resp.HTTPResponse, resp.Body)
+	}
+	return nil
+}
+
+// Healthz check the service is alive.
+func (a *API) Healthz(ctx context.Context) (*HealthResponse, error) {
+	resp, err := a.raw.HealthzWithResponse(ctx, a.authorize)
+	if err != nil {
+		return nil, err
+	}
+	if resp.JSON200 == nil {
+		return nil, newError(resp.HTTPResponse, resp.Body)
+	}
+	return resp.JSON200, nil
+}
+
+// Readyz check the service is ready to serve. The report is also returned
+// with the error when the service is not ready.
+func (a *API) Readyz(ctx context.Context) (*HealthResponse, error) {
+	resp, err := a.raw.ReadyzWithResponse(ctx, a.authorize)
+	if err != nil {
+		return nil, err
+	}
+	if resp.JSON200 == nil {
+		return resp.JSON503, newError(resp.HTTPResponse, resp.Body)
+	}
+	return resp.JSON200, nil
+}
+
+// authorize set the API key or bearer token of the request, if any.
+func (a *API) authorize(ctx context.Context, req *http.Request) error {
+	if a.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+a.apiKey)
+		return nil
+	}
+	if a.token == nil {
+		return nil
+	}
+	token, err := a.token(ctx)
+	if err != nil {
+		return fmt.Errorf("get token: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// newError return the Error of a response the operation did not succeed with.
+func newError(resp *http.Response, body []byte) *Error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+		RetryAfter: retryAfter(resp),
+	}
+
+	var payload ErrorResponse
+	if err := json.Unmarshal(body, &payload); err == nil {
+		e.Message = payload.Message
+		if payload.RequestId != nil {
+			e.RequestID = *payload.RequestId
+		}
+	}
+	return e
+}
+
+// retryAfter return the delay of the Retry-After header, zero when absent.
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// retryDoer retry requests failing with a network error or a transient
+// status. Only requests safe to repeat are retried: idempotent methods and
+// POST sent with an Idempotency-Key.
+type retryDoer struct {
+	next       HttpRequestDoer
+	maxRetries int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+func (d *retryDoer) Do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := d.next.Do(req)
+		if attempt == d.maxRetries || !canRetry(req) || !shouldRetry(req.Context(), resp, err) {
+			return resp, err
+		}
+
+		wait := d.backoff << attempt
+		if wait > d.maxBackoff {
+			wait = d.maxBackoff
+		}
+		if resp != nil {
+			if after := retryAfter(resp); after > d.maxBackoff {
+				return resp, err
+			} else if after > 0 {
+				wait = after
+			}
+		}
+
+		// the body was consumed by the failed attempt.
+		next := req.Clone(req.Context())
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			if next.Body, err = req.GetBody(); err != nil {
+				return nil, fmt.Errorf("rewind request body: %w", err)
+			}
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		req = next
+	}
+}
+
+// canRetry reports whether repeating req has no other effect than sending it once.
+func canRetry(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		return req.Header.Get("Idempotency-Key") != ""
+	}
+	return false
+}
+
+// shouldRetry reports whether the attempt failed for a reason that may go away.
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAPI(t *testing.T) {
+	t.Run("TestAPI", func(t *testing.T) {
+		Convey("TestAPI", t, func(c C) {
+			type (
+				// response is written for the attempt of the same index, the
+				// last one for every later attempt.
+				response struct {
+					status int
+					header map[string]string
+					body   string
+				}
+			)
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				responses []response
+				// call sends one request through api.
+				call         func(api *API) error
+				wantAttempts int
+				wantErr      error
+				wantError    *Error
+				wantAuth     string
+				wantBodies   []string
+			}{
+				{
+					testID:    1,
+					testDesc:  "Success - token is injected",
+					responses: []response{{status: http.StatusOK, body: `{"phone":"+6281234567","name":"Budi","login_count":1}`}},
+					call: func(api *API) error {
+						user, etag, err := api.WithToken(StaticToken("mock-token")).GetUser(context.Background(), nil)
+						if err == nil && (user.Name != "Budi" || etag != `"1"`) {
+							return fmt.Errorf("unexpected user %+v %s", user, etag)
+						}
+						return err
+					},
+					wantAttempts: 1,
+					wantAuth:     "Bearer mock-token",
+				},
+				{
+					testID:   2,
+					testDesc: "Success - GET retried after transient error",
+					responses: []response{
+						{status: http.StatusServiceUnavailable, body: `{"message":"not ready"}`},
+						{status: http.StatusOK, body: `{"sessions":[]}`},
+					},
+					call: func(api *API) error {
+						_, err := api.ListUserSessions(context.Background())
+						return err
+					},
+					wantAttempts: 2,
+				},
+				{
+					testID:   3,
+					testDesc: "Success - POST with Idempotency-Key retried with the same body",
+					responses: []response{
+						{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "0"}, body: `{"message":"too many requests"}`},
+						{status: http.StatusOK, body: `{"id":1}`},
+					},
+					call: func(api *API) error {
+						key := "mock-key"
+						_, err := api.UserRegister(context.Background(), &UserRegisterParams{IdempotencyKey: &key}, RegisterRequest{Phone: "+6281234567", Name: "Budi", Password: "Secret1!"})
+						return err
+					},
+					wantAttempts: 2,
+					wantBodies: []string{
+						`{"name":"Budi","password":"Secret1!","phone":"+6281234567"}`,
+						`{"name":"Budi","password":"Secret1!","phone":"+6281234567"}`,
+					},
+				},
+				{
+					testID:    4,
+					testDesc:  "Failed - POST without Idempotency-Key is not retried",
+					responses: []response{{status: http.StatusServiceUnavailable, body: `{"message":"not ready"}`}},
+					call: func(api *API) error {
+						identifier := "+6281234567"
+						_, err := api.Login(context.Background(), LoginRequest{Identifier: &identifier, Password: "Secret1!"})
+						return err
+					},
+					wantAttempts: 1,
+					wantErr:      ErrUnavailable,
+				},
+				{
+					testID:    5,
+					testDesc:  "Failed - retries exhausted",
+					responses: []response{{status: http.StatusBadGateway}},
+					call: func(api *API) error {
+						_, err := api.Healthz(context.Background())
+						return err
+					},
+					wantAttempts: 3,
+					wantError:    &Error{StatusCode: http.StatusBadGateway},
+				},
+				{
+					testID:    6,
+					testDesc:  "Failed - Retry-After longer than MaxBackoff is not waited for",
+					responses: []response{{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "60"}, body: `{"message":"too many requests"}`}},
+					call: func(api *API) error {
+						_, err := api.ListUserEvents(context.Background(), nil)
+						return err
+					},
+					wantAttempts: 1,
+					wantErr:      ErrTooManyRequests,
+					wantError:    &Error{StatusCode: http.StatusTooManyRequests, Message: "too many requests", RetryAfter: time.Minute},
+				},
+				{
+					testID:    7,
+					testDesc:  "Failed - typed error",
+					responses: []response{{status: http.StatusNotFound, header: map[string]string{"X-Request-ID": "mock-request-id"}, body: `{"message":"session not found","request_id":"mock-request-id"}`}},
+					call: func(api *API) error {
+						return api.RevokeUserSession(context.Background(), "mock-session-id")
+					},
+					wantAttempts: 1,
+					wantErr:      ErrNotFound,
+					wantError:    &Error{StatusCode: http.StatusNotFound, Message: "session not found", RequestID: "mock-request-id"},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					var (
+						mu       sync.Mutex
+						attempts int
+						auth     string
+						bodies   []string
+					)
+					srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						mu.Lock()
+						defer mu.Unlock()
+						body, _ := io.ReadAll(r.Body)
+						if len(body) > 0 {
+							bodies = append(bodies, string(body))
+						}
+						auth = r.Header.Get("Authorization")
+
+						resp := tc.responses[len(tc.responses)-1]
+						if attempts < len(tc.responses) {
+							resp = tc.responses[attempts]
+						}
+						attempts++
+
+						w.Header().Set("Content-Type", "application/json")
+						w.Header().Set("ETag", `"1"`)
+						for key, value := range resp.header {
+							w.Header().Set(key, value)
+						}
+						w.WriteHeader(resp.status)
+						_, _ = w.Write([]byte(resp.body))
+					}))
+					defer srv.Close()
+
+					api, err := NewAPI(NewAPIOptions{
+						BaseURL:    srv.URL,
+						Backoff:    time.Millisecond,
+						MaxBackoff: 10 * time.Millisecond,
+					})
+					So(err, ShouldBeNil)
+
+					err = tc.call(api)
+					// assert
+					So(attempts, ShouldEqual, tc.wantAttempts)
+					if tc.wantErr != nil {
+						So(errors.Is(err, tc.wantErr), ShouldBeTrue)
+					}
+					if tc.wantError != nil {
+						var apiErr *Error
+						So(errors.As(err, &apiErr), ShouldBeTrue)
+						So(apiErr, ShouldResemble, tc.wantError)
+					}
+					if tc.wantErr == nil && tc.wantError == nil {
+						So(err, ShouldBeNil)
+					}
+					So(auth, ShouldEqual, tc.wantAuth)
+					if tc.wantBodies != nil {
+						So(bodies, ShouldResemble, tc.wantBodies)
+					}
+				})
+			}
+		})
+	})
+}
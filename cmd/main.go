@@ -1,33 +1,540 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"strings"
+	"time"
+	// the image has no zoneinfo, timezones are validated by name.
+	_ "time/tzdata"
 
+	"github.com/SawitProRecruitment/UserService/common"
+	"github.com/SawitProRecruitment/UserService/config"
 	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/generated/oauth"
+	v2 "github.com/SawitProRecruitment/UserService/generated/v2"
 	"github.com/SawitProRecruitment/UserService/handler"
+	"github.com/SawitProRecruitment/UserService/health"
+	"github.com/SawitProRecruitment/UserService/idempotency"
+	"github.com/SawitProRecruitment/UserService/job"
+	"github.com/SawitProRecruitment/UserService/lifecycle"
+	"github.com/SawitProRecruitment/UserService/logging"
+	"github.com/SawitProRecruitment/UserService/mail"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/SawitProRecruitment/UserService/migrations"
+	"github.com/SawitProRecruitment/UserService/oidc"
+	"github.com/SawitProRecruitment/UserService/ratelimit"
 	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/storage"
+	"github.com/SawitProRecruitment/UserService/tracing"
+	"github.com/SawitProRecruitment/UserService/validation"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	serviceName = "user-service"
+	// healthCheckTimeout bounds each readiness check.
+	healthCheckTimeout = 2 * time.Second
+	// rateLimitPruneInterval is how often full rate limit buckets are deleted from Postgres.
+	rateLimitPruneInterval = 10 * time.Minute
+	// multipartOverhead is allowed on top of AVATAR_MAX_BYTES for the
+	// boundaries and part headers of an upload.
+	multipartOverhead = 64 << 10
 )
 
 func main() {
-	e := echo.New()
+	cfg, err := config.Load()
+	if err != nil {
+		fatal(err)
+	}
+	logger := logging.New(logging.NewOptions{Level: cfg.Log.Level})
+	slog.SetDefault(logger)
+	logger.Info("effective config", slog.String("config", cfg.String()))
+
+	provider := newTracerProvider(cfg)
+
+	repo := newRepository(cfg)
+	migrator, err := migrations.NewMigrator(migrations.NewMigratorOptions{Db: repo.Db})
+	if err != nil {
+		fatal(err)
+	}
+	if cfg.Database.AutoMigrate {
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			fatal(err)
+		}
+		logger.Info("applied migrations", slog.Int("count", len(applied)))
+	}
 
-	var server generated.ServerInterface = newServer()
+	specV1, err := generated.GetSwagger()
+	if err != nil {
+		fatal(err)
+	}
+	specV2, err := v2.GetSwagger()
+	if err != nil {
+		fatal(err)
+	}
+	specOAuth, err := oauth.GetSwagger()
+	if err != nil {
+		fatal(err)
+	}
+	// operations of both versions and the OpenID Connect provider, for the
+	// middlewares.
+	spec := common.MergeSpecs(specV1, specV2, specOAuth)
+	m := newMetrics(spec, repo)
+	e := newEcho(cfg, logger, m)
 
+	registry := newHealthRegistry(repo, migrator)
+	tracedRepo := repository.NewTracedRepository(repository.NewTracedRepositoryOptions{Next: repo})
+	blobs := newBlobStore(cfg)
+	server := newServer(cfg, tracedRepo, registry, m, newMailer(cfg, logger), blobs, newIDTokenKey(cfg, logger))
+	e.Use(newDeprecation(cfg, specV1))
+	// before the rate limiter and idempotency, which authorize API keys and
+	// tokens with the scopes of the operation.
+	e.Use(common.SecurityMiddleware(spec))
+	e.Use(newRateLimiter(cfg, spec, tracedRepo, server).Middleware())
+	// before the validator, which reads the whole body.
+	e.Use(newAvatarBodyLimit(cfg))
+	// before idempotency, so rejected requests do not use up their key.
+	e.Use(newValidator(cfg, spec).Middleware())
+	e.Use(idempotency.Middleware(idempotency.MiddlewareOptions{
+		Store: tracedRepo,
+		TTL:   cfg.Idempotency.TTL,
+		Scope: server.IdempotencyScope,
+		// login responses carry tokens or codes, they must not be stored.
+		Skipper: func(c echo.Context) bool {
+			switch c.Path() {
+			case "/login", "/login/oidc", handler.V2BaseURL + "/sessions", "/oauth/authorize", "/oauth/token":
+				return true
+			}
+			return false
+		},
+	}))
 	generated.RegisterHandlers(e, server)
-	e.Logger.Fatal(e.Start(":1323"))
+	v2.RegisterHandlersWithBaseURL(e, server, handler.V2BaseURL)
+	if cfg.OIDC.Issuer != "" {
+		oauth.RegisterHandlers(e, server)
+	}
+	if store, ok := blobs.(*storage.FileStore); ok && strings.HasPrefix(store.BaseURL, "/") {
+		e.Static(store.BaseURL, store.Dir)
+	}
+
+	manager := lifecycle.NewManager(lifecycle.NewManagerOptions{
+		Server:          newHTTPServer(cfg.HTTP.Addr, cfg, e),
+		DrainDelay:      cfg.HTTP.DrainDelay,
+		ShutdownTimeout: cfg.HTTP.ShutdownTimeout,
+	})
+	if cfg.Metrics.Addr == "" {
+		e.GET("/metrics", echo.WrapHandler(m.Handler()))
+	} else {
+		admin := http.NewServeMux()
+		admin.Handle("/metrics", m.Handler())
+		manager.AddServer(newHTTPServer(cfg.Metrics.Addr, cfg, admin))
+		logger.Info("serving metrics", slog.String("addr", cfg.Metrics.Addr))
+	}
+	manager.OnDrain(registry.SetShuttingDown)
+	manager.OnShutdown("tracing", provider.Shutdown)
+	manager.OnShutdown("database", func(context.Context) error {
+		return repo.Db.Close()
+	})
+
+	runJob(manager, "login history pruner", newLoginHistoryPruner(cfg, tracedRepo).Run)
+	runJob(manager, "idempotency key pruner", newIdempotencyKeyPruner(cfg, tracedRepo).Run)
+	if cfg.RateLimit.Store == "postgres" {
+		runJob(manager, "rate limit bucket pruner", newRateLimitBucketPruner(cfg, tracedRepo).Run)
+	}
+
+	logger.Info("listening", slog.String("addr", cfg.HTTP.Addr))
+	if err = manager.Run(context.Background()); err != nil {
+		fatal(err)
+	}
+	logger.Info("shutdown complete")
+}
+
+func newEcho(cfg *config.Config, logger *slog.Logger, m *metrics.Metrics) *echo.Echo {
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.IPExtractor = ipExtractor(cfg.HTTP.TrustedProxies)
+
+	// first, so every response and log entry carries the request id.
+	e.Use(logging.RequestID(logger))
+	e.Use(otelecho.Middleware(serviceName, otelecho.WithSkipper(func(c echo.Context) bool {
+		switch c.Path() {
+		case "/healthz", "/readyz", "/metrics":
+			return true
+		}
+		return false
+	})))
+	// before the access log and the limiters, so rejected requests are counted.
+	e.Use(m.Middleware())
+	e.Use(logging.AccessLog())
+	// before the rate limiter, the validator and idempotency, which read the
+	// whole body.
+	e.Use(newBodyLimit(cfg))
+
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+			AllowOrigins: cfg.CORS.AllowedOrigins,
+			ExposeHeaders: []string{
+				"ETag",
+				echo.HeaderXRequestID,
+				echo.HeaderRetryAfter,
+				ratelimit.HeaderRateLimitLimit,
+				ratelimit.HeaderRateLimitRemaining,
+				ratelimit.HeaderRateLimitReset,
+				ratelimit.HeaderRateLimitPolicy,
+				idempotency.HeaderIdempotentReplayed,
+				echo.HeaderLocation,
+				common.HeaderDeprecation,
+				common.HeaderSunset,
+				common.HeaderLink,
+			},
+		}))
+	}
+
+	return e
+}
+
+// ipExtractor read the client ip from X-Forwarded-For when the request comes
+// through one of proxies, otherwise from the peer address.
+func ipExtractor(proxies []string) echo.IPExtractor {
+	if len(proxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		// validated by config.
+		_, ipNet, _ := net.ParseCIDR(proxy)
+		opts = append(opts, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(opts...)
+}
+
+// newRateLimiter limit requests per operation, the default policy is
+// derived from RATE_LIMIT_RPS and RATE_LIMIT_BURST.
+func newRateLimiter(cfg *config.Config, spec *openapi3.T, repo repository.RepositoryInterface, server *handler.Server) *ratelimit.Limiter {
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Store == "postgres" {
+		store = ratelimit.NewPostgresStore(ratelimit.NewPostgresStoreOptions{Repository: repo})
+	}
+
+	var def ratelimit.Policy
+	if cfg.RateLimit.RequestsPerSecond > 0 {
+		def = ratelimit.Policy{
+			Limit:  cfg.RateLimit.Burst,
+			Window: time.Duration(float64(cfg.RateLimit.Burst) / cfg.RateLimit.RequestsPerSecond * float64(time.Second)),
+			Key:    ratelimit.KeyIP,
+		}
+	}
+	policies := map[string]ratelimit.Policy{}
+	for operation, policy := range cfg.RateLimit.Policies {
+		policies[operation] = ratelimit.Policy{Limit: policy.Limit, Window: policy.Window, Key: policy.Key}
+	}
+
+	limiter, err := ratelimit.NewLimiter(ratelimit.NewLimiterOptions{
+		Spec:         spec,
+		Store:        store,
+		Policies:     policies,
+		Default:      def,
+		UserID:       server.TokenUserID,
+		APIKey:       server.APIKeyRateLimit,
+		APIKeyScheme: handler.APIKeySecurityScheme,
+		APIKeyPolicy: ratelimit.Policy{
+			Limit:  cfg.APIKeys.RateLimit,
+			Window: cfg.APIKeys.RateLimitWindow,
+		},
+	})
+	if err != nil {
+		fatal(err)
+	}
+	return limiter
+}
+
+// newDeprecation mark version 1 responses as deprecated in favor of version 2.
+func newDeprecation(cfg *config.Config, specV1 *openapi3.T) echo.MiddlewareFunc {
+	return common.DeprecationMiddleware(common.DeprecationOptions{
+		Spec:         specV1,
+		DeprecatedAt: cfg.API.V1DeprecatedAt,
+		SunsetAt:     cfg.API.V1SunsetAt,
+		Successor:    handler.V2BaseURL,
+		// probes are not part of the versioned API.
+		Skipper: func(c echo.Context) bool {
+			return c.Path() == "/healthz" || c.Path() == "/readyz"
+		},
+	})
+}
+
+func newValidator(cfg *config.Config, spec *openapi3.T) *validation.Validator {
+	validator, err := validation.NewValidator(validation.NewValidatorOptions{
+		Spec:              spec,
+		ValidateResponses: cfg.Validation.Responses,
+	})
+	if err != nil {
+		fatal(err)
+	}
+	return validator
+}
+
+func newTracerProvider(cfg *config.Config) *tracing.Provider {
+	provider, err := tracing.NewProvider(context.Background(), tracing.NewProviderOptions{
+		ServiceName: serviceName,
+		Exporter:    cfg.Tracing.Exporter,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal(err)
+	}
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(tracing.Propagator())
+	return provider
+}
+
+func newHTTPServer(addr string, cfg *config.Config, h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
 }
 
-func newServer() *handler.Server {
-	dbDsn := os.Getenv("DATABASE_URL")
-	secret := os.Getenv("SECRET")
-	var repo repository.RepositoryInterface = repository.NewRepository(repository.NewRepositoryOptions{
-		Dsn: dbDsn,
+func newRepository(cfg *config.Config) *repository.Repository {
+	return repository.NewRepository(repository.NewRepositoryOptions{
+		Dsn:             cfg.Database.URL,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
 	})
+}
+
+func newHealthRegistry(repo *repository.Repository, migrator *migrations.Migrator) *health.Registry {
+	registry := health.NewRegistry(health.NewRegistryOptions{
+		Timeout: healthCheckTimeout,
+	})
+	registry.Register("postgres", func(ctx context.Context) (string, error) {
+		return "", repo.Db.PingContext(ctx)
+	})
+	registry.Register("migrations", migrator.Check)
+	return registry
+}
+
+func newMetrics(spec *openapi3.T, repo *repository.Repository) *metrics.Metrics {
+	return metrics.NewMetrics(metrics.NewMetricsOptions{
+		Spec: spec,
+		Db:   repo.Db,
+	})
+}
+
+func newServer(cfg *config.Config, repo repository.RepositoryInterface, registry *health.Registry, m *metrics.Metrics, mailer mail.Sender, blobs storage.BlobStore, idTokenKey *rsa.PrivateKey) *handler.Server {
 	opts := handler.NewServerOptions{
 		Repository: repo,
-		SecretKey:  secret,
+		SecretKey:  cfg.Auth.Secret,
+		TokenTTL:   cfg.Auth.TokenTTL,
+		BcryptCost: cfg.Auth.BcryptCost,
+		Health:     registry,
+		Metrics:    m,
+		Mailer:     mailer,
+
+		EmailVerificationTTL: cfg.Mail.VerificationTTL,
+		EmailVerificationURL: cfg.Mail.VerificationURL,
+		Blobs:                blobs,
+		AvatarMaxBytes:       int64(cfg.Avatar.MaxBytes),
+		Issuer:               strings.TrimSuffix(cfg.OIDC.Issuer, "/"),
+		IDTokenKey:           idTokenKey,
+		AuthorizationCodeTTL: cfg.OIDC.CodeTTL,
+		IdentityVerifier:     newIdentityVerifier(cfg),
+		AdminUserIDs:         cfg.APIKeys.AdminUserIDs,
 	}
 	return handler.NewServer(opts)
 }
+
+// newIdentityVerifier return the verifier of the ID tokens of the social
+// login providers.
+func newIdentityVerifier(cfg *config.Config) *oidc.Verifier {
+	providers := make([]oidc.Provider, 0, len(cfg.SocialLogin.Providers))
+	for name, provider := range cfg.SocialLogin.Providers {
+		providers = append(providers, oidc.Provider{
+			Name:      name,
+			Issuer:    provider.Issuer,
+			ClientIDs: provider.ClientIDs,
+			JWKSURL:   provider.JWKSURL,
+		})
+	}
+	return oidc.NewVerifier(oidc.NewVerifierOptions{
+		Providers: providers,
+		CacheTTL:  cfg.SocialLogin.JWKSCacheTTL,
+	})
+}
+
+// newIDTokenKey return the RSA key of OIDC_SIGNING_KEY_FILE, PKCS #1 or
+// PKCS #8 PEM encoded. A key is generated when no file is configured, nil
+// when the provider is disabled.
+func newIDTokenKey(cfg *config.Config, logger *slog.Logger) *rsa.PrivateKey {
+	if cfg.OIDC.Issuer == "" {
+		return nil
+	}
+	if cfg.OIDC.SigningKeyFile == "" {
+		logger.Warn("OIDC_SIGNING_KEY_FILE is not set, ID tokens are signed with a generated key that changes on restart")
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			fatal(err)
+		}
+		return key
+	}
+
+	data, err := os.ReadFile(cfg.OIDC.SigningKeyFile)
+	if err != nil {
+		fatal(fmt.Errorf("read signing key: %w", err))
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		fatal(fmt.Errorf("read signing key: %s is not PEM encoded", cfg.OIDC.SigningKeyFile))
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		fatal(fmt.Errorf("parse signing key: %w", err))
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		fatal(fmt.Errorf("parse signing key: %s is not an RSA key", cfg.OIDC.SigningKeyFile))
+	}
+	return key
+}
+
+// newBlobStore return the store selected by BLOB_STORE.
+func newBlobStore(cfg *config.Config) storage.BlobStore {
+	if cfg.Blob.Store == "s3" {
+		return storage.NewS3Store(storage.NewS3StoreOptions{
+			Endpoint:        cfg.Blob.S3.Endpoint,
+			Region:          cfg.Blob.S3.Region,
+			Bucket:          cfg.Blob.S3.Bucket,
+			AccessKeyID:     cfg.Blob.S3.AccessKeyID,
+			SecretAccessKey: cfg.Blob.S3.SecretAccessKey,
+			BaseURL:         cfg.Blob.BaseURL,
+		})
+	}
+	baseURL := cfg.Blob.BaseURL
+	if baseURL == "" {
+		baseURL = "/blobs"
+	}
+	return storage.NewFileStore(storage.NewFileStoreOptions{
+		Dir:     cfg.Blob.Dir,
+		BaseURL: baseURL,
+	})
+}
+
+// avatarPath is the upload route, whose body is bounded by AVATAR_MAX_BYTES.
+const avatarPath = handler.V2BaseURL + "/users/me/avatar"
+
+// newBodyLimit reject bodies larger than HTTP_MAX_BODY_BYTES with 413 before
+// they are read, except avatar uploads.
+func newBodyLimit(cfg *config.Config) echo.MiddlewareFunc {
+	return middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
+		Limit: fmt.Sprintf("%dB", cfg.HTTP.MaxBodyBytes),
+		Skipper: func(c echo.Context) bool {
+			return c.Path() == avatarPath
+		},
+	})
+}
+
+// newAvatarBodyLimit reject avatar uploads larger than AVATAR_MAX_BYTES with
+// 413 before they are read, other requests are bounded by newBodyLimit.
+func newAvatarBodyLimit(cfg *config.Config) echo.MiddlewareFunc {
+	return middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
+		Limit: fmt.Sprintf("%dB", cfg.Avatar.MaxBytes+multipartOverhead),
+		Skipper: func(c echo.Context) bool {
+			return c.Path() != avatarPath
+		},
+	})
+}
+
+// newMailer return the sender selected by MAIL_SENDER.
+func newMailer(cfg *config.Config, logger *slog.Logger) mail.Sender {
+	if cfg.Mail.Sender == "smtp" {
+		return mail.NewSMTPSender(mail.NewSMTPSenderOptions{
+			Addr:     cfg.Mail.SMTP.Addr,
+			Username: cfg.Mail.SMTP.Username,
+			Password: cfg.Mail.SMTP.Password,
+			From:     cfg.Mail.From,
+		})
+	}
+	return mail.NewLogSender(mail.NewLogSenderOptions{Logger: logger})
+}
+
+func newLoginHistoryPruner(cfg *config.Config, repo repository.RepositoryInterface) *job.LoginHistoryPruner {
+	return job.NewLoginHistoryPruner(job.NewLoginHistoryPrunerOptions{
+		Repository: repo,
+		Retention:  cfg.LoginHistory.Retention,
+		Interval:   cfg.LoginHistory.PruneInterval,
+	})
+}
+
+func newRateLimitBucketPruner(cfg *config.Config, repo repository.RepositoryInterface) *job.RateLimitBucketPruner {
+	return job.NewRateLimitBucketPruner(job.NewRateLimitBucketPrunerOptions{
+		Repository: repo,
+		Interval:   rateLimitPruneInterval,
+	})
+}
+
+func newIdempotencyKeyPruner(cfg *config.Config, repo repository.RepositoryInterface) *job.IdempotencyKeyPruner {
+	return job.NewIdempotencyKeyPruner(job.NewIdempotencyKeyPrunerOptions{
+		Repository: repo,
+		Interval:   cfg.Idempotency.PruneInterval,
+	})
+}
+
+// runJob run job in the background and stop it when the manager shuts down.
+func runJob(manager *lifecycle.Manager, name string, run func(ctx context.Context)) {
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(ctx)
+	}()
+	manager.OnShutdown(name, func(ctx context.Context) error {
+		stop()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// fatal log err and exit, slog has no Fatal.
+func fatal(err error) {
+	slog.Error("fatal", slog.Any("error", err))
+	os.Exit(1)
+}
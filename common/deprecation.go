@@ -0,0 +1,58 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// Deprecation response headers, see RFC 9745 and RFC 8594.
+const (
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset      = "Sunset"
+	HeaderLink        = "Link"
+)
+
+type DeprecationOptions struct {
+	// Spec lists the deprecated operations.
+	Spec *openapi3.T
+	// DeprecatedAt is when the operations were deprecated.
+	DeprecatedAt time.Time
+	// SunsetAt is when the operations stop being served, no Sunset header when zero.
+	SunsetAt time.Time
+	// Successor is the path of the version replacing them, no Link header when empty.
+	Successor string
+	Skipper   middleware.Skipper
+}
+
+// DeprecationMiddleware mark the responses of the operations of the spec as
+// deprecated.
+func DeprecationMiddleware(opts DeprecationOptions) echo.MiddlewareFunc {
+	if opts.Skipper == nil {
+		opts.Skipper = middleware.DefaultSkipper
+	}
+	operations := OperationIDs(opts.Spec)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, ok := operations[c.Request().Method+" "+c.Path()]; !ok || opts.Skipper(c) {
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set(HeaderDeprecation, fmt.Sprintf("@%d", opts.DeprecatedAt.Unix()))
+			if !opts.SunsetAt.IsZero() {
+				header.Set(HeaderSunset, opts.SunsetAt.UTC().Format(http.TimeFormat))
+			}
+			if opts.Successor != "" {
+				header.Add(HeaderLink, fmt.Sprintf(`<%s>; rel="successor-version"`, opts.Successor))
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecationMiddleware(t *testing.T) {
+	spec := &openapi3.T{
+		Paths: openapi3.Paths{
+			"/users": &openapi3.PathItem{
+				Get: &openapi3.Operation{OperationID: "getUser"},
+			},
+			"/healthz": &openapi3.PathItem{
+				Get: &openapi3.Operation{OperationID: "healthz"},
+			},
+		},
+	}
+
+	testCases := []struct {
+		testID     int
+		testDesc   string
+		path       string
+		sunsetAt   time.Time
+		successor  string
+		wantHeader http.Header
+	}{
+		{
+			testID:    1,
+			testDesc:  "Success - deprecated operation",
+			path:      "/users",
+			sunsetAt:  time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC),
+			successor: "/v2",
+			wantHeader: http.Header{
+				HeaderDeprecation: {"@1798675200"},
+				HeaderSunset:      {"Fri, 30 Apr 2027 00:00:00 GMT"},
+				HeaderLink:        {`</v2>; rel="successor-version"`},
+			},
+		},
+		{
+			testID:   2,
+			testDesc: "Success - without sunset and successor",
+			path:     "/users",
+			wantHeader: http.Header{
+				HeaderDeprecation: {"@1798675200"},
+			},
+		},
+		{
+			testID:     3,
+			testDesc:   "Success - skipped operation",
+			path:       "/healthz",
+			wantHeader: http.Header{},
+		},
+		{
+			testID:     4,
+			testDesc:   "Success - operation of another spec",
+			path:       "/v2/users/me",
+			wantHeader: http.Header{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testDesc, func(t *testing.T) {
+			e := echo.New()
+			e.Use(DeprecationMiddleware(DeprecationOptions{
+				Spec:         spec,
+				DeprecatedAt: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+				SunsetAt:     tc.sunsetAt,
+				Successor:    tc.successor,
+				Skipper: func(c echo.Context) bool {
+					return c.Path() == "/healthz"
+				},
+			}))
+			handler := func(c echo.Context) error {
+				return c.NoContent(http.StatusNoContent)
+			}
+			e.GET("/users", handler)
+			e.GET("/healthz", handler)
+			e.GET("/v2/users/me", handler)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+			for _, key := range []string{HeaderDeprecation, HeaderSunset, HeaderLink} {
+				assert.Equal(t, tc.wantHeader.Values(key), rec.Header().Values(key), key)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package common
+
+import (
+	"errors"
+	"strings"
+)
+
+// IdentifierType is the kind of identifier a user logs in with.
+type IdentifierType string
+
+const (
+	IdentifierPhone IdentifierType = "phone"
+	IdentifierEmail IdentifierType = "email"
+)
+
+// ErrInvalidIdentifier is returned for an identifier that is neither a phone
+// number nor an email.
+var ErrInvalidIdentifier = errors.New("identifier must be a phone number or an email")
+
+// An Identifier is a normalized login identifier, Value is in the form the
+// repository stores it.
+type Identifier struct {
+	Type  IdentifierType
+	Value string
+}
+
+// ParseIdentifier detect the type of identifier and normalize it. Emails are
+// lower cased. Phone numbers lose their separators and local numbers like
+// 0812... get the +62 country code.
+func ParseIdentifier(identifier string) (Identifier, error) {
+	identifier = strings.TrimSpace(identifier)
+	if strings.Contains(identifier, "@") {
+		email := NormalizeEmail(identifier)
+		at := strings.LastIndex(email, "@")
+		if at == 0 || at == len(email)-1 || strings.ContainsAny(email, " \t") {
+			return Identifier{}, ErrInvalidIdentifier
+		}
+		return Identifier{Type: IdentifierEmail, Value: email}, nil
+	}
+
+	phone := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')', '.':
+			return -1
+		}
+		return r
+	}, identifier)
+	switch {
+	case strings.HasPrefix(phone, "0"):
+		phone = "+62" + phone[1:]
+	case strings.HasPrefix(phone, "62"):
+		phone = "+" + phone
+	}
+	if len(phone) < 2 || phone[0] != '+' || strings.IndexFunc(phone[1:], func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return Identifier{}, ErrInvalidIdentifier
+	}
+	return Identifier{Type: IdentifierPhone, Value: phone}, nil
+}
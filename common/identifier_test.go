@@ -0,0 +1,34 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIdentifier(t *testing.T) {
+	testCases := []struct {
+		testID     int
+		testDesc   string
+		identifier string
+		want       Identifier
+		wantErr    bool
+	}{
+		{testID: 1, testDesc: "Failed - empty", identifier: " ", wantErr: true},
+		{testID: 2, testDesc: "Failed - username", identifier: "budi", wantErr: true},
+		{testID: 3, testDesc: "Failed - email without domain", identifier: "budi@", wantErr: true},
+		{testID: 4, testDesc: "Failed - phone with letters", identifier: "+62812ABC", wantErr: true},
+		{testID: 5, testDesc: "Success - email lower cased", identifier: " Budi@Example.COM ", want: Identifier{Type: IdentifierEmail, Value: "budi@example.com"}},
+		{testID: 6, testDesc: "Success - international phone", identifier: "+6281234567", want: Identifier{Type: IdentifierPhone, Value: "+6281234567"}},
+		{testID: 7, testDesc: "Success - local phone with separators", identifier: "0812-3456 7", want: Identifier{Type: IdentifierPhone, Value: "+6281234567"}},
+		{testID: 8, testDesc: "Success - phone without plus", identifier: "62 812 34567", want: Identifier{Type: IdentifierPhone, Value: "+6281234567"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testDesc, func(t *testing.T) {
+			got, err := ParseIdentifier(tc.identifier)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
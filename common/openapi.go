@@ -0,0 +1,75 @@
+package common
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// OperationIDs map "METHOD /echo/path/:param" to the operation id of the spec.
+func OperationIDs(spec *openapi3.T) map[string]string {
+	ids := map[string]string{}
+	if spec == nil {
+		return ids
+	}
+	for path, item := range spec.Paths {
+		route := EchoRoute(path)
+		for method, operation := range item.Operations() {
+			ids[method+" "+route] = operation.OperationID
+		}
+	}
+	return ids
+}
+
+// EchoRoute convert OpenAPI path /users/{id} to echo route /users/:id.
+func EchoRoute(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + segment[1:len(segment)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// MergeSpecs return a spec with the paths of every spec, prefixed by the path
+// of its first server, e.g. /users of a spec served at /v2 becomes /v2/users.
+// References are resolved already, so of the components only the security
+// schemes, looked up by name, are merged.
+func MergeSpecs(specs ...*openapi3.T) *openapi3.T {
+	merged := &openapi3.T{
+		Paths: openapi3.Paths{},
+		Components: &openapi3.Components{
+			SecuritySchemes: openapi3.SecuritySchemes{},
+		},
+	}
+	for _, spec := range specs {
+		if merged.OpenAPI == "" {
+			merged.OpenAPI = spec.OpenAPI
+			merged.Info = spec.Info
+		}
+		base := BasePath(spec)
+		for path, item := range spec.Paths {
+			merged.Paths[base+path] = item
+		}
+		if spec.Components != nil {
+			for name, scheme := range spec.Components.SecuritySchemes {
+				merged.Components.SecuritySchemes[name] = scheme
+			}
+		}
+	}
+	return merged
+}
+
+// BasePath return the path of the first server of spec, without trailing slash.
+func BasePath(spec *openapi3.T) string {
+	if len(spec.Servers) == 0 {
+		return ""
+	}
+	u, err := url.Parse(spec.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
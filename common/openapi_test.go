@@ -0,0 +1,84 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationIDs(t *testing.T) {
+	testCases := []struct {
+		testID   int
+		testDesc string
+		spec     *openapi3.T
+		want     map[string]string
+	}{
+		{
+			testID:   1,
+			testDesc: "Success - without spec",
+			spec:     nil,
+			want:     map[string]string{},
+		},
+		{
+			testID:   2,
+			testDesc: "Success - path parameters use echo syntax",
+			spec: &openapi3.T{
+				Paths: openapi3.Paths{
+					"/users": &openapi3.PathItem{
+						Get:   &openapi3.Operation{OperationID: "getUser"},
+						Patch: &openapi3.Operation{OperationID: "UpdateUser"},
+					},
+					"/users/sessions/{id}": &openapi3.PathItem{
+						Delete: &openapi3.Operation{OperationID: "revokeUserSession"},
+					},
+				},
+			},
+			want: map[string]string{
+				"GET /users":                 "getUser",
+				"PATCH /users":               "UpdateUser",
+				"DELETE /users/sessions/:id": "revokeUserSession",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testDesc, func(t *testing.T) {
+			assert.Equal(t, tc.want, OperationIDs(tc.spec))
+		})
+	}
+}
+
+func TestMergeSpecs(t *testing.T) {
+	v1 := &openapi3.T{
+		OpenAPI: "3.0.0",
+		Servers: openapi3.Servers{{URL: "http://localhost"}},
+		Paths: openapi3.Paths{
+			"/users": &openapi3.PathItem{
+				Get: &openapi3.Operation{OperationID: "getUser"},
+			},
+		},
+	}
+	bearer := &openapi3.SecuritySchemeRef{Value: openapi3.NewJWTSecurityScheme()}
+	v2 := &openapi3.T{
+		OpenAPI: "3.0.0",
+		Servers: openapi3.Servers{{URL: "/v2/"}},
+		Components: &openapi3.Components{
+			SecuritySchemes: openapi3.SecuritySchemes{"bearerAuth": bearer},
+		},
+		Paths: openapi3.Paths{
+			"/users/me": &openapi3.PathItem{
+				Get: &openapi3.Operation{OperationID: "getMe"},
+			},
+		},
+	}
+
+	merged := MergeSpecs(v1, v2)
+
+	assert.Equal(t, "3.0.0", merged.OpenAPI)
+	assert.Equal(t, map[string]string{
+		"GET /users":       "getUser",
+		"GET /v2/users/me": "getMe",
+	}, OperationIDs(merged))
+	assert.Equal(t, openapi3.SecuritySchemes{"bearerAuth": bearer}, merged.Components.SecuritySchemes)
+}
@@ -0,0 +1,15 @@
+package common
+
+import (
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/labstack/echo/v4"
+)
+
+// ErrorResponse return error body tagged with the request id, if any.
+func ErrorResponse(c echo.Context, message string) generated.ErrorResponse {
+	resp := generated.ErrorResponse{Message: message}
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		resp.RequestId = &id
+	}
+	return resp
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+)
+
+// OperationSecurity map "METHOD /echo/path/:param" to the scopes the
+// security of the operation requires of each scheme it accepts. Operations
+// without security are left out.
+func OperationSecurity(spec *openapi3.T) map[string]map[string][]string {
+	security := map[string]map[string][]string{}
+	if spec == nil {
+		return security
+	}
+	for path, item := range spec.Paths {
+		route := EchoRoute(path)
+		for method, operation := range item.Operations() {
+			if operation.Security == nil {
+				continue
+			}
+			schemes := map[string][]string{}
+			for _, requirement := range *operation.Security {
+				for scheme, scopes := range requirement {
+					schemes[scheme] = append([]string{}, scopes...)
+				}
+			}
+			security[method+" "+route] = schemes
+		}
+	}
+	return security
+}
+
+// SecurityMiddleware set the scopes the operation requires of each scheme on
+// the context, under the "<scheme>.Scopes" keys the generated wrappers set
+// them, so middlewares running before the handler authorize requests like
+// it does.
+func SecurityMiddleware(spec *openapi3.T) echo.MiddlewareFunc {
+	security := OperationSecurity(spec)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			for scheme, scopes := range security[c.Request().Method+" "+c.Path()] {
+				c.Set(scheme+".Scopes", scopes)
+			}
+			return next(c)
+		}
+	}
+}
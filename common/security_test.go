@@ -0,0 +1,96 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityMiddleware(t *testing.T) {
+	spec := &openapi3.T{
+		Paths: openapi3.Paths{
+			"/login": &openapi3.PathItem{
+				Post: &openapi3.Operation{OperationID: "login"},
+			},
+			"/users": &openapi3.PathItem{
+				Patch: &openapi3.Operation{
+					OperationID: "updateUser",
+					Security: &openapi3.SecurityRequirements{
+						{"bearerAuth": {"profile:write"}},
+						{"apiKeyAuth": {"profile:write"}},
+					},
+				},
+			},
+			"/userinfo": &openapi3.PathItem{
+				Get: &openapi3.Operation{
+					OperationID: "getUserinfo",
+					Security:    &openapi3.SecurityRequirements{{"bearerAuth": {}}},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		testID     int
+		testDesc   string
+		method     string
+		path       string
+		wantScopes map[string][]string
+	}{
+		{
+			testID:     1,
+			testDesc:   "Success - operation without security",
+			method:     http.MethodPost,
+			path:       "/login",
+			wantScopes: map[string][]string{},
+		},
+		{
+			testID:   2,
+			testDesc: "Success - scopes of every scheme",
+			method:   http.MethodPatch,
+			path:     "/users",
+			wantScopes: map[string][]string{
+				"bearerAuth": {"profile:write"},
+				"apiKeyAuth": {"profile:write"},
+			},
+		},
+		{
+			testID:   3,
+			testDesc: "Success - scheme without scopes",
+			method:   http.MethodGet,
+			path:     "/userinfo",
+			wantScopes: map[string][]string{
+				"bearerAuth": {},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testDesc, func(t *testing.T) {
+			got := map[string][]string{}
+			e := echo.New()
+			e.Use(SecurityMiddleware(spec))
+			handler := func(c echo.Context) error {
+				for _, scheme := range []string{"bearerAuth", "apiKeyAuth"} {
+					if scopes, ok := c.Get(scheme + ".Scopes").([]string); ok {
+						got[scheme] = scopes
+					}
+				}
+				return c.NoContent(http.StatusNoContent)
+			}
+			e.POST("/login", handler)
+			e.PATCH("/users", handler)
+			e.GET("/userinfo", handler)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+			assert.Equal(t, tc.wantScopes, got)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package common
+
+import "strings"
+
+// maxDeviceNameLength limits device name to fit the user_sessions column.
+const maxDeviceNameLength = 100
+
+// userAgentBrowsers is ordered so that more specific tokens are matched first,
+// e.g. Edge and Chrome user agents also contain "Safari/".
+var userAgentBrowsers = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"okhttp/", "Android app"},
+	{"CFNetwork/", "iOS app"},
+	{"curl/", "curl"},
+}
+
+var userAgentPlatforms = []struct {
+	token string
+	name  string
+}{
+	{"iPhone", "iPhone"},
+	{"iPad", "iPad"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// DeviceName derive a human readable device name from user agent,
+// like "Chrome on Android". Unknown user agents return "Unknown device".
+func DeviceName(userAgent string) string {
+	var browser, platform string
+	for _, b := range userAgentBrowsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, p := range userAgentPlatforms {
+		if strings.Contains(userAgent, p.token) {
+			platform = p.name
+			break
+		}
+	}
+
+	var name string
+	switch {
+	case browser != "" && platform != "":
+		name = browser + " on " + platform
+	case browser != "":
+		name = browser
+	case platform != "":
+		name = platform
+	case strings.TrimSpace(userAgent) != "":
+		// headers are not guaranteed to be UTF-8, Postgres rejects invalid text.
+		name = strings.ToValidUTF8(strings.TrimSpace(userAgent), "")
+	default:
+		name = "Unknown device"
+	}
+
+	// the column counts characters, cutting bytes could split one.
+	if runes := []rune(name); len(runes) > maxDeviceNameLength {
+		name = string(runes[:maxDeviceNameLength])
+	}
+	return name
+}
@@ -0,0 +1,95 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceName(t *testing.T) {
+	type (
+		args struct {
+			payload string
+		}
+	)
+
+	testCases := []struct {
+		testID   int
+		testDesc string
+		args     args
+		want     string
+	}{
+		{
+			testID:   1,
+			testDesc: "Chrome on Android",
+			args: args{
+				payload: `Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Mobile Safari/537.36`,
+			},
+			want: "Chrome on Android",
+		},
+		{
+			testID:   2,
+			testDesc: "Safari on iPhone",
+			args: args{
+				payload: `Mozilla/5.0 (iPhone; CPU iPhone OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1`,
+			},
+			want: "Safari on iPhone",
+		},
+		{
+			testID:   3,
+			testDesc: "Edge on Windows",
+			args: args{
+				payload: `Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Safari/537.36 Edg/116.0.1938.69`,
+			},
+			want: "Edge on Windows",
+		},
+		{
+			testID:   4,
+			testDesc: "Browser only",
+			args: args{
+				payload: `okhttp/4.11.0`,
+			},
+			want: "Android app",
+		},
+		{
+			testID:   5,
+			testDesc: "Unrecognized user agent",
+			args: args{
+				payload: `sawitpro-cli/1.0`,
+			},
+			want: "sawitpro-cli/1.0",
+		},
+		{
+			testID:   6,
+			testDesc: "Empty user agent",
+			args: args{
+				payload: ``,
+			},
+			want: "Unknown device",
+		},
+		{
+			testID:   7,
+			testDesc: "Long multibyte user agent",
+			args: args{
+				payload: strings.Repeat("端末", 60),
+			},
+			want: strings.Repeat("端末", 50),
+		},
+		{
+			testID:   8,
+			testDesc: "Invalid UTF-8 user agent",
+			args: args{
+				payload: "sawitpro-cli/1.0 \xe7\xab",
+			},
+			want: "sawitpro-cli/1.0 ",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testDesc, func(t *testing.T) {
+			resp := DeviceName(tc.args.payload)
+			assert.Equal(t, tc.want, resp)
+		})
+	}
+}
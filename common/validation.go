@@ -1,9 +1,15 @@
 package common
 
 import (
+	"net/mail"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
 )
 
 // ValidatePhone validate phone number with given rules.
@@ -62,3 +68,95 @@ func ValidatePassword(password string) (errMsg []string) {
 	}
 	return
 }
+
+// NormalizeEmail return email trimmed and lower cased, the form it is stored in.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateEmail validate email address with given rules.
+// A successful ValidateEmail returns empty errMsg.
+func ValidateEmail(email string) (errMsg []string) {
+	if len(email) > 254 {
+		errMsg = append(errMsg, "Email must be less than 254")
+	}
+	// a bare address, without display name or comments.
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || !strings.Contains(email[strings.LastIndex(email, "@")+1:], ".") {
+		errMsg = append(errMsg, "Email must be a valid email address")
+	}
+	return
+}
+
+// ValidateDisplayName validate display name with given rules.
+// A successful ValidateDisplayName returns empty errMsg.
+func ValidateDisplayName(name string) (errMsg []string) {
+	if utf8.RuneCountInString(name) > 60 {
+		errMsg = append(errMsg, "Display name must be less than 60")
+	}
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		errMsg = append(errMsg, "Display name must not contain control characters")
+	}
+	return
+}
+
+// ValidateBirthDate validate birth date formatted as YYYY-MM-DD with given rules.
+// A successful ValidateBirthDate returns empty errMsg.
+func ValidateBirthDate(date string) (errMsg []string) {
+	birthDate, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return append(errMsg, "Birth date must be formatted as YYYY-MM-DD")
+	}
+	if birthDate.After(time.Now()) {
+		errMsg = append(errMsg, "Birth date must not be in the future")
+	}
+	if birthDate.Year() < 1900 {
+		errMsg = append(errMsg, "Birth date must be after 1900")
+	}
+	return
+}
+
+// NormalizeLocale return the canonical form of a BCP 47 locale, like en-US.
+// Invalid locales are returned unchanged.
+func NormalizeLocale(locale string) string {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return locale
+	}
+	return tag.String()
+}
+
+// ValidateLocale validate locale is a BCP 47 language tag, like en or id-ID.
+// A successful ValidateLocale returns empty errMsg.
+func ValidateLocale(locale string) (errMsg []string) {
+	if len(locale) > 35 {
+		errMsg = append(errMsg, "Locale must be less than 35")
+	}
+	if _, err := language.Parse(locale); err != nil {
+		errMsg = append(errMsg, "Locale must be a BCP 47 language tag")
+	}
+	return
+}
+
+// ValidateTimezone validate timezone is an IANA time zone name, like Asia/Jakarta.
+// A successful ValidateTimezone returns empty errMsg.
+func ValidateTimezone(timezone string) (errMsg []string) {
+	// Local depends on the host, it is not a zone name.
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "Local" || len(timezone) > 64 {
+		errMsg = append(errMsg, "Timezone must be an IANA time zone name")
+	}
+	return
+}
+
+// ValidateAvatarURL validate avatar url with given rules.
+// A successful ValidateAvatarURL returns empty errMsg.
+func ValidateAvatarURL(avatarURL string) (errMsg []string) {
+	if len(avatarURL) > 2048 {
+		errMsg = append(errMsg, "Avatar URL must be less than 2048")
+	}
+	u, err := url.Parse(avatarURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		errMsg = append(errMsg, "Avatar URL must be an absolute https URL")
+	}
+	return
+}
@@ -1,7 +1,9 @@
 package common
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -170,3 +172,131 @@ func TestValidatePassword(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateEmail(t *testing.T) {
+	testCases := []struct {
+		testID   int
+		testDesc string
+		email    string
+		wantErr  bool
+	}{
+		{testID: 1, testDesc: "Failed - missing domain", email: "budi@", wantErr: true},
+		{testID: 2, testDesc: "Failed - display name", email: "Budi <budi@example.com>", wantErr: true},
+		{testID: 3, testDesc: "Failed - domain without dot", email: "budi@localhost", wantErr: true},
+		{testID: 4, testDesc: "Success", email: "budi@example.com", wantErr: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testDesc, func(t *testing.T) {
+			resp := ValidateEmail(tc.email)
+			assert.Equal(t, len(resp) > 0, tc.wantErr)
+		})
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	assert.Equal(t, "budi@example.com", NormalizeEmail(" Budi@Example.COM "))
+}
+
+func TestValidateDisplayName(t *testing.T) {
+	testCases := []struct {
+		testID   int
+		testDesc string
+		name     string
+		wantErr  bool
+	}{
+		{testID: 1, testDesc: "Failed - too long", name: strings.Repeat("a", 61), wantErr: true},
+		{testID: 2, testDesc: "Failed - control character", name: "Budi\n", wantErr: true},
+		{testID: 3, testDesc: "Success - non ascii", name: "Budi 🌴", wantErr: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testDesc, func(t *testing.T) {
+			resp := ValidateDisplayName(tc.name)
+			assert.Equal(t, len(resp) > 0, tc.wantErr)
+		})
+	}
+}
+
+func TestValidateBirthDate(t *testing.T) {
+	testCases := []struct {
+		testID   int
+		testDesc string
+		date     string
+		wantErr  bool
+	}{
+		{testID: 1, testDesc: "Failed - not a date", date: "01-02-1990", wantErr: true},
+		{testID: 2, testDesc: "Failed - future", date: time.Now().AddDate(1, 0, 0).Format(time.DateOnly), wantErr: true},
+		{testID: 3, testDesc: "Failed - too old", date: "1850-01-01", wantErr: true},
+		{testID: 4, testDesc: "Success", date: "1990-02-01", wantErr: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testDesc, func(t *testing.T) {
+			resp := ValidateBirthDate(tc.date)
+			assert.Equal(t, len(resp) > 0, tc.wantErr)
+		})
+	}
+}
+
+func TestValidateLocale(t *testing.T) {
+	testCases := []struct {
+		testID   int
+		testDesc string
+		locale   string
+		wantErr  bool
+		want     string
+	}{
+		{testID: 1, testDesc: "Failed - not a language tag", locale: "english!", wantErr: true, want: "english!"},
+		{testID: 2, testDesc: "Success - normalized", locale: "id_id", wantErr: false, want: "id-ID"},
+		{testID: 3, testDesc: "Success", locale: "en", wantErr: false, want: "en"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testDesc, func(t *testing.T) {
+			resp := ValidateLocale(tc.locale)
+			assert.Equal(t, len(resp) > 0, tc.wantErr)
+			assert.Equal(t, tc.want, NormalizeLocale(tc.locale))
+		})
+	}
+}
+
+func TestValidateTimezone(t *testing.T) {
+	testCases := []struct {
+		testID   int
+		testDesc string
+		timezone string
+		wantErr  bool
+	}{
+		{testID: 1, testDesc: "Failed - unknown zone", timezone: "Asia/Atlantis", wantErr: true},
+		{testID: 2, testDesc: "Failed - local", timezone: "Local", wantErr: true},
+		{testID: 3, testDesc: "Success", timezone: "Asia/Jakarta", wantErr: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testDesc, func(t *testing.T) {
+			resp := ValidateTimezone(tc.timezone)
+			assert.Equal(t, len(resp) > 0, tc.wantErr)
+		})
+	}
+}
+
+func TestValidateAvatarURL(t *testing.T) {
+	testCases := []struct {
+		testID    int
+		testDesc  string
+		avatarURL string
+		wantErr   bool
+	}{
+		{testID: 1, testDesc: "Failed - not https", avatarURL: "http://example.com/a.png", wantErr: true},
+		{testID: 2, testDesc: "Failed - relative", avatarURL: "/a.png", wantErr: true},
+		{testID: 3, testDesc: "Success", avatarURL: "https://cdn.example.com/a.png", wantErr: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testDesc, func(t *testing.T) {
+			resp := ValidateAvatarURL(tc.avatarURL)
+			assert.Equal(t, len(resp) > 0, tc.wantErr)
+		})
+	}
+}
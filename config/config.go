@@ -0,0 +1,715 @@
+// This file contains the service configuration loaded at startup.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// minSecretLength is the minimum length in bytes of the JWT signing secret.
+const minSecretLength = 32
+
+// redacted replaces secret values when the config is printed.
+const redacted = "[REDACTED]"
+
+// providerNamePattern matches the names of social login providers, which
+// are stored with the identities of users.
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// Config is the effective service configuration.
+// Values are resolved from defaults, then the optional YAML file named by
+// CONFIG_FILE, then environment variables.
+type Config struct {
+	HTTP         HTTPConfig         `yaml:"http"`
+	Database     DatabaseConfig     `yaml:"database"`
+	Auth         AuthConfig         `yaml:"auth"`
+	CORS         CORSConfig         `yaml:"cors"`
+	RateLimit    RateLimitConfig    `yaml:"rate_limit"`
+	Log          LogConfig          `yaml:"log"`
+	Metrics      MetricsConfig      `yaml:"metrics"`
+	Tracing      TracingConfig      `yaml:"tracing"`
+	LoginHistory LoginHistoryConfig `yaml:"login_history"`
+	Idempotency  IdempotencyConfig  `yaml:"idempotency"`
+	Validation   ValidationConfig   `yaml:"validation"`
+	API          APIConfig          `yaml:"api"`
+	Mail         MailConfig         `yaml:"mail"`
+	Avatar       AvatarConfig       `yaml:"avatar"`
+	Blob         BlobConfig         `yaml:"blob"`
+	OIDC         OIDCConfig         `yaml:"oidc"`
+	SocialLogin  SocialLoginConfig  `yaml:"social_login"`
+	APIKeys      APIKeysConfig      `yaml:"api_keys"`
+}
+
+type HTTPConfig struct {
+	Addr         string        `yaml:"addr"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// DrainDelay keeps serving with failing readiness before the listener closes on shutdown.
+	DrainDelay time.Duration `yaml:"drain_delay"`
+	// ShutdownTimeout bounds draining in-flight requests and releasing resources on shutdown.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// TrustedProxies lists the IPs or CIDRs of proxies whose X-Forwarded-For
+	// header is trusted for the client IP. The peer address is used when empty.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// MaxBodyBytes is the largest request body accepted, avatar uploads are
+	// bounded by AVATAR_MAX_BYTES instead.
+	MaxBodyBytes int `yaml:"max_body_bytes"`
+}
+
+type DatabaseConfig struct {
+	URL             string        `yaml:"url"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	// AutoMigrate applies pending migrations at startup.
+	AutoMigrate bool `yaml:"auto_migrate"`
+}
+
+type AuthConfig struct {
+	Secret     string        `yaml:"secret"`
+	TokenTTL   time.Duration `yaml:"token_ttl"`
+	BcryptCost int           `yaml:"bcrypt_cost"`
+}
+
+// CORSConfig lists origins allowed to call the API from a browser.
+// CORS is disabled when AllowedOrigins is empty.
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// RateLimitConfig limits requests per operation id. Operations without a
+// policy share a default limit per client IP, disabled when
+// RequestsPerSecond is zero.
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+	// Store is memory for a single instance or postgres to share limits
+	// between instances.
+	Store    string                     `yaml:"store"`
+	Policies map[string]RateLimitPolicy `yaml:"policies"`
+}
+
+// RateLimitPolicy allows Limit requests per Window for each key, one of ip,
+// user or phone. A zero Limit disables rate limiting of the operation.
+type RateLimitPolicy struct {
+	Limit  int           `yaml:"limit"`
+	Window time.Duration `yaml:"window"`
+	Key    string        `yaml:"key"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level"`
+}
+
+// MetricsConfig places the Prometheus /metrics endpoint.
+// It is served on the API listener when Addr is empty.
+type MetricsConfig struct {
+	Addr string `yaml:"addr"`
+}
+
+// TracingConfig selects where OpenTelemetry spans are exported.
+// The otlp exporter reads the standard OTEL_EXPORTER_OTLP_* variables.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`
+	File        string  `yaml:"file"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type LoginHistoryConfig struct {
+	Retention     time.Duration `yaml:"retention"`
+	PruneInterval time.Duration `yaml:"prune_interval"`
+}
+
+// IdempotencyConfig sets how long responses to requests sent with an
+// Idempotency-Key are replayed. Expired keys are pruned every PruneInterval.
+type IdempotencyConfig struct {
+	TTL           time.Duration `yaml:"ttl"`
+	PruneInterval time.Duration `yaml:"prune_interval"`
+}
+
+// ValidationConfig sets whether responses are validated against the OpenAPI
+// spec, on top of requests. Meant for tests, responses are buffered.
+type ValidationConfig struct {
+	Responses bool `yaml:"responses"`
+}
+
+// APIConfig announces the deprecation of API version 1, its responses carry
+// Deprecation and Sunset headers. No Sunset header is sent when V1SunsetAt is zero.
+type APIConfig struct {
+	V1DeprecatedAt time.Time `yaml:"v1_deprecated_at"`
+	V1SunsetAt     time.Time `yaml:"v1_sunset_at"`
+}
+
+// MailConfig selects how emails, like email verifications, are sent. The log
+// sender writes them to the log instead, for development.
+type MailConfig struct {
+	Sender string     `yaml:"sender"`
+	From   string     `yaml:"from"`
+	SMTP   SMTPConfig `yaml:"smtp"`
+	// VerificationURL is the page confirming an email, the token is passed
+	// in the token query parameter. Only the token is mailed when empty.
+	VerificationURL string        `yaml:"verification_url"`
+	VerificationTTL time.Duration `yaml:"verification_ttl"`
+}
+
+// SMTPConfig is the server of the smtp sender, authenticated when Username is set.
+type SMTPConfig struct {
+	Addr     string `yaml:"addr"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// AvatarConfig bounds avatar uploads, MaxBytes is the largest file accepted.
+type AvatarConfig struct {
+	MaxBytes int `yaml:"max_bytes"`
+}
+
+// BlobConfig selects where uploads, like avatars, are stored: files under Dir
+// or objects of an S3-compatible bucket. BaseURL is where they are served,
+// /blobs for files and the bucket URL for S3 when empty.
+type BlobConfig struct {
+	Store   string   `yaml:"store"`
+	Dir     string   `yaml:"dir"`
+	BaseURL string   `yaml:"base_url"`
+	S3      S3Config `yaml:"s3"`
+}
+
+// S3Config is the bucket of the s3 blob store, Endpoint is the URL of S3 or
+// of a compatible service such as MinIO.
+type S3Config struct {
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+}
+
+// OIDCConfig makes the service an OpenID Connect provider for the registered
+// OAuth clients, disabled when Issuer is empty. ID tokens are signed with the
+// RSA key of the PEM file SigningKeyFile, or a key generated at startup when
+// empty, whose tokens cannot be verified after a restart.
+type OIDCConfig struct {
+	Issuer         string        `yaml:"issuer"`
+	SigningKeyFile string        `yaml:"signing_key_file"`
+	CodeTTL        time.Duration `yaml:"code_ttl"`
+}
+
+// SocialLoginConfig lists the external OpenID Connect providers, by name,
+// whose ID tokens log users in. Their key sets are cached for JWKSCacheTTL.
+type SocialLoginConfig struct {
+	Providers    map[string]SocialLoginProvider `yaml:"providers"`
+	JWKSCacheTTL time.Duration                  `yaml:"jwks_cache_ttl"`
+}
+
+// SocialLoginProvider accepts the ID tokens of Issuer issued to one of
+// ClientIDs. JWKSURL is discovered from the issuer when empty.
+type SocialLoginProvider struct {
+	Issuer    string   `yaml:"issuer"`
+	ClientIDs []string `yaml:"client_ids"`
+	JWKSURL   string   `yaml:"jwks_url"`
+}
+
+// APIKeysConfig lists the users managing the API keys of service
+// integrations, nobody does when AdminUserIDs is empty. A key without a rate
+// limit of its own allows RateLimit requests per RateLimitWindow.
+type APIKeysConfig struct {
+	AdminUserIDs    []int64       `yaml:"admin_user_ids"`
+	RateLimit       int           `yaml:"rate_limit"`
+	RateLimitWindow time.Duration `yaml:"rate_limit_window"`
+}
+
+// Default return config with every optional value set.
+func Default() Config {
+	return Config{
+		HTTP: HTTPConfig{
+			Addr:         ":1323",
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  60 * time.Second,
+			// below the default Kubernetes termination grace period of 30s.
+			ShutdownTimeout: 20 * time.Second,
+			MaxBodyBytes:    1 << 20,
+		},
+		Database: DatabaseConfig{
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 30 * time.Minute,
+			AutoMigrate:     true,
+		},
+		Auth: AuthConfig{
+			TokenTTL:   24 * time.Hour,
+			BcryptCost: bcrypt.DefaultCost,
+		},
+		RateLimit: RateLimitConfig{
+			Burst: 20,
+			Store: "memory",
+			Policies: map[string]RateLimitPolicy{
+				"login":         {Limit: 5, Window: time.Minute, Key: "phone"},
+				"userRegister":  {Limit: 10, Window: time.Hour, Key: "ip"},
+				"createSession": {Limit: 5, Window: time.Minute, Key: "phone"},
+				"createUser":    {Limit: 10, Window: time.Hour, Key: "ip"},
+				// mails to a user, and guesses of a verification token.
+				"createEmailVerification": {Limit: 3, Window: time.Hour, Key: "user"},
+				"verifyEmail":             {Limit: 10, Window: time.Minute, Key: "ip"},
+				// resizing an image is expensive.
+				"uploadAvatar": {Limit: 10, Window: time.Hour, Key: "user"},
+				// the login page of the OpenID Connect provider, and guesses
+				// of an authorization code.
+				"submitAuthorization": {Limit: 5, Window: time.Minute, Key: "phone"},
+				"createToken":         {Limit: 30, Window: time.Minute, Key: "ip"},
+				// social logins sign users up without a password to guess.
+				"loginOIDC": {Limit: 10, Window: time.Minute, Key: "ip"},
+			},
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+		},
+		LoginHistory: LoginHistoryConfig{
+			Retention:     90 * 24 * time.Hour,
+			PruneInterval: time.Hour,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:           24 * time.Hour,
+			PruneInterval: time.Hour,
+		},
+		API: APIConfig{
+			V1DeprecatedAt: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+			V1SunsetAt:     time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC),
+		},
+		Mail: MailConfig{
+			Sender:          "log",
+			VerificationTTL: 24 * time.Hour,
+		},
+		Avatar: AvatarConfig{
+			MaxBytes: 5 << 20,
+		},
+		Blob: BlobConfig{
+			Store: "file",
+			Dir:   "data/blobs",
+			S3: S3Config{
+				Region: "us-east-1",
+			},
+		},
+		OIDC: OIDCConfig{
+			CodeTTL: time.Minute,
+		},
+		SocialLogin: SocialLoginConfig{
+			JWKSCacheTTL: time.Hour,
+		},
+		APIKeys: APIKeysConfig{
+			RateLimit:       600,
+			RateLimitWindow: time.Minute,
+		},
+	}
+}
+
+// Load resolve config from the process environment and validate it.
+func Load() (*Config, error) {
+	return load(os.LookupEnv)
+}
+
+func load(lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+
+	if path, ok := lookupEnv("CONFIG_FILE"); ok && path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(lookupEnv); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// loadFile overlay values present in the YAML file at path.
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err = decoder.Decode(c); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadEnv overlay values set in environment variables.
+func (c *Config) loadEnv(lookupEnv func(string) (string, bool)) error {
+	e := envReader{lookupEnv: lookupEnv}
+
+	e.string("HTTP_ADDR", &c.HTTP.Addr)
+	e.duration("HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout)
+	e.duration("HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout)
+	e.duration("HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout)
+	e.duration("HTTP_DRAIN_DELAY", &c.HTTP.DrainDelay)
+	e.duration("HTTP_SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout)
+	e.list("HTTP_TRUSTED_PROXIES", &c.HTTP.TrustedProxies)
+	e.int("HTTP_MAX_BODY_BYTES", &c.HTTP.MaxBodyBytes)
+
+	e.string("DATABASE_URL", &c.Database.URL)
+	e.int("DATABASE_MAX_OPEN_CONNS", &c.Database.MaxOpenConns)
+	e.int("DATABASE_MAX_IDLE_CONNS", &c.Database.MaxIdleConns)
+	e.duration("DATABASE_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime)
+	e.bool("DATABASE_AUTO_MIGRATE", &c.Database.AutoMigrate)
+
+	e.string("SECRET", &c.Auth.Secret)
+	e.duration("JWT_TTL", &c.Auth.TokenTTL)
+	e.int("BCRYPT_COST", &c.Auth.BcryptCost)
+
+	e.list("CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins)
+
+	e.float("RATE_LIMIT_RPS", &c.RateLimit.RequestsPerSecond)
+	e.int("RATE_LIMIT_BURST", &c.RateLimit.Burst)
+	e.string("RATE_LIMIT_STORE", &c.RateLimit.Store)
+	e.rateLimitPolicies("RATE_LIMIT_POLICIES", &c.RateLimit.Policies)
+
+	e.string("LOG_LEVEL", &c.Log.Level)
+
+	e.string("METRICS_ADDR", &c.Metrics.Addr)
+
+	e.string("TRACING_EXPORTER", &c.Tracing.Exporter)
+	e.string("TRACING_FILE", &c.Tracing.File)
+	e.float("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	e.duration("LOGIN_HISTORY_RETENTION", &c.LoginHistory.Retention)
+	e.duration("LOGIN_HISTORY_PRUNE_INTERVAL", &c.LoginHistory.PruneInterval)
+
+	e.duration("IDEMPOTENCY_TTL", &c.Idempotency.TTL)
+	e.duration("IDEMPOTENCY_PRUNE_INTERVAL", &c.Idempotency.PruneInterval)
+
+	e.bool("VALIDATE_RESPONSES", &c.Validation.Responses)
+
+	e.date("API_V1_DEPRECATED_AT", &c.API.V1DeprecatedAt)
+	e.date("API_V1_SUNSET_AT", &c.API.V1SunsetAt)
+
+	e.string("MAIL_SENDER", &c.Mail.Sender)
+	e.string("MAIL_FROM", &c.Mail.From)
+	e.string("SMTP_ADDR", &c.Mail.SMTP.Addr)
+	e.string("SMTP_USERNAME", &c.Mail.SMTP.Username)
+	e.string("SMTP_PASSWORD", &c.Mail.SMTP.Password)
+	e.string("EMAIL_VERIFICATION_URL", &c.Mail.VerificationURL)
+	e.duration("EMAIL_VERIFICATION_TTL", &c.Mail.VerificationTTL)
+
+	e.int("AVATAR_MAX_BYTES", &c.Avatar.MaxBytes)
+
+	e.string("BLOB_STORE", &c.Blob.Store)
+	e.string("BLOB_DIR", &c.Blob.Dir)
+	e.string("BLOB_BASE_URL", &c.Blob.BaseURL)
+	e.string("S3_ENDPOINT", &c.Blob.S3.Endpoint)
+	e.string("S3_REGION", &c.Blob.S3.Region)
+	e.string("S3_BUCKET", &c.Blob.S3.Bucket)
+	e.string("S3_ACCESS_KEY_ID", &c.Blob.S3.AccessKeyID)
+	e.string("S3_SECRET_ACCESS_KEY", &c.Blob.S3.SecretAccessKey)
+
+	e.string("OIDC_ISSUER", &c.OIDC.Issuer)
+	e.string("OIDC_SIGNING_KEY_FILE", &c.OIDC.SigningKeyFile)
+	e.duration("OIDC_CODE_TTL", &c.OIDC.CodeTTL)
+
+	e.socialLoginProviders("SOCIAL_LOGIN_PROVIDERS", &c.SocialLogin.Providers)
+	e.duration("SOCIAL_LOGIN_JWKS_CACHE_TTL", &c.SocialLogin.JWKSCacheTTL)
+
+	e.int64List("API_KEY_ADMIN_USER_IDS", &c.APIKeys.AdminUserIDs)
+	e.int("API_KEY_RATE_LIMIT", &c.APIKeys.RateLimit)
+	e.duration("API_KEY_RATE_LIMIT_WINDOW", &c.APIKeys.RateLimitWindow)
+
+	return e.err()
+}
+
+// Validate check required values are set and every value is usable.
+// All problems are reported at once.
+func (c *Config) Validate() error {
+	var errs []string
+
+	if c.HTTP.Addr == "" {
+		errs = append(errs, "HTTP_ADDR is required")
+	}
+	if c.HTTP.ReadTimeout <= 0 || c.HTTP.WriteTimeout <= 0 || c.HTTP.IdleTimeout <= 0 || c.HTTP.ShutdownTimeout <= 0 {
+		errs = append(errs, "HTTP timeouts must be positive")
+	}
+	if c.HTTP.MaxBodyBytes < 1 {
+		errs = append(errs, "HTTP_MAX_BODY_BYTES must be at least 1")
+	}
+	if c.HTTP.DrainDelay < 0 {
+		errs = append(errs, "HTTP_DRAIN_DELAY must not be negative")
+	}
+	for _, proxy := range c.HTTP.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Sprintf("HTTP_TRUSTED_PROXIES contains invalid IP or CIDR %q", proxy))
+		}
+	}
+
+	if c.Database.URL == "" {
+		errs = append(errs, "DATABASE_URL is required")
+	}
+	if c.Database.MaxOpenConns < 1 {
+		errs = append(errs, "DATABASE_MAX_OPEN_CONNS must be at least 1")
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, "DATABASE_MAX_IDLE_CONNS must be between 0 and DATABASE_MAX_OPEN_CONNS")
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		errs = append(errs, "DATABASE_CONN_MAX_LIFETIME must not be negative")
+	}
+
+	if c.Auth.Secret == "" {
+		errs = append(errs, "SECRET is required")
+	} else if len(c.Auth.Secret) < minSecretLength {
+		errs = append(errs, fmt.Sprintf("SECRET must be at least %d bytes", minSecretLength))
+	}
+	if c.Auth.TokenTTL <= 0 {
+		errs = append(errs, "JWT_TTL must be positive")
+	}
+	if c.Auth.BcryptCost < bcrypt.MinCost || c.Auth.BcryptCost > bcrypt.MaxCost {
+		errs = append(errs, fmt.Sprintf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Sprintf("CORS_ALLOWED_ORIGINS contains invalid origin %q", origin))
+		}
+	}
+
+	if c.RateLimit.RequestsPerSecond < 0 {
+		errs = append(errs, "RATE_LIMIT_RPS must not be negative")
+	}
+	if c.RateLimit.RequestsPerSecond > 0 && c.RateLimit.Burst < 1 {
+		errs = append(errs, "RATE_LIMIT_BURST must be at least 1")
+	}
+	switch c.RateLimit.Store {
+	case "memory", "postgres":
+	default:
+		errs = append(errs, fmt.Sprintf("RATE_LIMIT_STORE %q must be one of memory, postgres", c.RateLimit.Store))
+	}
+	for _, operation := range sortedKeys(c.RateLimit.Policies) {
+		policy := c.RateLimit.Policies[operation]
+		switch {
+		case policy.Limit < 0:
+			errs = append(errs, fmt.Sprintf("RATE_LIMIT_POLICIES %s limit must not be negative", operation))
+		case policy.Limit == 0:
+		case policy.Window <= 0:
+			errs = append(errs, fmt.Sprintf("RATE_LIMIT_POLICIES %s window must be positive", operation))
+		case policy.Key != "ip" && policy.Key != "user" && policy.Key != "phone":
+			errs = append(errs, fmt.Sprintf("RATE_LIMIT_POLICIES %s key %q must be one of ip, user, phone", operation, policy.Key))
+		}
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Sprintf("LOG_LEVEL %q must be one of debug, info, warn, error", c.Log.Level))
+	}
+
+	if c.Metrics.Addr != "" && c.Metrics.Addr == c.HTTP.Addr {
+		errs = append(errs, "METRICS_ADDR must differ from HTTP_ADDR, leave it empty to serve metrics on HTTP_ADDR")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	case "file":
+		if c.Tracing.File == "" {
+			errs = append(errs, "TRACING_FILE is required when TRACING_EXPORTER is file")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("TRACING_EXPORTER %q must be one of none, stdout, file, otlp", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	if c.LoginHistory.Retention <= 0 {
+		errs = append(errs, "LOGIN_HISTORY_RETENTION must be positive")
+	}
+	if c.LoginHistory.PruneInterval <= 0 {
+		errs = append(errs, "LOGIN_HISTORY_PRUNE_INTERVAL must be positive")
+	}
+	if c.Idempotency.TTL <= 0 {
+		errs = append(errs, "IDEMPOTENCY_TTL must be positive")
+	}
+	if c.Idempotency.PruneInterval <= 0 {
+		errs = append(errs, "IDEMPOTENCY_PRUNE_INTERVAL must be positive")
+	}
+
+	if !c.API.V1SunsetAt.IsZero() && !c.API.V1SunsetAt.After(c.API.V1DeprecatedAt) {
+		errs = append(errs, "API_V1_SUNSET_AT must be after API_V1_DEPRECATED_AT")
+	}
+
+	switch c.Mail.Sender {
+	case "log":
+	case "smtp":
+		if c.Mail.SMTP.Addr == "" {
+			errs = append(errs, "SMTP_ADDR is required when MAIL_SENDER is smtp")
+		} else if _, _, err := net.SplitHostPort(c.Mail.SMTP.Addr); err != nil {
+			errs = append(errs, fmt.Sprintf("SMTP_ADDR %q must be host:port", c.Mail.SMTP.Addr))
+		}
+		if c.Mail.From == "" {
+			errs = append(errs, "MAIL_FROM is required when MAIL_SENDER is smtp")
+		} else if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+			errs = append(errs, fmt.Sprintf("MAIL_FROM %q must be an email address", c.Mail.From))
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("MAIL_SENDER %q must be one of log, smtp", c.Mail.Sender))
+	}
+	if c.Mail.VerificationURL != "" {
+		if u, err := url.Parse(c.Mail.VerificationURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Sprintf("EMAIL_VERIFICATION_URL %q must be an absolute URL", c.Mail.VerificationURL))
+		}
+	}
+	if c.Mail.VerificationTTL <= 0 {
+		errs = append(errs, "EMAIL_VERIFICATION_TTL must be positive")
+	}
+
+	if c.Avatar.MaxBytes < 1 {
+		errs = append(errs, "AVATAR_MAX_BYTES must be at least 1")
+	}
+
+	switch c.Blob.Store {
+	case "file":
+		if c.Blob.Dir == "" {
+			errs = append(errs, "BLOB_DIR is required when BLOB_STORE is file")
+		}
+	case "s3":
+		if u, err := url.Parse(c.Blob.S3.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Sprintf("S3_ENDPOINT %q must be an absolute URL", c.Blob.S3.Endpoint))
+		}
+		if c.Blob.S3.Region == "" {
+			errs = append(errs, "S3_REGION is required when BLOB_STORE is s3")
+		}
+		if c.Blob.S3.Bucket == "" {
+			errs = append(errs, "S3_BUCKET is required when BLOB_STORE is s3")
+		}
+		if c.Blob.S3.AccessKeyID == "" || c.Blob.S3.SecretAccessKey == "" {
+			errs = append(errs, "S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required when BLOB_STORE is s3")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("BLOB_STORE %q must be one of file, s3", c.Blob.Store))
+	}
+	if c.Blob.BaseURL != "" {
+		u, err := url.Parse(c.Blob.BaseURL)
+		absolute := err == nil && u.Scheme != "" && u.Host != ""
+		if !absolute && (err != nil || !strings.HasPrefix(c.Blob.BaseURL, "/") || c.Blob.Store != "file") {
+			errs = append(errs, fmt.Sprintf("BLOB_BASE_URL %q must be an absolute URL, or a path when BLOB_STORE is file", c.Blob.BaseURL))
+		}
+	}
+
+	if c.OIDC.Issuer != "" {
+		u, err := url.Parse(c.OIDC.Issuer)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			errs = append(errs, fmt.Sprintf("OIDC_ISSUER %q must be an http or https URL without query or fragment", c.OIDC.Issuer))
+		}
+	}
+	if c.OIDC.CodeTTL <= 0 {
+		errs = append(errs, "OIDC_CODE_TTL must be positive")
+	}
+
+	issuers := map[string]string{}
+	for _, name := range sortedKeys(c.SocialLogin.Providers) {
+		provider := c.SocialLogin.Providers[name]
+		if !providerNamePattern.MatchString(name) {
+			errs = append(errs, fmt.Sprintf("SOCIAL_LOGIN_PROVIDERS name %q must be lowercase letters, digits, - or _", name))
+		}
+		if u, err := url.Parse(provider.Issuer); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs = append(errs, fmt.Sprintf("SOCIAL_LOGIN_PROVIDERS %s issuer %q must be an http or https URL", name, provider.Issuer))
+		} else if other, ok := issuers[strings.TrimSuffix(provider.Issuer, "/")]; ok {
+			errs = append(errs, fmt.Sprintf("SOCIAL_LOGIN_PROVIDERS %s and %s have the same issuer", other, name))
+		}
+		issuers[strings.TrimSuffix(provider.Issuer, "/")] = name
+		if len(provider.ClientIDs) == 0 {
+			errs = append(errs, fmt.Sprintf("SOCIAL_LOGIN_PROVIDERS %s needs at least one client id", name))
+		}
+		if provider.JWKSURL != "" {
+			if u, err := url.Parse(provider.JWKSURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				errs = append(errs, fmt.Sprintf("SOCIAL_LOGIN_PROVIDERS %s jwks url %q must be an http or https URL", name, provider.JWKSURL))
+			}
+		}
+	}
+	if c.SocialLogin.JWKSCacheTTL <= 0 {
+		errs = append(errs, "SOCIAL_LOGIN_JWKS_CACHE_TTL must be positive")
+	}
+
+	for _, id := range c.APIKeys.AdminUserIDs {
+		if id < 1 {
+			errs = append(errs, fmt.Sprintf("API_KEY_ADMIN_USER_IDS contains invalid user id %d", id))
+		}
+	}
+	if c.APIKeys.RateLimit < 1 {
+		errs = append(errs, "API_KEY_RATE_LIMIT must be at least 1")
+	}
+	if c.APIKeys.RateLimitWindow <= 0 {
+		errs = append(errs, "API_KEY_RATE_LIMIT_WINDOW must be positive")
+	}
+
+	if len(errs) > 0 {
+		return errors.New("invalid config: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Redacted return a copy of config safe to print.
+// The JWT secret, the database and SMTP passwords and the S3 secret key are masked.
+func (c Config) Redacted() Config {
+	if c.Auth.Secret != "" {
+		c.Auth.Secret = redacted
+	}
+	if c.Mail.SMTP.Password != "" {
+		c.Mail.SMTP.Password = redacted
+	}
+	if c.Blob.S3.SecretAccessKey != "" {
+		c.Blob.S3.SecretAccessKey = redacted
+	}
+	if u, err := url.Parse(c.Database.URL); err != nil {
+		c.Database.URL = redacted
+	} else {
+		c.Database.URL = u.Redacted()
+	}
+	return c
+}
+
+// String return config as YAML with secrets redacted.
+func (c Config) String() string {
+	out, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return fmt.Sprintf("marshal config: %v", err)
+	}
+	return string(out)
+}
+
+// sortedKeys return the keys of m in order, so errors are reported stably.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const mockSecret = "0123456789abcdef0123456789abcdef"
+
+func mockEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("TestLoad", func(t *testing.T) {
+		Convey("TestLoad", t, func(c C) {
+			configFile := filepath.Join(t.TempDir(), "config.yml")
+			_ = os.WriteFile(configFile, []byte(`
+http:
+  addr: ":8080"
+  read_timeout: 5s
+auth:
+  bcrypt_cost: 12
+cors:
+  allowed_origins: ["https://app.example.com"]
+rate_limit:
+  policies:
+    getUser: {limit: 100, window: 1m, key: user}
+api:
+  v1_deprecated_at: 2026-11-01
+`), 0o600)
+			unknownFieldFile := filepath.Join(t.TempDir(), "config.yml")
+			_ = os.WriteFile(unknownFieldFile, []byte("http:\n  adress: \":8080\"\n"), 0o600)
+
+			testCases := []struct {
+				testID     int
+				testDesc   string
+				env        map[string]string
+				wantErrMsg []string
+				check      func(cfg *Config)
+			}{
+				{
+					testID:     1,
+					testDesc:   "Failed - required values missing",
+					env:        map[string]string{},
+					wantErrMsg: []string{"DATABASE_URL is required", "SECRET is required"},
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - weak secret",
+					env: map[string]string{
+						"DATABASE_URL": "postgres://localhost/db",
+						"SECRET":       "sawitpro",
+					},
+					wantErrMsg: []string{"SECRET must be at least 32 bytes"},
+				},
+				{
+					testID:   3,
+					testDesc: "Failed - unparsable values",
+					env: map[string]string{
+						"DATABASE_URL":          "postgres://localhost/db",
+						"SECRET":                mockSecret,
+						"HTTP_READ_TIMEOUT":     "soon",
+						"BCRYPT_COST":           "high",
+						"DATABASE_AUTO_MIGRATE": "sometimes",
+						"RATE_LIMIT_POLICIES":   "login=5/1m/phone,login,userRegister=ten/1h/ip",
+						"API_V1_SUNSET_AT":      "someday",
+
+						"API_KEY_ADMIN_USER_IDS": "1,admin",
+					},
+					wantErrMsg: []string{
+						`HTTP_READ_TIMEOUT "soon" must be a duration`,
+						`BCRYPT_COST "high" must be an integer`,
+						`DATABASE_AUTO_MIGRATE "sometimes" must be true or false`,
+						`RATE_LIMIT_POLICIES entry "login" must look like login=5/1m/phone`,
+						`RATE_LIMIT_POLICIES entry "userRegister=ten/1h/ip" limit must be an integer`,
+						`API_V1_SUNSET_AT "someday" must be a date like 2027-04-30`,
+						`API_KEY_ADMIN_USER_IDS entry "admin" must be an integer`,
+					},
+				},
+				{
+					testID:   4,
+					testDesc: "Failed - out of range values",
+					env: map[string]string{
+						"DATABASE_URL":         "postgres://localhost/db",
+						"SECRET":               mockSecret,
+						"BCRYPT_COST":          "2",
+						"LOG_LEVEL":            "verbose",
+						"CORS_ALLOWED_ORIGINS": "example.com",
+						"RATE_LIMIT_RPS":       "10",
+						"RATE_LIMIT_BURST":     "0",
+						"TRACING_EXPORTER":     "file",
+						"IDEMPOTENCY_TTL":      "0s",
+						"HTTP_TRUSTED_PROXIES": "10.0.0.0/8,proxy",
+						"RATE_LIMIT_STORE":     "redis",
+						"RATE_LIMIT_POLICIES":  "login=5/0s/phone,userRegister=5/1h/email",
+						"API_V1_SUNSET_AT":     "2026-01-01",
+						"MAIL_SENDER":          "smtp",
+						"SMTP_ADDR":            "mail.example.com",
+						"AVATAR_MAX_BYTES":     "0",
+						"BLOB_STORE":           "s3",
+						"BLOB_BASE_URL":        "/blobs",
+						"S3_ENDPOINT":          "minio:9000",
+						"OIDC_ISSUER":          "https://auth.example.com?tenant=1",
+						"OIDC_CODE_TTL":        "0s",
+
+						"SOCIAL_LOGIN_PROVIDERS":      "Google=https://accounts.google.com|web,apple=appleid.apple.com|ios,github=https://github.com|",
+						"SOCIAL_LOGIN_JWKS_CACHE_TTL": "0s",
+
+						"API_KEY_ADMIN_USER_IDS":    "0",
+						"API_KEY_RATE_LIMIT":        "0",
+						"API_KEY_RATE_LIMIT_WINDOW": "0s",
+					},
+					wantErrMsg: []string{
+						"TRACING_FILE is required when TRACING_EXPORTER is file",
+						"BCRYPT_COST must be between 4 and 31",
+						`LOG_LEVEL "verbose" must be one of`,
+						`CORS_ALLOWED_ORIGINS contains invalid origin "example.com"`,
+						"RATE_LIMIT_BURST must be at least 1",
+						"IDEMPOTENCY_TTL must be positive",
+						`HTTP_TRUSTED_PROXIES contains invalid IP or CIDR "proxy"`,
+						`RATE_LIMIT_STORE "redis" must be one of memory, postgres`,
+						"RATE_LIMIT_POLICIES login window must be positive",
+						`RATE_LIMIT_POLICIES userRegister key "email" must be one of ip, user, phone`,
+						"API_V1_SUNSET_AT must be after API_V1_DEPRECATED_AT",
+						`SMTP_ADDR "mail.example.com" must be host:port`,
+						"MAIL_FROM is required when MAIL_SENDER is smtp",
+						"AVATAR_MAX_BYTES must be at least 1",
+						`S3_ENDPOINT "minio:9000" must be an absolute URL`,
+						"S3_BUCKET is required when BLOB_STORE is s3",
+						"S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required when BLOB_STORE is s3",
+						`BLOB_BASE_URL "/blobs" must be an absolute URL, or a path when BLOB_STORE is file`,
+						`OIDC_ISSUER "https://auth.example.com?tenant=1" must be an http or https URL without query or fragment`,
+						"OIDC_CODE_TTL must be positive",
+						`SOCIAL_LOGIN_PROVIDERS name "Google" must be lowercase letters, digits, - or _`,
+						`SOCIAL_LOGIN_PROVIDERS apple issuer "appleid.apple.com" must be an http or https URL`,
+						"SOCIAL_LOGIN_PROVIDERS github needs at least one client id",
+						"SOCIAL_LOGIN_JWKS_CACHE_TTL must be positive",
+						"API_KEY_ADMIN_USER_IDS contains invalid user id 0",
+						"API_KEY_RATE_LIMIT must be at least 1",
+						"API_KEY_RATE_LIMIT_WINDOW must be positive",
+					},
+				},
+				{
+					testID:   5,
+					testDesc: "Failed - config file missing",
+					env: map[string]string{
+						"CONFIG_FILE": filepath.Join(t.TempDir(), "missing.yml"),
+					},
+					wantErrMsg: []string{"read config file"},
+				},
+				{
+					testID:   6,
+					testDesc: "Failed - config file has unknown field",
+					env: map[string]string{
+						"CONFIG_FILE": unknownFieldFile,
+					},
+					wantErrMsg: []string{"field adress not found"},
+				},
+				{
+					testID:   7,
+					testDesc: "Success - defaults",
+					env: map[string]string{
+						"DATABASE_URL": "postgres://localhost/db",
+						"SECRET":       mockSecret,
+					},
+					check: func(cfg *Config) {
+						So(cfg.HTTP.Addr, ShouldEqual, ":1323")
+						So(cfg.HTTP.MaxBodyBytes, ShouldEqual, 1<<20)
+						So(cfg.Auth.TokenTTL, ShouldEqual, 24*time.Hour)
+						So(cfg.LoginHistory.Retention, ShouldEqual, 2160*time.Hour)
+						So(cfg.Idempotency.TTL, ShouldEqual, 24*time.Hour)
+						So(cfg.RateLimit.Store, ShouldEqual, "memory")
+						So(cfg.RateLimit.Policies["login"], ShouldResemble, RateLimitPolicy{Limit: 5, Window: time.Minute, Key: "phone"})
+						So(cfg.CORS.AllowedOrigins, ShouldBeEmpty)
+						So(cfg.Database.AutoMigrate, ShouldBeTrue)
+						So(cfg.Validation.Responses, ShouldBeFalse)
+						So(cfg.API.V1SunsetAt, ShouldEqual, time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC))
+						So(cfg.Mail.Sender, ShouldEqual, "log")
+						So(cfg.Mail.VerificationTTL, ShouldEqual, 24*time.Hour)
+						So(cfg.Avatar.MaxBytes, ShouldEqual, 5<<20)
+						So(cfg.Blob.Store, ShouldEqual, "file")
+						So(cfg.Blob.Dir, ShouldEqual, "data/blobs")
+						So(cfg.OIDC.Issuer, ShouldBeEmpty)
+						So(cfg.OIDC.CodeTTL, ShouldEqual, time.Minute)
+						So(cfg.SocialLogin.Providers, ShouldBeEmpty)
+						So(cfg.SocialLogin.JWKSCacheTTL, ShouldEqual, time.Hour)
+						So(cfg.APIKeys, ShouldResemble, APIKeysConfig{RateLimit: 600, RateLimitWindow: time.Minute})
+					},
+				},
+				{
+					testID:   8,
+					testDesc: "Success - environment overrides config file",
+					env: map[string]string{
+						"CONFIG_FILE":           configFile,
+						"DATABASE_URL":          "postgres://localhost/db",
+						"SECRET":                mockSecret,
+						"HTTP_ADDR":             ":9090",
+						"DATABASE_AUTO_MIGRATE": "false",
+						"CORS_ALLOWED_ORIGINS":  "https://a.example.com, https://b.example.com,",
+						"HTTP_TRUSTED_PROXIES":  "10.0.0.0/8, 192.0.2.1",
+						"HTTP_MAX_BODY_BYTES":   "65536",
+						"RATE_LIMIT_POLICIES":   "login=3/1m/phone, userRegister=0/1h/ip",
+						"VALIDATE_RESPONSES":    "true",
+						"API_V1_SUNSET_AT":      "2027-06-30T00:00:00Z",
+						"MAIL_SENDER":           "smtp",
+						"MAIL_FROM":             "no-reply@example.com",
+						"SMTP_ADDR":             "mail.example.com:587",
+						"SMTP_PASSWORD":         "mock-smtp-password",
+						"BLOB_STORE":            "s3",
+						"S3_ENDPOINT":           "http://minio:9000",
+						"S3_BUCKET":             "avatars",
+						"S3_ACCESS_KEY_ID":      "mock-access-key-id",
+						"S3_SECRET_ACCESS_KEY":  "mock-s3-secret",
+						"OIDC_ISSUER":           "https://auth.example.com",
+						"OIDC_SIGNING_KEY_FILE": "/run/secrets/oidc.pem",
+
+						"SOCIAL_LOGIN_PROVIDERS": "google=https://accounts.google.com|web-client|ios-client, apple=https://appleid.apple.com|com.example.app",
+						"API_KEY_ADMIN_USER_IDS": "1, 42,",
+					},
+					check: func(cfg *Config) {
+						So(cfg.HTTP.Addr, ShouldEqual, ":9090")
+						So(cfg.HTTP.ReadTimeout, ShouldEqual, 5*time.Second)
+						So(cfg.HTTP.WriteTimeout, ShouldEqual, 15*time.Second)
+						So(cfg.Auth.BcryptCost, ShouldEqual, 12)
+						So(cfg.Database.AutoMigrate, ShouldBeFalse)
+						So(cfg.Validation.Responses, ShouldBeTrue)
+						So(cfg.CORS.AllowedOrigins, ShouldResemble, []string{"https://a.example.com", "https://b.example.com"})
+						So(cfg.HTTP.TrustedProxies, ShouldResemble, []string{"10.0.0.0/8", "192.0.2.1"})
+						So(cfg.HTTP.MaxBodyBytes, ShouldEqual, 65536)
+						So(cfg.RateLimit.Policies, ShouldResemble, map[string]RateLimitPolicy{
+							"login":         {Limit: 3, Window: time.Minute, Key: "phone"},
+							"userRegister":  {Limit: 0, Window: time.Hour, Key: "ip"},
+							"getUser":       {Limit: 100, Window: time.Minute, Key: "user"},
+							"createSession": {Limit: 5, Window: time.Minute, Key: "phone"},
+							"createUser":    {Limit: 10, Window: time.Hour, Key: "ip"},
+
+							"createEmailVerification": {Limit: 3, Window: time.Hour, Key: "user"},
+							"verifyEmail":             {Limit: 10, Window: time.Minute, Key: "ip"},
+							"uploadAvatar":            {Limit: 10, Window: time.Hour, Key: "user"},
+							"submitAuthorization":     {Limit: 5, Window: time.Minute, Key: "phone"},
+							"createToken":             {Limit: 30, Window: time.Minute, Key: "ip"},
+							"loginOIDC":               {Limit: 10, Window: time.Minute, Key: "ip"},
+						})
+						So(cfg.API.V1DeprecatedAt, ShouldEqual, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC))
+						So(cfg.API.V1SunsetAt, ShouldEqual, time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC))
+						So(cfg.Mail.SMTP.Addr, ShouldEqual, "mail.example.com:587")
+						So(cfg.String(), ShouldNotContainSubstring, "mock-smtp-password")
+						So(cfg.Blob.S3, ShouldResemble, S3Config{
+							Endpoint:        "http://minio:9000",
+							Region:          "us-east-1",
+							Bucket:          "avatars",
+							AccessKeyID:     "mock-access-key-id",
+							SecretAccessKey: "mock-s3-secret",
+						})
+						So(cfg.String(), ShouldNotContainSubstring, "mock-s3-secret")
+						So(cfg.OIDC, ShouldResemble, OIDCConfig{
+							Issuer:         "https://auth.example.com",
+							SigningKeyFile: "/run/secrets/oidc.pem",
+							CodeTTL:        time.Minute,
+						})
+						So(cfg.SocialLogin.Providers, ShouldResemble, map[string]SocialLoginProvider{
+							"google": {Issuer: "https://accounts.google.com", ClientIDs: []string{"web-client", "ios-client"}},
+							"apple":  {Issuer: "https://appleid.apple.com", ClientIDs: []string{"com.example.app"}},
+						})
+						So(cfg.APIKeys.AdminUserIDs, ShouldResemble, []int64{1, 42})
+					},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					cfg, err := load(mockEnv(tc.env))
+					// assert
+					if len(tc.wantErrMsg) > 0 {
+						So(err, ShouldNotBeNil)
+						for _, msg := range tc.wantErrMsg {
+							So(err.Error(), ShouldContainSubstring, msg)
+						}
+						return
+					}
+					So(err, ShouldBeNil)
+					tc.check(cfg)
+				})
+			}
+		})
+	})
+}
+
+func TestString(t *testing.T) {
+	t.Run("TestString", func(t *testing.T) {
+		Convey("TestString", t, func(c C) {
+			cfg := Default()
+			cfg.Auth.Secret = mockSecret
+			cfg.Database.URL = "postgres://postgres:s3cr3t@db:5432/database?sslmode=disable"
+
+			output := cfg.String()
+
+			So(output, ShouldNotContainSubstring, mockSecret)
+			So(output, ShouldNotContainSubstring, "s3cr3t")
+			So(output, ShouldContainSubstring, "postgres://postgres:xxxxx@db:5432/database")
+			So(output, ShouldContainSubstring, "read_timeout: 15s")
+			So(strings.Count(output, redacted), ShouldEqual, 1)
+			// original config is untouched
+			So(cfg.Auth.Secret, ShouldEqual, mockSecret)
+		})
+	})
+}
@@ -0,0 +1,207 @@
+// This file contains the environment variable parsing used by Load.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envReader overlay environment variables onto config fields.
+// Unset variables leave the field unchanged, parse errors are collected.
+type envReader struct {
+	lookupEnv func(string) (string, bool)
+	errs      []string
+}
+
+func (e *envReader) lookup(key string) (string, bool) {
+	value, ok := e.lookupEnv(key)
+	if !ok {
+		return "", false
+	}
+	return strings.TrimSpace(value), true
+}
+
+func (e *envReader) string(key string, dst *string) {
+	if value, ok := e.lookup(key); ok {
+		*dst = value
+	}
+}
+
+func (e *envReader) int(key string, dst *int) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Sprintf("%s %q must be an integer", key, value))
+		return
+	}
+	*dst = n
+}
+
+func (e *envReader) bool(key string, dst *bool) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Sprintf("%s %q must be true or false", key, value))
+		return
+	}
+	*dst = b
+}
+
+func (e *envReader) float(key string, dst *float64) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Sprintf("%s %q must be a number", key, value))
+		return
+	}
+	*dst = f
+}
+
+func (e *envReader) duration(key string, dst *time.Duration) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Sprintf("%s %q must be a duration like 30s or 2160h", key, value))
+		return
+	}
+	*dst = d
+}
+
+// date parse a date like 2027-04-30, or an RFC 3339 timestamp.
+func (e *envReader) date(key string, dst *time.Time) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, value)
+	}
+	if err != nil {
+		e.errs = append(e.errs, fmt.Sprintf("%s %q must be a date like 2027-04-30", key, value))
+		return
+	}
+	*dst = t
+}
+
+// list parse comma separated values, empty entries are dropped.
+func (e *envReader) list(key string, dst *[]string) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+// int64List parse comma separated integers, empty entries are dropped.
+func (e *envReader) int64List(key string, dst *[]int64) {
+	var items []string
+	e.list(key, &items)
+	if items == nil {
+		return
+	}
+	values := make([]int64, 0, len(items))
+	for _, item := range items {
+		n, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Sprintf("%s entry %q must be an integer", key, item))
+			continue
+		}
+		values = append(values, n)
+	}
+	*dst = values
+}
+
+// rateLimitPolicies parse comma separated operationId=limit/window/key
+// entries, e.g. login=5/1m/phone. Entries override the policy of their
+// operation, other operations keep theirs.
+func (e *envReader) rateLimitPolicies(key string, dst *map[string]RateLimitPolicy) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	if *dst == nil {
+		*dst = map[string]RateLimitPolicy{}
+	}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		operation, spec, _ := strings.Cut(item, "=")
+		parts := strings.Split(spec, "/")
+		if operation == "" || len(parts) != 3 {
+			e.errs = append(e.errs, fmt.Sprintf("%s entry %q must look like login=5/1m/phone", key, item))
+			continue
+		}
+		limit, err := strconv.Atoi(parts[0])
+		if err != nil {
+			e.errs = append(e.errs, fmt.Sprintf("%s entry %q limit must be an integer", key, item))
+			continue
+		}
+		window, err := time.ParseDuration(parts[1])
+		if err != nil {
+			e.errs = append(e.errs, fmt.Sprintf("%s entry %q window must be a duration like 1m", key, item))
+			continue
+		}
+		(*dst)[operation] = RateLimitPolicy{Limit: limit, Window: window, Key: parts[2]}
+	}
+}
+
+// socialLoginProviders parse comma separated name=issuer|client-id|...
+// entries, e.g. google=https://accounts.google.com|web-client|ios-client.
+// Entries override the provider of their name, other providers are kept.
+func (e *envReader) socialLoginProviders(key string, dst *map[string]SocialLoginProvider) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	if *dst == nil {
+		*dst = map[string]SocialLoginProvider{}
+	}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		name, spec, _ := strings.Cut(item, "=")
+		parts := strings.Split(spec, "|")
+		if name == "" || len(parts) < 2 {
+			e.errs = append(e.errs, fmt.Sprintf("%s entry %q must look like google=https://accounts.google.com|client-id", key, item))
+			continue
+		}
+		provider := SocialLoginProvider{Issuer: parts[0]}
+		for _, clientID := range parts[1:] {
+			if clientID = strings.TrimSpace(clientID); clientID != "" {
+				provider.ClientIDs = append(provider.ClientIDs, clientID)
+			}
+		}
+		(*dst)[name] = provider
+	}
+}
+
+func (e *envReader) err() error {
+	if len(e.errs) > 0 {
+		return errors.New("invalid config: " + strings.Join(e.errs, "; "))
+	}
+	return nil
+}
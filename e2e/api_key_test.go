@@ -0,0 +1,59 @@
+package e2e
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/client"
+	"github.com/SawitProRecruitment/UserService/repository"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAPIKey(t *testing.T) {
+	t.Run("TestAPIKey", func(t *testing.T) {
+		Convey("TestAPIKey", t, func(c C) {
+			api, repo := newServiceWithRepository(t, nil)
+			ctx := context.Background()
+
+			userID, err := register(api)
+			So(err, ShouldBeNil)
+
+			// keys are issued by administrators through version 2, the
+			// service only stores their hash.
+			key := "usk_e2e00000_secret"
+			sum := sha256.Sum256([]byte(key))
+			_, err = repo.CreateAPIKey(ctx, repository.APIKey{
+				ID:      "e2e-api-key",
+				Name:    "reporting",
+				Prefix:  "usk_e2e00000",
+				KeyHash: hex.EncodeToString(sum[:]),
+				UserID:  userID,
+				Scopes:  []string{"profile:read"},
+			})
+			So(err, ShouldBeNil)
+			integration := api.WithAPIKey(key)
+
+			user, _, err := integration.GetUser(ctx, nil)
+			So(err, ShouldBeNil)
+			So(user.Phone, ShouldEqual, mockPhone)
+			_, err = integration.ListUserEvents(ctx, nil)
+			So(err, ShouldBeNil)
+
+			// the key lacks the scopes of writes and sessions.
+			name := "Budi Santoso"
+			_, _, err = integration.UpdateUser(ctx, nil, client.UpdateUserRequest{Name: &name})
+			So(errors.Is(err, client.ErrForbidden), ShouldBeTrue)
+			_, err = integration.ListUserSessions(ctx)
+			So(errors.Is(err, client.ErrForbidden), ShouldBeTrue)
+
+			// a revoked key is rejected.
+			So(repo.RevokeAPIKey(ctx, "e2e-api-key"), ShouldBeNil)
+			_, _, err = integration.GetUser(ctx, nil)
+			So(errors.Is(err, client.ErrForbidden), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,347 @@
+// Package e2e drives every operation of api.yml through the generated client
+// against the real handler, served over HTTP and backed by the in-process
+// repository.
+package e2e
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/client"
+	"github.com/SawitProRecruitment/UserService/common"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/handler"
+	"github.com/SawitProRecruitment/UserService/idempotency"
+	"github.com/SawitProRecruitment/UserService/logging"
+	"github.com/SawitProRecruitment/UserService/oidc"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/validation"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	mockPhone    = "+6281234567"
+	mockPassword = "Secret1!"
+)
+
+// newService start the service on an httptest server and return a client of it.
+func newService(t *testing.T) *client.API {
+	return newServiceWithVerifier(t, nil)
+}
+
+// newServiceWithVerifier start the service verifying social logins with
+// verifier and return a client of it.
+func newServiceWithVerifier(t *testing.T, verifier *oidc.Verifier) *client.API {
+	api, _ := newServiceWithRepository(t, verifier)
+	return api
+}
+
+// newServiceWithRepository start the service like newServiceWithVerifier and
+// also return its repository, to set up what the API can not.
+func newServiceWithRepository(t *testing.T, verifier *oidc.Verifier) (*client.API, *repository.MemoryRepository) {
+	repo := repository.NewMemoryRepository(repository.NewMemoryRepositoryOptions{})
+	server := handler.NewServer(handler.NewServerOptions{
+		Repository:       repo,
+		SecretKey:        "e2e-secret",
+		BcryptCost:       bcrypt.MinCost,
+		IdentityVerifier: verifier,
+	})
+
+	spec, err := generated.GetSwagger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// responses are validated too, so drift from api.yml fails the tests.
+	validator, err := validation.NewValidator(validation.NewValidatorOptions{
+		Spec:              spec,
+		ValidateResponses: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	e.Use(logging.RequestID(logging.New(logging.NewOptions{Writer: io.Discard})))
+	e.Use(common.SecurityMiddleware(spec))
+	e.Use(validator.Middleware())
+	e.Use(idempotency.Middleware(idempotency.MiddlewareOptions{
+		Store: repo,
+		TTL:   time.Hour,
+		Scope: server.IdempotencyScope,
+		Skipper: func(c echo.Context) bool {
+			return c.Path() == "/login" || c.Path() == "/login/oidc"
+		},
+	}))
+	generated.RegisterHandlers(e, server)
+
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+
+	api, err := client.NewAPI(client.NewAPIOptions{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api, repo
+}
+
+// register create the mock user and return its id.
+func register(api *client.API) (int64, error) {
+	resp, err := api.UserRegister(context.Background(), nil, client.RegisterRequest{
+		Phone:    mockPhone,
+		Name:     "Budi",
+		Password: mockPassword,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Id, nil
+}
+
+// login log in the mock user and return a client authorized by its token.
+func login(api *client.API) (*client.API, error) {
+	identifier := mockPhone
+	resp, err := api.Login(context.Background(), client.LoginRequest{Identifier: &identifier, Password: mockPassword})
+	if err != nil {
+		return nil, err
+	}
+	return api.WithToken(client.StaticToken(resp.Token)), nil
+}
+
+func TestHealth(t *testing.T) {
+	t.Run("TestHealth", func(t *testing.T) {
+		Convey("TestHealth", t, func(c C) {
+			api := newService(t)
+			ctx := context.Background()
+
+			live, err := api.Healthz(ctx)
+			So(err, ShouldBeNil)
+			So(live.Status, ShouldEqual, client.Pass)
+
+			ready, err := api.Readyz(ctx)
+			So(err, ShouldBeNil)
+			So(ready.Status, ShouldEqual, client.Pass)
+		})
+	})
+}
+
+func TestUserRegister(t *testing.T) {
+	t.Run("TestUserRegister", func(t *testing.T) {
+		Convey("TestUserRegister", t, func(c C) {
+			api := newService(t)
+			ctx := context.Background()
+			key := "e2e-register"
+			body := client.RegisterRequest{Phone: mockPhone, Name: "Budi", Password: mockPassword}
+
+			created, err := api.UserRegister(ctx, &client.UserRegisterParams{IdempotencyKey: &key}, body)
+			So(err, ShouldBeNil)
+			So(created.Id, ShouldEqual, 1)
+
+			// retry with the same key replays the first response.
+			replayed, err := api.UserRegister(ctx, &client.UserRegisterParams{IdempotencyKey: &key}, body)
+			So(err, ShouldBeNil)
+			So(replayed.Id, ShouldEqual, created.Id)
+
+			// same key with another body.
+			body.Name = "Andi"
+			_, err = api.UserRegister(ctx, &client.UserRegisterParams{IdempotencyKey: &key}, body)
+			So(errors.Is(err, client.ErrUnprocessable), ShouldBeTrue)
+
+			_, err = api.UserRegister(ctx, nil, body)
+			So(errors.Is(err, client.ErrBadRequest), ShouldBeTrue)
+			var apiErr *client.Error
+			So(errors.As(err, &apiErr), ShouldBeTrue)
+			So(apiErr.Message, ShouldEqual, "phone number already exist")
+			So(apiErr.RequestID, ShouldNotBeEmpty)
+
+			_, err = api.UserRegister(ctx, nil, client.RegisterRequest{Phone: "+6281234568", Name: "Budi", Password: "secret"})
+			So(errors.Is(err, client.ErrBadRequest), ShouldBeTrue)
+		})
+	})
+}
+
+func TestProfile(t *testing.T) {
+	t.Run("TestProfile", func(t *testing.T) {
+		Convey("TestProfile", t, func(c C) {
+			api := newService(t)
+			ctx := context.Background()
+			_, err := register(api)
+			So(err, ShouldBeNil)
+
+			phone := mockPhone
+			_, err = api.Login(ctx, client.LoginRequest{Phone: &phone, Password: "Wrong1!!"})
+			So(errors.Is(err, client.ErrBadRequest), ShouldBeTrue)
+
+			_, _, err = api.GetUser(ctx, nil)
+			So(errors.Is(err, client.ErrForbidden), ShouldBeTrue)
+
+			authorized, err := login(api)
+			So(err, ShouldBeNil)
+
+			user, etag, err := authorized.GetUser(ctx, nil)
+			So(err, ShouldBeNil)
+			So(user.Phone, ShouldEqual, mockPhone)
+			So(user.Name, ShouldEqual, "Budi")
+			So(user.LoginCount, ShouldEqual, 1)
+			So(user.LastLoginAt, ShouldNotBeNil)
+			So(etag, ShouldEqual, `"1"`)
+
+			// not modified since etag.
+			user, _, err = authorized.GetUser(ctx, &client.GetUserParams{IfNoneMatch: &etag})
+			So(err, ShouldBeNil)
+			So(user, ShouldBeNil)
+
+			name := "Andi"
+			user, etag, err = authorized.UpdateUser(ctx, &client.UpdateUserParams{IfMatch: &etag}, client.UpdateUserRequest{Name: &name})
+			So(err, ShouldBeNil)
+			So(user.Name, ShouldEqual, name)
+			So(etag, ShouldEqual, `"2"`)
+
+			stale := `"1"`
+			_, _, err = authorized.UpdateUser(ctx, &client.UpdateUserParams{IfMatch: &stale}, client.UpdateUserRequest{Name: &name})
+			So(errors.Is(err, client.ErrPreconditionFailed), ShouldBeTrue)
+
+			email, locale, birthDate := "Budi@Example.com", "en-us", "1990-01-31"
+			user, etag, err = authorized.UpdateUser(ctx, &client.UpdateUserParams{IfMatch: &etag}, client.UpdateUserRequest{
+				Email:     &email,
+				Locale:    &locale,
+				BirthDate: &birthDate,
+			})
+			So(err, ShouldBeNil)
+			So(string(*user.Email), ShouldEqual, "budi@example.com")
+			So(user.EmailVerified, ShouldBeFalse)
+			So(*user.Locale, ShouldEqual, "en-US")
+			So(user.BirthDate.String(), ShouldEqual, birthDate)
+			So(etag, ShouldEqual, `"3"`)
+
+			// an unverified email is not a login identifier, a local phone number is.
+			_, err = api.Login(ctx, client.LoginRequest{Identifier: &email, Password: mockPassword})
+			So(errors.Is(err, client.ErrBadRequest), ShouldBeTrue)
+			local := "0812-34567"
+			_, err = api.Login(ctx, client.LoginRequest{Identifier: &local, Password: mockPassword})
+			So(err, ShouldBeNil)
+
+			empty := ""
+			user, etag, err = authorized.UpdateUser(ctx, &client.UpdateUserParams{IfMatch: &etag}, client.UpdateUserRequest{Locale: &empty})
+			So(err, ShouldBeNil)
+			So(user.Locale, ShouldBeNil)
+			So(etag, ShouldEqual, `"4"`)
+
+			events, err := authorized.ListUserEvents(ctx, nil)
+			So(err, ShouldBeNil)
+			types := []client.UserEventType{}
+			for _, event := range events.Events {
+				types = append(types, event.Type)
+			}
+			So(types, ShouldResemble, []client.UserEventType{
+				client.ProfileUpdated,
+				client.LoginSucceeded,
+				client.ProfileUpdated,
+				client.ProfileUpdated,
+				client.LoginSucceeded,
+				client.LoginFailed,
+				client.Registered,
+			})
+			So(*events.Events[0].Changes, ShouldResemble, map[string]client.FieldChange{
+				"locale": {Before: "en-US", After: ""},
+			})
+			So(*events.Events[3].Changes, ShouldResemble, map[string]client.FieldChange{
+				"name": {Before: "Budi", After: "Andi"},
+			})
+
+			limit := 1000
+			_, err = authorized.ListUserEvents(ctx, &client.ListUserEventsParams{Limit: &limit})
+			So(errors.Is(err, client.ErrBadRequest), ShouldBeTrue)
+		})
+	})
+}
+
+func TestSessions(t *testing.T) {
+	t.Run("TestSessions", func(t *testing.T) {
+		Convey("TestSessions", t, func(c C) {
+			api := newService(t)
+			ctx := context.Background()
+			_, err := register(api)
+			So(err, ShouldBeNil)
+
+			first, err := login(api)
+			So(err, ShouldBeNil)
+			second, err := login(api)
+			So(err, ShouldBeNil)
+
+			// one login per page, newest first.
+			limit := 1
+			page, err := second.ListUserLogins(ctx, &client.ListUserLoginsParams{Limit: &limit})
+			So(err, ShouldBeNil)
+			So(page.Logins, ShouldHaveLength, 1)
+			So(page.NextBefore, ShouldNotBeNil)
+			last, err := second.ListUserLogins(ctx, &client.ListUserLoginsParams{Limit: &limit, Before: page.NextBefore})
+			So(err, ShouldBeNil)
+			So(last.Logins, ShouldHaveLength, 1)
+			So(last.Logins[0].Id, ShouldBeLessThan, page.Logins[0].Id)
+			So(last.NextBefore, ShouldBeNil)
+
+			sessions, err := second.ListUserSessions(ctx)
+			So(err, ShouldBeNil)
+			So(sessions.Sessions, ShouldHaveLength, 2)
+			var other string
+			for _, session := range sessions.Sessions {
+				if !session.Current {
+					other = session.Id
+				}
+			}
+			So(other, ShouldNotBeEmpty)
+
+			So(second.RevokeUserSession(ctx, other), ShouldBeNil)
+			err = second.RevokeUserSession(ctx, other)
+			So(errors.Is(err, client.ErrNotFound), ShouldBeTrue)
+
+			// the token of the revoked session is rejected.
+			_, _, err = first.GetUser(ctx, nil)
+			So(errors.Is(err, client.ErrForbidden), ShouldBeTrue)
+			_, _, err = second.GetUser(ctx, nil)
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
+func TestScopedToken(t *testing.T) {
+	t.Run("TestScopedToken", func(t *testing.T) {
+		Convey("TestScopedToken", t, func(c C) {
+			api := newService(t)
+			ctx := context.Background()
+
+			_, err := register(api)
+			So(err, ShouldBeNil)
+
+			identifier, scope := mockPhone, "profile:read"
+			resp, err := api.Login(ctx, client.LoginRequest{Identifier: &identifier, Password: mockPassword, Scope: &scope})
+			So(err, ShouldBeNil)
+			readOnly := api.WithToken(client.StaticToken(resp.Token))
+
+			user, _, err := readOnly.GetUser(ctx, nil)
+			So(err, ShouldBeNil)
+			So(user.Phone, ShouldEqual, mockPhone)
+
+			// the token was not granted profile:write nor sessions:manage.
+			name := "Budi Santoso"
+			_, _, err = readOnly.UpdateUser(ctx, nil, client.UpdateUserRequest{Name: &name})
+			So(errors.Is(err, client.ErrForbidden), ShouldBeTrue)
+			_, err = readOnly.ListUserSessions(ctx)
+			So(errors.Is(err, client.ErrForbidden), ShouldBeTrue)
+
+			// a login without scope is granted every scope.
+			full, err := login(api)
+			So(err, ShouldBeNil)
+			user, _, err = full.UpdateUser(ctx, nil, client.UpdateUserRequest{Name: &name})
+			So(err, ShouldBeNil)
+			So(user.Name, ShouldEqual, name)
+		})
+	})
+}
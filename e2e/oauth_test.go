@@ -0,0 +1,239 @@
+package e2e
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/generated/oauth"
+	"github.com/SawitProRecruitment/UserService/handler"
+	"github.com/SawitProRecruitment/UserService/logging"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/validation"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	mockClientID    = "e2e-app"
+	mockRedirectURI = "https://app.example.com/callback"
+	// mockCodeVerifier is the PKCE example of RFC 7636.
+	mockCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+// newOAuthService start the OpenID Connect provider on an httptest server,
+// with the mock user and a first party public client, and return its URL.
+func newOAuthService(t *testing.T) string {
+	repo := repository.NewMemoryRepository(repository.NewMemoryRepositoryOptions{})
+	ctx := context.Background()
+	password, err := handler.HashPassword(ctx, mockPassword, bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = repo.Createuser(ctx, repository.RegisterUser{Phone: mockPhone, Name: "Budi", Password: password}); err != nil {
+		t.Fatal(err)
+	}
+	err = repo.CreateOAuthClient(ctx, repository.OAuthClient{
+		ID:           mockClientID,
+		Name:         "E2E App",
+		RedirectURIs: []string{mockRedirectURI},
+		FirstParty:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+	server := handler.NewServer(handler.NewServerOptions{
+		Repository: repo,
+		SecretKey:  "e2e-secret",
+		BcryptCost: bcrypt.MinCost,
+		Issuer:     srv.URL,
+		IDTokenKey: key,
+	})
+
+	spec, err := oauth.GetSwagger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// responses are validated too, so drift from api_oauth.yml fails the tests.
+	validator, err := validation.NewValidator(validation.NewValidatorOptions{
+		Spec:              spec,
+		ValidateResponses: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Use(logging.RequestID(logging.New(logging.NewOptions{Writer: io.Discard})))
+	e.Use(validator.Middleware())
+	oauth.RegisterHandlers(e, server)
+
+	return srv.URL
+}
+
+// getJSON decode the JSON response of a GET of url into v.
+func getJSON(rawURL string, token string, v any) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+}
+
+func TestOAuthAuthorizationCodeFlow(t *testing.T) {
+	t.Run("TestOAuthAuthorizationCodeFlow", func(t *testing.T) {
+		Convey("TestOAuthAuthorizationCodeFlow", t, func(c C) {
+			baseURL := newOAuthService(t)
+			// redirects to the client are not followed.
+			browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			}}
+			sum := sha256.Sum256([]byte(mockCodeVerifier))
+			authorization := url.Values{
+				"response_type":         {"code"},
+				"client_id":             {mockClientID},
+				"redirect_uri":          {mockRedirectURI},
+				"scope":                 {"openid profile phone"},
+				"state":                 {"e2e-state"},
+				"nonce":                 {"e2e-nonce"},
+				"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+				"code_challenge_method": {"S256"},
+			}
+
+			var config oauth.OpenIDConfiguration
+			status, err := getJSON(baseURL+"/.well-known/openid-configuration", "", &config)
+			So(err, ShouldBeNil)
+			So(status, ShouldEqual, http.StatusOK)
+			So(config.Issuer, ShouldEqual, baseURL)
+
+			// the login page posts the request back with the credentials.
+			resp, err := browser.Get(config.AuthorizationEndpoint + "?" + authorization.Encode())
+			So(err, ShouldBeNil)
+			page, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(string(page), ShouldContainSubstring, "Log in to E2E App")
+
+			form := url.Values{"identifier": {mockPhone}, "password": {mockPassword}}
+			for name, values := range authorization {
+				form[name] = values
+			}
+			resp, err = browser.PostForm(config.AuthorizationEndpoint, form)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, http.StatusFound)
+			location, err := resp.Location()
+			So(err, ShouldBeNil)
+			So(location.Query().Get("state"), ShouldEqual, "e2e-state")
+			code := location.Query().Get("code")
+			So(code, ShouldNotBeEmpty)
+
+			exchange := url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {code},
+				"redirect_uri":  {mockRedirectURI},
+				"client_id":     {mockClientID},
+				"code_verifier": {mockCodeVerifier},
+			}
+			resp, err = http.PostForm(config.TokenEndpoint, exchange)
+			So(err, ShouldBeNil)
+			var tokens oauth.TokenResponse
+			_ = json.NewDecoder(resp.Body).Decode(&tokens)
+			resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(resp.Header.Get("Cache-Control"), ShouldEqual, "no-store")
+			So(tokens.IdToken, ShouldNotBeNil)
+
+			// the ID token is verified with the key of the JWKS.
+			var jwks oauth.JWKS
+			status, err = getJSON(config.JwksUri, "", &jwks)
+			So(err, ShouldBeNil)
+			So(status, ShouldEqual, http.StatusOK)
+			idToken, err := jwt.Parse(*tokens.IdToken, func(token *jwt.Token) (interface{}, error) {
+				for _, key := range jwks.Keys {
+					if key.Kid == token.Header["kid"] {
+						n, _ := base64.RawURLEncoding.DecodeString(key.N)
+						e, _ := base64.RawURLEncoding.DecodeString(key.E)
+						return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+					}
+				}
+				return nil, jwt.ErrTokenUnverifiable
+			}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithIssuer(baseURL), jwt.WithAudience(mockClientID))
+			So(err, ShouldBeNil)
+			claims := idToken.Claims.(jwt.MapClaims)
+			So(claims["nonce"], ShouldEqual, "e2e-nonce")
+			So(claims["name"], ShouldEqual, "Budi")
+			So(claims["phone_number"], ShouldEqual, mockPhone)
+
+			// the access token is a session token of the user, granted the
+			// claims of the profile and phone scopes.
+			var userinfo oauth.Userinfo
+			status, err = getJSON(config.UserinfoEndpoint, tokens.AccessToken, &userinfo)
+			So(err, ShouldBeNil)
+			So(status, ShouldEqual, http.StatusOK)
+			name, phone := "Budi", mockPhone
+			So(userinfo, ShouldResemble, oauth.Userinfo{Sub: claims["sub"].(string), Name: &name, PhoneNumber: &phone})
+
+			// a code is exchanged once.
+			resp, err = http.PostForm(config.TokenEndpoint, exchange)
+			So(err, ShouldBeNil)
+			var oauthErr oauth.OAuthError
+			_ = json.NewDecoder(resp.Body).Decode(&oauthErr)
+			resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, http.StatusBadRequest)
+			So(oauthErr.Error, ShouldEqual, "invalid_grant")
+		})
+	})
+}
+
+func TestOAuthClientSecret(t *testing.T) {
+	t.Run("TestOAuthClientSecret", func(t *testing.T) {
+		Convey("TestOAuthClientSecret", t, func(c C) {
+			baseURL := newOAuthService(t)
+
+			// the public client has no secret to send.
+			req, err := http.NewRequest(http.MethodPost, baseURL+"/oauth/token", strings.NewReader(url.Values{
+				"grant_type": {"authorization_code"},
+				"code":       {"unknown-code"},
+			}.Encode()))
+			So(err, ShouldBeNil)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			req.SetBasicAuth(mockClientID, "e2e-secret")
+			resp, err := http.DefaultClient.Do(req)
+			So(err, ShouldBeNil)
+			var oauthErr oauth.OAuthError
+			_ = json.NewDecoder(resp.Body).Decode(&oauthErr)
+			resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, http.StatusUnauthorized)
+			So(resp.Header.Get(echo.HeaderWWWAuthenticate), ShouldEqual, "Basic")
+			So(oauthErr.Error, ShouldEqual, "invalid_client")
+		})
+	})
+}
@@ -0,0 +1,91 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/client"
+	"github.com/SawitProRecruitment/UserService/oidc"
+	"github.com/SawitProRecruitment/UserService/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSocialLogin(t *testing.T) {
+	t.Run("TestSocialLogin", func(t *testing.T) {
+		Convey("TestSocialLogin", t, func(c C) {
+			issuer := oidctest.NewIssuer()
+			defer issuer.Close()
+			api := newServiceWithVerifier(t, oidc.NewVerifier(oidc.NewVerifierOptions{
+				Providers: []oidc.Provider{{Name: "e2e", Issuer: issuer.URL, ClientIDs: []string{"e2e-app"}}},
+			}))
+			ctx := context.Background()
+			idToken := func(subject string, email string) client.OIDCLoginRequest {
+				return client.OIDCLoginRequest{IdToken: issuer.Sign(jwt.MapClaims{
+					"sub":            subject,
+					"aud":            "e2e-app",
+					"email":          email,
+					"email_verified": true,
+					"name":           "Budi",
+				})}
+			}
+
+			// the first login signs the user up, the next ones log it in.
+			first, created, err := api.LoginOIDC(ctx, idToken("e2e-subject", "budi@example.com"))
+			So(err, ShouldBeNil)
+			So(created, ShouldBeTrue)
+			second, created, err := api.LoginOIDC(ctx, idToken("e2e-subject", "budi@example.com"))
+			So(err, ShouldBeNil)
+			So(created, ShouldBeFalse)
+			So(second.Id, ShouldEqual, first.Id)
+
+			social := api.WithToken(client.StaticToken(second.Token))
+			user, _, err := social.GetUser(ctx, nil)
+			So(err, ShouldBeNil)
+			So(user.Name, ShouldEqual, "Budi")
+			So(string(*user.Email), ShouldEqual, "budi@example.com")
+			So(user.EmailVerified, ShouldBeTrue)
+
+			// the verified email of the user is not taken over by another identity.
+			_, _, err = api.LoginOIDC(ctx, idToken("other-subject", "budi@example.com"))
+			So(errors.Is(err, client.ErrConflict), ShouldBeTrue)
+
+			// a token of another issuer is rejected.
+			other := oidctest.NewIssuer()
+			defer other.Close()
+			_, _, err = api.LoginOIDC(ctx, client.OIDCLoginRequest{IdToken: other.Sign(jwt.MapClaims{"sub": "e2e-subject", "aud": "e2e-app"})})
+			So(errors.Is(err, client.ErrUnauthorized), ShouldBeTrue)
+
+			// the only identity of a user without password stays linked.
+			err = social.UnlinkUserIdentity(ctx, "e2e")
+			So(errors.Is(err, client.ErrConflict), ShouldBeTrue)
+
+			// a user with a password links and unlinks an identity.
+			_, err = register(api)
+			So(err, ShouldBeNil)
+			member, err := login(api)
+			So(err, ShouldBeNil)
+			identity, err := member.LinkUserIdentity(ctx, idToken("member-subject", "member@example.com"))
+			So(err, ShouldBeNil)
+			So(identity.Provider, ShouldEqual, "e2e")
+			_, err = member.LinkUserIdentity(ctx, idToken("e2e-subject", "budi@example.com"))
+			So(errors.Is(err, client.ErrConflict), ShouldBeTrue)
+
+			identities, err := member.ListUserIdentities(ctx)
+			So(err, ShouldBeNil)
+			So(identities.Identities, ShouldHaveLength, 1)
+			So(*identities.Identities[0].Email, ShouldEqual, "member@example.com")
+
+			linked, created, err := api.LoginOIDC(ctx, idToken("member-subject", "member@example.com"))
+			So(err, ShouldBeNil)
+			So(created, ShouldBeFalse)
+			So(linked.Id, ShouldNotEqual, first.Id)
+
+			So(member.UnlinkUserIdentity(ctx, "e2e"), ShouldBeNil)
+			err = member.UnlinkUserIdentity(ctx, "e2e")
+			So(errors.Is(err, client.ErrNotFound), ShouldBeTrue)
+		})
+	})
+}
@@ -8,23 +8,85 @@ import (
 	"compress/gzip"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/labstack/echo/v4"
+	"github.com/oapi-codegen/runtime"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+const (
+	ApiKeyAuthScopes = "apiKeyAuth.Scopes"
+	BearerAuthScopes = "bearerAuth.Scopes"
+)
+
+// Defines values for HealthStatus.
+const (
+	Fail HealthStatus = "fail"
+	Pass HealthStatus = "pass"
+)
+
+// Defines values for UserEventType.
+const (
+	EmailVerified    UserEventType = "email_verified"
+	IdentityLinked   UserEventType = "identity_linked"
+	IdentityUnlinked UserEventType = "identity_unlinked"
+	LoginFailed      UserEventType = "login_failed"
+	LoginSucceeded   UserEventType = "login_succeeded"
+	ProfileUpdated   UserEventType = "profile_updated"
+	Registered       UserEventType = "registered"
+	SessionRevoked   UserEventType = "session_revoked"
 )
 
 // ErrorResponse defines model for ErrorResponse.
 type ErrorResponse struct {
 	Message string `json:"message"`
+
+	// RequestId Id of the request, echoed from or generated for the X-Request-ID header.
+	RequestId *string `json:"request_id,omitempty"`
+}
+
+// FieldChange defines model for FieldChange.
+type FieldChange struct {
+	After  string `json:"after"`
+	Before string `json:"before"`
+}
+
+// HealthCheck defines model for HealthCheck.
+type HealthCheck struct {
+	Details   *string      `json:"details,omitempty"`
+	Error     *string      `json:"error,omitempty"`
+	LatencyMs float64      `json:"latency_ms"`
+	Name      string       `json:"name"`
+	Status    HealthStatus `json:"status"`
 }
 
+// HealthResponse defines model for HealthResponse.
+type HealthResponse struct {
+	Checks *[]HealthCheck `json:"checks,omitempty"`
+	Status HealthStatus   `json:"status"`
+}
+
+// HealthStatus defines model for HealthStatus.
+type HealthStatus string
+
 // LoginRequest defines model for LoginRequest.
 type LoginRequest struct {
-	Password string `json:"password"`
-	Phone    string `json:"phone"`
+	// Identifier Phone number, like +6281234567 or 081234567, or verified email of the user.
+	Identifier *string `json:"identifier,omitempty"`
+	Password   string  `json:"password"`
+
+	// Phone Phone number of the user, use identifier.
+	// Deprecated:
+	Phone *string `json:"phone,omitempty"`
+
+	// Scope Space separated scopes of the token, among profile:read, profile:write, sessions:manage, identities:manage and admin:api_keys. The token has every scope but admin:api_keys when omitted, administrators ask for admin:api_keys to manage API keys.
+	Scope *string `json:"scope,omitempty"`
 }
 
 // LoginResponse defines model for LoginResponse.
@@ -33,6 +95,15 @@ type LoginResponse struct {
 	Token string `json:"token"`
 }
 
+// OIDCLoginRequest defines model for OIDCLoginRequest.
+type OIDCLoginRequest struct {
+	// IdToken ID token issued to the service by the provider.
+	IdToken string `json:"id_token"`
+
+	// Nonce Nonce of the authentication request, checked against the token when given.
+	Nonce *string `json:"nonce,omitempty"`
+}
+
 // RegisterRequest defines model for RegisterRequest.
 type RegisterRequest struct {
 	Name     string `json:"name"`
@@ -47,47 +118,208 @@ type RegisterResponse struct {
 
 // UpdateUserRequest defines model for UpdateUserRequest.
 type UpdateUserRequest struct {
-	Name  string `json:"name"`
-	Phone string `json:"phone"`
+	AvatarUrl   *string `json:"avatar_url,omitempty"`
+	BirthDate   *string `json:"birth_date,omitempty"`
+	DisplayName *string `json:"display_name,omitempty"`
+	Email       *string `json:"email,omitempty"`
+	Locale      *string `json:"locale,omitempty"`
+	Name        *string `json:"name,omitempty"`
+	Phone       *string `json:"phone,omitempty"`
+	Timezone    *string `json:"timezone,omitempty"`
 }
 
-// UpdateUserResponse defines model for UpdateUserResponse.
-type UpdateUserResponse struct {
-	Id int64 `json:"id"`
+// UserEvent defines model for UserEvent.
+type UserEvent struct {
+	Changes   *map[string]FieldChange `json:"changes,omitempty"`
+	CreatedAt time.Time               `json:"created_at"`
+	Id        int64                   `json:"id"`
+	IpAddress string                  `json:"ip_address"`
+
+	// Provider Identity provider of a registration or of a linked or unlinked identity.
+	Provider  *string       `json:"provider,omitempty"`
+	Reason    *string       `json:"reason,omitempty"`
+	SessionId *string       `json:"session_id,omitempty"`
+	Type      UserEventType `json:"type"`
+	UserAgent string        `json:"user_agent"`
+}
+
+// UserEventType defines model for UserEvent.Type.
+type UserEventType string
+
+// UserEventsResponse defines model for UserEventsResponse.
+type UserEventsResponse struct {
+	Events []UserEvent `json:"events"`
+}
+
+// UserIdentitiesResponse defines model for UserIdentitiesResponse.
+type UserIdentitiesResponse struct {
+	Identities []UserIdentity `json:"identities"`
+}
+
+// UserIdentity defines model for UserIdentity.
+type UserIdentity struct {
+	CreatedAt time.Time `json:"created_at"`
+
+	// Email Email asserted by the provider when the identity was linked.
+	Email    *string `json:"email,omitempty"`
+	Provider string  `json:"provider"`
+}
+
+// UserLogin defines model for UserLogin.
+type UserLogin struct {
+	CreatedAt time.Time `json:"created_at"`
+	Id        int64     `json:"id"`
+	IpAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+}
+
+// UserLoginsResponse defines model for UserLoginsResponse.
+type UserLoginsResponse struct {
+	Logins []UserLogin `json:"logins"`
+
+	// NextBefore Cursor of the next page, absent on the last page.
+	NextBefore *int64 `json:"next_before,omitempty"`
 }
 
 // UserResponse defines model for UserResponse.
 type UserResponse struct {
-	Name  string `json:"name"`
-	Phone string `json:"phone"`
+	AvatarUrl     *string              `json:"avatar_url,omitempty"`
+	BirthDate     *openapi_types.Date  `json:"birth_date,omitempty"`
+	DisplayName   *string              `json:"display_name,omitempty"`
+	Email         *openapi_types.Email `json:"email,omitempty"`
+	EmailVerified bool                 `json:"email_verified"`
+	LastLoginAt   *time.Time           `json:"last_login_at,omitempty"`
+	Locale        *string              `json:"locale,omitempty"`
+	LoginCount    int64                `json:"login_count"`
+	Name          string               `json:"name"`
+	Phone         string               `json:"phone"`
+	Timezone      *string              `json:"timezone,omitempty"`
+}
+
+// UserSession defines model for UserSession.
+type UserSession struct {
+	CreatedAt time.Time `json:"created_at"`
+
+	// Current Whether the session belongs to the token of this request.
+	Current    bool   `json:"current"`
+	DeviceName string `json:"device_name"`
+	Id         string `json:"id"`
+	IpAddress  string `json:"ip_address"`
+
+	// LastSeenAt When the session was last used, recorded at most once a minute.
+	LastSeenAt time.Time `json:"last_seen_at"`
+	UserAgent  string    `json:"user_agent"`
+}
+
+// UserSessionsResponse defines model for UserSessionsResponse.
+type UserSessionsResponse struct {
+	Sessions []UserSession `json:"sessions"`
+}
+
+// IdempotencyKey defines model for IdempotencyKey.
+type IdempotencyKey = string
+
+// IfMatch defines model for IfMatch.
+type IfMatch = string
+
+// IfNoneMatch defines model for IfNoneMatch.
+type IfNoneMatch = string
+
+// TooManyRequests defines model for TooManyRequests.
+type TooManyRequests = ErrorResponse
+
+// GetUserParams defines parameters for GetUser.
+type GetUserParams struct {
+	// IfNoneMatch Return 304 when the user still has this ETag.
+	IfNoneMatch *IfNoneMatch `json:"If-None-Match,omitempty"`
+}
+
+// UpdateUserParams defines parameters for UpdateUser.
+type UpdateUserParams struct {
+	// IfMatch Only apply the update when the user still has this ETag.
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// ListUserEventsParams defines parameters for ListUserEvents.
+type ListUserEventsParams struct {
+	// Limit Maximum number of events to return, newest first.
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// ListUserLoginsParams defines parameters for ListUserLogins.
+type ListUserLoginsParams struct {
+	// Limit Maximum number of logins to return, newest first.
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Before Only return logins older than this login id, taken from next_before of the previous page.
+	Before *int64 `form:"before,omitempty" json:"before,omitempty"`
+}
+
+// UserRegisterParams defines parameters for UserRegister.
+type UserRegisterParams struct {
+	// IdempotencyKey Unique key of the request. Retrying with the same key and body replays the first response instead of running the request again.
+	IdempotencyKey *IdempotencyKey `json:"Idempotency-Key,omitempty"`
 }
 
 // LoginJSONRequestBody defines body for Login for application/json ContentType.
 type LoginJSONRequestBody = LoginRequest
 
-// GetUserJSONRequestBody defines body for GetUser for application/json ContentType.
-type GetUserJSONRequestBody = RegisterRequest
+// LoginOIDCJSONRequestBody defines body for LoginOIDC for application/json ContentType.
+type LoginOIDCJSONRequestBody = OIDCLoginRequest
 
 // UpdateUserJSONRequestBody defines body for UpdateUser for application/json ContentType.
 type UpdateUserJSONRequestBody = UpdateUserRequest
 
+// LinkUserIdentityJSONRequestBody defines body for LinkUserIdentity for application/json ContentType.
+type LinkUserIdentityJSONRequestBody = OIDCLoginRequest
+
 // UserRegisterJSONRequestBody defines body for UserRegister for application/json ContentType.
 type UserRegisterJSONRequestBody = RegisterRequest
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Liveness, the process is up and serving requests.
+	// (GET /healthz)
+	Healthz(ctx echo.Context) error
 	// Login user.
 	// (POST /login)
 	Login(ctx echo.Context) error
+	// Login user with the ID token of an external OpenID Connect provider.
+	// (POST /login/oidc)
+	LoginOIDC(ctx echo.Context) error
+	// Readiness, every dependency check passes and the service is not shutting down.
+	// (GET /readyz)
+	Readyz(ctx echo.Context) error
 	// Get user data.
 	// (GET /users)
-	GetUser(ctx echo.Context) error
+	GetUser(ctx echo.Context, params GetUserParams) error
 	// Update user data.
 	// (PATCH /users)
-	UpdateUser(ctx echo.Context) error
+	UpdateUser(ctx echo.Context, params UpdateUserParams) error
+	// List recent security events of the user.
+	// (GET /users/events)
+	ListUserEvents(ctx echo.Context, params ListUserEventsParams) error
+	// List the external identities linked to the user.
+	// (GET /users/identities)
+	ListUserIdentities(ctx echo.Context) error
+	// Link an external identity to the user.
+	// (POST /users/identities)
+	LinkUserIdentity(ctx echo.Context) error
+	// Unlink the identity of a provider from the user.
+	// (DELETE /users/identities/{provider})
+	UnlinkUserIdentity(ctx echo.Context, provider string) error
+	// List login history of the user.
+	// (GET /users/logins)
+	ListUserLogins(ctx echo.Context, params ListUserLoginsParams) error
 	// Register a new user.
 	// (POST /users/register)
-	UserRegister(ctx echo.Context) error
+	UserRegister(ctx echo.Context, params UserRegisterParams) error
+	// List active sessions of the user.
+	// (GET /users/sessions)
+	ListUserSessions(ctx echo.Context) error
+	// Revoke a session of the user, tokens of the session stop working.
+	// (DELETE /users/sessions/{id})
+	RevokeUserSession(ctx echo.Context, id string) error
 }
 
 // ServerInterfaceWrapper converts echo contexts to parameters.
@@ -95,6 +327,15 @@ type ServerInterfaceWrapper struct {
 	Handler ServerInterface
 }
 
+// Healthz converts echo context to params.
+func (w *ServerInterfaceWrapper) Healthz(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.Healthz(ctx)
+	return err
+}
+
 // Login converts echo context to params.
 func (w *ServerInterfaceWrapper) Login(ctx echo.Context) error {
 	var err error
@@ -104,12 +345,54 @@ func (w *ServerInterfaceWrapper) Login(ctx echo.Context) error {
 	return err
 }
 
+// LoginOIDC converts echo context to params.
+func (w *ServerInterfaceWrapper) LoginOIDC(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.LoginOIDC(ctx)
+	return err
+}
+
+// Readyz converts echo context to params.
+func (w *ServerInterfaceWrapper) Readyz(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.Readyz(ctx)
+	return err
+}
+
 // GetUser converts echo context to params.
 func (w *ServerInterfaceWrapper) GetUser(ctx echo.Context) error {
 	var err error
 
+	ctx.Set(BearerAuthScopes, []string{"profile:read"})
+
+	ctx.Set(ApiKeyAuthScopes, []string{"profile:read"})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetUserParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-None-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-None-Match")]; found {
+		var IfNoneMatch IfNoneMatch
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-None-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-None-Match", runtime.ParamLocationHeader, valueList[0], &IfNoneMatch)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-None-Match: %s", err))
+		}
+
+		params.IfNoneMatch = &IfNoneMatch
+	}
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.GetUser(ctx)
+	err = w.Handler.GetUser(ctx, params)
 	return err
 }
 
@@ -117,8 +400,125 @@ func (w *ServerInterfaceWrapper) GetUser(ctx echo.Context) error {
 func (w *ServerInterfaceWrapper) UpdateUser(ctx echo.Context) error {
 	var err error
 
+	ctx.Set(BearerAuthScopes, []string{"profile:write"})
+
+	ctx.Set(ApiKeyAuthScopes, []string{"profile:write"})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params UpdateUserParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch IfMatch
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Match", runtime.ParamLocationHeader, valueList[0], &IfMatch)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-Match: %s", err))
+		}
+
+		params.IfMatch = &IfMatch
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.UpdateUser(ctx, params)
+	return err
+}
+
+// ListUserEvents converts echo context to params.
+func (w *ServerInterfaceWrapper) ListUserEvents(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{"profile:read"})
+
+	ctx.Set(ApiKeyAuthScopes, []string{"profile:read"})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListUserEventsParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ListUserEvents(ctx, params)
+	return err
+}
+
+// ListUserIdentities converts echo context to params.
+func (w *ServerInterfaceWrapper) ListUserIdentities(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{"profile:read"})
+
+	ctx.Set(ApiKeyAuthScopes, []string{"profile:read"})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ListUserIdentities(ctx)
+	return err
+}
+
+// LinkUserIdentity converts echo context to params.
+func (w *ServerInterfaceWrapper) LinkUserIdentity(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{"identities:manage"})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.LinkUserIdentity(ctx)
+	return err
+}
+
+// UnlinkUserIdentity converts echo context to params.
+func (w *ServerInterfaceWrapper) UnlinkUserIdentity(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "provider" -------------
+	var provider string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "provider", runtime.ParamLocationPath, ctx.Param("provider"), &provider)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter provider: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{"identities:manage"})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.UnlinkUserIdentity(ctx, provider)
+	return err
+}
+
+// ListUserLogins converts echo context to params.
+func (w *ServerInterfaceWrapper) ListUserLogins(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{"profile:read"})
+
+	ctx.Set(ApiKeyAuthScopes, []string{"profile:read"})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListUserLoginsParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "before" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "before", ctx.QueryParams(), &params.Before)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter before: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.UpdateUser(ctx)
+	err = w.Handler.ListUserLogins(ctx, params)
 	return err
 }
 
@@ -126,8 +526,61 @@ func (w *ServerInterfaceWrapper) UpdateUser(ctx echo.Context) error {
 func (w *ServerInterfaceWrapper) UserRegister(ctx echo.Context) error {
 	var err error
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params UserRegisterParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "Idempotency-Key" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Idempotency-Key")]; found {
+		var IdempotencyKey IdempotencyKey
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for Idempotency-Key, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "Idempotency-Key", runtime.ParamLocationHeader, valueList[0], &IdempotencyKey)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter Idempotency-Key: %s", err))
+		}
+
+		params.IdempotencyKey = &IdempotencyKey
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.UserRegister(ctx, params)
+	return err
+}
+
+// ListUserSessions converts echo context to params.
+func (w *ServerInterfaceWrapper) ListUserSessions(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{"sessions:manage"})
+
+	ctx.Set(ApiKeyAuthScopes, []string{"sessions:manage"})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ListUserSessions(ctx)
+	return err
+}
+
+// RevokeUserSession converts echo context to params.
+func (w *ServerInterfaceWrapper) RevokeUserSession(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{"sessions:manage"})
+
+	ctx.Set(ApiKeyAuthScopes, []string{"sessions:manage"})
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.UserRegister(ctx)
+	err = w.Handler.RevokeUserSession(ctx, id)
 	return err
 }
 
@@ -159,26 +612,84 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 		Handler: si,
 	}
 
+	router.GET(baseURL+"/healthz", wrapper.Healthz)
 	router.POST(baseURL+"/login", wrapper.Login)
+	router.POST(baseURL+"/login/oidc", wrapper.LoginOIDC)
+	router.GET(baseURL+"/readyz", wrapper.Readyz)
 	router.GET(baseURL+"/users", wrapper.GetUser)
 	router.PATCH(baseURL+"/users", wrapper.UpdateUser)
+	router.GET(baseURL+"/users/events", wrapper.ListUserEvents)
+	router.GET(baseURL+"/users/identities", wrapper.ListUserIdentities)
+	router.POST(baseURL+"/users/identities", wrapper.LinkUserIdentity)
+	router.DELETE(baseURL+"/users/identities/:provider", wrapper.UnlinkUserIdentity)
+	router.GET(baseURL+"/users/logins", wrapper.ListUserLogins)
 	router.POST(baseURL+"/users/register", wrapper.UserRegister)
+	router.GET(baseURL+"/users/sessions", wrapper.ListUserSessions)
+	router.DELETE(baseURL+"/users/sessions/:id", wrapper.RevokeUserSession)
 
 }
 
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/9xWwW7bOhD8FWHfOwqW2wQ96BigDQK0l6Q5BTkw1FpiKpHMcuXAMPTvBUnbqiPZLlLb",
-	"KHqTxfXO7Ax37CVI01ijUbODfAlOVtiI8PiZyNAtOmu0Q//CkrFIrDAcN+icKMMBLyxCDo5J6RK6LgXC",
-	"l1YRFpA/bAof03WheXpGydCl8NWUSt/iS4uOhxBWOPdqqBjBSMFWRv8GeixL+157aOwaVQUGM0ONYMhB",
-	"af50CZs2SjOWSL4Pmx+oD3NSBaxrx9jcYqkcI+3URYsGxzU5omAB5IBuPdM/lG4o0BjcvS0E4717lzTv",
-	"mP4Qh/MMvRfqxPP6cqVnxjeqlcQVh4gK326+h1uvuPYfPdPkDmmupG85R3LKaMjhw2Q6mfpKY1ELqyCH",
-	"i/DKXy+uwiBZ7XcwDGiisX5MwcromwLyuKIQyaPjK1MsfJE0mlGHemFtrWT4RvbsjO7jzD/9TziDHP7L",
-	"+rzLVmGXbaVQty0RU4vhRXQgcP04nR4be+VvAC/QSVKWo3h3rZToXBL16VK4PCL6dsaPoF+JIumVScG1",
-	"TSNosTYkaR3SJJxk/jHIU+KIf9fI/n6cyMG3kXlmE7d2dI+HJXJQLCkEi+jlxfm8/GLoSRUF6jdOXv/K",
-	"ahJ/R1hWQw/77DuRjcOAP7eRw3TfY2df85c4GelvmbnZzIxWO7I7Y+Pcq6p/clEHf1j2uCsJvZgaX4Og",
-	"Z4/eGz0XtdoVv+tJErFhOIldHNI8JPHDElqqIYeK2eZZVhsp6so73z12PwMAAP//ASahZfkLAAA=",
+	"H4sIAAAAAAAC/+wba3PctvGvYNh+K6U7yXLa3KcqtpMqseOM7DSdSTxnHLF3h4gEGACUTHv03zu7AN+8",
+	"h6yXp+kXW0eCi93FvnfxKUp0lmsFytlo9ilaAxdg6M8Xb/kK/xdgEyNzJ7WKZtG/wVipFdNL5tbACguG",
+	"Ce74YRRHNllDxvEbV+YQzSLrjFSr6Po6js65g5cyk47+GcI9hz8KsM4ynqb6CgTLwbArqYS+GgUtlYMV",
+	"mC7sc8i4VLjlZvgpLB1bwFIbYAuQasVS/BTETbaxMELCG0i0EpYVysmUuEOQmbRsWaRpyQxYp80+O4Ez",
+	"5enSgdlnF+NJYwlXbIE/nZE7N7mOo5wbnoELx30mIMu1A5WUP0A53PdnJf8ogF1AWZ192PeQEbrIySvp",
+	"1vTK8swv5UqwhRZIe57y0tLbpTTWITdyrSwwqawDLhCsKRQeX4csvuJSHf6mojiSiIgX0SiOFM+Qphbi",
+	"B4h5m+yMf3gJauXW0ez46dN4RC7Plq+4S9ZDel+rtGQ8z9PSC3ouuAN2tQbVCL51Mk3ZmiNZ0jLUmMON",
+	"aC4P/E7b9eRs+aNWsAGnc3CFUezJ9OR2iOAWe2BzHUfVIZGIvNX6FVdlpUr4KNHKgSJlQF7JhCOqk98t",
+	"4vupBfuvBpbRLPrLpLE3E//WTl4Yo8152Mnv26Obu0qZ4EMCIEBEcdtW1ap5UJuXsQ3DF5OeMWrr9kHH",
+	"huwFpPmiByhYiT2B4OpK9w9q5d/6cWMmrolrgaFkvTs8nX2KcqNzME76o8zAWr6CkVPHM6fznUsxlMAz",
+	"0dP+mEGy1iDY0uiMacNWoMBwh0+0oZX/OQgSc3D2nHncUTiHso8gpQERzX6tEXxXL9SL3yEhFn0rIRXP",
+	"1lytRijjFecGdHmjP6527a3DujiAGsPgX8BTt362huRiiIEAx2VqR3EAPJbRNyknEzbP6MOlNhl30SwS",
+	"ulik0LBLFdkCz7tS5xFI1nFX2F1K50l449f2WUCwa0gd5DazY7O0Jcgo+ks6yPZEzXP3ut6OG8PLO6Iv",
+	"gNhMy5t6D1BFhp/k3CInllymre8arr/UK6mCoJMcCiFRaXj6U4sXS55aiHvskQKUk0s55u5/WmsFzJ96",
+	"zFJ5AexvXx3/4+j4ycnTr/6OCjetfsT46xIMQhIMjVLaDtNQ5+ADz/IUEV8UQv4z/DxMdBaNkIQkX2kj",
+	"RqUsR8Q8vrmBBDU+mjlTQLyFgjY+Mf7LGtoPx1Cwic5hJAjKeQLMQs69qaFltoLu9AWomPFMqxXLjV7K",
+	"FGYGuIjrX1dGOoiZBWulVnaWccVXEAd08GDCI4pguMikmvFczi+gtIfsbbUJuVy4BFN6FNiicL3V3lPr",
+	"TDoHIvYvpXWGO20s4/aC7GTvG6dZ2P70pzNGm1IA1Jxfm6ydxrQ+yDGBD4K7SXel6NgjqdxXJ1E8CCjj",
+	"iBiy27pKEVVrx7B5ffb82a1UaV7j0fNcz8ORSWsLEMhiilTBXMoE2MKHebnRl1JsEEalVTIijD/i40r2",
+	"eOHWoFwIhBovSSYQhI9mrWvk1MvHSl6C2u0Va/LGWHcOK2kdmM/j3EZ3sp8V2CGAtCyu/MpWeWzIuKVI",
+	"DgVvbLufKbD/2e7Pt0yq9tOjPif5JXfczAuTjoch0rj1HDcdfS2kxUxpvvE8yLCPvkl1wtPxjzaf7oYD",
+	"jCMnM/i48XSHfLRgXlyGXKDv/jFUs5sZu92Tt8O9sZ0TA+gE5tx1Iyfu4ACJGFPlva2azOdcCAN2PJ6r",
+	"7MVYpEyepKxNCloIzgxJt/HWQYeHqVRoG7RhhQp/B0dUjhoiAzwkWINXwaOF4H3w2j9oohoTlI0SqhTt",
+	"7twWSZ1i+ScY89DP4HTmPhkWUbOdgUt9QU9IPOdVGBLFUUXJ3FPWflJROxpQFRbMnK+CRO3jVHBJ58Q6",
+	"QDqC8m6bBNvNpgcuq0rZXpFsDXIYx/YoCIA34XVWxyXbzGK15kb4VYK6E8UW/B1oliM24DOUtLZ0Xc16",
+	"gY8ZtxYMxn49z91URioxY1fcBhUb1aa2DjcB1krrVQq7Q6vq473EiyKbu2HOXVmwG6vZrfSLGLBFhsng",
+	"3Ex+PU9H8kQFH9y8Sfq7QvSsMFbX+QguZTllAHxhQTmmvQyl3PoXKDk3jTgCMZt4sZkL3Qii3rYwMop3",
+	"BRQd6Yni2wQYNSj/ZJOGNsa+gbbQOgWufGHDurl3JDeR7yaYaXRSioOz5+OLEXyiC+X21IpbBkQNTqdW",
+	"8sn3/IIbx3dbi24c3EZ7wMxNYvPGu9y7sSJJYUxQ/a5+/LIGtwYTciTakS0g1Wplq9TJpy+kQdLW3YAo",
+	"HpEBAZhkbRa5DdHKDtNFkmUBKsEaUKA66JMbQH0uLGbiBhJtBKZkjmXaosonwDjLpCpcV9+3svDGBrTN",
+	"jD3NaY/W5tx2CMkWSxu4cjNbG8DuDBVq4EP8KEZNCiNd+QYhB4uXyx+gPC3cSNfjVFVVkCp3X2BjqVtK",
+	"iRmZbW7Ze4SijfxIMfaMnRJo9lsxnT5JLqCkP+C9r+KEwlEqLYYSWiFc5BJ9y7gBkiCtwHeuEIesoF6b",
+	"MeWWplQHh0ZqPJm+GM0NmIpg/+vbSt6+/+Vt1C+jnXbLF8gCRvYj9shUbbNA0cpw5XyZQzomFZPOMtsr",
+	"m72nxe9ZknKZfRZDPE59lpDIkAkguhr6187lvsEj1VKTw5cJBPkMrHt19tZbW0f2FcWOvfFVmiiOLn3/",
+	"OZpFR4fTwymu1DkonstoFj2hR1hfcGsSK2yVpG79Ef9e+W5MTcyZiGah2vsx6nW6jqfTO+tu9YrjI+2t",
+	"QB2T2P2Wl+CVpMgybspoFr3E2hBYG1dxbgLW4uIip+oklbDUqrLA9pC+n6R1rKntCOU+bKq7Pd9oUd4Z",
+	"yZ0C3nXXODhTwPU9srtbzhzjNua31nrlQfk5ucPdd7Yyv+GC1ZyJo5PjrzeBrDk06fdde+KBdIQKf3Pw",
+	"Ey1F0j79Lhpvu7q7AGblSlV2JdFqKVeFAdGkVShpo6XTQ3ZKu6NEBpflS9qqycDcmtMchNKuKnaU4GIs",
+	"d+BRILROv8Kj1oo3uNIUjvjGwdVa+2a3xlNE64bYIeBu4hcmC7xRGhF/LDbfkwoM6thfvBocT48eaXdf",
+	"BFNwRcf76Co5PXq4vc/UJU+lYHV3gmqAF0pfqVr1PFJfPxxSb2+kjnduxpoxooYrSzQn8MGBUTxlr3NQ",
+	"Z8/ZM60UJK7VtCH7Z4CLcrPLP/evvxSPT9giD59OnzwgBqdMQA5KgEpK35xivszLtOk0xqRldl04hxGG",
+	"0Feqd2zITemjE98HHYDNubVgyUL34KI36MAOB4hCYDee33fgMCaMugNsv44zpFkyaU9WXb+7x+Pv1Ha2",
+	"2L4VuGZ+sjvPVM1ebpv+oTUE/sn0ZGRer4JMbM608GMBVqrEx/DUcqRRsdvsfTJ98nCG6VttFlIIUJ00",
+	"kg6/nU/92u2Ov7uOuwnm4P27tkR/1z6WQ98BDdN4XUFsOoefIYttObz76GPY1Hzg8GNfHajX3EoEpw/v",
+	"sDsBw+OoAO588nA7k0FBW7LUhRK0/dHxA29P9gzreNvt2Z7WgYaAtpmHakHHPnjd6piI2mlNmv7gqO96",
+	"Ka1r2oxDs9El+RX/ILMia81OefAYgRkaBY4xagbr/Eh1PfL7RwGmbIpRNDnbmfQVsORF6qLZ8TSOMr9N",
+	"NDuaTmm6Ifwa6W/ct9vstV+3JQ7SBjMdWP4nNgT35wtRXpmBBJRj1RaVEHZGC1sq0G1Db1WDpqsd3bNg",
+	"jfTP9xKuFjH/uweMx1jnVg3FVaUmlHvCQcdbKkrtfK3Tk8c/QsU4wC+xiiMTyvMUlgFsvQmT2Lcxulit",
+	"WauYVdeZsPLT9GwaiCwPO1EuyOgCB6Fjw5SNr5ZXlfHBqGdMN2iQ6GYSp7UqJudXzWNWaVKzwWvkN0tS",
+	"icc8WnKS6qIzJPElVZ6O7kPdylHDWR2XF69HM9w5L1PNKd+t5Paxo7kHrvE0ddK2rvNQZA0CXqscqlpP",
+	"r3dZpoGKDc2PuuhUdmqUnN7lXSafKjSuvTVKwU9A9LI00uOe3vXCLgqasGnUxEytuZ6u6my7MDUMj062",
+	"DAbW029/nhziR92ccN9H1EL46LrgO5wpDo6VjQ6Qc6BuQ8wsOMZZNcbso+9bK4OX1G4HgxxXzSK6YzWq",
+	"Fs3c1NaAy49f3Tzv8OAfL++IR29nelwq5HQqaF6FKz+QQo+ZFDFzHGMSYl5rJqwRQLiUurD1mNcYKfWd",
+	"sIaW3dNg950t9Ybp9gpog6D8P1u6r2Dai91aWqdNuTFJquauN/fnfQUtrLpxfbF7mfu+yoz9GycPXGQc",
+	"3BTZogG+M/14TcZRBXhAH3da36fvXtLv3Z6ndg9dJpfUgFwZsN5aHB8/rEPuI4ZVv8KC8PhzJuRyCQaU",
+	"qwi7oz5kJVStjnRHc9tzc1t9bTWCd9+ljcGo315+oCbji7XEvbuZY8Z4uGRoj3ni5GU9BLq5bFUtmHyS",
+	"YmtCcU5XXVqc3yufoMnPO84kwv6sunzz58kjKsrrdgRmqzylXnrDjvuWLy8JjNcTxp3bzU1lqD2EbJ3O",
+	"2ZU2F1KtDj1p2BGvJIdG/mlMcjaZ0Aj8WqNpe3f93wEAJ3+zpqpHAAA=",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
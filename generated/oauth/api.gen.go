@@ -0,0 +1,495 @@
+// Package oauth provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/deepmap/oapi-codegen version v1.16.2 DO NOT EDIT.
+package oauth
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+	"github.com/oapi-codegen/runtime"
+)
+
+const (
+	BasicAuthScopes  = "basicAuth.Scopes"
+	BearerAuthScopes = "bearerAuth.Scopes"
+)
+
+// Defines values for AuthorizationFormConsent.
+const (
+	Allow AuthorizationFormConsent = "allow"
+	Deny  AuthorizationFormConsent = "deny"
+)
+
+// AuthorizationForm defines model for AuthorizationForm.
+type AuthorizationForm struct {
+	ClientId            string  `json:"client_id"`
+	CodeChallenge       *string `json:"code_challenge,omitempty"`
+	CodeChallengeMethod *string `json:"code_challenge_method,omitempty"`
+
+	// Consent allow or deny, asked of clients that are not first party.
+	Consent *AuthorizationFormConsent `json:"consent,omitempty"`
+
+	// Identifier Phone number or verified email of the user.
+	Identifier   *string `json:"identifier,omitempty"`
+	Nonce        *string `json:"nonce,omitempty"`
+	Password     *string `json:"password,omitempty"`
+	RedirectUri  string  `json:"redirect_uri"`
+	ResponseType string  `json:"response_type"`
+	Scope        *string `json:"scope,omitempty"`
+	State        *string `json:"state,omitempty"`
+}
+
+// AuthorizationFormConsent allow or deny, asked of clients that are not first party.
+type AuthorizationFormConsent string
+
+// Error defines model for Error.
+type Error struct {
+	Message string `json:"message"`
+
+	// RequestId Id of the request, echoed from or generated for the X-Request-ID header.
+	RequestId *string `json:"request_id,omitempty"`
+}
+
+// JWK defines model for JWK.
+type JWK struct {
+	Alg string `json:"alg"`
+	E   string `json:"e"`
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	Use string `json:"use"`
+}
+
+// JWKS defines model for JWKS.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// OAuthError defines model for OAuthError.
+type OAuthError struct {
+	Error            string  `json:"error"`
+	ErrorDescription *string `json:"error_description,omitempty"`
+}
+
+// OpenIDConfiguration defines model for OpenIDConfiguration.
+type OpenIDConfiguration struct {
+	AuthorizationEndpoint             string    `json:"authorization_endpoint"`
+	ClaimsSupported                   *[]string `json:"claims_supported,omitempty"`
+	CodeChallengeMethodsSupported     *[]string `json:"code_challenge_methods_supported,omitempty"`
+	GrantTypesSupported               *[]string `json:"grant_types_supported,omitempty"`
+	IdTokenSigningAlgValuesSupported  []string  `json:"id_token_signing_alg_values_supported"`
+	Issuer                            string    `json:"issuer"`
+	JwksUri                           string    `json:"jwks_uri"`
+	ResponseTypesSupported            []string  `json:"response_types_supported"`
+	ScopesSupported                   *[]string `json:"scopes_supported,omitempty"`
+	SubjectTypesSupported             []string  `json:"subject_types_supported"`
+	TokenEndpoint                     string    `json:"token_endpoint"`
+	TokenEndpointAuthMethodsSupported *[]string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	UserinfoEndpoint                  string    `json:"userinfo_endpoint"`
+}
+
+// TokenRequest defines model for TokenRequest.
+type TokenRequest struct {
+	ClientId     *string `json:"client_id,omitempty"`
+	ClientSecret *string `json:"client_secret,omitempty"`
+	Code         *string `json:"code,omitempty"`
+	CodeVerifier *string `json:"code_verifier,omitempty"`
+
+	// GrantType Only authorization_code is supported.
+	GrantType   string  `json:"grant_type"`
+	RedirectUri *string `json:"redirect_uri,omitempty"`
+}
+
+// TokenResponse defines model for TokenResponse.
+type TokenResponse struct {
+	// AccessToken Bearer token of a new session of the user.
+	AccessToken string `json:"access_token"`
+
+	// ExpiresIn Seconds until the access token expires, absent when it never does.
+	ExpiresIn *int `json:"expires_in,omitempty"`
+
+	// IdToken ID token, issued when the openid scope was granted.
+	IdToken   *string `json:"id_token,omitempty"`
+	Scope     *string `json:"scope,omitempty"`
+	TokenType string  `json:"token_type"`
+}
+
+// Userinfo defines model for Userinfo.
+type Userinfo struct {
+	// Name Name of the user, when the token is granted the profile scope.
+	Name *string `json:"name,omitempty"`
+
+	// PhoneNumber Phone number of the user, when the token is granted the phone scope.
+	PhoneNumber *string `json:"phone_number,omitempty"`
+
+	// Sub Id of the user.
+	Sub string `json:"sub"`
+}
+
+// ClientID defines model for ClientID.
+type ClientID = string
+
+// CodeChallenge defines model for CodeChallenge.
+type CodeChallenge = string
+
+// CodeChallengeMethod defines model for CodeChallengeMethod.
+type CodeChallengeMethod = string
+
+// Nonce defines model for Nonce.
+type Nonce = string
+
+// RedirectURI defines model for RedirectURI.
+type RedirectURI = string
+
+// ResponseType defines model for ResponseType.
+type ResponseType = string
+
+// Scope defines model for Scope.
+type Scope = string
+
+// State defines model for State.
+type State = string
+
+// InvalidAuthorization defines model for InvalidAuthorization.
+type InvalidAuthorization = Error
+
+// TooManyRequests defines model for TooManyRequests.
+type TooManyRequests = Error
+
+// AuthorizeParams defines parameters for Authorize.
+type AuthorizeParams struct {
+	// ResponseType Only code is supported.
+	ResponseType ResponseType `form:"response_type" json:"response_type"`
+	ClientId     ClientID     `form:"client_id" json:"client_id"`
+
+	// RedirectUri One of the redirect URIs registered for the client.
+	RedirectUri RedirectURI `form:"redirect_uri" json:"redirect_uri"`
+
+	// Scope Space separated scopes among openid, profile and phone.
+	Scope *Scope `form:"scope,omitempty" json:"scope,omitempty"`
+
+	// State Opaque value returned to the client with the code.
+	State *State `form:"state,omitempty" json:"state,omitempty"`
+
+	// Nonce Opaque value returned to the client in the ID token.
+	Nonce *Nonce `form:"nonce,omitempty" json:"nonce,omitempty"`
+
+	// CodeChallenge Base64url SHA-256 of the code_verifier, required.
+	CodeChallenge *CodeChallenge `form:"code_challenge,omitempty" json:"code_challenge,omitempty"`
+
+	// CodeChallengeMethod Only S256 is supported.
+	CodeChallengeMethod *CodeChallengeMethod `form:"code_challenge_method,omitempty" json:"code_challenge_method,omitempty"`
+}
+
+// SubmitAuthorizationFormdataRequestBody defines body for SubmitAuthorization for application/x-www-form-urlencoded ContentType.
+type SubmitAuthorizationFormdataRequestBody = AuthorizationForm
+
+// CreateTokenFormdataRequestBody defines body for CreateToken for application/x-www-form-urlencoded ContentType.
+type CreateTokenFormdataRequestBody = TokenRequest
+
+// ServerInterface represents all server handlers.
+type ServerInterface interface {
+	// OpenID Connect discovery document.
+	// (GET /.well-known/openid-configuration)
+	GetOpenIDConfiguration(ctx echo.Context) error
+	// Show the login page of an authorization request.
+	// (GET /oauth/authorize)
+	Authorize(ctx echo.Context, params AuthorizeParams) error
+	// Log in and authorize the client.
+	// (POST /oauth/authorize)
+	SubmitAuthorization(ctx echo.Context) error
+	// Public keys verifying ID tokens.
+	// (GET /oauth/jwks)
+	GetJWKS(ctx echo.Context) error
+	// Exchange an authorization code for tokens.
+	// (POST /oauth/token)
+	CreateToken(ctx echo.Context) error
+	// Claims of the user of the access token.
+	// (GET /userinfo)
+	GetUserinfo(ctx echo.Context) error
+}
+
+// ServerInterfaceWrapper converts echo contexts to parameters.
+type ServerInterfaceWrapper struct {
+	Handler ServerInterface
+}
+
+// GetOpenIDConfiguration converts echo context to params.
+func (w *ServerInterfaceWrapper) GetOpenIDConfiguration(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetOpenIDConfiguration(ctx)
+	return err
+}
+
+// Authorize converts echo context to params.
+func (w *ServerInterfaceWrapper) Authorize(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params AuthorizeParams
+	// ------------- Required query parameter "response_type" -------------
+
+	err = runtime.BindQueryParameter("form", true, true, "response_type", ctx.QueryParams(), &params.ResponseType)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter response_type: %s", err))
+	}
+
+	// ------------- Required query parameter "client_id" -------------
+
+	err = runtime.BindQueryParameter("form", true, true, "client_id", ctx.QueryParams(), &params.ClientId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter client_id: %s", err))
+	}
+
+	// ------------- Required query parameter "redirect_uri" -------------
+
+	err = runtime.BindQueryParameter("form", true, true, "redirect_uri", ctx.QueryParams(), &params.RedirectUri)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter redirect_uri: %s", err))
+	}
+
+	// ------------- Optional query parameter "scope" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "scope", ctx.QueryParams(), &params.Scope)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter scope: %s", err))
+	}
+
+	// ------------- Optional query parameter "state" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "state", ctx.QueryParams(), &params.State)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter state: %s", err))
+	}
+
+	// ------------- Optional query parameter "nonce" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "nonce", ctx.QueryParams(), &params.Nonce)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter nonce: %s", err))
+	}
+
+	// ------------- Optional query parameter "code_challenge" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "code_challenge", ctx.QueryParams(), &params.CodeChallenge)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter code_challenge: %s", err))
+	}
+
+	// ------------- Optional query parameter "code_challenge_method" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "code_challenge_method", ctx.QueryParams(), &params.CodeChallengeMethod)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter code_challenge_method: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.Authorize(ctx, params)
+	return err
+}
+
+// SubmitAuthorization converts echo context to params.
+func (w *ServerInterfaceWrapper) SubmitAuthorization(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.SubmitAuthorization(ctx)
+	return err
+}
+
+// GetJWKS converts echo context to params.
+func (w *ServerInterfaceWrapper) GetJWKS(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetJWKS(ctx)
+	return err
+}
+
+// CreateToken converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateToken(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BasicAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.CreateToken(ctx)
+	return err
+}
+
+// GetUserinfo converts echo context to params.
+func (w *ServerInterfaceWrapper) GetUserinfo(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetUserinfo(ctx)
+	return err
+}
+
+// This is a simple interface which specifies echo.Route addition functions which
+// are present on both echo.Echo and echo.Group, since we want to allow using
+// either of them for path registration
+type EchoRouter interface {
+	CONNECT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	HEAD(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	OPTIONS(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	PATCH(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	PUT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	TRACE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+}
+
+// RegisterHandlers adds each server route to the EchoRouter.
+func RegisterHandlers(router EchoRouter, si ServerInterface) {
+	RegisterHandlersWithBaseURL(router, si, "")
+}
+
+// Registers handlers, and prepends BaseURL to the paths, so that the paths
+// can be served under a prefix.
+func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL string) {
+
+	wrapper := ServerInterfaceWrapper{
+		Handler: si,
+	}
+
+	router.GET(baseURL+"/.well-known/openid-configuration", wrapper.GetOpenIDConfiguration)
+	router.GET(baseURL+"/oauth/authorize", wrapper.Authorize)
+	router.POST(baseURL+"/oauth/authorize", wrapper.SubmitAuthorization)
+	router.GET(baseURL+"/oauth/jwks", wrapper.GetJWKS)
+	router.POST(baseURL+"/oauth/token", wrapper.CreateToken)
+	router.GET(baseURL+"/userinfo", wrapper.GetUserinfo)
+
+}
+
+// Base64 encoded, gzipped, json marshaled Swagger object
+var swaggerSpec = []string{
+
+	"H4sIAAAAAAAC/9Ra62/bOBL/Vwa6+yjH2W5vgcu3brqPdPtC7SIHdAuDlsY2G4lUScqOb+H//cChXpQo",
+	"20nTBe5LG0vkzHCevxnqryiReSEFCqOjq7+iDbIUFf15zZINXkthlMzs7xR1onhhuBTRVfQBdSGFRg0J",
+	"U2rPxRqMvEOhgSkEIQ0kdn96EcWRTjaYM0vD7AuMriJtFBfr6HCIo9cyYY7kkEPKFSYGPn64AbkCs0FI",
+	"Mo7CxLDjZuMeyBRBKmACUCmpTrH7wAy+5jk39E+I6dcStdHAskzuMIUCFey4SOUuSJoLg2tUPu0PmDMu",
+	"LMtx+hmuDCxxJRXCEq36MrsV04ew0Rg4wgwTKVINpTA8IyURZeAaVmWW7UGhNlKdwwmN2r9YGVTncFHu",
+	"aJAwAUv70yh+BpPb29sXpdmgMDxhBoecOm+5FEDEEPC+wMRgCss9cZcFKlpw3AMOcVQwxXI0tZeTR928",
+	"tH9zy+5riWofxZFgud3pPG7B0yiO7Am5wjS6MqrE4452LVO83rAsQ7EOHOpnpvGn56XKYPb7i8mzf/3U",
+	"eLhMcbFFxVccVQw1S3uqoHh2edLweYBMb9BsZDqU7J3I9jCzEnENuiwKqcy5/Be5I3pcjLdSJAGVvCvY",
+	"1xJhy7KSvKdUAlMwshP4wAX9unnpks2YVII4nMgEVXr5+OEmpASsDaI6aUiDwjXXBhWmsJKqI9qYKPX2",
+	"Ran4A12oTrFzehO0E6W/c+ykKloLYvMwOWaJDAkwK1iCoNHGkw1EbZdpYLkUaxuOgqcxFEqueIbARArF",
+	"RgocE5B2nzDZzDDzSMfxCsaoDET/RPqoNUnZ40ZsWcZTm6Kk4v9tKlkihUFByZkVRVblrukX7V63DP6p",
+	"cBVdRf+YtmV46t7q6S9KSZcjDd6b6cbkmb85IJ2vmY/iTsidqJUgFZSi48Fd145ht+HJpqne9TvS5J8i",
+	"olK95uI9W2PvhI+VjuhBwdbYKehUxevYy5g2wIzBvDDAV8DEPuqE7hHM4Fk/iru4pos4Quqvlk6bdST6",
+	"XMo3TOzr+v3kRh5ox5b5qnLjfYKYYuqfo8EBkwbLHDtOD/l0gcTEAyxnEWl39AhVkORMInZ1DTQmDdI4",
+	"urnFJAfSWqVIu88Lw1+lyu3DQskCleEuYNtyPvTQuF9NTy+pC154pdCVe/iWJWhpgzFFsY+B6TtMrcc7",
+	"2TSYDTNNHK640gYKpszepi0UZR5dfXI0IuszYh99jofceYrCEIgYCvDeJmIQZb5EZeWo4EYK1qhZHXyl",
+	"RoLTA9Kirt+DNwXTeidVWB9eJQwv6Nao0AqdyLE3dWkYVo221H0alEEf3XXka1Uql18wIS91oTpwqRy1",
+	"ZiPeUmHiyt98K9ykLcSgVTFgspEWWSiZW7usUaCrrTXW+M+kSkCTm5fgQiJgot6hawFDZ3p1+8fwRCxb",
+	"B08TPuPdSDDdmX3wuQg+LfUZ5rMkHUO3ISZRLck4Gj3fbHjAO9zT/9xgrk9laKuiQ0OaKcX2Q8EswRD/",
+	"dzYnjTgO1o/xnuVF5hojwhOLtWJUtoYmsHsWniOdUppjExSuQHHz8lqKFV+XqimKPWfoJtUFirSQvKr7",
+	"g4yXMZ7rRQNGPRUPVvsKHcmsj6ZGGqQwfzQJni6oy1hovralbsGy9YJQ5uNJal26jDxY+mV3p89LjY/m",
+	"7hD6o3eX5DjfJoJT6FEv8pcsrP99qzPYUsbFSh5j3IuaylLxmP8PjhLi0rHqERuOq/ZcHwwF99xuq6rF",
+	"g3GQe6sxUWhGYdDoi2Z+EVzRRuZIU+trPNjiPhRc9IzbEeGI6py5AhkxSVBrZ5fAZAeZQuXmE7bAMxC4",
+	"A41acylOYiu8L7hCveDinJGbk6TiVW2NgS01tbwbFMANCNyiglSi7nBsJnCtiwUQSjVmiYGiIXUkq2mb",
+	"4FXLDzumgTQ6Yptx1OZcu3aFtgw6HZ4ENp4hPGohq36swnNoUNf+90//luXYtVfcHt8pnDfHpof1qIOO",
+	"G1QEDUAWDnefQuUP4Ev7xrnqcnkMfI64Yk/VlshQqZY6JqXiZj+jsSypc8k0Tyzuabpgu4eetnw2xhRW",
+	"uiWZul7tftnWjZnoKnp1O6/HMUSi5xaOhpWitmvGE6xi1hk1enMzJ0/jhjzLOgHMUG15guDwD1xLITAx",
+	"8F7JLU+JwRaVdor64eLy4tJSsC7PCh5dRT/SI9vtmA0deHqxwyyb0Kxl6kJjkvRR1dpl0mZOfZNGV9Fv",
+	"aEIgrDdlenZ5+WTzhhC7wPThDRqWMsNqLylq3ZDNyzxnak+jN0+DKdeJ3KLaQyqTMqexqN0wlTanT+vE",
+	"jh19+GznlUNCJtfaTnulqDqkaqpnp0UXQIBau+zqTbjsmLE71aI22htl2YxIw6b6xgia+4CLP63mfQO9",
+	"aET2Lw4+hbXcLpl609tDfHJ9cxFxxtru7PqM5W6Ae85C6qLPWOhG+OecyrsGeeiG6o7i8DkcECFSzbpp",
+	"O648xNGPl89O76jVajc8P4dFcPzrR8hsI3dultlMO8lvhQ9z6hnABdUJqU0IG4Euq4JfhwnXXe92fu0u",
+	"BYy0Y8MNE2sEZqCKQHdrAr+2Q6Vm6mQjpQlQR0uWxk6nuFjHIM0GVbNYWpxGZamacllJaCYVCqJZucy5",
+	"8bXUzEZ+lun+SHa7n+x2u8lKqnxSqgyFPVx6frobjgQPh0P/+uPwf+VecfT82b9Pb+4PrH23fC3XNr3a",
+	"fNkY3bvN6qRt28Mcq2A0Y/mOJYvoB2rUq9m7t3CLS/gD9zBD0zvi+3KZ8QTsaMZNOOkjhRrTau+IDQAO",
+	"hx6VSxqosqwNmM6ttQu93+fz9/CzxTkgFXhNVAyFE6ferVEQfOPNQp5ewLx/Awx5qQ3kzCTt9VU7JKmr",
+	"cziVBCLxWiEzOK8A898SgV4fen7wPYnn+I1cwIXm7pOVDhSO4sE3MJPORzDHria8D2YOhzbKnwa4tZPE",
+	"wEGqVFHbnj6J8ZzCWs0lnh/+JpGuK0jmf76xYjzr32Td3t5O+t+AHFN0/5ORw+GbkmLVwVhQZwFKp335",
+	"9PnwuZtSfmkqqgio103pu8ml7LScQbxL8w6Xdu3otHbE6iq96fLksNtnqkXFMdhmx+WgwYV71W9Wb+2T",
+	"UGL4DU3TH3/HgGx4BP2lqwKKxSf211FXfcO1JpRTzd9t+CjcyjtSvkuWT+6vl8+//8Go3aVrRFmKdODu",
+	"Xvfdd/ahPeq/u4544dhqVNu6MSpVFl1F0+jw+fC/AQANDTXoXygAAA==",
+}
+
+// GetSwagger returns the content of the embedded swagger specification file
+// or error if failed to decode
+func decodeSpec() ([]byte, error) {
+	zipped, err := base64.StdEncoding.DecodeString(strings.Join(swaggerSpec, ""))
+	if err != nil {
+		return nil, fmt.Errorf("error base64 decoding spec: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(zipped))
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %w", err)
+	}
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(zr)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+var rawSpec = decodeSpecCached()
+
+// a naive cached of a decoded swagger spec
+func decodeSpecCached() func() ([]byte, error) {
+	data, err := decodeSpec()
+	return func() ([]byte, error) {
+		return data, err
+	}
+}
+
+// Constructs a synthetic filesystem for resolving external references when loading openapi specifications.
+func PathToRawSpec(pathToFile string) map[string]func() ([]byte, error) {
+	res := make(map[string]func() ([]byte, error))
+	if len(pathToFile) > 0 {
+		res[pathToFile] = rawSpec
+	}
+
+	return res
+}
+
+// GetSwagger returns the Swagger specification corresponding to the generated code
+// in this file. The external references of Swagger specification are resolved.
+// The logic of resolving external references is tightly connected to "import-mapping" feature.
+// Externally referenced files must be embedded in the corresponding golang packages.
+// Urls can be supported but this task was out of the scope.
+func GetSwagger() (swagger *openapi3.T, err error) {
+	resolvePath := PathToRawSpec("")
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = func(loader *openapi3.Loader, url *url.URL) ([]byte, error) {
+		pathToFile := url.String()
+		pathToFile = path.Clean(pathToFile)
+		getSpec, ok := resolvePath[pathToFile]
+		if !ok {
+			err1 := fmt.Errorf("path not found: %s", pathToFile)
+			return nil, err1
+		}
+		return getSpec()
+	}
+	var specData []byte
+	specData, err = rawSpec()
+	if err != nil {
+		return
+	}
+	swagger, err = loader.LoadFromData(specData)
+	if err != nil {
+		return
+	}
+	return
+}
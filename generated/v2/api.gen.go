@@ -0,0 +1,674 @@
+// Package v2 provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/deepmap/oapi-codegen version v1.16.2 DO NOT EDIT.
+package v2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+	"github.com/oapi-codegen/runtime"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+const (
+	ApiKeyAuthScopes = "apiKeyAuth.Scopes"
+	BearerAuthScopes = "bearerAuth.Scopes"
+)
+
+// Defines values for APIKeyScope.
+const (
+	ProfileRead    APIKeyScope = "profile:read"
+	ProfileWrite   APIKeyScope = "profile:write"
+	SessionsManage APIKeyScope = "sessions:manage"
+)
+
+// APIKey defines model for APIKey.
+type APIKey struct {
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Id         string     `json:"id"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Name       string     `json:"name"`
+
+	// Prefix Start of the key, to recognise it without storing it.
+	Prefix    string        `json:"prefix"`
+	RateLimit *int          `json:"rate_limit,omitempty"`
+	RevokedAt *time.Time    `json:"revoked_at,omitempty"`
+	Scopes    []APIKeyScope `json:"scopes"`
+	UserId    int64         `json:"user_id"`
+}
+
+// APIKeyList defines model for APIKeyList.
+type APIKeyList struct {
+	Data []APIKey `json:"data"`
+}
+
+// APIKeyScope defines model for APIKeyScope.
+type APIKeyScope string
+
+// AvatarThumbnail defines model for AvatarThumbnail.
+type AvatarThumbnail struct {
+	// Size Width and height in pixels.
+	Size int    `json:"size"`
+	Url  string `json:"url"`
+}
+
+// CreateAPIKeyRequest defines model for CreateAPIKeyRequest.
+type CreateAPIKeyRequest struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Name What the key is used for, like the name of the integration.
+	Name string `json:"name"`
+
+	// RateLimit Requests allowed per rate limit window, defaults to the limit configured for API keys.
+	RateLimit *int          `json:"rate_limit,omitempty"`
+	Scopes    []APIKeyScope `json:"scopes"`
+
+	// UserId Id of the user the key acts as.
+	UserId int64 `json:"user_id"`
+}
+
+// CreateSessionRequest defines model for CreateSessionRequest.
+type CreateSessionRequest struct {
+	// Identifier Phone number, like +6281234567 or 081234567, or verified email of the user.
+	Identifier *string `json:"identifier,omitempty"`
+	Password   string  `json:"password"`
+
+	// Phone Phone number of the user, use identifier.
+	// Deprecated:
+	Phone *string `json:"phone,omitempty"`
+
+	// Scope Space separated scopes of the token, among profile:read, profile:write, sessions:manage, identities:manage and admin:api_keys. The token has every scope but admin:api_keys when omitted, administrators ask for admin:api_keys to manage API keys.
+	Scope *string `json:"scope,omitempty"`
+}
+
+// CreateUserRequest defines model for CreateUserRequest.
+type CreateUserRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Phone    string `json:"phone"`
+}
+
+// CreatedAPIKey defines model for CreatedAPIKey.
+type CreatedAPIKey struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Id        string     `json:"id"`
+
+	// Key The key, sent as `Authorization: ApiKey <key>`.
+	Key        string     `json:"key"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Name       string     `json:"name"`
+
+	// Prefix Start of the key, to recognise it without storing it.
+	Prefix    string        `json:"prefix"`
+	RateLimit *int          `json:"rate_limit,omitempty"`
+	RevokedAt *time.Time    `json:"revoked_at,omitempty"`
+	Scopes    []APIKeyScope `json:"scopes"`
+	UserId    int64         `json:"user_id"`
+}
+
+// Error defines model for Error.
+type Error struct {
+	Message string `json:"message"`
+
+	// RequestId Id of the request, echoed from or generated for the X-Request-ID header.
+	RequestId *string `json:"request_id,omitempty"`
+}
+
+// Session defines model for Session.
+type Session struct {
+	// ExpiresAt When the token expires, absent when it never does.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Id Id of the session, as listed by /users/sessions.
+	Id string `json:"id"`
+
+	// Token Bearer token of the session.
+	Token  string `json:"token"`
+	UserId int64  `json:"user_id"`
+}
+
+// UpdateUserRequest defines model for UpdateUserRequest.
+type UpdateUserRequest struct {
+	AvatarUrl   *string `json:"avatar_url,omitempty"`
+	BirthDate   *string `json:"birth_date,omitempty"`
+	DisplayName *string `json:"display_name,omitempty"`
+	Email       *string `json:"email,omitempty"`
+	Locale      *string `json:"locale,omitempty"`
+	Name        *string `json:"name,omitempty"`
+	Phone       *string `json:"phone,omitempty"`
+	Timezone    *string `json:"timezone,omitempty"`
+}
+
+// UploadAvatarRequest defines model for UploadAvatarRequest.
+type UploadAvatarRequest struct {
+	// Avatar A JPEG, PNG or WebP image.
+	Avatar openapi_types.File `json:"avatar"`
+}
+
+// User defines model for User.
+type User struct {
+	AvatarThumbnails *[]AvatarThumbnail   `json:"avatar_thumbnails,omitempty"`
+	AvatarUrl        *string              `json:"avatar_url,omitempty"`
+	BirthDate        *openapi_types.Date  `json:"birth_date,omitempty"`
+	DisplayName      *string              `json:"display_name,omitempty"`
+	Email            *openapi_types.Email `json:"email,omitempty"`
+	EmailVerified    bool                 `json:"email_verified"`
+	Id               int64                `json:"id"`
+	LastLoginAt      *time.Time           `json:"last_login_at,omitempty"`
+	Locale           *string              `json:"locale,omitempty"`
+	LoginCount       int64                `json:"login_count"`
+	Name             string               `json:"name"`
+	Phone            string               `json:"phone"`
+	Timezone         *string              `json:"timezone,omitempty"`
+}
+
+// VerifyEmailRequest defines model for VerifyEmailRequest.
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// IdempotencyKey defines model for IdempotencyKey.
+type IdempotencyKey = string
+
+// IfMatch defines model for IfMatch.
+type IfMatch = string
+
+// IfNoneMatch defines model for IfNoneMatch.
+type IfNoneMatch = string
+
+// BadRequest defines model for BadRequest.
+type BadRequest = Error
+
+// Forbidden defines model for Forbidden.
+type Forbidden = Error
+
+// InsufficientScope defines model for InsufficientScope.
+type InsufficientScope = Error
+
+// NotFound defines model for NotFound.
+type NotFound = Error
+
+// TooManyRequests defines model for TooManyRequests.
+type TooManyRequests = Error
+
+// Unauthorized defines model for Unauthorized.
+type Unauthorized = Error
+
+// ListAPIKeysParams defines parameters for ListAPIKeys.
+type ListAPIKeysParams struct {
+	// UserId Only list the keys acting as this user.
+	UserId *int64 `form:"user_id,omitempty" json:"user_id,omitempty"`
+}
+
+// CreateUserParams defines parameters for CreateUser.
+type CreateUserParams struct {
+	// IdempotencyKey Unique key of the request. Retrying with the same key and body replays the first response instead of running the request again.
+	IdempotencyKey *IdempotencyKey `json:"Idempotency-Key,omitempty"`
+}
+
+// GetMeParams defines parameters for GetMe.
+type GetMeParams struct {
+	// IfNoneMatch Return 304 when the user still has this ETag.
+	IfNoneMatch *IfNoneMatch `json:"If-None-Match,omitempty"`
+}
+
+// UpdateMeParams defines parameters for UpdateMe.
+type UpdateMeParams struct {
+	// IfMatch Only apply the update when the user still has this ETag.
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// UploadAvatarParams defines parameters for UploadAvatar.
+type UploadAvatarParams struct {
+	// IfMatch Only apply the update when the user still has this ETag.
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// CreateAPIKeyJSONRequestBody defines body for CreateAPIKey for application/json ContentType.
+type CreateAPIKeyJSONRequestBody = CreateAPIKeyRequest
+
+// VerifyEmailJSONRequestBody defines body for VerifyEmail for application/json ContentType.
+type VerifyEmailJSONRequestBody = VerifyEmailRequest
+
+// CreateSessionJSONRequestBody defines body for CreateSession for application/json ContentType.
+type CreateSessionJSONRequestBody = CreateSessionRequest
+
+// CreateUserJSONRequestBody defines body for CreateUser for application/json ContentType.
+type CreateUserJSONRequestBody = CreateUserRequest
+
+// UpdateMeJSONRequestBody defines body for UpdateMe for application/json ContentType.
+type UpdateMeJSONRequestBody = UpdateUserRequest
+
+// UploadAvatarMultipartRequestBody defines body for UploadAvatar for multipart/form-data ContentType.
+type UploadAvatarMultipartRequestBody = UploadAvatarRequest
+
+// ServerInterface represents all server handlers.
+type ServerInterface interface {
+	// List the API keys, newest first, only allowed to administrators.
+	// (GET /admin/api-keys)
+	ListAPIKeys(ctx echo.Context, params ListAPIKeysParams) error
+	// Issue an API key acting as a user, only allowed to administrators.
+	// (POST /admin/api-keys)
+	CreateAPIKey(ctx echo.Context) error
+	// Revoke an API key, only allowed to administrators.
+	// (DELETE /admin/api-keys/{id})
+	RevokeAPIKey(ctx echo.Context, id string) error
+	// Confirm the email of a user with the token mailed to it.
+	// (POST /email-verifications)
+	VerifyEmail(ctx echo.Context) error
+	// Log in, opening a session of the user.
+	// (POST /sessions)
+	CreateSession(ctx echo.Context) error
+	// Register a new user.
+	// (POST /users)
+	CreateUser(ctx echo.Context, params CreateUserParams) error
+	// Get the authenticated user.
+	// (GET /users/me)
+	GetMe(ctx echo.Context, params GetMeParams) error
+	// Update the authenticated user.
+	// (PATCH /users/me)
+	UpdateMe(ctx echo.Context, params UpdateMeParams) error
+	// Upload the avatar of the authenticated user.
+	// (PUT /users/me/avatar)
+	UploadAvatar(ctx echo.Context, params UploadAvatarParams) error
+	// Mail a new verification token to the email of the authenticated user.
+	// (POST /users/me/email-verifications)
+	CreateEmailVerification(ctx echo.Context) error
+}
+
+// ServerInterfaceWrapper converts echo contexts to parameters.
+type ServerInterfaceWrapper struct {
+	Handler ServerInterface
+}
+
+// ListAPIKeys converts echo context to params.
+func (w *ServerInterfaceWrapper) ListAPIKeys(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{"admin:api_keys"})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListAPIKeysParams
+	// ------------- Optional query parameter "user_id" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "user_id", ctx.QueryParams(), &params.UserId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter user_id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ListAPIKeys(ctx, params)
+	return err
+}
+
+// CreateAPIKey converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateAPIKey(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{"admin:api_keys"})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.CreateAPIKey(ctx)
+	return err
+}
+
+// RevokeAPIKey converts echo context to params.
+func (w *ServerInterfaceWrapper) RevokeAPIKey(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{"admin:api_keys"})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RevokeAPIKey(ctx, id)
+	return err
+}
+
+// VerifyEmail converts echo context to params.
+func (w *ServerInterfaceWrapper) VerifyEmail(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.VerifyEmail(ctx)
+	return err
+}
+
+// CreateSession converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateSession(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.CreateSession(ctx)
+	return err
+}
+
+// CreateUser converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateUser(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params CreateUserParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "Idempotency-Key" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Idempotency-Key")]; found {
+		var IdempotencyKey IdempotencyKey
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for Idempotency-Key, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "Idempotency-Key", runtime.ParamLocationHeader, valueList[0], &IdempotencyKey)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter Idempotency-Key: %s", err))
+		}
+
+		params.IdempotencyKey = &IdempotencyKey
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.CreateUser(ctx, params)
+	return err
+}
+
+// GetMe converts echo context to params.
+func (w *ServerInterfaceWrapper) GetMe(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{"profile:read"})
+
+	ctx.Set(ApiKeyAuthScopes, []string{"profile:read"})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetMeParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-None-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-None-Match")]; found {
+		var IfNoneMatch IfNoneMatch
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-None-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-None-Match", runtime.ParamLocationHeader, valueList[0], &IfNoneMatch)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-None-Match: %s", err))
+		}
+
+		params.IfNoneMatch = &IfNoneMatch
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetMe(ctx, params)
+	return err
+}
+
+// UpdateMe converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateMe(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{"profile:write"})
+
+	ctx.Set(ApiKeyAuthScopes, []string{"profile:write"})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params UpdateMeParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch IfMatch
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Match", runtime.ParamLocationHeader, valueList[0], &IfMatch)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-Match: %s", err))
+		}
+
+		params.IfMatch = &IfMatch
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.UpdateMe(ctx, params)
+	return err
+}
+
+// UploadAvatar converts echo context to params.
+func (w *ServerInterfaceWrapper) UploadAvatar(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{"profile:write"})
+
+	ctx.Set(ApiKeyAuthScopes, []string{"profile:write"})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params UploadAvatarParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch IfMatch
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Match", runtime.ParamLocationHeader, valueList[0], &IfMatch)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-Match: %s", err))
+		}
+
+		params.IfMatch = &IfMatch
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.UploadAvatar(ctx, params)
+	return err
+}
+
+// CreateEmailVerification converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateEmailVerification(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{"profile:write"})
+
+	ctx.Set(ApiKeyAuthScopes, []string{"profile:write"})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.CreateEmailVerification(ctx)
+	return err
+}
+
+// This is a simple interface which specifies echo.Route addition functions which
+// are present on both echo.Echo and echo.Group, since we want to allow using
+// either of them for path registration
+type EchoRouter interface {
+	CONNECT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	HEAD(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	OPTIONS(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	PATCH(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	PUT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	TRACE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+}
+
+// RegisterHandlers adds each server route to the EchoRouter.
+func RegisterHandlers(router EchoRouter, si ServerInterface) {
+	RegisterHandlersWithBaseURL(router, si, "")
+}
+
+// Registers handlers, and prepends BaseURL to the paths, so that the paths
+// can be served under a prefix.
+func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL string) {
+
+	wrapper := ServerInterfaceWrapper{
+		Handler: si,
+	}
+
+	router.GET(baseURL+"/admin/api-keys", wrapper.ListAPIKeys)
+	router.POST(baseURL+"/admin/api-keys", wrapper.CreateAPIKey)
+	router.DELETE(baseURL+"/admin/api-keys/:id", wrapper.RevokeAPIKey)
+	router.POST(baseURL+"/email-verifications", wrapper.VerifyEmail)
+	router.POST(baseURL+"/sessions", wrapper.CreateSession)
+	router.POST(baseURL+"/users", wrapper.CreateUser)
+	router.GET(baseURL+"/users/me", wrapper.GetMe)
+	router.PATCH(baseURL+"/users/me", wrapper.UpdateMe)
+	router.PUT(baseURL+"/users/me/avatar", wrapper.UploadAvatar)
+	router.POST(baseURL+"/users/me/email-verifications", wrapper.CreateEmailVerification)
+
+}
+
+// Base64 encoded, gzipped, json marshaled Swagger object
+var swaggerSpec = []string{
+
+	"H4sIAAAAAAAC/+xbWXMbN/L/Kij8/287InXZu+bTKonjUmInKluOtspxyeCgh0Q0A4wBjCQmxe++1QDm",
+	"Hh46yGSr8mKLnAHQ3ej+9ck/aKyyXEmQ1tDJH3QOjIN2f76+ZDP8n4OJtcitUJJO6C+gjVCSqITYOZDC",
+	"gB7RiJp4DhnD1+0iBzqhxmohZ3S5jOhbFTO/urvZBbPzcqdYA7PAiQajCh3Dpl3fMwtvRSas+6e/93v4",
+	"WoCxhrA0VXfASQ6a3AnJ1d3g1kJamIFu7/0eMiYkHrl6/xQSS6aQKA1kCkLOSIpLgT/kGAMDLHyAWElu",
+	"SCGtSJ2Q3M5EGJIUabpAWVmltzkJrF6cJRb0NqdozxqJmSRT/Gi12OKQq6urs8LOQVoRMwv9kxpPUYXc",
+	"ZkDgPocYb366cKerHLR7Yb0GLCOaM80ysEFfzzlkubIg48WPsOif/lGKrwWQG1iUKhf4HBEnHry5O2Hn",
+	"7pFhmX+VSU6miqOs85QtjHuaCG0sSj9X0gAR0lhgHLfVhUR1aYmRzZiQo18ljahAQryN0YhKliFLDcIP",
+	"kPIm1xm7fwtyZud0cvziRTRgB+fJO2bjeZ/fn2W6ICzPUy/WIufMArmbg6wslxgr0pTMGbIlDEGTH60k",
+	"MznwJ623y/PkJyVhBU3vwRZakpPD06cRgkdsQc0youUlORX5hvFgtvgpVtKCdH+imIJajn8zHqrqbf9f",
+	"Q0In9P/GNVaO/VMzfq210v6oNqvn8palgpdaQJcR/V7pqeAc5O5Pv5wDYQ1j5F7MwhCpLGGSMJ4JKYzV",
+	"zCodEaXdXVh1A5KkLL7xiu7emrBcXN/AwhATqxx+lcjLuTRFkohYgLQf8Ov98HR2ce7M0pPIPEVOxkIP",
+	"QAhS+pOy36tC8t0T+BFFjPJN3HnLiF4q9Y7JRekrdk8CupTgJeA+BuDAadT06pXPOaj85tBBYcW442Wb",
+	"Tuug5Ry32qRe0dkouL8tN8G3S6d2UHm1tYtr/+eE9lGicSgtfoc96MU7YYyQs4iIgAlKEw236ga4t7j2",
+	"FV1dXR10Hek65rp+d+lICFTh4rOL8+ARc42mYYWHwxByXTPHeKJ0hn9R9BMHVmRAe/4monCfCw3mQWsE",
+	"HwDniKbM2OvCPJAA7wQGtss1JOJ+IL6xTNvS5d/AIiJWEQ2xmkmBzts6r68KS4xVuBkRFn0P3LMsT/GM",
+	"wtxcnySv2FF8PEiSZhau09KcupFRRMNdP4hNB2zuloSFzGzSPX/FHoiX1XZMa7bAzwj+14K3jhfSvjyl",
+	"UY9eR7DHUzr5hJcXhF6JuN6vojNqKtPnalM1/Q1iZ6yewLfC2L4ecmbZAzntM9kh2+25mpDKZYEsMnw/",
+	"1yoRKUw0ME6j6uOdFhY5N2CMUNJMMibZDOjngSs7u2WW6ct5kU0lE2mfTyN+H4iKrwS3cxdnzkHM5pYI",
+	"SXJxD6kZDdxORAudtu6x0IIOBYZNabiT/dIhmXzr7s5LphEgMc4FEsnSiwYfCUsNRB3WHgMLpSV3xDFn",
+	"tjRVjFcQIEiCMUoqbsA9wYWlQTvB1PlCI15+eRjRTMjy49FGw90if9S1e/WpZEQ4JKxIrUFUqVO0WMlE",
+	"zArtaS/DFuOzAIy8siJr0tS436dZfibkuV92tBYGOsEqbyb0lfhZjPw7PXwobATE6AHFavX74E3scfon",
+	"OEgrEjGU4F7MlQQii2wKpRb94+Xxv46OT05fvPwnOuPD8oMLhG9B406cYLSSdisdtVuYFlz8O3wcxSob",
+	"0vGcGXOn9LADzJEwT2+uwYXpdGJ1AdEaDpr0RPgvqXkfrfQkA24xZzEQA5hEY37g76fc3YUlEWGZkjPS",
+	"xMaItKAxIh1kjAI5eDHhK4dt7UxiRC7LQ1zSB7egFyGYnxa287bPFVUmrAUetVMX1M8bZ2OdNVaRcHzb",
+	"+Or760D+egStLnK1AmP4/zjtXR3TbKU+Gyh3r9U+fDMjvA4YWZr+nNDJpy19ctfj3QwVYi7LOMyAtIQZ",
+	"8uUsBOMOxifkLBc/woL8WhwensQ3sHB/wJfRxkvC4/psfV5G1MfmPY+cgTFsNiREvzEYuwEvw1sRgXiu",
+	"EO21yhBEZiDBG1YScuv/HATlODj/jvjYfTNHJYFDlxUAs89V2xl33Wsou3jjC69GhE3dbThLE5ZItEjC",
+	"FbTBf4swf5WgAk5EeOGpMKHgN0YYM+Pw0AwCmKO0v/U3wDTowEf7kMFtnhoD167MEzR0JR9z/kAgyIRs",
+	"fnvUhQbmgsrrEPT1mJoKbefXPGSJvcdcGKxaXq8EGOfiBp+kKmbp8KLVcLUCkSKKOvP7SrgakGOqGPcB",
+	"dUOSQ6IZKDOTHy5ev4nIxU9v0BKvYHpBRMZm0FLlqZBMLzYaYDhk8LIN6FVUXdsyDXhAJNfJHwbyuLY2",
+	"rE8BusrRMmIaPUVZqq38N9GKV6/LSKqx21SpFJis8WKjLYZCQapmQj4ov6g1uPb5gh+cfzf8Mm4fq0La",
+	"Lal6ohXUNJ0ZwcY/sBumLduojw6AOi69SXtP9EOa+ws+XLzGFx8XsFSQvJ7YVUCJUSnEhRZ28QGVP1iO",
+	"c/sYCwzYtKxKv8KYwjuPfhn7oRGFC0ND5BuckpK4b1VBJkz7pFNJMFVilBWuPab1Yk1fp0VDfbGeTWef",
+	"zoOVDPtP35eK98PVJY16yOa9XUMExF1+5IkpO0+Bo5lm0roaI7p0gY7dENOJ+7+4l7+QOGUie5RAPE1d",
+	"kfjuHp0Evmr+59bmvkoqZKKQ81TEIA3UkTB9d37p7cU6C3GF9Q+gb0UMNKK3vgdNJ/R4dDg6xDdVDpLl",
+	"gk7oifsK41w7d2o1djoyZrk4wCwAv5r5cnPF0zmnE4q1KR/IGtruLX4abK2heEqFMJgqo/TLBlaZLTrF",
+	"+FqA8zSBuWZOXNaVN0cknzu9rOPDw2erXDeKc+v7LiaqCtfu+oWM04KD63WcHh6tOqcifNwqvLtFJ5sX",
+	"1X0zXHH8avOKbt+liTjuPpum94m2U0f6GYVtiizDAMErBrEtIUi4A2N9HzgiynVaQ5nIqk6COnI+QZkB",
+	"lWvW3WiVcXyj+OLZrnaotLds47TVBSx72nX0zCSUSeWAgv1YobrHH4fy1kCaYAHQSVe7xjFgjVRDkDii",
+	"2ZyZOb4U5iB8b/L08HAVTbWKNHrBe1Tew9PNK6qG5Z+i7ed4EYj4pb+tkY2FotNGfV9GXdQd/yH40rv1",
+	"FCz0TeG9g5XKFDrw62AUEb1GUQegbSVeNwrQh8/TfpSBihjwbf9KsfvmeVlRlzdS3UnMjViqgfE203vX",
+	"OH/1DZXbUsNcoHvgA10vLHezw1DbCHl3hLQDQfVWQDugh26Tqg79aEjbk1qVMWmpWFGoKrk+9x0zlZZh",
+	"K8dT9mr3lJ1JZeegfUOjKumjG/dlfee7n6bwlQZ/i90enTV2V0lAy3qkLITJTKRlSB70uCx+rVbeVoNk",
+	"p4FCpwmz50ih5HDgOsMjonKQTzCJo30MfsVKa4gtyVttG02q0vvzaN1bNSOY+qFInIcuy5/tlpXTMfxz",
+	"o4K5glbP9w6RWL8y7oxdeke7K/VsVlb3rJtOOKuGvsLwQ3uOpzn3vG5+p3pvuXysWr/aD9K3FFqYRvgw",
+	"E8aCxu4cRhXV6Gt7nrYz6Orjdpz7FJLkWs00GBMi+OPj/XDUJQndlZs4cJQzwkWSgAZpm3Ocz2C774PE",
+	"CMNcsmep4wxW1inegH0HDzfTxljuTmsJq+xkeCK1bTHlTw7WWYt7x+1/MhQ6VTOYmeLe5RshY1+xmolb",
+	"kG62+CnH7i4r6M/UPjxlXB+It1ren7Ff2yy69p63wvQ34Ksg/Uv0FY5y4rutrb4j9iiFbSrr8zuUfqtu",
+	"K4eyH0Pxw/pPN5G/VCXkOdT7z3J2SofAftDrObqOjvc0YY4+aj26bYkCfrhxDQyUL7RwwBvOaihoOrFx",
+	"3aPNCzs8CuJasyjXWKs8D/k+MV8Lpv3okAYcX+R+wK7sqkau/JeBZTjpicu5Xz4idZMUvzZgq9E8pmdg",
+	"bL2L2x6f5BpuhSoMKVzjGXjYJCIiIUwuIneAK11x397oolzdr94R0mVFakXOtB1jr+CgnJndFuz6/fS/",
+	"4e5/Au7+SrCC5JzsB30rVHBGq4mdMz+31JisTcvfpJwevdgzVe5XVP05k10jL1qxR15nymWivxmHN9RK",
+	"VxX1QrXK/7q1BYZSkVRJvBn3k5ZRDxJ9wu4qmb80jqU9iDke/JFxtaBFSaPus/vLduU7nE6Vyjv/v8Oj",
+	"hnR64VCrXL2DJsLTbOedc/cu2b7ta1cIEFoD38NG5WjUt6Vrd5NYdHx7jGOm/x0AtXk1Z00/AAA=",
+}
+
+// GetSwagger returns the content of the embedded swagger specification file
+// or error if failed to decode
+func decodeSpec() ([]byte, error) {
+	zipped, err := base64.StdEncoding.DecodeString(strings.Join(swaggerSpec, ""))
+	if err != nil {
+		return nil, fmt.Errorf("error base64 decoding spec: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(zipped))
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %w", err)
+	}
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(zr)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+var rawSpec = decodeSpecCached()
+
+// a naive cached of a decoded swagger spec
+func decodeSpecCached() func() ([]byte, error) {
+	data, err := decodeSpec()
+	return func() ([]byte, error) {
+		return data, err
+	}
+}
+
+// Constructs a synthetic filesystem for resolving external references when loading openapi specifications.
+func PathToRawSpec(pathToFile string) map[string]func() ([]byte, error) {
+	res := make(map[string]func() ([]byte, error))
+	if len(pathToFile) > 0 {
+		res[pathToFile] = rawSpec
+	}
+
+	return res
+}
+
+// GetSwagger returns the Swagger specification corresponding to the generated code
+// in this file. The external references of Swagger specification are resolved.
+// The logic of resolving external references is tightly connected to "import-mapping" feature.
+// Externally referenced files must be embedded in the corresponding golang packages.
+// Urls can be supported but this task was out of the scope.
+func GetSwagger() (swagger *openapi3.T, err error) {
+	resolvePath := PathToRawSpec("")
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = func(loader *openapi3.Loader, url *url.URL) ([]byte, error) {
+		pathToFile := url.String()
+		pathToFile = path.Clean(pathToFile)
+		getSpec, ok := resolvePath[pathToFile]
+		if !ok {
+			err1 := fmt.Errorf("path not found: %s", pathToFile)
+			return nil, err1
+		}
+		return getSpec()
+	}
+	var specData []byte
+	specData, err = rawSpec()
+	if err != nil {
+		return
+	}
+	swagger, err = loader.LoadFromData(specData)
+	if err != nil {
+		return
+	}
+	return
+}
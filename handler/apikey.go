@@ -0,0 +1,232 @@
+// This file contains the API keys of service integrations. A key acts as a
+// user, limited to its scopes, on the operations whose security accepts
+// apiKeyAuth.
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	v2 "github.com/SawitProRecruitment/UserService/generated/v2"
+	"github.com/SawitProRecruitment/UserService/logging"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// apiKeyScheme is the authorization scheme of API keys, "ApiKey <key>".
+const apiKeyScheme = "ApiKey"
+
+// APIKeySecurityScheme is the security scheme of API keys in the specs.
+const APIKeySecurityScheme = "apiKeyAuth"
+
+// apiKeyPrefix starts every key, so a leaked key is easy to recognise.
+const apiKeyPrefix = "usk_"
+
+// apiKeyContextKey caches the lookup of the key of a request, the rate
+// limiter and the handler share it.
+const apiKeyContextKey = "handler.api_key"
+
+// maxAPIKeyNameLength is the longest name of a key, in characters.
+const maxAPIKeyNameLength = 60
+
+// apiKeyTouchInterval is how often the last use of a key is recorded, busy
+// keys do not write on every request.
+const apiKeyTouchInterval = time.Minute
+
+var (
+	errInvalidAPIKey     = errors.New("invalid, expired or revoked API key")
+	errAPIKeyNotAccepted = errors.New("API keys are not accepted by this operation")
+	errNotAdmin          = errors.New("only administrators can manage API keys")
+)
+
+// apiKeyLookup is the key of a request, or why it was rejected.
+type apiKeyLookup struct {
+	key repository.APIKey
+	err error
+}
+
+// apiKeyCredentials return the key of an "ApiKey <key>" authorization
+// header, ok is false for any other scheme.
+func apiKeyCredentials(auth string) (key string, ok bool) {
+	scheme, key, found := strings.Cut(auth, " ")
+	if !found || !strings.EqualFold(scheme, apiKeyScheme) {
+		return "", false
+	}
+	return strings.TrimSpace(key), true
+}
+
+// newAPIKey generate a key, the prefix it is listed by and the hash it is
+// stored by.
+func newAPIKey() (key string, prefix string, hash string, err error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", "", "", err
+	}
+	secret, _, err := newSecretToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	prefix = apiKeyPrefix + id[:8]
+	key = prefix + "_" + secret
+	return key, prefix, hashSecretToken(key), nil
+}
+
+// requestAPIKey return the key of the authorization header, looked up once
+// per request. Unknown, expired and revoked keys return errInvalidAPIKey.
+func (s *Server) requestAPIKey(c echo.Context) (repository.APIKey, error) {
+	if lookup, ok := c.Get(apiKeyContextKey).(apiKeyLookup); ok {
+		return lookup.key, lookup.err
+	}
+
+	var lookup apiKeyLookup
+	raw, _ := apiKeyCredentials(c.Request().Header.Get(echo.HeaderAuthorization))
+	key, err := s.Repository.GetAPIKeyByHash(c.Request().Context(), hashSecretToken(raw))
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		lookup.err = errInvalidAPIKey
+	case err != nil:
+		lookup.err = err
+	case key.RevokedAt != nil, key.ExpiresAt != nil && !time.Now().Before(*key.ExpiresAt):
+		lookup.err = errInvalidAPIKey
+	default:
+		lookup.key = key
+	}
+	c.Set(apiKeyContextKey, lookup)
+	return lookup.key, lookup.err
+}
+
+// acceptedAPIKey return the valid key of the request when the operation
+// accepts it. The key must carry every scope the operation requires of keys.
+// Operations whose security has no apiKeyAuth reject keys.
+func (s *Server) acceptedAPIKey(c echo.Context) (key repository.APIKey, err error) {
+	required, ok := c.Get(generated.ApiKeyAuthScopes).([]string)
+	if !ok {
+		return key, errAPIKeyNotAccepted
+	}
+	key, err = s.requestAPIKey(c)
+	if err != nil {
+		return key, err
+	}
+	if err := checkScopes(key.Scopes, required); err != nil {
+		return repository.APIKey{}, err
+	}
+	return key, nil
+}
+
+// authenticateAPIKey validate the key of the request, see acceptedAPIKey,
+// and return the user it acts as.
+func (s *Server) authenticateAPIKey(c echo.Context) (p principal, err error) {
+	key, err := s.acceptedAPIKey(c)
+	if err != nil {
+		return p, err
+	}
+
+	// last use is informational, the request goes on when it is not recorded.
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > apiKeyTouchInterval {
+		ctx := c.Request().Context()
+		if err := s.Repository.TouchAPIKey(ctx, key.ID); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "touch api key", slog.Any("error", err))
+		}
+	}
+
+	return principal{UserID: key.UserID, APIKeyID: key.ID, Scopes: key.Scopes}, nil
+}
+
+// activeAPIKey return the key authenticating the request, ok is false for
+// requests without a key or whose key the operation rejects, see
+// acceptedAPIKey. The scopes of the operation must be on the context, set by
+// common.SecurityMiddleware before the generated wrapper runs.
+func (s *Server) activeAPIKey(c echo.Context) (key repository.APIKey, ok bool) {
+	if _, ok := apiKeyCredentials(c.Request().Header.Get(echo.HeaderAuthorization)); !ok {
+		return key, false
+	}
+	key, err := s.acceptedAPIKey(c)
+	return key, err == nil
+}
+
+// APIKeyRateLimit return the id and rate limit of the key authenticating the
+// request, see activeAPIKey, id is empty otherwise. A zero limit stands for
+// the limit configured for keys.
+func (s *Server) APIKeyRateLimit(c echo.Context) (id string, limit int) {
+	key, ok := s.activeAPIKey(c)
+	if !ok {
+		return "", 0
+	}
+	if key.RateLimit != nil {
+		limit = *key.RateLimit
+	}
+	return key.ID, limit
+}
+
+// authenticateAdmin authenticate the request by token and check the caller
+// is an administrator. The token must also be granted ScopeAdminAPIKeys, see
+// the security of the operation.
+func (s *Server) authenticateAdmin(c echo.Context) (p principal, err error) {
+	p, err = s.authenticate(c)
+	if err != nil {
+		return p, err
+	}
+	if !slices.Contains(s.AdminUserIDs, p.UserID) {
+		return p, errNotAdmin
+	}
+	return p, nil
+}
+
+// validateCreateAPIKey check the payload of a new key and return its unique
+// scopes, in the order given.
+func validateCreateAPIKey(payload v2.CreateAPIKeyRequest) (scopes []string, err error) {
+	var errMsg []string
+	if n := utf8.RuneCountInString(payload.Name); n == 0 || n > maxAPIKeyNameLength {
+		errMsg = append(errMsg, "name must be between 1 and "+strconv.Itoa(maxAPIKeyNameLength)+" characters")
+	}
+	if len(payload.Scopes) == 0 {
+		errMsg = append(errMsg, "scopes must not be empty")
+	}
+	for _, scope := range payload.Scopes {
+		switch scope {
+		case v2.ProfileRead, v2.ProfileWrite, v2.SessionsManage:
+			if !slices.Contains(scopes, string(scope)) {
+				scopes = append(scopes, string(scope))
+			}
+		default:
+			errMsg = append(errMsg, fmt.Sprintf("unknown scope %q", scope))
+		}
+	}
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+		errMsg = append(errMsg, "expires_at must be in the future")
+	}
+	if payload.RateLimit != nil && *payload.RateLimit < 1 {
+		errMsg = append(errMsg, "rate_limit must be at least 1")
+	}
+	if len(errMsg) > 0 {
+		return nil, validationError(errMsg)
+	}
+	return scopes, nil
+}
+
+// newAPIKeyV2 return the response of key, without the key itself.
+func newAPIKeyV2(key repository.APIKey) v2.APIKey {
+	scopes := make([]v2.APIKeyScope, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = v2.APIKeyScope(scope)
+	}
+	return v2.APIKey{
+		Id:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		UserId:     key.UserID,
+		Scopes:     scopes,
+		RateLimit:  key.RateLimit,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
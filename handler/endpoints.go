@@ -1,195 +1,499 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
 	"strings"
 
 	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/health"
+	"github.com/SawitProRecruitment/UserService/metrics"
 	"github.com/SawitProRecruitment/UserService/repository"
 	"github.com/labstack/echo/v4"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
 )
 
 // POST API create new user data.
 // http://localhost:1323/users/register
-func (s *Server) UserRegister(c echo.Context) error {
-	ctx := c.Request().Context()
+// Idempotency-Key is handled by the idempotency middleware.
+func (s *Server) UserRegister(c echo.Context, _ generated.UserRegisterParams) error {
+	ctx := requestContext(c)
 	var payload generated.RegisterRequest
 	err := c.Bind(&payload)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, generated.ErrorResponse{
-			Message: "Invalid payload: failed to parse",
-		})
+		return c.JSON(http.StatusBadRequest, errorResponse(c, "Invalid payload: failed to parse"))
 	}
 
-	input := repository.RegisterUser{
+	user, err := s.registerUser(ctx, repository.RegisterUser{
 		Phone:    strings.TrimSpace(payload.Phone),
 		Name:     strings.TrimSpace(payload.Name),
 		Password: strings.TrimSpace(payload.Password),
+	})
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
 	}
 
-	// validate input with given rules.
-	if errs := input.Validate(); len(errs) > 0 {
-		var resp generated.ErrorResponse
-		resp.Message = strings.Join(errs, ", ")
-		return c.JSON(http.StatusBadRequest, resp)
+	return c.JSON(http.StatusOK, generated.RegisterResponse{
+		Id: user.ID,
+	})
+}
+
+// GET API which return the name and phone number of the cats based on authorization.
+// http://localhost:1323/users
+func (s *Server) GetUser(c echo.Context, params generated.GetUserParams) error {
+	ctx := requestContext(c)
+
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
+	if err != nil {
+		return c.JSON(http.StatusForbidden, errorResponse(c, err.Error()))
 	}
 
-	// check whether user phone exist.
-	_, err = s.Repository.GetUserByPhone(ctx, payload.Phone)
-	if err == nil {
-		return c.JSON(http.StatusBadRequest, generated.ErrorResponse{
-			Message: "phone number already exist",
-		})
+	// get user data by id.
+	resp, err := s.Repository.GetUserByID(ctx, caller.UserID)
+	if err != nil {
+		return c.JSON(http.StatusForbidden, errorResponse(c, err.Error()))
+	}
+
+	// skip the body when client already has this version.
+	etag := formatETag(resp.Version)
+	c.Response().Header().Set("ETag", etag)
+	if params.IfNoneMatch != nil && matchETag(*params.IfNoneMatch, etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.JSON(http.StatusOK, newUserResponse(resp))
+}
+
+// PATCH API responsible to update user data.
+// http://localhost:1323/users
+func (s *Server) UpdateUser(c echo.Context, params generated.UpdateUserParams) error {
+	ctx := requestContext(c)
+
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
+	if err != nil {
+		return c.JSON(http.StatusForbidden, errorResponse(c, err.Error()))
 	}
 
-	// hash and salt user password.
-	input.Password, _ = HashPassword(input.Password)
+	var payload generated.UpdateUserRequest
+	err = c.Bind(&payload)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, "Invalid payload: failed to parse"))
+	}
 
-	// create user data.
-	resp, err := s.Repository.Createuser(ctx, input)
+	resp, err := s.updateUser(ctx, caller, repository.UpdateUser{
+		Phone:       payload.Phone,
+		Name:        payload.Name,
+		Email:       payload.Email,
+		DisplayName: payload.DisplayName,
+		BirthDate:   payload.BirthDate,
+		Locale:      payload.Locale,
+		Timezone:    payload.Timezone,
+		AvatarURL:   payload.AvatarUrl,
+	}, params.IfMatch)
+	if errors.Is(err, errVersionMismatch) {
+		return c.JSON(http.StatusPreconditionFailed, errorResponse(c, err.Error()))
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, errorResponse(c, "user not found"))
+	}
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, generated.ErrorResponse{
-			Message: err.Error(),
-		})
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
 	}
 
-	return c.JSON(http.StatusOK, generated.RegisterResponse{
-		Id: resp.ID,
+	c.Response().Header().Set("ETag", formatETag(resp.Version))
+	return c.JSON(http.StatusOK, newUserResponse(resp))
+}
+
+// newUserResponse return the version 1 representation of user.
+func newUserResponse(user repository.User) generated.UserResponse {
+	resp := generated.UserResponse{
+		Phone:         user.Phone,
+		Name:          user.Name,
+		LoginCount:    user.LoginCount,
+		LastLoginAt:   user.LastLoginAt,
+		Email:         (*openapi_types.Email)(user.Email),
+		EmailVerified: user.Email != nil && user.EmailVerifiedAt != nil,
+		DisplayName:   user.DisplayName,
+		Locale:        user.Locale,
+		Timezone:      user.Timezone,
+		AvatarUrl:     user.AvatarURL,
+	}
+	if user.BirthDate != nil {
+		resp.BirthDate = &openapi_types.Date{Time: *user.BirthDate}
+	}
+	return resp
+}
+
+// POST API responsible to log in user.
+// http://localhost:1323/login
+func (s *Server) Login(c echo.Context) error {
+	ctx := requestContext(c)
+	var payload generated.LoginRequest
+	err := c.Bind(&payload)
+	if err != nil {
+		s.Metrics.LoginFailed(metrics.LoginFailedInvalidPayload)
+		return c.JSON(http.StatusBadRequest, errorResponse(c, "Invalid payload: failed to parse"))
+	}
+
+	identifier := loginIdentifier(payload.Identifier, payload.Phone)
+	if strings.TrimSpace(identifier) == "" {
+		s.Metrics.LoginFailed(metrics.LoginFailedInvalidPayload)
+		return c.JSON(http.StatusBadRequest, errorResponse(c, errNoIdentifier.Error()))
+	}
+	scopes, err := parseLoginScope(payload.Scope)
+	if err != nil {
+		s.Metrics.LoginFailed(metrics.LoginFailedInvalidPayload)
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	}
+
+	user, err := s.checkCredentials(ctx, identifier, payload.Password)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	}
+
+	sess, err := s.openSession(c, user, scopes)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, generated.LoginResponse{
+		Id:    user.ID,
+		Token: sess.Token,
 	})
 }
 
-// GET API which return the name and phone number of the cats based on authorization.
-// http://localhost:1323/users
-func (s *Server) GetUser(c echo.Context) error {
-	ctx := c.Request().Context()
-	auth := c.Request().Header.Get("Authorization")
+// POST API responsible to login user with the ID token of an external
+// OpenID Connect provider, a user is created for a new identity.
+// http://localhost:1323/login/oidc
+func (s *Server) LoginOIDC(c echo.Context) error {
+	ctx := requestContext(c)
+	var payload generated.OIDCLoginRequest
+	err := c.Bind(&payload)
+	if err != nil {
+		s.Metrics.LoginFailed(metrics.LoginFailedInvalidPayload)
+		return c.JSON(http.StatusBadRequest, errorResponse(c, "Invalid payload: failed to parse"))
+	}
 
-	// validate authorization.
-	token, err := s.ValidateJWT(auth)
+	claims, err := s.verifyIDToken(ctx, payload)
+	if errors.Is(err, errInvalidIDToken) {
+		s.Metrics.LoginFailed(metrics.LoginFailedInvalidIDToken)
+		return c.JSON(http.StatusUnauthorized, errorResponse(c, err.Error()))
+	}
 	if err != nil {
-		return c.JSON(http.StatusForbidden, generated.ErrorResponse{Message: err.Error()})
+		s.Metrics.LoginFailed(metrics.LoginFailedError)
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
 	}
 
-	// get user_id from token.
-	userID, err := strconv.Atoi(s.GetJWTClaims(token, "user_id"))
+	user, created, err := s.identityUser(ctx, claims)
+	if errors.Is(err, errIdentityEmailExists) {
+		s.Metrics.LoginFailed(metrics.LoginFailedIdentityConflict)
+		return c.JSON(http.StatusConflict, errorResponse(c, err.Error()))
+	}
 	if err != nil {
-		return c.JSON(http.StatusForbidden, generated.ErrorResponse{Message: err.Error()})
+		s.Metrics.LoginFailed(metrics.LoginFailedError)
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
 	}
 
-	// get user data by id.
-	resp, err := s.Repository.GetUserByID(ctx, int64(userID))
+	sess, err := s.openSession(c, user, sessionScopes)
 	if err != nil {
-		return c.JSON(http.StatusForbidden, generated.ErrorResponse{
-			Message: err.Error(),
-		})
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
 	}
 
-	return c.JSON(http.StatusOK, generated.UserResponse{
-		Phone: resp.Phone,
-		Name:  resp.Name,
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	return c.JSON(status, generated.LoginResponse{
+		Id:    user.ID,
+		Token: sess.Token,
 	})
 }
 
-// PATCH API responsible to update user data.
-// http://localhost:1323/users
-func (s *Server) UpdateUser(c echo.Context) error {
-	ctx := c.Request().Context()
-	auth := c.Request().Header.Get("Authorization")
+// GET API which return the external identities linked to the user.
+// http://localhost:1323/users/identities
+func (s *Server) ListUserIdentities(c echo.Context) error {
+	ctx := requestContext(c)
 
-	// validate authorization.
-	token, err := s.ValidateJWT(auth)
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
 	if err != nil {
-		return c.JSON(http.StatusForbidden, generated.ErrorResponse{Message: err.Error()})
+		return c.JSON(http.StatusForbidden, errorResponse(c, err.Error()))
 	}
 
-	// get user_id from token.
-	userID, err := strconv.Atoi(s.GetJWTClaims(token, "user_id"))
+	identities, err := s.Repository.ListUserIdentities(ctx, caller.UserID)
 	if err != nil {
-		return c.JSON(http.StatusForbidden, generated.ErrorResponse{Message: err.Error()})
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
 	}
 
-	var payload generated.UpdateUserRequest
+	resp := generated.UserIdentitiesResponse{
+		Identities: make([]generated.UserIdentity, 0, len(identities)),
+	}
+	for _, identity := range identities {
+		resp.Identities = append(resp.Identities, newUserIdentityResponse(identity))
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// POST API responsible to link the identity of an ID token to the user.
+// http://localhost:1323/users/identities
+func (s *Server) LinkUserIdentity(c echo.Context) error {
+	ctx := requestContext(c)
+
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
+	if err != nil {
+		return c.JSON(http.StatusForbidden, errorResponse(c, err.Error()))
+	}
+
+	var payload generated.OIDCLoginRequest
 	err = c.Bind(&payload)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, generated.ErrorResponse{
-			Message: "Invalid payload: failed to parse",
-		})
+		return c.JSON(http.StatusBadRequest, errorResponse(c, "Invalid payload: failed to parse"))
 	}
 
-	input := repository.User{
-		ID:    int64(userID),
-		Name:  strings.TrimSpace(payload.Name),
-		Phone: strings.TrimSpace(payload.Phone),
+	claims, err := s.verifyIDToken(ctx, payload)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
 	}
 
-	// validate input with given rules.
-	if errs := input.Validate(); len(errs) > 0 {
-		var resp generated.ErrorResponse
-		resp.Message = strings.Join(errs, ", ")
-		return c.JSON(http.StatusBadRequest, resp)
+	identity, err := s.Repository.CreateUserIdentity(ctx, repository.UserIdentity{
+		Provider: claims.Provider,
+		Subject:  claims.Subject,
+		UserID:   caller.UserID,
+		Email:    identityEmail(claims),
+	})
+	if errors.Is(err, repository.ErrDuplicateData) {
+		return c.JSON(http.StatusConflict, errorResponse(c, errIdentityLinked.Error()))
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
 	}
 
-	// process update user data.
-	resp, err := s.Repository.UpdateUser(ctx, input)
+	return c.JSON(http.StatusCreated, newUserIdentityResponse(identity))
+}
+
+// DELETE API responsible to unlink the identity of a provider from the user.
+// http://localhost:1323/users/identities/:provider
+func (s *Server) UnlinkUserIdentity(c echo.Context, provider string) error {
+	ctx := requestContext(c)
+
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, generated.ErrorResponse{
-			Message: err.Error(),
-		})
+		return c.JSON(http.StatusForbidden, errorResponse(c, err.Error()))
 	}
 
-	return c.JSON(http.StatusOK, generated.UserResponse{
-		Phone: resp.Phone,
-		Name:  resp.Name,
-	})
+	err = s.unlinkIdentity(ctx, caller, provider)
+	switch {
+	case errors.Is(err, errIdentityNotFound):
+		return c.JSON(http.StatusNotFound, errorResponse(c, err.Error()))
+	case errors.Is(err, errLastLoginMethod):
+		return c.JSON(http.StatusConflict, errorResponse(c, err.Error()))
+	case err != nil:
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	}
+
+	return c.NoContent(http.StatusNoContent)
 }
 
-// POST API responsible to log in user.
-// http://localhost:1323/login
-func (s *Server) Login(c echo.Context) error {
-	ctx := c.Request().Context()
-	var payload generated.LoginRequest
-	err := c.Bind(&payload)
+// GET API which return recent security events of the user.
+// http://localhost:1323/users/events
+func (s *Server) ListUserEvents(c echo.Context, params generated.ListUserEventsParams) error {
+	ctx := requestContext(c)
+
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, generated.ErrorResponse{
-			Message: "Invalid payload: failed to parse",
-		})
+		return c.JSON(http.StatusForbidden, errorResponse(c, err.Error()))
 	}
 
-	// check whether phone number exist.
-	user, err := s.Repository.GetUserByPhone(ctx, payload.Phone)
+	limit, err := listLimit(params.Limit)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, generated.ErrorResponse{
-			Message: err.Error(),
-		})
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
 	}
 
-	// compare user password.
-	err = ComparePassword(payload.Password, user.Password)
+	events, err := s.Repository.ListUserEvents(ctx, caller.UserID, limit)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, generated.ErrorResponse{
-			Message: "incorrect password or phone number",
-		})
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	}
+
+	resp := generated.UserEventsResponse{
+		Events: make([]generated.UserEvent, 0, len(events)),
+	}
+	for _, event := range events {
+		item := generated.UserEvent{
+			Id:        event.ID,
+			Type:      generated.UserEventType(event.Type),
+			IpAddress: event.IP,
+			UserAgent: event.UserAgent,
+			CreatedAt: event.CreatedAt,
+		}
+		if reason := event.Details.Reason; reason != "" {
+			item.Reason = &reason
+		}
+		if sessionID := event.Details.SessionID; sessionID != "" {
+			item.SessionId = &sessionID
+		}
+		if provider := event.Details.Provider; provider != "" {
+			item.Provider = &provider
+		}
+		if len(event.Details.Changes) > 0 {
+			changes := make(map[string]generated.FieldChange, len(event.Details.Changes))
+			for field, change := range event.Details.Changes {
+				changes[field] = generated.FieldChange{Before: change.Before, After: change.After}
+			}
+			item.Changes = &changes
+		}
+		resp.Events = append(resp.Events, item)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GET API which return login history of the user.
+// http://localhost:1323/users/logins
+func (s *Server) ListUserLogins(c echo.Context, params generated.ListUserLoginsParams) error {
+	ctx := requestContext(c)
+
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
+	if err != nil {
+		return c.JSON(http.StatusForbidden, errorResponse(c, err.Error()))
 	}
 
-	// generate JWT.
-	token, err := s.GenerateJWT(user.ID)
+	limit, err := listLimit(params.Limit)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, generated.ErrorResponse{
-			Message: err.Error(),
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	}
+
+	// fetch one extra login to know whether there is a next page.
+	logins, err := s.Repository.ListUserLogins(ctx, caller.UserID, params.Before, limit+1)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	}
+
+	var resp generated.UserLoginsResponse
+	if len(logins) > limit {
+		logins = logins[:limit]
+		resp.NextBefore = &logins[limit-1].ID
+	}
+	resp.Logins = make([]generated.UserLogin, 0, len(logins))
+	for _, login := range logins {
+		resp.Logins = append(resp.Logins, generated.UserLogin{
+			Id:        login.ID,
+			IpAddress: login.IP,
+			UserAgent: login.UserAgent,
+			CreatedAt: login.CreatedAt,
 		})
 	}
 
-	// increate user login count.
-	err = s.Repository.IncreaseLoginCount(ctx, user.ID)
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GET API which return active sessions of the user.
+// http://localhost:1323/users/sessions
+func (s *Server) ListUserSessions(c echo.Context) error {
+	ctx := requestContext(c)
+
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, generated.ErrorResponse{
-			Message: err.Error(),
+		return c.JSON(http.StatusForbidden, errorResponse(c, err.Error()))
+	}
+
+	sessions, err := s.Repository.ListActiveUserSessions(ctx, caller.UserID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	}
+
+	resp := generated.UserSessionsResponse{
+		Sessions: make([]generated.UserSession, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, generated.UserSession{
+			Id:         session.ID,
+			DeviceName: session.DeviceName,
+			IpAddress:  session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID == caller.SessionID,
 		})
 	}
 
-	return c.JSON(http.StatusOK, generated.LoginResponse{
-		Id:    user.ID,
-		Token: token,
+	return c.JSON(http.StatusOK, resp)
+}
+
+// DELETE API responsible to revoke a session of the user.
+// http://localhost:1323/users/sessions/:id
+func (s *Server) RevokeUserSession(c echo.Context, id string) error {
+	ctx := requestContext(c)
+
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
+	if err != nil {
+		return c.JSON(http.StatusForbidden, errorResponse(c, err.Error()))
+	}
+
+	err = s.Repository.RevokeUserSession(ctx, id, caller.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, errorResponse(c, "session not found"))
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GET API for liveness probes, it does not check dependencies.
+// http://localhost:1323/healthz
+func (s *Server) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, generated.HealthResponse{
+		Status: generated.Pass,
 	})
 }
+
+// GET API for readiness probes, runs every registered dependency check.
+// http://localhost:1323/readyz
+func (s *Server) Readyz(c echo.Context) error {
+	resp := generated.HealthResponse{
+		Status: generated.Pass,
+		Checks: &[]generated.HealthCheck{},
+	}
+	if s.Health == nil {
+		return c.JSON(http.StatusOK, resp)
+	}
+
+	report := s.Health.Check(c.Request().Context())
+	resp.Status = generated.HealthStatus(report.Status)
+	for _, result := range report.Checks {
+		check := generated.HealthCheck{
+			Name:      result.Name,
+			Status:    generated.HealthStatus(result.Status),
+			LatencyMs: float64(result.Latency.Microseconds()) / 1000,
+		}
+		if result.Details != "" {
+			details := result.Details
+			check.Details = &details
+		}
+		if result.Error != "" {
+			errMsg := result.Error
+			check.Error = &errMsg
+		}
+		*resp.Checks = append(*resp.Checks, check)
+	}
+
+	if report.Status != health.StatusPass {
+		return c.JSON(http.StatusServiceUnavailable, resp)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,226 @@
+// This file contains the endpoints of the OpenID Connect provider, see
+// api_oauth.yml.
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated/oauth"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// GET API which return the OpenID Connect discovery document.
+// http://localhost:1323/.well-known/openid-configuration
+func (s *Server) GetOpenIDConfiguration(c echo.Context) error {
+	scopes := slices.Clone(supportedScopes)
+	return c.JSON(http.StatusOK, oauth.OpenIDConfiguration{
+		Issuer:                            s.Issuer,
+		AuthorizationEndpoint:             s.Issuer + "/oauth/authorize",
+		TokenEndpoint:                     s.Issuer + "/oauth/token",
+		UserinfoEndpoint:                  s.Issuer + "/userinfo",
+		JwksUri:                           s.Issuer + "/oauth/jwks",
+		ScopesSupported:                   &scopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               &[]string{grantTypeAuthorizationCode},
+		SubjectTypesSupported:             []string{"public"},
+		IdTokenSigningAlgValuesSupported:  []string{jwt.SigningMethodRS256.Alg()},
+		TokenEndpointAuthMethodsSupported: &[]string{"none", "client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     &[]string{codeChallengeS256},
+		ClaimsSupported:                   &[]string{"iss", "sub", "aud", "iat", "exp", "auth_time", "nonce", "name", "phone_number"},
+	})
+}
+
+// GET API which return the public keys verifying ID tokens.
+// http://localhost:1323/oauth/jwks
+func (s *Server) GetJWKS(c echo.Context) error {
+	return c.JSON(http.StatusOK, oauth.JWKS{
+		Keys: []oauth.JWK{newJWK(&s.IDTokenKey.PublicKey)},
+	})
+}
+
+// GET API which return the login page of an authorization request.
+// http://localhost:1323/oauth/authorize?response_type=code&client_id=...
+func (s *Server) Authorize(c echo.Context, params oauth.AuthorizeParams) error {
+	req := newAuthorizationRequest(params)
+	client, scope, err := s.checkAuthorizationRequest(requestContext(c), req)
+	if err != nil {
+		return s.authorizationError(c, req, err)
+	}
+
+	return renderLoginPage(c, client, req, scope, "", "")
+}
+
+// POST API which log the user in and redirect to the client with a code.
+// http://localhost:1323/oauth/authorize
+// First party clients are authorized without asking for consent.
+func (s *Server) SubmitAuthorization(c echo.Context) error {
+	ctx := requestContext(c)
+	req := formAuthorizationRequest(c)
+	client, scope, err := s.checkAuthorizationRequest(ctx, req)
+	if err != nil {
+		return s.authorizationError(c, req, err)
+	}
+
+	consent := c.FormValue("consent")
+	if consent == string(oauth.Deny) {
+		return redirectError(c, req, oauthError{Code: oauthAccessDenied, Description: "the user denied the authorization"})
+	}
+	identifier := strings.TrimSpace(c.FormValue("identifier"))
+	if identifier == "" {
+		s.Metrics.LoginFailed(metrics.LoginFailedInvalidPayload)
+		return renderLoginPage(c, client, req, scope, identifier, errNoIdentifier.Error())
+	}
+	if !client.FirstParty && consent != string(oauth.Allow) {
+		return renderLoginPage(c, client, req, scope, identifier, "Allow or deny the access of "+client.Name)
+	}
+
+	// unknown identifier and wrong password are not told apart.
+	user, err := s.checkCredentials(ctx, identifier, c.FormValue("password"))
+	if err != nil {
+		return renderLoginPage(c, client, req, scope, identifier, errInvalidPassword.Error())
+	}
+
+	code, codeHash, err := newSecretToken()
+	if err != nil {
+		return internalError(c, "failed to authorize client", err)
+	}
+	err = s.Repository.CreateAuthorizationCode(ctx, repository.AuthorizationCode{
+		CodeHash:      codeHash,
+		ClientID:      client.ID,
+		UserID:        user.ID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         scope,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(s.AuthorizationCodeTTL),
+	})
+	if err != nil {
+		return internalError(c, "failed to authorize client", err)
+	}
+
+	return redirectToClient(c, req.RedirectURI, req.State, url.Values{"code": {code}})
+}
+
+// authorizationError respond to an invalid authorization request, which is
+// redirected to the client unless the client or its redirect URI is unknown.
+func (s *Server) authorizationError(c echo.Context, req authorizationRequest, err error) error {
+	var oauthErr oauthError
+	switch {
+	case errors.Is(err, errInvalidClientRedirect):
+		return renderPage(c, http.StatusBadRequest, errorPageTemplate, err.Error())
+	case errors.As(err, &oauthErr):
+		return redirectError(c, req, oauthErr)
+	}
+	return internalError(c, "failed to authorize client", err)
+}
+
+// POST API which exchange an authorization code for tokens.
+// http://localhost:1323/oauth/token
+// The access token is the token of a new session, like the one of a login.
+func (s *Server) CreateToken(c echo.Context) error {
+	ctx := requestContext(c)
+	header := c.Response().Header()
+	header.Set("Cache-Control", "no-store")
+	header.Set("Pragma", "no-cache")
+
+	if c.FormValue("grant_type") != grantTypeAuthorizationCode {
+		return c.JSON(http.StatusBadRequest, oauthError{Code: oauthUnsupportedGrantType, Description: "grant_type must be " + grantTypeAuthorizationCode}.response())
+	}
+
+	var oauthErr oauthError
+	client, err := s.authenticateClient(c)
+	switch {
+	case errors.As(err, &oauthErr):
+		header.Set(echo.HeaderWWWAuthenticate, "Basic")
+		return c.JSON(http.StatusUnauthorized, oauthErr.response())
+	case err != nil:
+		return internalError(c, "failed to authenticate client", err)
+	}
+
+	// the code is consumed even when the request turns out invalid, so a
+	// leaked code cannot be tried again.
+	invalidGrant := oauthError{Code: oauthInvalidGrant, Description: "authorization code is invalid or expired"}
+	code, err := s.Repository.ConsumeAuthorizationCode(ctx, hashSecretToken(c.FormValue("code")))
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return c.JSON(http.StatusBadRequest, invalidGrant.response())
+	case err != nil:
+		return internalError(c, "failed to exchange authorization code", err)
+	}
+	if code.ClientID != client.ID || code.RedirectURI != c.FormValue("redirect_uri") || !verifyCodeChallenge(code.CodeChallenge, c.FormValue("code_verifier")) {
+		return c.JSON(http.StatusBadRequest, invalidGrant.response())
+	}
+
+	user, err := s.Repository.GetUserByID(ctx, code.UserID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return c.JSON(http.StatusBadRequest, invalidGrant.response())
+	case err != nil:
+		return internalError(c, "failed to exchange authorization code", err)
+	}
+
+	sess, err := s.openSession(c, user, accessTokenScopes(code.Scope))
+	if err != nil {
+		return internalError(c, "failed to open session", err)
+	}
+
+	resp := oauth.TokenResponse{
+		AccessToken: sess.Token,
+		TokenType:   "Bearer",
+	}
+	if s.TokenTTL > 0 {
+		expiresIn := int(s.TokenTTL.Seconds())
+		resp.ExpiresIn = &expiresIn
+	}
+	if code.Scope != "" {
+		resp.Scope = &code.Scope
+	}
+	if hasScope(code.Scope, scopeOpenID) {
+		idToken, err := s.signIDToken(user, code)
+		if err != nil {
+			return internalError(c, "failed to sign id token", err)
+		}
+		resp.IdToken = &idToken
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GET API which return the claims of the user of the token.
+// http://localhost:1323/userinfo
+func (s *Server) GetUserinfo(c echo.Context) error {
+	ctx := requestContext(c)
+
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
+	if err != nil {
+		return unauthorized(c, err)
+	}
+
+	user, err := s.Repository.GetUserByID(ctx, caller.UserID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return c.JSON(http.StatusNotFound, errorResponse(c, "user not found"))
+	case err != nil:
+		return internalError(c, "failed to get user", err)
+	}
+
+	// only the claims of the scopes granted to the token are returned.
+	resp := oauth.Userinfo{Sub: fmt.Sprint(user.ID)}
+	if slices.Contains(caller.Scopes, scopeProfile) {
+		resp.Name = &user.Name
+	}
+	if slices.Contains(caller.Scopes, scopePhone) {
+		resp.PhoneNumber = &user.Phone
+	}
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,830 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated/oauth"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	// mockIDTokenKey signs the ID tokens of provideTest.
+	mockIDTokenKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+
+	mockRedirectURI = "https://app.example.com/callback"
+	// mockClient is a first party public client.
+	mockClient = repository.OAuthClient{
+		ID:           "mock-app",
+		Name:         "Mock App",
+		RedirectURIs: []string{mockRedirectURI},
+		FirstParty:   true,
+	}
+	// mockThirdPartyClient is a confidential client asking for consent,
+	// its secret is mock-secret.
+	mockThirdPartyClient = repository.OAuthClient{
+		ID:           "mock-partner",
+		Name:         "Mock Partner",
+		SecretHash:   hashSecretToken("mock-secret"),
+		RedirectURIs: []string{mockRedirectURI},
+	}
+
+	// mockCodeVerifier and mockCodeChallenge are the PKCE example of RFC 7636.
+	mockCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	mockCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+// mockAuthorizeParams return the parameters of a valid authorization request of client.
+func mockAuthorizeParams(clientID string) oauth.AuthorizeParams {
+	return oauth.AuthorizeParams{
+		ResponseType:        "code",
+		ClientId:            clientID,
+		RedirectUri:         mockRedirectURI,
+		Scope:               strPtr("openid profile"),
+		State:               strPtr("mock-state"),
+		Nonce:               strPtr("mock-nonce"),
+		CodeChallenge:       strPtr(mockCodeChallenge),
+		CodeChallengeMethod: strPtr(codeChallengeS256),
+	}
+}
+
+// mockAuthorizationForm return the form of a valid authorization request of
+// client, with fields added or replaced by extra.
+func mockAuthorizationForm(clientID string, extra url.Values) string {
+	form := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {mockRedirectURI},
+		"scope":                 {"openid profile"},
+		"state":                 {"mock-state"},
+		"nonce":                 {"mock-nonce"},
+		"code_challenge":        {mockCodeChallenge},
+		"code_challenge_method": {codeChallengeS256},
+	}
+	for name, values := range extra {
+		form[name] = values
+	}
+	return form.Encode()
+}
+
+func TestGetOpenIDConfiguration(t *testing.T) {
+	t.Run("TestGetOpenIDConfiguration", func(t *testing.T) {
+		Convey("TestGetOpenIDConfiguration", t, func(c C) {
+			testDep := provideTest(t)
+			defer testDep()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/.well-known/openid-configuration", nil)
+			rr := httptest.NewRecorder()
+			_ = server.GetOpenIDConfiguration(e.NewContext(req, rr))
+
+			// assert
+			So(rr.Code, ShouldEqual, http.StatusOK)
+			var resp oauth.OpenIDConfiguration
+			_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+			So(resp.Issuer, ShouldEqual, "https://auth.example.com")
+			So(resp.TokenEndpoint, ShouldEqual, "https://auth.example.com/oauth/token")
+			So(resp.JwksUri, ShouldEqual, "https://auth.example.com/oauth/jwks")
+			So(resp.IdTokenSigningAlgValuesSupported, ShouldResemble, []string{"RS256"})
+			So(*resp.CodeChallengeMethodsSupported, ShouldResemble, []string{"S256"})
+		})
+	})
+}
+
+func TestGetJWKS(t *testing.T) {
+	t.Run("TestGetJWKS", func(t *testing.T) {
+		Convey("TestGetJWKS", t, func(c C) {
+			testDep := provideTest(t)
+			defer testDep()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/oauth/jwks", nil)
+			rr := httptest.NewRecorder()
+			_ = server.GetJWKS(e.NewContext(req, rr))
+
+			// assert
+			So(rr.Code, ShouldEqual, http.StatusOK)
+			var resp oauth.JWKS
+			_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+			So(resp.Keys, ShouldHaveLength, 1)
+			So(resp.Keys[0].Kid, ShouldEqual, server.idTokenKeyID)
+			So(resp.Keys[0].Kty, ShouldEqual, "RSA")
+			So(resp.Keys[0].E, ShouldEqual, "AQAB")
+		})
+	})
+}
+
+func TestAuthorize(t *testing.T) {
+	t.Run("TestAuthorize", func(t *testing.T) {
+		Convey("TestAuthorize", t, func(c C) {
+			testCases := []struct {
+				testID         int
+				testDesc       string
+				params         func(params *oauth.AuthorizeParams)
+				mockFunc       func()
+				wantStatusCode int
+				// wantRedirect are the query parameters of the redirect.
+				wantRedirect url.Values
+				// wantBody are fragments of the html page.
+				wantBody []string
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - unknown client is not redirected to",
+					params:   func(params *oauth.AuthorizeParams) {},
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(repository.OAuthClient{}, repository.ErrNotFound)
+					},
+					wantStatusCode: http.StatusBadRequest,
+					wantBody:       []string{"Invalid authorization request"},
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - unregistered redirect_uri is not redirected to",
+					params: func(params *oauth.AuthorizeParams) {
+						params.RedirectUri = "https://evil.example.com/callback"
+					},
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+					},
+					wantStatusCode: http.StatusBadRequest,
+					wantBody:       []string{"Invalid authorization request"},
+				},
+				{
+					testID:   3,
+					testDesc: "Failed - unsupported response_type",
+					params: func(params *oauth.AuthorizeParams) {
+						params.ResponseType = "token"
+					},
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+					},
+					wantStatusCode: http.StatusFound,
+					wantRedirect:   url.Values{"error": {"unsupported_response_type"}, "state": {"mock-state"}},
+				},
+				{
+					testID:   4,
+					testDesc: "Failed - plain code_challenge_method",
+					params: func(params *oauth.AuthorizeParams) {
+						params.CodeChallengeMethod = strPtr("plain")
+					},
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+					},
+					wantStatusCode: http.StatusFound,
+					wantRedirect:   url.Values{"error": {"invalid_request"}, "state": {"mock-state"}},
+				},
+				{
+					testID:   5,
+					testDesc: "Failed - unsupported scope",
+					params: func(params *oauth.AuthorizeParams) {
+						params.Scope = strPtr("openid email")
+					},
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+					},
+					wantStatusCode: http.StatusFound,
+					wantRedirect:   url.Values{"error": {"invalid_scope"}, "state": {"mock-state"}},
+				},
+				{
+					testID:   6,
+					testDesc: "Failed - error GetOAuthClient",
+					params:   func(params *oauth.AuthorizeParams) {},
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(repository.OAuthClient{}, errors.New("error"))
+					},
+					wantStatusCode: http.StatusInternalServerError,
+				},
+				{
+					testID:   7,
+					testDesc: "Success - first party client is not asked for consent",
+					params:   func(params *oauth.AuthorizeParams) {},
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+					},
+					wantStatusCode: http.StatusOK,
+					wantBody: []string{
+						"Log in to Mock App",
+						`<input type="hidden" name="client_id" value="mock-app">`,
+						`<input type="hidden" name="state" value="mock-state">`,
+						"<button>Log in</button>",
+					},
+				},
+				{
+					testID:   8,
+					testDesc: "Success - third party client is asked for consent",
+					params: func(params *oauth.AuthorizeParams) {
+						params.ClientId = "mock-partner"
+					},
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-partner").Return(mockThirdPartyClient, nil)
+					},
+					wantStatusCode: http.StatusOK,
+					wantBody: []string{
+						"Mock Partner asks for your openid profile",
+						`<button name="consent" value="allow">Allow</button>`,
+					},
+				},
+				{
+					testID:   9,
+					testDesc: "Failed - admin scope is never granted to clients",
+					params: func(params *oauth.AuthorizeParams) {
+						params.Scope = strPtr("openid admin:api_keys")
+					},
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+					},
+					wantStatusCode: http.StatusFound,
+					wantRedirect:   url.Values{"error": {"invalid_scope"}, "state": {"mock-state"}},
+				},
+			}
+
+			for _, tc := range testCases {
+				testDep := provideTest(t)
+				defer testDep()
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					tc.mockFunc()
+					params := mockAuthorizeParams("mock-app")
+					tc.params(&params)
+
+					e := echo.New()
+					req := httptest.NewRequest(echo.GET, "/oauth/authorize", nil)
+					rr := httptest.NewRecorder()
+					c := e.NewContext(req, rr)
+					_ = server.Authorize(c, params)
+
+					// assert
+					So(rr.Code, ShouldEqual, tc.wantStatusCode)
+					for _, fragment := range tc.wantBody {
+						So(rr.Body.String(), ShouldContainSubstring, fragment)
+					}
+					if tc.wantBody != nil {
+						So(rr.Header().Get(echo.HeaderXFrameOptions), ShouldEqual, "DENY")
+					}
+					if tc.wantRedirect != nil {
+						location, err := url.Parse(rr.Header().Get(echo.HeaderLocation))
+						So(err, ShouldBeNil)
+						So(location.Host, ShouldEqual, "app.example.com")
+						for name := range tc.wantRedirect {
+							So(location.Query().Get(name), ShouldEqual, tc.wantRedirect.Get(name))
+						}
+					}
+				})
+			}
+		})
+	})
+}
+
+func TestSubmitAuthorization(t *testing.T) {
+	t.Run("TestSubmitAuthorization", func(t *testing.T) {
+		Convey("TestSubmitAuthorization", t, func(c C) {
+			// storedCode is the code stored by the last request.
+			var storedCode repository.AuthorizationCode
+
+			testCases := []struct {
+				testID         int
+				testDesc       string
+				payload        string
+				mockFunc       func()
+				wantStatusCode int
+				// wantRedirect are the query parameters of the redirect.
+				wantRedirect url.Values
+				// wantBody are fragments of the html page.
+				wantBody []string
+				wantCode bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - unknown client is not redirected to",
+					payload:  mockAuthorizationForm("mock-app", url.Values{"identifier": {"+6280989444"}, "password": {"password1!A"}}),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(repository.OAuthClient{}, repository.ErrNotFound)
+					},
+					wantStatusCode: http.StatusBadRequest,
+					wantBody:       []string{"Invalid authorization request"},
+				},
+				{
+					testID:   2,
+					testDesc: "Success - denied consent is redirected without credentials",
+					payload:  mockAuthorizationForm("mock-partner", url.Values{"consent": {"deny"}}),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-partner").Return(mockThirdPartyClient, nil)
+					},
+					wantStatusCode: http.StatusFound,
+					wantRedirect:   url.Values{"error": {"access_denied"}, "state": {"mock-state"}},
+				},
+				{
+					testID:   3,
+					testDesc: "Failed - identifier missing",
+					payload:  mockAuthorizationForm("mock-app", url.Values{"password": {"password1!A"}}),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+					},
+					wantStatusCode: http.StatusOK,
+					wantBody:       []string{"identifier is required"},
+				},
+				{
+					testID:   4,
+					testDesc: "Failed - third party client without consent",
+					payload:  mockAuthorizationForm("mock-partner", url.Values{"identifier": {"+6280989444"}, "password": {"password1!A"}}),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-partner").Return(mockThirdPartyClient, nil)
+					},
+					wantStatusCode: http.StatusOK,
+					wantBody:       []string{"Allow or deny the access of Mock Partner"},
+				},
+				{
+					testID:   5,
+					testDesc: "Failed - wrong password shows the login page again",
+					payload:  mockAuthorizationForm("mock-app", url.Values{"identifier": {"+6280989444"}, "password": {"password-mock"}}),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+						mockRepository.EXPECT().GetUserByPhone(gomock.Any(), "+6280989444").Return(repository.User{
+							ID:       1,
+							Password: "$2a$04$eMb1vD6rv6hXe/PKA2Wzj.b1dO0oW2PTYQzA5ez8Rm3GrD6ULrKd2",
+						}, nil)
+						mockRepository.EXPECT().CreateUserEvent(gomock.Any(), gomock.Any()).Return(nil)
+					},
+					wantStatusCode: http.StatusOK,
+					wantBody: []string{
+						"incorrect password or identifier",
+						`name="identifier" value="&#43;6280989444"`,
+					},
+				},
+				{
+					testID:   6,
+					testDesc: "Failed - error CreateAuthorizationCode",
+					payload:  mockAuthorizationForm("mock-app", url.Values{"identifier": {"+6280989444"}, "password": {"password1!A"}}),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+						mockRepository.EXPECT().GetUserByPhone(gomock.Any(), "+6280989444").Return(repository.User{
+							ID:       1,
+							Password: "$2a$04$eMb1vD6rv6hXe/PKA2Wzj.b1dO0oW2PTYQzA5ez8Rm3GrD6ULrKd2",
+						}, nil)
+						mockRepository.EXPECT().CreateAuthorizationCode(gomock.Any(), gomock.Any()).Return(errors.New("error"))
+					},
+					wantStatusCode: http.StatusInternalServerError,
+				},
+				{
+					testID:   7,
+					testDesc: "Success - first party client",
+					payload:  mockAuthorizationForm("mock-app", url.Values{"identifier": {"+6280989444"}, "password": {"password1!A"}}),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+						mockRepository.EXPECT().GetUserByPhone(gomock.Any(), "+6280989444").Return(repository.User{
+							ID:       1,
+							Password: "$2a$04$eMb1vD6rv6hXe/PKA2Wzj.b1dO0oW2PTYQzA5ez8Rm3GrD6ULrKd2",
+						}, nil)
+						mockRepository.EXPECT().CreateAuthorizationCode(gomock.Any(), gomock.Any()).DoAndReturn(
+							func(_ any, input repository.AuthorizationCode) error {
+								storedCode = input
+								return nil
+							})
+					},
+					wantStatusCode: http.StatusFound,
+					wantRedirect:   url.Values{"state": {"mock-state"}},
+					wantCode:       true,
+				},
+				{
+					testID:   8,
+					testDesc: "Success - third party client with consent",
+					payload:  mockAuthorizationForm("mock-partner", url.Values{"identifier": {"+6280989444"}, "password": {"password1!A"}, "consent": {"allow"}}),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-partner").Return(mockThirdPartyClient, nil)
+						mockRepository.EXPECT().GetUserByPhone(gomock.Any(), "+6280989444").Return(repository.User{
+							ID:       1,
+							Password: "$2a$04$eMb1vD6rv6hXe/PKA2Wzj.b1dO0oW2PTYQzA5ez8Rm3GrD6ULrKd2",
+						}, nil)
+						mockRepository.EXPECT().CreateAuthorizationCode(gomock.Any(), gomock.Any()).DoAndReturn(
+							func(_ any, input repository.AuthorizationCode) error {
+								storedCode = input
+								return nil
+							})
+					},
+					wantStatusCode: http.StatusFound,
+					wantRedirect:   url.Values{"state": {"mock-state"}},
+					wantCode:       true,
+				},
+			}
+
+			for _, tc := range testCases {
+				testDep := provideTest(t)
+				defer testDep()
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					tc.mockFunc()
+					storedCode = repository.AuthorizationCode{}
+
+					e := echo.New()
+					req := httptest.NewRequest(echo.POST, "/oauth/authorize", strings.NewReader(tc.payload))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+					rr := httptest.NewRecorder()
+					c := e.NewContext(req, rr)
+					_ = server.SubmitAuthorization(c)
+
+					// assert
+					So(rr.Code, ShouldEqual, tc.wantStatusCode)
+					for _, fragment := range tc.wantBody {
+						So(rr.Body.String(), ShouldContainSubstring, fragment)
+					}
+					if tc.wantRedirect == nil {
+						return
+					}
+					location, err := url.Parse(rr.Header().Get(echo.HeaderLocation))
+					So(err, ShouldBeNil)
+					for name := range tc.wantRedirect {
+						So(location.Query().Get(name), ShouldEqual, tc.wantRedirect.Get(name))
+					}
+					if !tc.wantCode {
+						So(location.Query().Has("code"), ShouldBeFalse)
+						return
+					}
+
+					// only the hash of the redirected code is stored.
+					code := location.Query().Get("code")
+					So(code, ShouldNotBeEmpty)
+					So(storedCode.CodeHash, ShouldEqual, hashSecretToken(code))
+					So(storedCode.UserID, ShouldEqual, 1)
+					So(storedCode.Scope, ShouldEqual, "openid profile")
+					So(storedCode.Nonce, ShouldEqual, "mock-nonce")
+					So(storedCode.CodeChallenge, ShouldEqual, mockCodeChallenge)
+					So(storedCode.ExpiresAt, ShouldHappenWithin, time.Second, time.Now().Add(defaultAuthorizationCodeTTL))
+				})
+			}
+		})
+	})
+}
+
+func TestCreateToken(t *testing.T) {
+	t.Run("TestCreateToken", func(t *testing.T) {
+		Convey("TestCreateToken", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			mockCode := repository.AuthorizationCode{
+				CodeHash:      hashSecretToken("mock-code"),
+				ClientID:      "mock-app",
+				UserID:        1,
+				RedirectURI:   mockRedirectURI,
+				Scope:         "openid profile",
+				Nonce:         "mock-nonce",
+				CodeChallenge: mockCodeChallenge,
+				CreatedAt:     mockTime,
+			}
+			mockPayload := url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"mock-code"},
+				"redirect_uri":  {mockRedirectURI},
+				"client_id":     {"mock-app"},
+				"code_verifier": {mockCodeVerifier},
+			}
+			// payload return mockPayload with fields replaced by extra,
+			// empty values are removed.
+			payload := func(extra url.Values) string {
+				form := url.Values{}
+				for name, values := range mockPayload {
+					form[name] = values
+				}
+				for name, values := range extra {
+					if values[0] == "" {
+						delete(form, name)
+						continue
+					}
+					form[name] = values
+				}
+				return form.Encode()
+			}
+
+			testCases := []struct {
+				testID         int
+				testDesc       string
+				payload        string
+				basicAuth      [2]string
+				mockFunc       func()
+				wantStatusCode int
+				wantError      string
+				wantIDToken    bool
+				// wantScope is the scope claim of the access token.
+				wantScope string
+			}{
+				{
+					testID:         1,
+					testDesc:       "Failed - unsupported grant_type",
+					payload:        payload(url.Values{"grant_type": {"password"}}),
+					mockFunc:       func() {},
+					wantStatusCode: http.StatusBadRequest,
+					wantError:      "unsupported_grant_type",
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - unknown client",
+					payload:  payload(nil),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(repository.OAuthClient{}, repository.ErrNotFound)
+					},
+					wantStatusCode: http.StatusUnauthorized,
+					wantError:      "invalid_client",
+				},
+				{
+					testID:    3,
+					testDesc:  "Failed - wrong client secret",
+					payload:   payload(url.Values{"client_id": {""}}),
+					basicAuth: [2]string{"mock-partner", "wrong-secret"},
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-partner").Return(mockThirdPartyClient, nil)
+					},
+					wantStatusCode: http.StatusUnauthorized,
+					wantError:      "invalid_client",
+				},
+				{
+					testID:   4,
+					testDesc: "Failed - confidential client without secret",
+					payload:  payload(url.Values{"client_id": {"mock-partner"}}),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-partner").Return(mockThirdPartyClient, nil)
+					},
+					wantStatusCode: http.StatusUnauthorized,
+					wantError:      "invalid_client",
+				},
+				{
+					testID:   5,
+					testDesc: "Failed - unknown or used code",
+					payload:  payload(nil),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+						mockRepository.EXPECT().ConsumeAuthorizationCode(gomock.Any(), hashSecretToken("mock-code")).Return(repository.AuthorizationCode{}, repository.ErrNotFound)
+					},
+					wantStatusCode: http.StatusBadRequest,
+					wantError:      "invalid_grant",
+				},
+				{
+					testID:   6,
+					testDesc: "Failed - code_verifier does not match",
+					payload:  payload(url.Values{"code_verifier": {strings.Repeat("a", 43)}}),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+						mockRepository.EXPECT().ConsumeAuthorizationCode(gomock.Any(), hashSecretToken("mock-code")).Return(mockCode, nil)
+					},
+					wantStatusCode: http.StatusBadRequest,
+					wantError:      "invalid_grant",
+				},
+				{
+					testID:   7,
+					testDesc: "Failed - redirect_uri does not match",
+					payload:  payload(url.Values{"redirect_uri": {"https://app.example.com/other"}}),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+						mockRepository.EXPECT().ConsumeAuthorizationCode(gomock.Any(), hashSecretToken("mock-code")).Return(mockCode, nil)
+					},
+					wantStatusCode: http.StatusBadRequest,
+					wantError:      "invalid_grant",
+				},
+				{
+					testID:    8,
+					testDesc:  "Failed - code of another client",
+					payload:   payload(url.Values{"client_id": {""}}),
+					basicAuth: [2]string{"mock-partner", "mock-secret"},
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-partner").Return(mockThirdPartyClient, nil)
+						mockRepository.EXPECT().ConsumeAuthorizationCode(gomock.Any(), hashSecretToken("mock-code")).Return(mockCode, nil)
+					},
+					wantStatusCode: http.StatusBadRequest,
+					wantError:      "invalid_grant",
+				},
+				{
+					testID:   9,
+					testDesc: "Failed - error RecordLogin",
+					payload:  payload(nil),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+						mockRepository.EXPECT().ConsumeAuthorizationCode(gomock.Any(), hashSecretToken("mock-code")).Return(mockCode, nil)
+						mockRepository.EXPECT().GetUserByID(gomock.Any(), int64(1)).Return(repository.User{ID: 1, Name: "budi", Phone: "+6280989444"}, nil)
+						mockRepository.EXPECT().RecordLogin(gomock.Any(), gomock.Any()).Return(errors.New("error"))
+					},
+					wantStatusCode: http.StatusInternalServerError,
+				},
+				{
+					testID:   10,
+					testDesc: "Success - public client with openid scope",
+					payload:  payload(nil),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+						mockRepository.EXPECT().ConsumeAuthorizationCode(gomock.Any(), hashSecretToken("mock-code")).Return(mockCode, nil)
+						mockRepository.EXPECT().GetUserByID(gomock.Any(), int64(1)).Return(repository.User{ID: 1, Name: "budi", Phone: "+6280989444"}, nil)
+						mockRepository.EXPECT().RecordLogin(gomock.Any(), gomock.Any()).Return(nil)
+					},
+					wantStatusCode: http.StatusOK,
+					wantIDToken:    true,
+					wantScope:      "openid profile",
+				},
+				{
+					testID:    11,
+					testDesc:  "Success - confidential client with HTTP Basic",
+					payload:   payload(url.Values{"client_id": {""}}),
+					basicAuth: [2]string{"mock-partner", "mock-secret"},
+					mockFunc: func() {
+						code := mockCode
+						code.ClientID, code.Scope = "mock-partner", ""
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-partner").Return(mockThirdPartyClient, nil)
+						mockRepository.EXPECT().ConsumeAuthorizationCode(gomock.Any(), hashSecretToken("mock-code")).Return(code, nil)
+						mockRepository.EXPECT().GetUserByID(gomock.Any(), int64(1)).Return(repository.User{ID: 1, Name: "budi", Phone: "+6280989444"}, nil)
+						mockRepository.EXPECT().RecordLogin(gomock.Any(), gomock.Any()).Return(nil)
+					},
+					wantStatusCode: http.StatusOK,
+				},
+				{
+					testID:   12,
+					testDesc: "Success - access token granted the scopes asked for",
+					payload:  payload(nil),
+					mockFunc: func() {
+						code := mockCode
+						code.Scope = "openid profile profile:read sessions:manage"
+						mockRepository.EXPECT().GetOAuthClient(gomock.Any(), "mock-app").Return(mockClient, nil)
+						mockRepository.EXPECT().ConsumeAuthorizationCode(gomock.Any(), hashSecretToken("mock-code")).Return(code, nil)
+						mockRepository.EXPECT().GetUserByID(gomock.Any(), int64(1)).Return(repository.User{ID: 1, Name: "budi", Phone: "+6280989444"}, nil)
+						mockRepository.EXPECT().RecordLogin(gomock.Any(), gomock.Any()).Return(nil)
+					},
+					wantStatusCode: http.StatusOK,
+					wantIDToken:    true,
+					wantScope:      "openid profile profile:read sessions:manage",
+				},
+			}
+
+			for _, tc := range testCases {
+				testDep := provideTest(t)
+				defer testDep()
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					tc.mockFunc()
+
+					e := echo.New()
+					req := httptest.NewRequest(echo.POST, "/oauth/token", strings.NewReader(tc.payload))
+					req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+					if tc.basicAuth[0] != "" {
+						req.SetBasicAuth(tc.basicAuth[0], tc.basicAuth[1])
+					}
+					rr := httptest.NewRecorder()
+					c := e.NewContext(req, rr)
+					_ = server.CreateToken(c)
+
+					// assert
+					So(rr.Code, ShouldEqual, tc.wantStatusCode)
+					So(rr.Header().Get("Cache-Control"), ShouldEqual, "no-store")
+					if tc.wantError != "" {
+						var resp oauth.OAuthError
+						_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+						So(resp.Error, ShouldEqual, tc.wantError)
+					}
+					if tc.wantStatusCode == http.StatusUnauthorized {
+						So(rr.Header().Get(echo.HeaderWWWAuthenticate), ShouldEqual, "Basic")
+					}
+					if tc.wantStatusCode != http.StatusOK {
+						return
+					}
+
+					var resp oauth.TokenResponse
+					_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+					So(resp.TokenType, ShouldEqual, "Bearer")
+					token, err := server.parseJWT(resp.AccessToken)
+					So(err, ShouldBeNil)
+					So(server.GetJWTClaims(token, "user_id"), ShouldEqual, "1")
+					// only the scopes asked for are granted to the access token.
+					So(server.GetJWTClaims(token, "scope"), ShouldEqual, tc.wantScope)
+					if !tc.wantIDToken {
+						So(resp.IdToken, ShouldBeNil)
+						return
+					}
+
+					// the ID token is verified with the published key.
+					So(resp.IdToken, ShouldNotBeNil)
+					idToken, err := jwt.Parse(*resp.IdToken, func(token *jwt.Token) (interface{}, error) {
+						return &mockIDTokenKey.PublicKey, nil
+					}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithIssuer("https://auth.example.com"), jwt.WithAudience("mock-app"))
+					So(err, ShouldBeNil)
+					So(idToken.Header["kid"], ShouldEqual, server.idTokenKeyID)
+					claims := idToken.Claims.(jwt.MapClaims)
+					So(claims["sub"], ShouldEqual, "1")
+					So(claims["nonce"], ShouldEqual, "mock-nonce")
+					So(claims["name"], ShouldEqual, "budi")
+					So(claims["auth_time"], ShouldEqual, float64(mockTime.Unix()))
+					// the phone scope was not granted.
+					So(claims, ShouldNotContainKey, "phone_number")
+				})
+			}
+		})
+	})
+}
+
+func TestGetUserinfo(t *testing.T) {
+	t.Run("TestGetUserinfo", func(t *testing.T) {
+		Convey("TestGetUserinfo", t, func(c C) {
+			testCases := []struct {
+				testID         int
+				testDesc       string
+				authorization  string
+				mockFunc       func()
+				wantStatusCode int
+				wantResp       oauth.Userinfo
+			}{
+				{
+					testID:         1,
+					testDesc:       "Failed - error ValidateJWT",
+					authorization:  mockRevokedAuthorization,
+					mockFunc:       func() {},
+					wantStatusCode: http.StatusUnauthorized,
+				},
+				{
+					testID:        2,
+					testDesc:      "Failed - user not found",
+					authorization: mockAuthorization,
+					mockFunc: func() {
+						mockRepository.EXPECT().GetUserByID(gomock.Any(), int64(17)).Return(repository.User{}, repository.ErrNotFound)
+					},
+					wantStatusCode: http.StatusNotFound,
+				},
+				{
+					testID:        3,
+					testDesc:      "Success - token without OpenID Connect scopes",
+					authorization: mockAuthorization,
+					mockFunc: func() {
+						mockRepository.EXPECT().GetUserByID(gomock.Any(), int64(17)).Return(repository.User{ID: 17, Name: "budi", Phone: "+6280989444"}, nil)
+					},
+					wantStatusCode: http.StatusOK,
+					wantResp:       oauth.Userinfo{Sub: "17"},
+				},
+				{
+					testID:        4,
+					testDesc:      "Success - token granted the profile scope",
+					authorization: signMockToken(jwt.MapClaims{"user_id": "17", "sid": "mock-session-id", "scope": "openid profile"}),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetUserByID(gomock.Any(), int64(17)).Return(repository.User{ID: 17, Name: "budi", Phone: "+6280989444"}, nil)
+					},
+					wantStatusCode: http.StatusOK,
+					wantResp:       oauth.Userinfo{Sub: "17", Name: strPtr("budi")},
+				},
+				{
+					testID:        5,
+					testDesc:      "Success - token granted the profile and phone scopes",
+					authorization: signMockToken(jwt.MapClaims{"user_id": "17", "sid": "mock-session-id", "scope": "openid profile phone profile:read"}),
+					mockFunc: func() {
+						mockRepository.EXPECT().GetUserByID(gomock.Any(), int64(17)).Return(repository.User{ID: 17, Name: "budi", Phone: "+6280989444"}, nil)
+					},
+					wantStatusCode: http.StatusOK,
+					wantResp:       oauth.Userinfo{Sub: "17", Name: strPtr("budi"), PhoneNumber: strPtr("+6280989444")},
+				},
+			}
+
+			for _, tc := range testCases {
+				testDep := provideTest(t)
+				defer testDep()
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					tc.mockFunc()
+
+					e := echo.New()
+					req := httptest.NewRequest(echo.GET, "/userinfo", nil)
+					req.Header.Set(echo.HeaderAuthorization, tc.authorization)
+					rr := httptest.NewRecorder()
+					c := e.NewContext(req, rr)
+					_ = server.GetUserinfo(c)
+
+					// assert
+					So(rr.Code, ShouldEqual, tc.wantStatusCode)
+					if tc.wantStatusCode != http.StatusOK {
+						return
+					}
+					var resp oauth.Userinfo
+					_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+					So(resp, ShouldResemble, tc.wantResp)
+				})
+			}
+		})
+	})
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	t.Run("TestJWKThumbprint", func(t *testing.T) {
+		Convey("TestJWKThumbprint", t, func(c C) {
+			// the example of RFC 7638 section 3.1.
+			n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+			So(err, ShouldBeNil)
+			key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+
+			So(jwkThumbprint(key), ShouldEqual, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs")
+		})
+	})
+}
@@ -0,0 +1,435 @@
+// This file contains the endpoints of version 2 of the API, see api_v2.yml.
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/SawitProRecruitment/UserService/avatar"
+	v2 "github.com/SawitProRecruitment/UserService/generated/v2"
+	"github.com/SawitProRecruitment/UserService/idempotency"
+	"github.com/SawitProRecruitment/UserService/logging"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// V2BaseURL is the path version 2 of the API is served under.
+const V2BaseURL = "/v2"
+
+// POST API create new user data.
+// http://localhost:1323/v2/users
+// Idempotency-Key is handled by the idempotency middleware.
+func (s *Server) CreateUser(c echo.Context, _ v2.CreateUserParams) error {
+	ctx := requestContext(c)
+	var payload v2.CreateUserRequest
+	err := c.Bind(&payload)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, "Invalid payload: failed to parse"))
+	}
+
+	user, err := s.registerUser(ctx, repository.RegisterUser{
+		Phone:    strings.TrimSpace(payload.Phone),
+		Name:     strings.TrimSpace(payload.Name),
+		Password: strings.TrimSpace(payload.Password),
+	})
+	var invalid validationError
+	switch {
+	case errors.As(err, &invalid):
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	case errors.Is(err, errPhoneExists):
+		return c.JSON(http.StatusConflict, errorResponse(c, err.Error()))
+	case err != nil:
+		return internalError(c, "failed to create user", err)
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, V2BaseURL+"/users/me")
+	return c.JSON(http.StatusCreated, newUserV2(user))
+}
+
+// GET API which return the user of the token.
+// http://localhost:1323/v2/users/me
+func (s *Server) GetMe(c echo.Context, params v2.GetMeParams) error {
+	ctx := requestContext(c)
+
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
+	if err != nil {
+		return unauthorized(c, err)
+	}
+
+	user, err := s.Repository.GetUserByID(ctx, caller.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, errorResponse(c, "user not found"))
+	}
+	if err != nil {
+		return internalError(c, "failed to get user", err)
+	}
+
+	// skip the body when client already has this version.
+	etag := formatETag(user.Version)
+	c.Response().Header().Set("ETag", etag)
+	if params.IfNoneMatch != nil && matchETag(*params.IfNoneMatch, etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.JSON(http.StatusOK, newUserV2(user))
+}
+
+// PATCH API responsible to update the user of the token.
+// http://localhost:1323/v2/users/me
+func (s *Server) UpdateMe(c echo.Context, params v2.UpdateMeParams) error {
+	ctx := requestContext(c)
+
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
+	if err != nil {
+		return unauthorized(c, err)
+	}
+
+	var payload v2.UpdateUserRequest
+	err = c.Bind(&payload)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, "Invalid payload: failed to parse"))
+	}
+
+	user, err := s.updateUser(ctx, caller, repository.UpdateUser{
+		Phone:       payload.Phone,
+		Name:        payload.Name,
+		Email:       payload.Email,
+		DisplayName: payload.DisplayName,
+		BirthDate:   payload.BirthDate,
+		Locale:      payload.Locale,
+		Timezone:    payload.Timezone,
+		AvatarURL:   payload.AvatarUrl,
+	}, params.IfMatch)
+	var invalid validationError
+	switch {
+	case errors.As(err, &invalid), errors.Is(err, errEmptyUpdate):
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	case errors.Is(err, errVersionMismatch):
+		return c.JSON(http.StatusPreconditionFailed, errorResponse(c, err.Error()))
+	case errors.Is(err, errPhoneExists), errors.Is(err, errEmailExists):
+		return c.JSON(http.StatusConflict, errorResponse(c, err.Error()))
+	case errors.Is(err, repository.ErrNotFound):
+		return c.JSON(http.StatusNotFound, errorResponse(c, "user not found"))
+	case err != nil:
+		return internalError(c, "failed to update user", err)
+	}
+
+	c.Response().Header().Set("ETag", formatETag(user.Version))
+	return c.JSON(http.StatusOK, newUserV2(user))
+}
+
+// PUT API responsible to upload the avatar of the user of the token.
+// http://localhost:1323/v2/users/me/avatar
+func (s *Server) UploadAvatar(c echo.Context, params v2.UploadAvatarParams) error {
+	ctx := requestContext(c)
+
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
+	if err != nil {
+		return unauthorized(c, err)
+	}
+
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, "Invalid payload: avatar file is required"))
+	}
+	tooLarge := fmt.Sprintf("avatar must be at most %d bytes", s.AvatarMaxBytes)
+	if file.Size > s.AvatarMaxBytes {
+		return c.JSON(http.StatusRequestEntityTooLarge, errorResponse(c, tooLarge))
+	}
+	f, err := file.Open()
+	if err != nil {
+		return internalError(c, "failed to read avatar", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, s.AvatarMaxBytes+1))
+	if err != nil {
+		return internalError(c, "failed to read avatar", err)
+	}
+	if int64(len(data)) > s.AvatarMaxBytes {
+		return c.JSON(http.StatusRequestEntityTooLarge, errorResponse(c, tooLarge))
+	}
+
+	user, err := s.uploadAvatar(ctx, caller, data, params.IfMatch)
+	switch {
+	case errors.Is(err, avatar.ErrUnsupportedFormat):
+		return c.JSON(http.StatusUnsupportedMediaType, errorResponse(c, err.Error()))
+	case errors.Is(err, avatar.ErrInvalidImage), errors.Is(err, avatar.ErrImageTooLarge):
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	case errors.Is(err, errVersionMismatch):
+		return c.JSON(http.StatusPreconditionFailed, errorResponse(c, err.Error()))
+	case errors.Is(err, repository.ErrNotFound):
+		return c.JSON(http.StatusNotFound, errorResponse(c, "user not found"))
+	case err != nil:
+		return internalError(c, "failed to upload avatar", err)
+	}
+
+	c.Response().Header().Set("ETag", formatETag(user.Version))
+	return c.JSON(http.StatusOK, newUserV2(user))
+}
+
+// POST API responsible to mail a new verification token to the email of the
+// user of the token.
+// http://localhost:1323/v2/users/me/email-verifications
+func (s *Server) CreateEmailVerification(c echo.Context) error {
+	ctx := requestContext(c)
+
+	// validate authorization and get user_id from token.
+	caller, err := s.authenticate(c)
+	if err != nil {
+		return unauthorized(c, err)
+	}
+
+	user, err := s.Repository.GetUserByID(ctx, caller.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, errorResponse(c, "user not found"))
+	}
+	if err != nil {
+		return internalError(c, "failed to get user", err)
+	}
+	if user.Email == nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, errNoEmail.Error()))
+	}
+	if user.EmailVerifiedAt != nil {
+		return c.JSON(http.StatusConflict, errorResponse(c, errEmailVerified.Error()))
+	}
+
+	if err = s.sendEmailVerification(ctx, user); err != nil {
+		return internalError(c, "failed to send email verification", err)
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+// POST API responsible to confirm an email with the token mailed to it.
+// http://localhost:1323/v2/email-verifications
+func (s *Server) VerifyEmail(c echo.Context) error {
+	ctx := requestContext(c)
+	var payload v2.VerifyEmailRequest
+	err := c.Bind(&payload)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, "Invalid payload: failed to parse"))
+	}
+
+	err = s.verifyEmail(ctx, payload.Token)
+	var invalid validationError
+	switch {
+	case errors.As(err, &invalid):
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	case errors.Is(err, errVerificationNotFound):
+		return c.JSON(http.StatusNotFound, errorResponse(c, err.Error()))
+	case errors.Is(err, errEmailExists):
+		return c.JSON(http.StatusConflict, errorResponse(c, err.Error()))
+	case err != nil:
+		return internalError(c, "failed to verify email", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// POST API responsible to log in user, opening a session.
+// http://localhost:1323/v2/sessions
+func (s *Server) CreateSession(c echo.Context) error {
+	ctx := requestContext(c)
+	var payload v2.CreateSessionRequest
+	err := c.Bind(&payload)
+	if err != nil {
+		s.Metrics.LoginFailed(metrics.LoginFailedInvalidPayload)
+		return c.JSON(http.StatusBadRequest, errorResponse(c, "Invalid payload: failed to parse"))
+	}
+
+	identifier := loginIdentifier(payload.Identifier, payload.Phone)
+	if strings.TrimSpace(identifier) == "" {
+		s.Metrics.LoginFailed(metrics.LoginFailedInvalidPayload)
+		return c.JSON(http.StatusBadRequest, errorResponse(c, errNoIdentifier.Error()))
+	}
+	scopes, err := parseLoginScope(payload.Scope)
+	if err != nil {
+		s.Metrics.LoginFailed(metrics.LoginFailedInvalidPayload)
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	}
+
+	// unknown identifier and wrong password are not told apart.
+	user, err := s.checkCredentials(ctx, identifier, payload.Password)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, errorResponse(c, errInvalidPassword.Error()))
+	}
+
+	sess, err := s.openSession(c, user, scopes)
+	if err != nil {
+		return internalError(c, "failed to open session", err)
+	}
+
+	return c.JSON(http.StatusCreated, v2.Session{
+		Id:        sess.ID,
+		UserId:    user.ID,
+		Token:     sess.Token,
+		ExpiresAt: sess.ExpiresAt,
+	})
+}
+
+// POST API issuing an API key acting as a user, only allowed to administrators.
+// http://localhost:1323/v2/admin/api-keys
+func (s *Server) CreateAPIKey(c echo.Context) error {
+	ctx := requestContext(c)
+
+	caller, err := s.authenticateAdmin(c)
+	if err != nil {
+		return unauthorized(c, err)
+	}
+
+	var payload v2.CreateAPIKeyRequest
+	err = c.Bind(&payload)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, "Invalid payload: failed to parse"))
+	}
+	payload.Name = strings.TrimSpace(payload.Name)
+	scopes, err := validateCreateAPIKey(payload)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorResponse(c, err.Error()))
+	}
+
+	_, err = s.Repository.GetUserByID(ctx, payload.UserId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, errorResponse(c, "user not found"))
+	}
+	if err != nil {
+		return internalError(c, "failed to get user", err)
+	}
+
+	id, err := newTokenID()
+	if err != nil {
+		return internalError(c, "failed to create API key", err)
+	}
+	secret, prefix, hash, err := newAPIKey()
+	if err != nil {
+		return internalError(c, "failed to create API key", err)
+	}
+	key, err := s.Repository.CreateAPIKey(ctx, repository.APIKey{
+		ID:        id,
+		Name:      payload.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		UserID:    payload.UserId,
+		Scopes:    scopes,
+		RateLimit: payload.RateLimit,
+		ExpiresAt: payload.ExpiresAt,
+		CreatedBy: &caller.UserID,
+	})
+	if err != nil {
+		return internalError(c, "failed to create API key", err)
+	}
+
+	// the key is shown once, it must not be kept for idempotent retries.
+	idempotency.DoNotStore(c)
+	resp := newAPIKeyV2(key)
+	return c.JSON(http.StatusCreated, v2.CreatedAPIKey{
+		Id:         resp.Id,
+		Name:       resp.Name,
+		Prefix:     resp.Prefix,
+		UserId:     resp.UserId,
+		Scopes:     resp.Scopes,
+		RateLimit:  resp.RateLimit,
+		ExpiresAt:  resp.ExpiresAt,
+		LastUsedAt: resp.LastUsedAt,
+		CreatedAt:  resp.CreatedAt,
+		RevokedAt:  resp.RevokedAt,
+		Key:        secret,
+	})
+}
+
+// GET API which return the API keys, only allowed to administrators.
+// http://localhost:1323/v2/admin/api-keys
+func (s *Server) ListAPIKeys(c echo.Context, params v2.ListAPIKeysParams) error {
+	ctx := requestContext(c)
+
+	_, err := s.authenticateAdmin(c)
+	if err != nil {
+		return unauthorized(c, err)
+	}
+
+	keys, err := s.Repository.ListAPIKeys(ctx, params.UserId)
+	if err != nil {
+		return internalError(c, "failed to list API keys", err)
+	}
+
+	resp := v2.APIKeyList{Data: make([]v2.APIKey, 0, len(keys))}
+	for _, key := range keys {
+		resp.Data = append(resp.Data, newAPIKeyV2(key))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// DELETE API responsible to revoke an API key, only allowed to administrators.
+// http://localhost:1323/v2/admin/api-keys/:id
+func (s *Server) RevokeAPIKey(c echo.Context, id string) error {
+	ctx := requestContext(c)
+
+	_, err := s.authenticateAdmin(c)
+	if err != nil {
+		return unauthorized(c, err)
+	}
+
+	err = s.Repository.RevokeAPIKey(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, errorResponse(c, "API key not found"))
+	}
+	if err != nil {
+		return internalError(c, "failed to revoke API key", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// newUserV2 return the version 2 representation of user.
+func newUserV2(user repository.User) v2.User {
+	resp := v2.User{
+		Id:            user.ID,
+		Phone:         user.Phone,
+		Name:          user.Name,
+		LoginCount:    user.LoginCount,
+		LastLoginAt:   user.LastLoginAt,
+		Email:         (*openapi_types.Email)(user.Email),
+		EmailVerified: user.Email != nil && user.EmailVerifiedAt != nil,
+		DisplayName:   user.DisplayName,
+		Locale:        user.Locale,
+		Timezone:      user.Timezone,
+		AvatarUrl:     user.AvatarURL,
+	}
+	if user.BirthDate != nil {
+		resp.BirthDate = &openapi_types.Date{Time: *user.BirthDate}
+	}
+	if len(user.AvatarThumbnails) > 0 {
+		thumbnails := make([]v2.AvatarThumbnail, len(user.AvatarThumbnails))
+		for i, thumbnail := range user.AvatarThumbnails {
+			thumbnails[i] = v2.AvatarThumbnail{Size: thumbnail.Size, Url: thumbnail.URL}
+		}
+		resp.AvatarThumbnails = &thumbnails
+	}
+	return resp
+}
+
+// unauthorized respond 401 asking for a bearer token, or 403 when the
+// caller is authenticated but not allowed the operation.
+func unauthorized(c echo.Context, err error) error {
+	if errors.Is(err, errInsufficientScope) || errors.Is(err, errNotAdmin) {
+		return c.JSON(http.StatusForbidden, errorResponse(c, err.Error()))
+	}
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+	return c.JSON(http.StatusUnauthorized, errorResponse(c, err.Error()))
+}
+
+// internalError log err and respond 500 with message, err is not exposed.
+func internalError(c echo.Context, message string, err error) error {
+	ctx := c.Request().Context()
+	logging.FromContext(ctx).ErrorContext(ctx, message, slog.Any("error", err))
+	return c.JSON(http.StatusInternalServerError, errorResponse(c, message))
+}
@@ -1,17 +1,46 @@
 package handler
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/SawitProRecruitment/UserService/avatar"
+	"github.com/SawitProRecruitment/UserService/common"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/logging"
+	"github.com/SawitProRecruitment/UserService/mail"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/SawitProRecruitment/UserService/oidc"
+	"github.com/SawitProRecruitment/UserService/repository"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword hash and salt password.
-func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+// tracer records spans for the expensive steps of handlers.
+var tracer = otel.Tracer("github.com/SawitProRecruitment/UserService/handler")
+
+// HashPassword hash and salt password with the given bcrypt cost.
+func HashPassword(ctx context.Context, password string, cost int) (string, error) {
+	_, span := tracer.Start(ctx, "HashPassword", trace.WithAttributes(attribute.Int("bcrypt.cost", cost)))
+	defer span.End()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	if err != nil {
 		return "", err
 	}
@@ -19,29 +48,161 @@ func HashPassword(password string) (string, error) {
 }
 
 // ComparePassword compare password and hashedPassword.
-func ComparePassword(password string, hashedPassword string) error {
+func ComparePassword(ctx context.Context, password string, hashedPassword string) error {
+	_, span := tracer.Start(ctx, "ComparePassword")
+	defer span.End()
+
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
 
-// ValidateJWT validate jwt.
-func (s *Server) ValidateJWT(accessToken string) (token *jwt.Token, err error) {
+// listLimit return page size from optional limit parameter.
+func listLimit(limit *int) (int, error) {
+	if limit == nil {
+		return defaultListLimit, nil
+	}
+	if *limit < 1 || *limit > maxListLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxListLimit)
+	}
+	return *limit, nil
+}
+
+// trimSpace trim optional string, nil stays nil.
+func trimSpace(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*s)
+	return &trimmed
+}
+
+// formatETag format user version as strong entity tag.
+func formatETag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// parseETag parse strong entity tag produced by formatETag.
+func parseETag(etag string) (version int64, err error) {
+	etag = strings.TrimSpace(etag)
+	if len(etag) < 2 || !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		return 0, errors.New("invalid etag")
+	}
+
+	return strconv.ParseInt(etag[1:len(etag)-1], 10, 64)
+}
+
+// matchETag check whether If-None-Match header value matches etag.
+// Uses weak comparison as required for If-None-Match.
+func matchETag(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateJWT validate jwt and the session it was issued for.
+// Tokens of a revoked session are rejected.
+func (s *Server) ValidateJWT(ctx context.Context, accessToken string) (token *jwt.Token, err error) {
 	accessToken, err = getToken(accessToken)
 	if err != nil {
+		s.Metrics.TokenValidationFailed(metrics.TokenMalformed)
 		return
 	}
 
-	token, err = jwt.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
+	token, err = s.parseJWT(accessToken)
+	if err != nil {
+		s.Metrics.TokenValidationFailed(metrics.TokenInvalid)
+		return
+	}
+
+	userID, err := strconv.ParseInt(s.GetJWTClaims(token, "user_id"), 10, 64)
+	if err != nil {
+		s.Metrics.TokenValidationFailed(metrics.TokenInvalid)
+		return nil, errors.New("invalid token")
+	}
+	sessionID := s.GetJWTClaims(token, "sid")
+	if sessionID == "" {
+		s.Metrics.TokenValidationFailed(metrics.TokenInvalid)
+		return nil, errors.New("invalid token")
+	}
+
+	// check session is still active and mark it as seen.
+	err = s.Repository.TouchUserSession(ctx, sessionID, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		s.Metrics.TokenValidationFailed(metrics.TokenSessionRevoked)
+		return nil, errors.New("session has been revoked")
+	}
+	if err != nil {
+		s.Metrics.TokenValidationFailed(metrics.TokenError)
+		return nil, err
+	}
+
+	return
+}
+
+// tokenContextKey caches the validation of the token of a request,
+// idempotency and the handler share it.
+const tokenContextKey = "handler.token"
+
+// tokenLookup is the validated token of a request, or why it was rejected.
+type tokenLookup struct {
+	token *jwt.Token
+	err   error
+}
+
+// requestToken return the token of the authorization header, validated once
+// per request by ValidateJWT.
+func (s *Server) requestToken(c echo.Context) (*jwt.Token, error) {
+	if lookup, ok := c.Get(tokenContextKey).(tokenLookup); ok {
+		return lookup.token, lookup.err
+	}
+	var lookup tokenLookup
+	lookup.token, lookup.err = s.ValidateJWT(c.Request().Context(), c.Request().Header.Get(echo.HeaderAuthorization))
+	c.Set(tokenContextKey, lookup)
+	return lookup.token, lookup.err
+}
+
+// parseJWT verify the signature and expiry of accessToken.
+func (s *Server) parseJWT(accessToken string) (*jwt.Token, error) {
+	return jwt.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
 		}
 
 		return []byte(s.SecretKey), nil
 	})
+}
+
+// TokenUserID return the user of a validly signed token, empty otherwise.
+// The session is not checked, the handler still authenticates the request.
+func (s *Server) TokenUserID(c echo.Context) string {
+	accessToken, err := getToken(c.Request().Header.Get(echo.HeaderAuthorization))
 	if err != nil {
-		return
+		return ""
+	}
+	token, err := s.parseJWT(accessToken)
+	if err != nil {
+		return ""
 	}
+	return s.GetJWTClaims(token, "user_id")
+}
 
-	return
+// IdempotencyScope return the owner of an Idempotency-Key: the API key or
+// the session of the token authorized for the operation, otherwise the
+// client ip. Replays are served before the handler runs, so keys and tokens
+// are checked like the handler does, revoked sessions included.
+func (s *Server) IdempotencyScope(c echo.Context) string {
+	if key, ok := s.activeAPIKey(c); ok {
+		return "apikey:" + key.ID
+	}
+	if _, ok := apiKeyCredentials(c.Request().Header.Get(echo.HeaderAuthorization)); !ok {
+		if p, err := s.authenticateToken(c); err == nil {
+			return "session:" + p.SessionID
+		}
+	}
+	return "ip:" + c.RealIP()
 }
 
 // getToken split authorization and return auth.
@@ -54,18 +215,721 @@ func getToken(auth string) (string, error) {
 	return jwtToken[1], nil
 }
 
-// GenerateJWT generate JWT token.
-func (s *Server) GenerateJWT(userID int64) (token string, err error) {
-	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+// newTokenID generate random id identifying an issued token or session.
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GenerateJWT generate JWT token for a session, granted scopes.
+func (s *Server) GenerateJWT(userID int64, sessionID string, tokenID string, scopes []string) (token string, err error) {
+	claims := jwt.MapClaims{
 		"user_id": fmt.Sprint(userID),
-	})
+		"sid":     sessionID,
+		"jti":     tokenID,
+		"scope":   strings.Join(scopes, " "),
+	}
+	if s.TokenTTL > 0 {
+		now := time.Now()
+		claims["iat"] = now.Unix()
+		claims["exp"] = now.Add(s.TokenTTL).Unix()
+	}
 
-	token, err = claims.SignedString([]byte(s.SecretKey))
+	token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.SecretKey))
 
 	return
 }
 
-// GetJWTClaims jwt claims by key.
+// Scopes of tokens and API keys, the security of an operation in the spec
+// lists the ones it requires.
+const (
+	ScopeProfileRead    = "profile:read"
+	ScopeProfileWrite   = "profile:write"
+	ScopeSessionsManage = "sessions:manage"
+
+	// ScopeIdentitiesManage allows linking and unlinking external identities,
+	// which log the user in. It is granted to logins, never to OAuth clients
+	// or to API keys.
+	ScopeIdentitiesManage = "identities:manage"
+
+	// ScopeAdminAPIKeys allows an administrator to manage API keys. It is
+	// only granted to a login asking for it, never by default, to OAuth
+	// clients or to API keys.
+	ScopeAdminAPIKeys = "admin:api_keys"
+)
+
+// apiScopes are the scopes of the API shared by logins, OAuth clients and
+// API keys, in the order they are listed.
+var apiScopes = []string{ScopeProfileRead, ScopeProfileWrite, ScopeSessionsManage}
+
+// sessionScopes are the scopes of a login not asking for scopes.
+var sessionScopes = []string{ScopeProfileRead, ScopeProfileWrite, ScopeSessionsManage, ScopeIdentitiesManage}
+
+// loginScopes are the scopes a login can ask for, in the order they are
+// granted.
+var loginScopes = []string{ScopeProfileRead, ScopeProfileWrite, ScopeSessionsManage, ScopeIdentitiesManage, ScopeAdminAPIKeys}
+
+var errInsufficientScope = errors.New("insufficient scope")
+
+// parseLoginScope return the scopes of the space separated scope of a login,
+// in the order of loginScopes, sessionScopes when empty. An unknown scope is
+// a validationError.
+func parseLoginScope(scope *string) ([]string, error) {
+	if scope == nil || strings.TrimSpace(*scope) == "" {
+		return slices.Clone(sessionScopes), nil
+	}
+	requested := strings.Fields(*scope)
+	var errMsg []string
+	for _, s := range requested {
+		if !slices.Contains(loginScopes, s) {
+			errMsg = append(errMsg, fmt.Sprintf("unknown scope %q", s))
+		}
+	}
+	if len(errMsg) > 0 {
+		return nil, validationError(errMsg)
+	}
+	var scopes []string
+	for _, s := range loginScopes {
+		if slices.Contains(requested, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, nil
+}
+
+// tokenScopes return the scopes granted to token. Tokens issued before
+// scopes existed have no scope claim and keep sessionScopes until they expire.
+func (s *Server) tokenScopes(token *jwt.Token) []string {
+	claims, _ := token.Claims.(jwt.MapClaims)
+	scope, ok := claims["scope"].(string)
+	if !ok {
+		return slices.Clone(sessionScopes)
+	}
+	return strings.Fields(scope)
+}
+
+// checkScopes return errInsufficientScope when granted lacks one of the
+// required scopes.
+func checkScopes(granted []string, required []string) error {
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			return fmt.Errorf("%w: %s is required", errInsufficientScope, scope)
+		}
+	}
+	return nil
+}
+
+// A principal represents the authenticated caller of a request.
+type principal struct {
+	UserID    int64
+	SessionID string
+	// APIKeyID is the key authenticating the request, empty for tokens.
+	APIKeyID string
+	// Scopes are the scopes granted to the token or key.
+	Scopes []string
+}
+
+// authenticate validate authorization header and return the caller from
+// token, or from API key when the header has the ApiKey scheme. The token
+// or key must carry the scopes the operation requires of it, set on the
+// context by the generated wrapper from the security of the spec.
+func (s *Server) authenticate(c echo.Context) (p principal, err error) {
+	if _, ok := apiKeyCredentials(c.Request().Header.Get(echo.HeaderAuthorization)); ok {
+		return s.authenticateAPIKey(c)
+	}
+	return s.authenticateToken(c)
+}
+
+// authenticateToken validate the token of the request and its session, see
+// authenticate.
+func (s *Server) authenticateToken(c echo.Context) (p principal, err error) {
+	token, err := s.requestToken(c)
+	if err != nil {
+		return p, err
+	}
+
+	p.UserID, err = strconv.ParseInt(s.GetJWTClaims(token, "user_id"), 10, 64)
+	if err != nil {
+		return p, err
+	}
+	p.SessionID = s.GetJWTClaims(token, "sid")
+	p.Scopes = s.tokenScopes(token)
+
+	if required, ok := c.Get(generated.BearerAuthScopes).([]string); ok {
+		if err := checkScopes(p.Scopes, required); err != nil {
+			return p, err
+		}
+	}
+
+	return p, nil
+}
+
+// requestContext return request context carrying client info for user events.
+// The user agent is made valid UTF-8, which Postgres requires of text.
+func requestContext(c echo.Context) context.Context {
+	return repository.WithClientInfo(c.Request().Context(), repository.ClientInfo{
+		IP:        c.RealIP(),
+		UserAgent: strings.ToValidUTF8(c.Request().UserAgent(), ""),
+	})
+}
+
+// errorResponse return error body tagged with the request id, if any.
+func errorResponse(c echo.Context, message string) generated.ErrorResponse {
+	return common.ErrorResponse(c, message)
+}
+
+// GetJWTClaims jwt claims by key, empty when the claim is missing or not a string.
 func (s *Server) GetJWTClaims(token *jwt.Token, key string) string {
-	return token.Claims.(jwt.MapClaims)[key].(string)
+	claims, _ := token.Claims.(jwt.MapClaims)
+	value, _ := claims[key].(string)
+	return value
+}
+
+var (
+	errPhoneExists     = errors.New("phone number already exist")
+	errInvalidPassword = errors.New("incorrect password or identifier")
+	errEmptyUpdate     = errors.New("Invalid payload: at least one field must be provided")
+	errVersionMismatch = errors.New("If-Match does not match current user version")
+	errEmailExists     = errors.New("email already exist")
+	errNoEmail         = errors.New("user has no email")
+	errNoIdentifier    = errors.New("Invalid payload: identifier is required")
+	errEmailVerified   = errors.New("email is already verified")
+	// errVerificationNotFound does not tell an unknown token from an
+	// expired or used one.
+	errVerificationNotFound = errors.New("verification token is invalid or expired")
+	errInvalidIDToken       = errors.New("invalid ID token")
+	errIdentityEmailExists  = errors.New("email belongs to another user, log in and link the identity instead")
+	errIdentityLinked       = errors.New("identity is already linked, or the user has one of the provider")
+	errIdentityNotFound     = errors.New("identity not found")
+	errLastLoginMethod      = errors.New("identity is the only way the user can log in")
+)
+
+// validationError lists the rules an input breaks.
+type validationError []string
+
+func (e validationError) Error() string {
+	return strings.Join(e, ", ")
+}
+
+// registerUser validate input and store the user with a hashed password.
+func (s *Server) registerUser(ctx context.Context, input repository.RegisterUser) (repository.User, error) {
+	// validate input with given rules.
+	if errs := input.Validate(); len(errs) > 0 {
+		return repository.User{}, validationError(errs)
+	}
+
+	// check whether user phone exist.
+	if _, err := s.Repository.GetUserByPhone(ctx, input.Phone); err == nil {
+		return repository.User{}, errPhoneExists
+	}
+
+	// hash and salt user password.
+	input.Password, _ = HashPassword(ctx, input.Password, s.BcryptCost)
+
+	// create user data.
+	user, err := s.Repository.Createuser(ctx, input)
+	if errors.Is(err, repository.ErrDuplicateData) {
+		return repository.User{}, errPhoneExists
+	}
+	if err != nil {
+		return repository.User{}, err
+	}
+
+	s.Metrics.UserRegistered()
+	user.Phone = input.Phone
+	user.Name = input.Name
+	return user, nil
+}
+
+// loginIdentifier return the identifier of a login payload, identifier wins
+// over the deprecated phone.
+func loginIdentifier(identifier *string, phone *string) string {
+	if identifier != nil && strings.TrimSpace(*identifier) != "" {
+		return *identifier
+	}
+	if phone != nil {
+		return *phone
+	}
+	return ""
+}
+
+// checkCredentials return the user of identifier when password matches.
+// Every identifier type goes through the same steps and an unknown user is
+// compared against a dummy hash, so failures take as long and return the
+// same errInvalidPassword whether the user exists or not. Failures are
+// counted, logged and recorded as user events.
+func (s *Server) checkCredentials(ctx context.Context, identifier string, password string) (repository.User, error) {
+	// check whether the user of identifier exist.
+	id, err := common.ParseIdentifier(identifier)
+	var user repository.User
+	if err == nil {
+		user, err = s.Resolver.ResolveUser(ctx, id)
+	}
+	if err != nil {
+		_ = ComparePassword(ctx, password, s.dummyHash)
+		s.Metrics.LoginFailed(repository.LoginFailedUnknownUser)
+		logging.FromContext(ctx).InfoContext(ctx, "login failed",
+			slog.String("identifier_type", string(id.Type)), slog.String("identifier", id.Value),
+			slog.String("reason", repository.LoginFailedUnknownUser))
+		_ = s.Repository.CreateUserEvent(ctx, repository.UserEvent{
+			Type:    repository.UserEventLoginFailed,
+			Details: repository.UserEventDetails{Reason: repository.LoginFailedUnknownUser},
+		})
+		return repository.User{}, errInvalidPassword
+	}
+
+	// compare user password. A user signed up with an identity has none, the
+	// dummy hash is compared so the login takes as long as a wrong password.
+	if user.Password == "" {
+		_ = ComparePassword(ctx, password, s.dummyHash)
+		err = errInvalidPassword
+	} else {
+		err = ComparePassword(ctx, password, user.Password)
+	}
+	if err != nil {
+		s.Metrics.LoginFailed(repository.LoginFailedInvalidPassword)
+		logging.FromContext(ctx).InfoContext(ctx, "login failed",
+			slog.String("identifier_type", string(id.Type)), slog.String("identifier", id.Value),
+			slog.String("reason", repository.LoginFailedInvalidPassword))
+		_ = s.Repository.CreateUserEvent(ctx, repository.UserEvent{
+			UserID:  &user.ID,
+			Type:    repository.UserEventLoginFailed,
+			Details: repository.UserEventDetails{Reason: repository.LoginFailedInvalidPassword},
+		})
+		return repository.User{}, errInvalidPassword
+	}
+
+	return user, nil
+}
+
+// A session is opened by a successful login.
+type session struct {
+	ID    string
+	Token string
+	// ExpiresAt is nil when the token never expires.
+	ExpiresAt *time.Time
+}
+
+// openSession issue a token granted scopes for a new session of user and
+// record the login.
+func (s *Server) openSession(c echo.Context, user repository.User, scopes []string) (sess session, err error) {
+	defer func() {
+		if err != nil {
+			s.Metrics.LoginFailed(metrics.LoginFailedError)
+		}
+	}()
+
+	// generate JWT for a new session.
+	sess.ID, err = newTokenID()
+	if err != nil {
+		return session{}, err
+	}
+	tokenID, err := newTokenID()
+	if err != nil {
+		return session{}, err
+	}
+	sess.Token, err = s.GenerateJWT(user.ID, sess.ID, tokenID, scopes)
+	if err != nil {
+		return session{}, err
+	}
+	if token, err := s.parseJWT(sess.Token); err == nil {
+		if exp, _ := token.Claims.GetExpirationTime(); exp != nil {
+			sess.ExpiresAt = &exp.Time
+		}
+	}
+
+	// open the session and record the login in user login history.
+	err = s.Repository.RecordLogin(requestContext(c), repository.UserLogin{
+		UserID:     user.ID,
+		TokenID:    tokenID,
+		SessionID:  sess.ID,
+		DeviceName: common.DeviceName(c.Request().UserAgent()),
+	})
+	if err != nil {
+		return session{}, err
+	}
+
+	s.Metrics.LoginSucceeded()
+	return sess, nil
+}
+
+// updateUser apply the present fields of a partial update to the user of
+// caller. ifMatch, when set, must match the current version of the user.
+// A verification token is mailed to a new email.
+func (s *Server) updateUser(ctx context.Context, caller principal, input repository.UpdateUser, ifMatch *string) (repository.User, error) {
+	input.ID = caller.UserID
+	input.Phone = trimSpace(input.Phone)
+	input.Name = trimSpace(input.Name)
+	input.DisplayName = trimSpace(input.DisplayName)
+	input.BirthDate = trimSpace(input.BirthDate)
+	input.Timezone = trimSpace(input.Timezone)
+	input.AvatarURL = trimSpace(input.AvatarURL)
+	if input.Email != nil {
+		email := common.NormalizeEmail(*input.Email)
+		input.Email = &email
+	}
+	if input.Locale != nil {
+		locale := common.NormalizeLocale(strings.TrimSpace(*input.Locale))
+		input.Locale = &locale
+	}
+	if input.IsEmpty() {
+		return repository.User{}, errEmptyUpdate
+	}
+
+	version, err := parseIfMatch(ifMatch)
+	if err != nil {
+		return repository.User{}, err
+	}
+	input.Version = version
+
+	// validate present fields with given rules.
+	if errs := input.Validate(); len(errs) > 0 {
+		return repository.User{}, validationError(errs)
+	}
+
+	// a new avatar url replaces the uploaded avatar, if any.
+	var previous []repository.AvatarThumbnail
+	if input.AvatarURL != nil && s.Blobs != nil {
+		current, err := s.Repository.GetUserByID(ctx, input.ID)
+		if err != nil {
+			return repository.User{}, err
+		}
+		previous = current.AvatarThumbnails
+	}
+
+	// process update user data.
+	user, err := s.Repository.UpdateUser(ctx, input)
+	switch {
+	case errors.Is(err, repository.ErrVersionConflict):
+		return repository.User{}, errVersionMismatch
+	case errors.Is(err, repository.ErrDuplicateEmail):
+		return repository.User{}, errEmailExists
+	case errors.Is(err, repository.ErrDuplicateData):
+		return repository.User{}, errPhoneExists
+	case err != nil:
+		return repository.User{}, err
+	}
+	s.deleteAvatarBlobs(ctx, previous)
+
+	// the update succeeded, a mail that cannot be sent is only logged and
+	// the user can ask for a new one.
+	if input.Email != nil && user.Email != nil && user.EmailVerifiedAt == nil {
+		if err = s.sendEmailVerification(ctx, user); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "failed to send email verification", slog.Any("error", err))
+		}
+	}
+	return user, nil
+}
+
+// parseIfMatch return the version an If-Match header asks for, nil when
+// the header is absent or "*" which matches any version.
+func parseIfMatch(ifMatch *string) (*int64, error) {
+	if ifMatch == nil || strings.TrimSpace(*ifMatch) == "*" {
+		return nil, nil
+	}
+	version, err := parseETag(*ifMatch)
+	if err != nil {
+		return nil, errVersionMismatch
+	}
+	return &version, nil
+}
+
+// uploadAvatar resize the image in data to thumbnails, store them and set
+// them as the avatar of the user of caller, replacing the uploaded avatar
+// stored before. ifMatch, when set, must match the current version of the user.
+func (s *Server) uploadAvatar(ctx context.Context, caller principal, data []byte, ifMatch *string) (repository.User, error) {
+	if s.Blobs == nil {
+		return repository.User{}, errors.New("no blob store configured")
+	}
+	version, err := parseIfMatch(ifMatch)
+	if err != nil {
+		return repository.User{}, err
+	}
+
+	current, err := s.Repository.GetUserByID(ctx, caller.UserID)
+	if err != nil {
+		return repository.User{}, err
+	}
+	// checked before storing anything, the update checks it again.
+	if version != nil && *version != current.Version {
+		return repository.User{}, errVersionMismatch
+	}
+
+	thumbnails, err := processAvatar(ctx, data)
+	if err != nil {
+		return repository.User{}, err
+	}
+
+	// a new prefix per upload, so cached thumbnails are never stale.
+	prefix := make([]byte, 8)
+	if _, err = rand.Read(prefix); err != nil {
+		return repository.User{}, err
+	}
+	stored := make([]repository.AvatarThumbnail, 0, len(thumbnails))
+	for _, thumbnail := range thumbnails {
+		key := fmt.Sprintf("avatars/%d/%s/%d.%s", caller.UserID, hex.EncodeToString(prefix), thumbnail.Size, thumbnail.Ext)
+		if err = s.Blobs.Put(ctx, key, thumbnail.Data, thumbnail.ContentType); err != nil {
+			s.deleteAvatarBlobs(ctx, stored)
+			return repository.User{}, fmt.Errorf("store avatar: %w", err)
+		}
+		stored = append(stored, repository.AvatarThumbnail{Size: thumbnail.Size, URL: s.Blobs.URL(key), Key: key})
+	}
+
+	// the largest thumbnail is the avatar.
+	user, err := s.Repository.UpdateUser(ctx, repository.UpdateUser{
+		ID:               caller.UserID,
+		AvatarURL:        &stored[0].URL,
+		AvatarThumbnails: stored,
+		Version:          version,
+	})
+	if err != nil {
+		s.deleteAvatarBlobs(ctx, stored)
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return repository.User{}, errVersionMismatch
+		}
+		return repository.User{}, err
+	}
+	s.deleteAvatarBlobs(ctx, current.AvatarThumbnails)
+	return user, nil
+}
+
+// processAvatar resize the image in data to the avatar thumbnails.
+func processAvatar(ctx context.Context, data []byte) ([]avatar.Thumbnail, error) {
+	_, span := tracer.Start(ctx, "processAvatar", trace.WithAttributes(attribute.Int("avatar.bytes", len(data))))
+	defer span.End()
+
+	return avatar.Process(data, avatar.DefaultSizes)
+}
+
+// deleteAvatarBlobs delete the blobs of thumbnails no longer used. Failures
+// are only logged, the user is already updated.
+func (s *Server) deleteAvatarBlobs(ctx context.Context, thumbnails []repository.AvatarThumbnail) {
+	for _, thumbnail := range thumbnails {
+		if err := s.Blobs.Delete(ctx, thumbnail.Key); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "failed to delete avatar",
+				slog.String("key", thumbnail.Key), slog.Any("error", err))
+		}
+	}
+}
+
+// newSecretToken generate random token handed out once, like the token
+// mailed to confirm an email or an authorization code, and the hash it is
+// stored by.
+func newSecretToken() (token string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashSecretToken(token), nil
+}
+
+// hashSecretToken return the hex sha256 of token, only the hash is stored.
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sendEmailVerification mail a new verification token to the email of user,
+// the token mailed before is no longer valid. Nothing is sent without Mailer.
+func (s *Server) sendEmailVerification(ctx context.Context, user repository.User) error {
+	if s.Mailer == nil || user.Email == nil {
+		return nil
+	}
+
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = s.Repository.CreateEmailVerification(ctx, repository.EmailVerification{
+		TokenHash: hash,
+		UserID:    user.ID,
+		Email:     *user.Email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.EmailVerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(ctx, mail.Message{
+		To:      *user.Email,
+		Subject: "Verify your email",
+		Body:    s.verificationBody(token),
+	})
+}
+
+// verificationBody return the mail body carrying token, as a link when
+// EmailVerificationURL is set.
+func (s *Server) verificationBody(token string) string {
+	expiry := s.EmailVerificationTTL.String()
+	if s.EmailVerificationURL == "" {
+		return fmt.Sprintf("Confirm your email with this token:\n\n%s\n\nThe token expires in %s.\n", token, expiry)
+	}
+
+	link, _ := url.Parse(s.EmailVerificationURL)
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return fmt.Sprintf("Confirm your email by opening this link:\n\n%s\n\nThe link expires in %s.\n", link, expiry)
+}
+
+// verifyEmail confirm the email of the user the token was mailed to.
+func (s *Server) verifyEmail(ctx context.Context, token string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return validationError{"token is required"}
+	}
+
+	_, err := s.Repository.ConfirmEmailVerification(ctx, hashSecretToken(token))
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return errVerificationNotFound
+	case errors.Is(err, repository.ErrDuplicateEmail):
+		return errEmailExists
+	}
+	return err
+}
+
+// verifyIDToken return the claims of the ID token of payload. A token that
+// is invalid or of an unknown provider returns errInvalidIDToken.
+func (s *Server) verifyIDToken(ctx context.Context, payload generated.OIDCLoginRequest) (oidc.Claims, error) {
+	var nonce string
+	if payload.Nonce != nil {
+		nonce = *payload.Nonce
+	}
+
+	claims, err := s.IdentityVerifier.Verify(ctx, strings.TrimSpace(payload.IdToken), nonce)
+	if errors.Is(err, oidc.ErrInvalidToken) || errors.Is(err, oidc.ErrUnknownIssuer) {
+		logging.FromContext(ctx).InfoContext(ctx, "invalid id token", slog.String("error", err.Error()))
+		return oidc.Claims{}, errInvalidIDToken
+	}
+	return claims, err
+}
+
+// identityEmail return the normalized email of claims, nil when it has none.
+func identityEmail(claims oidc.Claims) *string {
+	email := common.NormalizeEmail(claims.Email)
+	if email == "" {
+		return nil
+	}
+	return &email
+}
+
+// identityName return the name of a user signing up with claims, the local
+// part of the email or the provider name when the token has no usable name.
+func identityName(claims oidc.Claims) string {
+	name := strings.TrimSpace(claims.Name)
+	if len(common.ValidateName(name)) > 0 {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if len(name) < 3 {
+		name = claims.Provider + " user"
+	}
+	// cut a long name at a rune boundary.
+	for len(name) > 60 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// identityUser return the user linked to the identity of claims, signing up
+// a user when it is not linked yet. created reports whether the user is
+// new. A verified email of another user returns errIdentityEmailExists, the
+// identity is not linked to that user without its password.
+func (s *Server) identityUser(ctx context.Context, claims oidc.Claims) (user repository.User, created bool, err error) {
+	identity, err := s.Repository.GetUserIdentity(ctx, claims.Provider, claims.Subject)
+	if err == nil {
+		user, err = s.Repository.GetUserByID(ctx, identity.UserID)
+		return user, false, err
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return repository.User{}, false, err
+	}
+
+	input := repository.RegisterIdentityUser{
+		Name: identityName(claims),
+		Identity: repository.UserIdentity{
+			Provider: claims.Provider,
+			Subject:  claims.Subject,
+			Email:    identityEmail(claims),
+		},
+	}
+	if claims.EmailVerified {
+		input.Email = input.Identity.Email
+	}
+	if input.Email != nil {
+		// pending emails of other users do not count, they are not proven
+		// to be theirs.
+		_, err := s.Repository.GetUserByEmail(ctx, *input.Email)
+		switch {
+		case err == nil:
+			return repository.User{}, false, errIdentityEmailExists
+		case !errors.Is(err, repository.ErrNotFound):
+			return repository.User{}, false, err
+		}
+	}
+
+	user, err = s.Repository.CreateIdentityUser(ctx, input)
+	switch {
+	case errors.Is(err, repository.ErrDuplicateEmail):
+		return repository.User{}, false, errIdentityEmailExists
+	case errors.Is(err, repository.ErrDuplicateData):
+		// the identity signed up concurrently.
+		identity, err = s.Repository.GetUserIdentity(ctx, claims.Provider, claims.Subject)
+		if err != nil {
+			return repository.User{}, false, err
+		}
+		user, err = s.Repository.GetUserByID(ctx, identity.UserID)
+		return user, false, err
+	case err != nil:
+		return repository.User{}, false, err
+	}
+
+	s.Metrics.UserRegistered()
+	return user, true, nil
+}
+
+// unlinkIdentity remove the identity of provider from the user of caller,
+// unless it is the only way the user can log in.
+func (s *Server) unlinkIdentity(ctx context.Context, caller principal, provider string) error {
+	user, err := s.Repository.GetUserByID(ctx, caller.UserID)
+	if err != nil {
+		return err
+	}
+	identities, err := s.Repository.ListUserIdentities(ctx, caller.UserID)
+	if err != nil {
+		return err
+	}
+
+	linked := slices.ContainsFunc(identities, func(identity repository.UserIdentity) bool {
+		return identity.Provider == provider
+	})
+	if !linked {
+		return errIdentityNotFound
+	}
+	// a user signed up with an identity has no password.
+	if user.Password == "" && len(identities) == 1 {
+		return errLastLoginMethod
+	}
+
+	err = s.Repository.DeleteUserIdentity(ctx, caller.UserID, provider)
+	if errors.Is(err, repository.ErrNotFound) {
+		return errIdentityNotFound
+	}
+	return err
+}
+
+// newUserIdentityResponse map an identity to its API representation.
+func newUserIdentityResponse(identity repository.UserIdentity) generated.UserIdentity {
+	return generated.UserIdentity{
+		Provider:  identity.Provider,
+		Email:     identity.Email,
+		CreatedAt: identity.CreatedAt,
+	}
 }
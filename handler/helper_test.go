@@ -0,0 +1,429 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestGenerateJWT(t *testing.T) {
+	t.Run("TestGenerateJWT", func(t *testing.T) {
+		Convey("TestGenerateJWT", t, func(c C) {
+			testCases := []struct {
+				testID   int
+				testDesc string
+				tokenTTL time.Duration
+				wantExp  bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Success - without ttl token never expires",
+					tokenTTL: 0,
+					wantExp:  false,
+				},
+				{
+					testID:   2,
+					testDesc: "Success - with ttl token expires",
+					tokenTTL: time.Hour,
+					wantExp:  true,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					s := NewServer(NewServerOptions{
+						SecretKey: "sawitpro",
+						TokenTTL:  tc.tokenTTL,
+					})
+
+					token, err := s.GenerateJWT(17, "mock-session-id", "mock-token-id", []string{ScopeProfileRead, ScopeSessionsManage})
+					So(err, ShouldBeNil)
+
+					parsed, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) {
+						return []byte("sawitpro"), nil
+					})
+					So(err, ShouldBeNil)
+					So(s.GetJWTClaims(parsed, "sid"), ShouldEqual, "mock-session-id")
+					So(s.GetJWTClaims(parsed, "scope"), ShouldEqual, "profile:read sessions:manage")
+
+					exp, err := parsed.Claims.GetExpirationTime()
+					So(err, ShouldBeNil)
+					So(exp != nil, ShouldEqual, tc.wantExp)
+					if tc.wantExp {
+						So(exp.Time, ShouldHappenWithin, time.Minute, time.Now().Add(tc.tokenTTL))
+					}
+				})
+			}
+		})
+	})
+}
+
+func TestParseLoginScope(t *testing.T) {
+	t.Run("TestParseLoginScope", func(t *testing.T) {
+		Convey("TestParseLoginScope", t, func(c C) {
+			testCases := []struct {
+				testID     int
+				testDesc   string
+				scope      *string
+				wantScopes []string
+				wantErr    bool
+			}{
+				{
+					testID:     1,
+					testDesc:   "Success - no scope grants the session scopes",
+					wantScopes: []string{ScopeProfileRead, ScopeProfileWrite, ScopeSessionsManage, ScopeIdentitiesManage},
+				},
+				{
+					testID:     2,
+					testDesc:   "Success - blank scope grants the session scopes",
+					scope:      strPtr(" "),
+					wantScopes: []string{ScopeProfileRead, ScopeProfileWrite, ScopeSessionsManage, ScopeIdentitiesManage},
+				},
+				{
+					testID:     3,
+					testDesc:   "Success - scopes are granted once in their order",
+					scope:      strPtr("sessions:manage profile:read sessions:manage"),
+					wantScopes: []string{ScopeProfileRead, ScopeSessionsManage},
+				},
+				{
+					testID:   4,
+					testDesc: "Failed - unknown scope",
+					scope:    strPtr("profile:read admin"),
+					wantErr:  true,
+				},
+				{
+					testID:     5,
+					testDesc:   "Success - admin scope only when asked for",
+					scope:      strPtr("admin:api_keys profile:read"),
+					wantScopes: []string{ScopeProfileRead, ScopeAdminAPIKeys},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					scopes, err := parseLoginScope(tc.scope)
+
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(scopes, ShouldResemble, tc.wantScopes)
+				})
+			}
+		})
+	})
+}
+
+func TestErrorResponse(t *testing.T) {
+	t.Run("TestErrorResponse", func(t *testing.T) {
+		Convey("TestErrorResponse", t, func(c C) {
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				requestID string
+			}{
+				{
+					testID:   1,
+					testDesc: "Success - without request id",
+				},
+				{
+					testID:    2,
+					testDesc:  "Success - with request id",
+					requestID: "req-123",
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					rec := httptest.NewRecorder()
+					ctx := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+					if tc.requestID != "" {
+						rec.Header().Set(echo.HeaderXRequestID, tc.requestID)
+					}
+
+					resp := errorResponse(ctx, "invalid phone")
+					// assert
+					So(resp.Message, ShouldEqual, "invalid phone")
+					if tc.requestID == "" {
+						So(resp.RequestId, ShouldBeNil)
+					} else {
+						So(*resp.RequestId, ShouldEqual, tc.requestID)
+					}
+				})
+			}
+		})
+	})
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	t.Run("TestIdempotencyScope", func(t *testing.T) {
+		Convey("TestIdempotencyScope", t, func(c C) {
+			testCases := []struct {
+				testID        int
+				testDesc      string
+				authorization string
+				// bearerScopes are the scopes the operation requires of tokens.
+				bearerScopes []string
+				mockFunc     func()
+				wantScope    string
+			}{
+				{
+					testID:    1,
+					testDesc:  "Success - anonymous request is scoped by ip",
+					mockFunc:  func() {},
+					wantScope: "ip:192.0.2.1",
+				},
+				{
+					testID:        2,
+					testDesc:      "Success - invalid token is scoped by ip",
+					authorization: "Bearer invalid",
+					mockFunc:      func() {},
+					wantScope:     "ip:192.0.2.1",
+				},
+				{
+					testID:        3,
+					testDesc:      "Success - authenticated request is scoped by session",
+					authorization: mockAuthorization,
+					mockFunc:      func() {},
+					wantScope:     "session:mock-session-id",
+				},
+				{
+					testID:        4,
+					testDesc:      "Success - token of a revoked session is scoped by ip",
+					authorization: mockRevokedAuthorization,
+					mockFunc:      func() {},
+					wantScope:     "ip:192.0.2.1",
+				},
+				{
+					testID:        5,
+					testDesc:      "Success - token lacking the scopes of the operation is scoped by ip",
+					authorization: mockReadOnlyAuthorization,
+					bearerScopes:  []string{ScopeProfileWrite},
+					mockFunc:      func() {},
+					wantScope:     "ip:192.0.2.1",
+				},
+				{
+					testID:        6,
+					testDesc:      "Success - key accepted by the operation is scoped by key",
+					authorization: mockAPIKeyAuthorization,
+					mockFunc: func() {
+						mockRepository.EXPECT().GetAPIKeyByHash(gomock.Any(), mockAPIKeyHash).Return(repository.APIKey{
+							ID:     "mock-api-key-id",
+							UserID: 17,
+							Scopes: []string{ScopeProfileWrite},
+						}, nil)
+					},
+					wantScope: "apikey:mock-api-key-id",
+				},
+			}
+
+			for _, tc := range testCases {
+				testDep := provideTest(t)
+				defer testDep()
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					tc.mockFunc()
+
+					req := httptest.NewRequest(http.MethodPost, "/users/register", nil)
+					if tc.authorization != "" {
+						req.Header.Set(echo.HeaderAuthorization, tc.authorization)
+					}
+					ctx := echo.New().NewContext(req, httptest.NewRecorder())
+					ctx.Set(generated.BearerAuthScopes, tc.bearerScopes)
+					ctx.Set(generated.ApiKeyAuthScopes, []string{ScopeProfileWrite})
+
+					// assert
+					So(server.IdempotencyScope(ctx), ShouldEqual, tc.wantScope)
+				})
+			}
+		})
+	})
+}
+
+func TestAPIKeyRateLimit(t *testing.T) {
+	t.Run("TestAPIKeyRateLimit", func(t *testing.T) {
+		Convey("TestAPIKeyRateLimit", t, func(c C) {
+			rateLimit := 50
+			mockKey := repository.APIKey{
+				ID:        "mock-api-key-id",
+				UserID:    17,
+				Scopes:    []string{ScopeProfileRead},
+				RateLimit: &rateLimit,
+			}
+
+			testCases := []struct {
+				testID        int
+				testDesc      string
+				authorization string
+				// apiKeyScopes are the scopes the operation requires of
+				// keys, nil when it does not accept keys.
+				apiKeyScopes []string
+				mockFunc     func()
+				wantID       string
+				wantLimit    int
+			}{
+				{
+					testID:        1,
+					testDesc:      "Success - request by token has no key",
+					authorization: mockAuthorization,
+					apiKeyScopes:  []string{ScopeProfileRead},
+					mockFunc:      func() {},
+				},
+				{
+					testID:        2,
+					testDesc:      "Success - operation not accepting keys",
+					authorization: mockAPIKeyAuthorization,
+					mockFunc:      func() {},
+				},
+				{
+					testID:        3,
+					testDesc:      "Success - key lacking the scopes of the operation",
+					authorization: mockAPIKeyAuthorization,
+					apiKeyScopes:  []string{ScopeProfileWrite},
+					mockFunc: func() {
+						mockRepository.EXPECT().GetAPIKeyByHash(gomock.Any(), mockAPIKeyHash).Return(mockKey, nil)
+					},
+				},
+				{
+					testID:        4,
+					testDesc:      "Success - key accepted by the operation",
+					authorization: mockAPIKeyAuthorization,
+					apiKeyScopes:  []string{ScopeProfileRead},
+					mockFunc: func() {
+						mockRepository.EXPECT().GetAPIKeyByHash(gomock.Any(), mockAPIKeyHash).Return(mockKey, nil)
+					},
+					wantID:    "mock-api-key-id",
+					wantLimit: 50,
+				},
+			}
+
+			for _, tc := range testCases {
+				testDep := provideTest(t)
+				defer testDep()
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					tc.mockFunc()
+
+					req := httptest.NewRequest(http.MethodGet, "/users", nil)
+					req.Header.Set(echo.HeaderAuthorization, tc.authorization)
+					ctx := echo.New().NewContext(req, httptest.NewRecorder())
+					if tc.apiKeyScopes != nil {
+						ctx.Set(generated.ApiKeyAuthScopes, tc.apiKeyScopes)
+					}
+
+					// assert
+					id, limit := server.APIKeyRateLimit(ctx)
+					So(id, ShouldEqual, tc.wantID)
+					So(limit, ShouldEqual, tc.wantLimit)
+				})
+			}
+		})
+	})
+}
+
+// median return the median of durations, sorting them.
+func median(durations []time.Duration) time.Duration {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return durations[len(durations)/2]
+}
+
+func TestCheckCredentialsTiming(t *testing.T) {
+	t.Run("TestCheckCredentialsTiming", func(t *testing.T) {
+		Convey("TestCheckCredentialsTiming", t, func(c C) {
+			// a cost high enough for bcrypt to outweigh the in memory lookups.
+			const cost, samples = 8, 15
+			ctx := context.Background()
+			repo := repository.NewMemoryRepository(repository.NewMemoryRepositoryOptions{})
+			hash, err := HashPassword(ctx, "password1!A", cost)
+			So(err, ShouldBeNil)
+			_, err = repo.Createuser(ctx, repository.RegisterUser{Name: "Budi", Phone: "+6281234567", Password: hash})
+			So(err, ShouldBeNil)
+			s := NewServer(NewServerOptions{Repository: repo, SecretKey: "sawitpro", BcryptCost: cost})
+
+			// interleave the attempts, so both see the same load.
+			var known, unknown []time.Duration
+			for i := 0; i < samples; i++ {
+				start := time.Now()
+				_, knownErr := s.checkCredentials(ctx, "+6281234567", "wrong-password")
+				known = append(known, time.Since(start))
+
+				start = time.Now()
+				_, unknownErr := s.checkCredentials(ctx, "+6289999999", "wrong-password")
+				unknown = append(unknown, time.Since(start))
+
+				So(unknownErr, ShouldEqual, knownErr)
+				So(unknownErr, ShouldEqual, errInvalidPassword)
+			}
+
+			// assert the distributions overlap, an unknown user skipping bcrypt
+			// would be orders of magnitude faster.
+			knownMedian, unknownMedian := median(known), median(unknown)
+			So(unknown[0], ShouldBeLessThanOrEqualTo, known[samples-1])
+			So(known[0], ShouldBeLessThanOrEqualTo, unknown[samples-1])
+			So(float64(unknownMedian), ShouldBeBetween, float64(knownMedian)/2, float64(knownMedian)*2)
+		})
+	})
+}
+
+func TestNewServerDummyHash(t *testing.T) {
+	t.Run("TestNewServerDummyHash", func(t *testing.T) {
+		Convey("TestNewServerDummyHash", t, func(c C) {
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				cost      int
+				wantCost  int
+				wantPanic bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Success - dummy hash at the configured cost",
+					cost:     bcrypt.MinCost + 1,
+					wantCost: bcrypt.MinCost + 1,
+				},
+				{
+					testID:   2,
+					testDesc: "Success - default cost when zero",
+					wantCost: bcrypt.DefaultCost,
+				},
+				{
+					testID:    3,
+					testDesc:  "Failed - cost bcrypt rejects",
+					cost:      bcrypt.MaxCost + 1,
+					wantPanic: true,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					newServer := func() *Server {
+						return NewServer(NewServerOptions{SecretKey: "sawitpro", BcryptCost: tc.cost})
+					}
+
+					// assert
+					if tc.wantPanic {
+						So(func() { newServer() }, ShouldPanic)
+						return
+					}
+					cost, err := bcrypt.Cost([]byte(newServer().dummyHash))
+					So(err, ShouldBeNil)
+					So(cost, ShouldEqual, tc.wantCost)
+				})
+			}
+		})
+	})
+}
@@ -0,0 +1,439 @@
+// This file contains the helpers of the OpenID Connect provider, see
+// api_oauth.yml.
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated/oauth"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// defaultAuthorizationCodeTTL is how long an authorization code can be
+	// exchanged when none is configured.
+	defaultAuthorizationCodeTTL = time.Minute
+	// defaultIDTokenTTL is how long an ID token is valid when access tokens
+	// never expire.
+	defaultIDTokenTTL = time.Hour
+	// codeChallengeS256 is the only PKCE method supported, plain is not.
+	codeChallengeS256 = "S256"
+	// grantTypeAuthorizationCode is the only grant type supported.
+	grantTypeAuthorizationCode = "authorization_code"
+)
+
+// OAuth error codes, see RFC 6749.
+const (
+	oauthInvalidRequest          = "invalid_request"
+	oauthInvalidClient           = "invalid_client"
+	oauthInvalidGrant            = "invalid_grant"
+	oauthInvalidScope            = "invalid_scope"
+	oauthAccessDenied            = "access_denied"
+	oauthUnsupportedResponseType = "unsupported_response_type"
+	oauthUnsupportedGrantType    = "unsupported_grant_type"
+)
+
+// Scopes of the OpenID Connect provider, see OpenID Connect Core 1.0
+// section 5.4.
+const (
+	scopeOpenID  = "openid"
+	scopeProfile = "profile"
+	scopePhone   = "phone"
+)
+
+// supportedScopes are the scopes a client can ask for, in the order they
+// are granted. The access token of a client is only granted the scopes it
+// asked for.
+var supportedScopes = []string{scopeOpenID, scopeProfile, scopePhone, ScopeProfileRead, ScopeProfileWrite, ScopeSessionsManage}
+
+// errInvalidClientRedirect is an authorization request of an unknown client
+// or an unregistered redirect URI, which must not be redirected to.
+var errInvalidClientRedirect = errors.New("unknown client or redirect_uri is not registered for the client")
+
+// An oauthError is an error of the OAuth protocol, Code is its error code.
+type oauthError struct {
+	Code        string
+	Description string
+}
+
+func (e oauthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// response return the OAuth error object of e.
+func (e oauthError) response() oauth.OAuthError {
+	resp := oauth.OAuthError{Error: e.Code}
+	if e.Description != "" {
+		resp.ErrorDescription = &e.Description
+	}
+	return resp
+}
+
+// An authorizationRequest represents the parameters of an authorization
+// request, posted back by the login page with the credentials of the user.
+type authorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// newAuthorizationRequest return the authorization request of the query params.
+func newAuthorizationRequest(params oauth.AuthorizeParams) authorizationRequest {
+	deref := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	return authorizationRequest{
+		ResponseType:        params.ResponseType,
+		ClientID:            params.ClientId,
+		RedirectURI:         params.RedirectUri,
+		Scope:               deref(params.Scope),
+		State:               deref(params.State),
+		Nonce:               deref(params.Nonce),
+		CodeChallenge:       deref(params.CodeChallenge),
+		CodeChallengeMethod: deref(params.CodeChallengeMethod),
+	}
+}
+
+// formAuthorizationRequest return the authorization request posted by the
+// login page. The generated form body has no form tags to bind with.
+func formAuthorizationRequest(c echo.Context) authorizationRequest {
+	return authorizationRequest{
+		ResponseType:        c.FormValue("response_type"),
+		ClientID:            c.FormValue("client_id"),
+		RedirectURI:         c.FormValue("redirect_uri"),
+		Scope:               c.FormValue("scope"),
+		State:               c.FormValue("state"),
+		Nonce:               c.FormValue("nonce"),
+		CodeChallenge:       c.FormValue("code_challenge"),
+		CodeChallengeMethod: c.FormValue("code_challenge_method"),
+	}
+}
+
+// params return the non-empty parameters of req, the hidden fields of the
+// login page.
+func (req authorizationRequest) params() map[string]string {
+	params := map[string]string{}
+	for name, value := range map[string]string{
+		"response_type":         req.ResponseType,
+		"client_id":             req.ClientID,
+		"redirect_uri":          req.RedirectURI,
+		"scope":                 req.Scope,
+		"state":                 req.State,
+		"nonce":                 req.Nonce,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+	} {
+		if value != "" {
+			params[name] = value
+		}
+	}
+	return params
+}
+
+// checkAuthorizationRequest return the client of req and the scope to
+// grant. errInvalidClientRedirect is returned for an unknown client or
+// redirect URI, an oauthError for the other invalid parameters, which are
+// redirected to the client.
+func (s *Server) checkAuthorizationRequest(ctx context.Context, req authorizationRequest) (client repository.OAuthClient, scope string, err error) {
+	client, err = s.Repository.GetOAuthClient(ctx, req.ClientID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return client, "", errInvalidClientRedirect
+	}
+	if err != nil {
+		return client, "", err
+	}
+	// redirect URIs are compared exactly, see RFC 6749 section 3.1.2.
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		return client, "", errInvalidClientRedirect
+	}
+
+	if req.ResponseType != "code" {
+		return client, "", oauthError{Code: oauthUnsupportedResponseType, Description: "response_type must be code"}
+	}
+	// public clients have no secret, every client must use PKCE.
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != codeChallengeS256 {
+		return client, "", oauthError{Code: oauthInvalidRequest, Description: "code_challenge with code_challenge_method S256 is required"}
+	}
+	scope, err = parseScope(req.Scope)
+	if err != nil {
+		return client, "", err
+	}
+
+	return client, scope, nil
+}
+
+// parseScope return the supported scopes of the space separated scope, in
+// the order of supportedScopes. An unknown scope is an invalid_scope error.
+func parseScope(scope string) (string, error) {
+	requested := strings.Fields(scope)
+	for _, s := range requested {
+		if !slices.Contains(supportedScopes, s) {
+			return "", oauthError{Code: oauthInvalidScope, Description: fmt.Sprintf("scope %q is not supported", s)}
+		}
+	}
+	var granted []string
+	for _, s := range supportedScopes {
+		if slices.Contains(requested, s) {
+			granted = append(granted, s)
+		}
+	}
+	return strings.Join(granted, " "), nil
+}
+
+// hasScope check whether the space separated scope contains want.
+func hasScope(scope string, want string) bool {
+	return slices.Contains(strings.Fields(scope), want)
+}
+
+// accessTokenScopes return the supported scopes of the space separated
+// scope, the scopes of the access token issued for it. The API scopes
+// authorize operations, the OpenID Connect ones the claims of /userinfo.
+func accessTokenScopes(scope string) []string {
+	var scopes []string
+	for _, s := range supportedScopes {
+		if hasScope(scope, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// verifyCodeChallenge check the PKCE verifier against an S256 challenge,
+// see RFC 7636.
+func verifyCodeChallenge(challenge string, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+// redirectToClient redirect the user agent to the registered redirectURI
+// with params, state is returned as it was received.
+func redirectToClient(c echo.Context, redirectURI string, state string, params url.Values) error {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return internalError(c, "failed to redirect to client", err)
+	}
+	query := u.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	u.RawQuery = query.Encode()
+	return c.Redirect(http.StatusFound, u.String())
+}
+
+// redirectError redirect an error of the authorization request to the client.
+func redirectError(c echo.Context, req authorizationRequest, err oauthError) error {
+	params := url.Values{"error": {err.Code}}
+	if err.Description != "" {
+		params.Set("error_description", err.Description)
+	}
+	return redirectToClient(c, req.RedirectURI, req.State, params)
+}
+
+// loginPageTemplate is the page the user logs in on, the parameters of the
+// authorization request are posted back as hidden fields. Clients that are
+// not first party also ask for consent.
+var loginPageTemplate = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Log in to {{.ClientName}}</title>
+</head>
+<body>
+<h1>Log in to {{.ClientName}}</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>
+{{end}}<form method="post" action="{{.Action}}">
+{{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}<label>Phone number or email <input name="identifier" value="{{.Identifier}}" autocomplete="username"></label>
+<label>Password <input type="password" name="password" autocomplete="current-password"></label>
+{{if .Consent}}<p>{{.ClientName}} asks for your {{if .Scope}}{{.Scope}}{{else}}account{{end}}.</p>
+<button name="consent" value="allow">Allow</button>
+<button name="consent" value="deny">Deny</button>
+{{else}}<button>Log in</button>
+{{end}}</form>
+</body>
+</html>
+`))
+
+// errorPageTemplate is the page of an authorization request that cannot be
+// redirected to the client.
+var errorPageTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Invalid authorization request</title>
+</head>
+<body>
+<h1>Invalid authorization request</h1>
+<p>{{.}}</p>
+</body>
+</html>
+`))
+
+// A loginPage represents the data of loginPageTemplate.
+type loginPage struct {
+	Action     string
+	ClientName string
+	Params     map[string]string
+	Identifier string
+	Scope      string
+	Consent    bool
+	Error      string
+}
+
+// renderPage respond with the html of tmpl, which must not be framed nor cached.
+func renderPage(c echo.Context, status int, tmpl *template.Template, data any) error {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return internalError(c, "failed to render page", err)
+	}
+	header := c.Response().Header()
+	header.Set(echo.HeaderXFrameOptions, "DENY")
+	header.Set(echo.HeaderContentSecurityPolicy, "frame-ancestors 'none'")
+	header.Set("Cache-Control", "no-store")
+	return c.HTMLBlob(status, buf.Bytes())
+}
+
+// renderLoginPage respond with the login page of req, with message as the
+// error of the last attempt if any.
+func renderLoginPage(c echo.Context, client repository.OAuthClient, req authorizationRequest, scope string, identifier string, message string) error {
+	return renderPage(c, http.StatusOK, loginPageTemplate, loginPage{
+		Action:     "/oauth/authorize",
+		ClientName: client.Name,
+		Params:     req.params(),
+		Identifier: identifier,
+		Scope:      scope,
+		Consent:    !client.FirstParty,
+		Error:      message,
+	})
+}
+
+// authenticateClient return the client of a token request. Confidential
+// clients authenticate with HTTP Basic or client_secret, public clients only
+// send their client_id. Failures are invalid_client errors.
+func (s *Server) authenticateClient(c echo.Context) (repository.OAuthClient, error) {
+	clientID, secret, basic := c.Request().BasicAuth()
+	if basic {
+		// credentials are form encoded before being put in the header, see
+		// RFC 6749 section 2.3.1.
+		var err error
+		if clientID, err = url.QueryUnescape(clientID); err != nil {
+			return repository.OAuthClient{}, oauthError{Code: oauthInvalidClient, Description: "malformed client credentials"}
+		}
+		if secret, err = url.QueryUnescape(secret); err != nil {
+			return repository.OAuthClient{}, oauthError{Code: oauthInvalidClient, Description: "malformed client credentials"}
+		}
+		if id := c.FormValue("client_id"); id != "" && id != clientID {
+			return repository.OAuthClient{}, oauthError{Code: oauthInvalidClient, Description: "client_id does not match the authenticated client"}
+		}
+	} else {
+		clientID, secret = c.FormValue("client_id"), c.FormValue("client_secret")
+	}
+
+	client, err := s.Repository.GetOAuthClient(c.Request().Context(), clientID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return client, oauthError{Code: oauthInvalidClient, Description: "client authentication failed"}
+	}
+	if err != nil {
+		return client, err
+	}
+	if client.SecretHash == "" && secret == "" {
+		return client, nil
+	}
+	if client.SecretHash == "" || subtle.ConstantTimeCompare([]byte(hashSecretToken(secret)), []byte(client.SecretHash)) != 1 {
+		return client, oauthError{Code: oauthInvalidClient, Description: "client authentication failed"}
+	}
+	return client, nil
+}
+
+// signIDToken return the ID token of user for the client of code, signed
+// with IDTokenKey. The claims of the profile and phone scopes are added
+// when they were granted.
+func (s *Server) signIDToken(user repository.User, code repository.AuthorizationCode) (string, error) {
+	ttl := s.TokenTTL
+	if ttl <= 0 {
+		ttl = defaultIDTokenTTL
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":       s.Issuer,
+		"sub":       fmt.Sprint(user.ID),
+		"aud":       code.ClientID,
+		"iat":       now.Unix(),
+		"exp":       now.Add(ttl).Unix(),
+		"auth_time": code.CreatedAt.Unix(),
+	}
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+	if hasScope(code.Scope, scopeProfile) {
+		claims["name"] = user.Name
+	}
+	if hasScope(code.Scope, scopePhone) {
+		claims["phone_number"] = user.Phone
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.idTokenKeyID
+	return token.SignedString(s.IDTokenKey)
+}
+
+// newJWK return the public JSON Web Key of key, see RFC 7517.
+func newJWK(key *rsa.PublicKey) oauth.JWK {
+	return oauth.JWK{
+		Kty: "RSA",
+		Kid: jwkThumbprint(key),
+		Use: "sig",
+		Alg: jwt.SigningMethodRS256.Alg(),
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// jwkThumbprint return the RFC 7638 thumbprint of key, the id it is
+// published under.
+func jwkThumbprint(key *rsa.PublicKey) string {
+	// the required members in lexicographic order, without whitespace.
+	members, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+	})
+	sum := sha256.Sum256(members)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -1,20 +1,139 @@
 package handler
 
-import "github.com/SawitProRecruitment/UserService/repository"
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/health"
+	"github.com/SawitProRecruitment/UserService/mail"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/SawitProRecruitment/UserService/oidc"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/storage"
+)
+
+// defaultAvatarMaxBytes is the largest avatar upload when none is configured.
+const defaultAvatarMaxBytes = 5 << 20
 
 type Server struct {
-	Repository repository.RepositoryInterface
-	SecretKey  string
+	Repository           repository.RepositoryInterface
+	Resolver             repository.IdentifierResolver
+	SecretKey            string
+	TokenTTL             time.Duration
+	BcryptCost           int
+	Health               *health.Registry
+	Metrics              *metrics.Metrics
+	Mailer               mail.Sender
+	EmailVerificationTTL time.Duration
+	EmailVerificationURL string
+	Blobs                storage.BlobStore
+	AvatarMaxBytes       int64
+	Issuer               string
+	IDTokenKey           *rsa.PrivateKey
+	AuthorizationCodeTTL time.Duration
+	IdentityVerifier     *oidc.Verifier
+	AdminUserIDs         []int64
+	// idTokenKeyID is the JWK thumbprint of IDTokenKey, the kid of ID tokens.
+	idTokenKeyID string
+	// dummyHash is compared against the password of unknown users, so their
+	// login costs as much as the one of a known user.
+	dummyHash string
 }
 
 type NewServerOptions struct {
 	Repository repository.RepositoryInterface
-	SecretKey  string
+	// Resolver looks up users by login identifier, phone numbers and
+	// verified emails of Repository when nil.
+	Resolver  repository.IdentifierResolver
+	SecretKey string
+	// TokenTTL is how long an issued token stays valid, tokens never expire when zero.
+	TokenTTL time.Duration
+	// BcryptCost is the password hashing cost, bcrypt.DefaultCost is used when
+	// zero. NewServer panics when bcrypt rejects it.
+	BcryptCost int
+	// Health runs the readiness checks, readiness always passes when nil.
+	Health *health.Registry
+	// Metrics records auth outcomes, nothing is recorded when nil.
+	Metrics *metrics.Metrics
+	// Mailer sends the email verification tokens, none are sent when nil.
+	Mailer mail.Sender
+	// EmailVerificationTTL is how long a verification token stays valid,
+	// 24 hours when zero.
+	EmailVerificationTTL time.Duration
+	// EmailVerificationURL is the page confirming an email, the token is
+	// added as the token query parameter. The bare token is mailed when empty.
+	EmailVerificationURL string
+	// Blobs keeps the uploaded avatars, uploads fail when nil.
+	Blobs storage.BlobStore
+	// AvatarMaxBytes is the largest avatar upload, 5 MiB when zero.
+	AvatarMaxBytes int64
+	// Issuer is the URL of the OpenID Connect provider, the iss of ID tokens.
+	Issuer string
+	// IDTokenKey signs the ID tokens, required by the OpenID Connect endpoints.
+	IDTokenKey *rsa.PrivateKey
+	// AuthorizationCodeTTL is how long an authorization code can be
+	// exchanged, 1 minute when zero.
+	AuthorizationCodeTTL time.Duration
+	// IdentityVerifier verifies the ID tokens of social logins, every token
+	// is rejected when nil.
+	IdentityVerifier *oidc.Verifier
+	// AdminUserIDs are the users allowed to manage API keys.
+	AdminUserIDs []int64
 }
 
 func NewServer(opts NewServerOptions) *Server {
+	if opts.EmailVerificationTTL <= 0 {
+		opts.EmailVerificationTTL = 24 * time.Hour
+	}
+	if opts.Resolver == nil {
+		opts.Resolver = repository.NewResolverChain(opts.Repository)
+	}
+	if opts.AvatarMaxBytes <= 0 {
+		opts.AvatarMaxBytes = defaultAvatarMaxBytes
+	}
+	if opts.AuthorizationCodeTTL <= 0 {
+		opts.AuthorizationCodeTTL = defaultAuthorizationCodeTTL
+	}
+	if opts.IdentityVerifier == nil {
+		opts.IdentityVerifier = oidc.NewVerifier(oidc.NewVerifierOptions{})
+	}
+	var idTokenKeyID string
+	if opts.IDTokenKey != nil {
+		idTokenKeyID = jwkThumbprint(&opts.IDTokenKey.PublicKey)
+	}
+	// hash a random password at the cost of the stored ones. Without it
+	// logins of unknown users would return early, so a cost bcrypt rejects
+	// is a programming error, config validates BCRYPT_COST.
+	password, err := newTokenID()
+	if err != nil {
+		panic(err)
+	}
+	dummyHash, err := HashPassword(context.Background(), password, opts.BcryptCost)
+	if err != nil {
+		panic(fmt.Errorf("hash dummy password: %w", err))
+	}
 	return &Server{
 		Repository: opts.Repository,
+		Resolver:   opts.Resolver,
 		SecretKey:  opts.SecretKey,
+		TokenTTL:   opts.TokenTTL,
+		BcryptCost: opts.BcryptCost,
+		Health:     opts.Health,
+		Metrics:    opts.Metrics,
+		Mailer:     opts.Mailer,
+
+		EmailVerificationTTL: opts.EmailVerificationTTL,
+		EmailVerificationURL: opts.EmailVerificationURL,
+		Blobs:                opts.Blobs,
+		AvatarMaxBytes:       opts.AvatarMaxBytes,
+		Issuer:               opts.Issuer,
+		IDTokenKey:           opts.IDTokenKey,
+		AuthorizationCodeTTL: opts.AuthorizationCodeTTL,
+		IdentityVerifier:     opts.IdentityVerifier,
+		AdminUserIDs:         opts.AdminUserIDs,
+		idTokenKeyID:         idTokenKeyID,
+		dummyHash:            dummyHash,
 	}
 }
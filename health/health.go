@@ -0,0 +1,131 @@
+// Package health contains the registry of dependency checks reported by the
+// readiness endpoint.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+)
+
+// errShuttingDown is reported once graceful shutdown has started.
+var errShuttingDown = errors.New("shutting down")
+
+// A CheckFunc checks one dependency.
+// The returned details are reported next to the result, e.g. a schema version.
+type CheckFunc func(ctx context.Context) (details string, err error)
+
+type Result struct {
+	Name    string
+	Status  string
+	Latency time.Duration
+	Details string
+	Error   string
+}
+
+type Report struct {
+	Status string
+	Checks []Result
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+type Registry struct {
+	Timeout time.Duration
+
+	mu           sync.RWMutex
+	checks       []check
+	shuttingDown atomic.Bool
+}
+
+type NewRegistryOptions struct {
+	// Timeout bounds every check, checks run concurrently.
+	Timeout time.Duration
+}
+
+func NewRegistry(opts NewRegistryOptions) *Registry {
+	return &Registry{
+		Timeout: opts.Timeout,
+	}
+}
+
+// Register add a named check, checks are reported in registration order.
+func (r *Registry) Register(name string, fn CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check{name: name, fn: fn})
+}
+
+// SetShuttingDown make every following report fail so load balancers stop
+// routing new requests while in-flight ones drain.
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Check run every registered check and report the overall status.
+func (r *Registry) Check(ctx context.Context) Report {
+	if r.shuttingDown.Load() {
+		return Report{
+			Status: StatusFail,
+			Checks: []Result{{Name: "shutdown", Status: StatusFail, Error: errShuttingDown.Error()}},
+		}
+	}
+
+	r.mu.RLock()
+	checks := r.checks
+	r.mu.RUnlock()
+
+	report := Report{
+		Status: StatusPass,
+		Checks: make([]Result, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			report.Checks[i] = r.run(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusPass {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c check) Result {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	details, err := c.fn(ctx)
+	result := Result{
+		Name:    c.name,
+		Status:  StatusPass,
+		Latency: time.Since(start),
+		Details: details,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCheck(t *testing.T) {
+	t.Run("TestCheck", func(t *testing.T) {
+		Convey("TestCheck", t, func(c C) {
+			pass := func(context.Context) (string, error) { return "ok", nil }
+			fail := func(context.Context) (string, error) { return "", errors.New("connection refused") }
+			slow := func(ctx context.Context) (string, error) {
+				<-ctx.Done()
+				return "", ctx.Err()
+			}
+
+			testCases := []struct {
+				testID       int
+				testDesc     string
+				checks       map[string]CheckFunc
+				order        []string
+				shuttingDown bool
+				wantStatus   string
+				wantChecks   []Result
+			}{
+				{
+					testID:     1,
+					testDesc:   "Success - no checks registered",
+					wantStatus: StatusPass,
+					wantChecks: []Result{},
+				},
+				{
+					testID:     2,
+					testDesc:   "Success - every check passes",
+					checks:     map[string]CheckFunc{"postgres": pass, "migrations": pass},
+					order:      []string{"postgres", "migrations"},
+					wantStatus: StatusPass,
+					wantChecks: []Result{
+						{Name: "postgres", Status: StatusPass, Details: "ok"},
+						{Name: "migrations", Status: StatusPass, Details: "ok"},
+					},
+				},
+				{
+					testID:     3,
+					testDesc:   "Failed - one check fails",
+					checks:     map[string]CheckFunc{"postgres": fail, "migrations": pass},
+					order:      []string{"postgres", "migrations"},
+					wantStatus: StatusFail,
+					wantChecks: []Result{
+						{Name: "postgres", Status: StatusFail, Error: "connection refused"},
+						{Name: "migrations", Status: StatusPass, Details: "ok"},
+					},
+				},
+				{
+					testID:     4,
+					testDesc:   "Failed - check exceeds timeout",
+					checks:     map[string]CheckFunc{"postgres": slow},
+					order:      []string{"postgres"},
+					wantStatus: StatusFail,
+					wantChecks: []Result{
+						{Name: "postgres", Status: StatusFail, Error: context.DeadlineExceeded.Error()},
+					},
+				},
+				{
+					testID:       5,
+					testDesc:     "Failed - shutting down",
+					checks:       map[string]CheckFunc{"postgres": pass},
+					order:        []string{"postgres"},
+					shuttingDown: true,
+					wantStatus:   StatusFail,
+					wantChecks: []Result{
+						{Name: "shutdown", Status: StatusFail, Error: "shutting down"},
+					},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					registry := NewRegistry(NewRegistryOptions{
+						Timeout: 50 * time.Millisecond,
+					})
+					for _, name := range tc.order {
+						registry.Register(name, tc.checks[name])
+					}
+					if tc.shuttingDown {
+						registry.SetShuttingDown()
+					}
+
+					report := registry.Check(context.Background())
+					// assert
+					So(report.Status, ShouldEqual, tc.wantStatus)
+					So(report.Checks, ShouldHaveLength, len(tc.wantChecks))
+					for i, result := range report.Checks {
+						So(result.Latency, ShouldBeLessThan, time.Second)
+						result.Latency = 0
+						So(result, ShouldResemble, tc.wantChecks[i])
+					}
+				})
+			}
+		})
+	})
+}
@@ -0,0 +1,213 @@
+// Package idempotency contains the echo middleware replaying the stored
+// response of requests retried with the same Idempotency-Key header.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/common"
+	"github.com/SawitProRecruitment/UserService/logging"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const (
+	// HeaderIdempotencyKey is the request header carrying the key.
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed is set on replayed responses.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	// maxKeyLength matches the idempotency_keys.key column.
+	maxKeyLength = 255
+
+	// noStoreKey is set on the context of responses that must not be stored.
+	noStoreKey = "idempotency.no_store"
+)
+
+// replayedHeaders are the response headers stored and replayed with the body.
+var replayedHeaders = []string{
+	echo.HeaderLocation,
+	"ETag",
+	common.HeaderDeprecation,
+	common.HeaderSunset,
+	common.HeaderLink,
+}
+
+// Store persists idempotency keys and the response of their first request.
+// It is implemented by the repository layer.
+type Store interface {
+	// CreateIdempotencyKey return repository.ErrDuplicateData when the key is in use.
+	CreateIdempotencyKey(ctx context.Context, input repository.IdempotencyKey) (err error)
+	GetIdempotencyKey(ctx context.Context, scope string, key string) (output repository.IdempotencyKey, err error)
+	CompleteIdempotencyKey(ctx context.Context, input repository.IdempotencyKey) (err error)
+	DeleteIdempotencyKey(ctx context.Context, scope string, key string) (err error)
+}
+
+type MiddlewareOptions struct {
+	Store Store
+	// TTL is how long a response is replayed.
+	TTL time.Duration
+	// Scope return the owner of the key, e.g. the user id or the client ip.
+	// Defaults to the client ip.
+	Scope func(c echo.Context) string
+	// Skipper skips requests whose response must not be stored.
+	Skipper middleware.Skipper
+}
+
+// DoNotStore mark the response of c as not storable, e.g. because it carries
+// a secret. The key is released instead, like after a server error.
+func DoNotStore(c echo.Context) {
+	c.Set(noStoreKey, true)
+}
+
+// Middleware store the first response of POST requests sent with an
+// Idempotency-Key and replay it to retries with the same key and body.
+// A key reused with another request is rejected with 422, a retry while the
+// first request is in flight with 409. Server errors and responses marked
+// with DoNotStore are not stored so the request can be retried.
+func Middleware(opts MiddlewareOptions) echo.MiddlewareFunc {
+	if opts.Scope == nil {
+		opts.Scope = func(c echo.Context) string { return "ip:" + c.RealIP() }
+	}
+	if opts.Skipper == nil {
+		opts.Skipper = middleware.DefaultSkipper
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			key := req.Header.Get(HeaderIdempotencyKey)
+			if req.Method != http.MethodPost || key == "" || opts.Skipper(c) {
+				return next(c)
+			}
+			if len(key) > maxKeyLength {
+				return c.JSON(http.StatusBadRequest, common.ErrorResponse(c, "Idempotency-Key is too long"))
+			}
+
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, common.ErrorResponse(c, "failed to read request body"))
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := req.Context()
+			record := repository.IdempotencyKey{
+				Scope:       opts.Scope(c),
+				Key:         key,
+				Fingerprint: fingerprint(req, body),
+				ExpiresAt:   time.Now().Add(opts.TTL),
+			}
+
+			err = opts.Store.CreateIdempotencyKey(ctx, record)
+			if errors.Is(err, repository.ErrDuplicateData) {
+				return replay(c, opts.Store, record)
+			}
+			if err != nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "create idempotency key", slog.Any("error", err))
+				return c.JSON(http.StatusInternalServerError, common.ErrorResponse(c, "failed to process Idempotency-Key"))
+			}
+
+			rec := &recorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = rec
+			if err = next(c); err != nil {
+				// let the error handler write the response so it can be stored.
+				c.Error(err)
+			}
+
+			// the response is stored even when the client went away.
+			ctx = context.WithoutCancel(ctx)
+			res := c.Response()
+			if res.Status >= http.StatusInternalServerError || c.Get(noStoreKey) != nil {
+				err = opts.Store.DeleteIdempotencyKey(ctx, record.Scope, record.Key)
+			} else {
+				record.StatusCode = res.Status
+				record.ContentType = res.Header().Get(echo.HeaderContentType)
+				record.Headers = storedHeaders(res.Header())
+				record.Body = rec.body.Bytes()
+				if err = opts.Store.CompleteIdempotencyKey(ctx, record); err != nil {
+					// do not leave the key in flight until it expires.
+					err = errors.Join(err, opts.Store.DeleteIdempotencyKey(ctx, record.Scope, record.Key))
+				}
+			}
+			if err != nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "store idempotency key", slog.Any("error", err))
+			}
+
+			return nil
+		}
+	}
+}
+
+// replay write the stored response of the key used by record.
+func replay(c echo.Context, store Store, record repository.IdempotencyKey) error {
+	ctx := c.Request().Context()
+	stored, err := store.GetIdempotencyKey(ctx, record.Scope, record.Key)
+	if errors.Is(err, repository.ErrNotFound) {
+		// expired or released since, the client may retry right away.
+		return c.JSON(http.StatusConflict, common.ErrorResponse(c, "request with this Idempotency-Key is in progress"))
+	}
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "get idempotency key", slog.Any("error", err))
+		return c.JSON(http.StatusInternalServerError, common.ErrorResponse(c, "failed to process Idempotency-Key"))
+	}
+
+	if stored.Fingerprint != record.Fingerprint {
+		return c.JSON(http.StatusUnprocessableEntity, common.ErrorResponse(c, "Idempotency-Key was used with a different request"))
+	}
+	if stored.StatusCode == 0 {
+		return c.JSON(http.StatusConflict, common.ErrorResponse(c, "request with this Idempotency-Key is in progress"))
+	}
+
+	header := c.Response().Header()
+	for name, value := range stored.Headers {
+		header.Set(name, value)
+	}
+	header.Set(HeaderIdempotentReplayed, "true")
+	return c.Blob(stored.StatusCode, stored.ContentType, stored.Body)
+}
+
+// storedHeaders return the replayed headers set on a response, nil when there
+// are none. Repeated headers are joined into one list.
+func storedHeaders(header http.Header) map[string]string {
+	var stored map[string]string
+	for _, name := range replayedHeaders {
+		values := header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		if stored == nil {
+			stored = map[string]string{}
+		}
+		stored[name] = strings.Join(values, ", ")
+	}
+	return stored
+}
+
+// fingerprint identify the request a key was first used with.
+func fingerprint(req *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder keeps a copy of the response body.
+type recorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
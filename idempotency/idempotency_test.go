@@ -0,0 +1,328 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const mockBody = `{"full_name":"Budi","phone_number":"+6281234567"}`
+
+func TestMiddleware(t *testing.T) {
+	t.Run("TestMiddleware", func(t *testing.T) {
+		Convey("TestMiddleware", t, func(c C) {
+			req := httptest.NewRequest(http.MethodPost, "/users/register", nil)
+			mockFingerprint := fingerprint(req, []byte(mockBody))
+
+			type (
+				args struct {
+					method string
+					path   string
+					key    string
+					status int
+					// headers are set by the handler.
+					headers map[string]string
+					// noStore marks the response with DoNotStore.
+					noStore bool
+				}
+			)
+
+			testCases := []struct {
+				testID      int
+				testDesc    string
+				args        args
+				mockFunc    func(mock *repository.MockRepositoryInterface)
+				wantCalled  bool
+				wantStatus  int
+				wantBody    string
+				wantReplay  bool
+				wantHeaders map[string]string
+				wantStored  *repository.IdempotencyKey
+			}{
+				{
+					testID:     1,
+					testDesc:   "Success - request without key is not stored",
+					args:       args{method: http.MethodPost, path: "/users/register", status: http.StatusOK},
+					mockFunc:   func(mock *repository.MockRepositoryInterface) {},
+					wantCalled: true,
+					wantStatus: http.StatusOK,
+					wantBody:   `{"id":1}`,
+				},
+				{
+					testID:     2,
+					testDesc:   "Success - other methods are not stored",
+					args:       args{method: http.MethodPatch, path: "/users", key: "mock-key", status: http.StatusOK},
+					mockFunc:   func(mock *repository.MockRepositoryInterface) {},
+					wantCalled: true,
+					wantStatus: http.StatusOK,
+					wantBody:   `{"id":1}`,
+				},
+				{
+					testID:     3,
+					testDesc:   "Success - skipped route is not stored",
+					args:       args{method: http.MethodPost, path: "/login", key: "mock-key", status: http.StatusOK},
+					mockFunc:   func(mock *repository.MockRepositoryInterface) {},
+					wantCalled: true,
+					wantStatus: http.StatusOK,
+					wantBody:   `{"id":1}`,
+				},
+				{
+					testID:     4,
+					testDesc:   "Failed - key too long",
+					args:       args{method: http.MethodPost, path: "/users/register", key: strings.Repeat("k", maxKeyLength+1)},
+					mockFunc:   func(mock *repository.MockRepositoryInterface) {},
+					wantStatus: http.StatusBadRequest,
+					wantBody:   `{"message":"Idempotency-Key is too long"}`,
+				},
+				{
+					testID:   5,
+					testDesc: "Failed - error create key",
+					args:     args{method: http.MethodPost, path: "/users/register", key: "mock-key"},
+					mockFunc: func(mock *repository.MockRepositoryInterface) {
+						mock.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Return(errors.New("error"))
+					},
+					wantStatus: http.StatusInternalServerError,
+					wantBody:   `{"message":"failed to process Idempotency-Key"}`,
+				},
+				{
+					testID:   6,
+					testDesc: "Success - first response is stored",
+					args:     args{method: http.MethodPost, path: "/users/register", key: "mock-key", status: http.StatusOK},
+					mockFunc: func(mock *repository.MockRepositoryInterface) {
+						mock.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Return(nil)
+					},
+					wantCalled: true,
+					wantStatus: http.StatusOK,
+					wantBody:   `{"id":1}`,
+					wantStored: &repository.IdempotencyKey{
+						Scope:       "ip:192.0.2.1",
+						Key:         "mock-key",
+						Fingerprint: mockFingerprint,
+						StatusCode:  http.StatusOK,
+						ContentType: "application/json; charset=UTF-8",
+						Body:        []byte(`{"id":1}` + "\n"),
+					},
+				},
+				{
+					testID:   7,
+					testDesc: "Success - client error is stored",
+					args:     args{method: http.MethodPost, path: "/users/register", key: "mock-key", status: http.StatusBadRequest},
+					mockFunc: func(mock *repository.MockRepositoryInterface) {
+						mock.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Return(nil)
+					},
+					wantCalled: true,
+					wantStatus: http.StatusBadRequest,
+					wantBody:   `{"id":1}`,
+					wantStored: &repository.IdempotencyKey{
+						Scope:       "ip:192.0.2.1",
+						Key:         "mock-key",
+						Fingerprint: mockFingerprint,
+						StatusCode:  http.StatusBadRequest,
+						ContentType: "application/json; charset=UTF-8",
+						Body:        []byte(`{"id":1}` + "\n"),
+					},
+				},
+				{
+					testID:   8,
+					testDesc: "Success - server error releases the key",
+					args:     args{method: http.MethodPost, path: "/users/register", key: "mock-key", status: http.StatusInternalServerError},
+					mockFunc: func(mock *repository.MockRepositoryInterface) {
+						mock.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Return(nil)
+						mock.EXPECT().DeleteIdempotencyKey(gomock.Any(), "ip:192.0.2.1", "mock-key").Return(nil)
+					},
+					wantCalled: true,
+					wantStatus: http.StatusInternalServerError,
+					wantBody:   `{"id":1}`,
+				},
+				{
+					testID:   9,
+					testDesc: "Success - failing to store releases the key",
+					args:     args{method: http.MethodPost, path: "/users/register", key: "mock-key", status: http.StatusOK},
+					mockFunc: func(mock *repository.MockRepositoryInterface) {
+						mock.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Return(nil)
+						mock.EXPECT().CompleteIdempotencyKey(gomock.Any(), gomock.Any()).Return(errors.New("error"))
+						mock.EXPECT().DeleteIdempotencyKey(gomock.Any(), "ip:192.0.2.1", "mock-key").Return(nil)
+					},
+					wantCalled: true,
+					wantStatus: http.StatusOK,
+					wantBody:   `{"id":1}`,
+				},
+				{
+					testID:   10,
+					testDesc: "Success - retry replays stored response",
+					args:     args{method: http.MethodPost, path: "/users/register", key: "mock-key"},
+					mockFunc: func(mock *repository.MockRepositoryInterface) {
+						mock.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Return(repository.ErrDuplicateData)
+						mock.EXPECT().GetIdempotencyKey(gomock.Any(), "ip:192.0.2.1", "mock-key").Return(repository.IdempotencyKey{
+							Fingerprint: mockFingerprint,
+							StatusCode:  http.StatusOK,
+							ContentType: echo.MIMEApplicationJSON,
+							Body:        []byte(`{"id":1}`),
+						}, nil)
+					},
+					wantStatus: http.StatusOK,
+					wantBody:   `{"id":1}`,
+					wantReplay: true,
+				},
+				{
+					testID:   11,
+					testDesc: "Failed - key reused with a different request",
+					args:     args{method: http.MethodPost, path: "/users/register", key: "mock-key"},
+					mockFunc: func(mock *repository.MockRepositoryInterface) {
+						mock.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Return(repository.ErrDuplicateData)
+						mock.EXPECT().GetIdempotencyKey(gomock.Any(), "ip:192.0.2.1", "mock-key").Return(repository.IdempotencyKey{
+							Fingerprint: "other-fingerprint",
+							StatusCode:  http.StatusOK,
+						}, nil)
+					},
+					wantStatus: http.StatusUnprocessableEntity,
+					wantBody:   `{"message":"Idempotency-Key was used with a different request"}`,
+				},
+				{
+					testID:   12,
+					testDesc: "Failed - first request still in flight",
+					args:     args{method: http.MethodPost, path: "/users/register", key: "mock-key"},
+					mockFunc: func(mock *repository.MockRepositoryInterface) {
+						mock.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Return(repository.ErrDuplicateData)
+						mock.EXPECT().GetIdempotencyKey(gomock.Any(), "ip:192.0.2.1", "mock-key").Return(repository.IdempotencyKey{
+							Fingerprint: mockFingerprint,
+						}, nil)
+					},
+					wantStatus: http.StatusConflict,
+					wantBody:   `{"message":"request with this Idempotency-Key is in progress"}`,
+				},
+				{
+					testID:   13,
+					testDesc: "Success - replayed headers are stored",
+					args: args{method: http.MethodPost, path: "/users/register", key: "mock-key", status: http.StatusCreated, headers: map[string]string{
+						echo.HeaderLocation: "/v2/users/1",
+						"ETag":              `"1"`,
+						"X-Custom":          "not-stored",
+					}},
+					mockFunc: func(mock *repository.MockRepositoryInterface) {
+						mock.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Return(nil)
+					},
+					wantCalled: true,
+					wantStatus: http.StatusCreated,
+					wantBody:   `{"id":1}`,
+					wantStored: &repository.IdempotencyKey{
+						Scope:       "ip:192.0.2.1",
+						Key:         "mock-key",
+						Fingerprint: mockFingerprint,
+						StatusCode:  http.StatusCreated,
+						ContentType: "application/json; charset=UTF-8",
+						Headers:     map[string]string{echo.HeaderLocation: "/v2/users/1", "ETag": `"1"`},
+						Body:        []byte(`{"id":1}` + "\n"),
+					},
+				},
+				{
+					testID:   14,
+					testDesc: "Success - retry replays stored headers",
+					args:     args{method: http.MethodPost, path: "/users/register", key: "mock-key"},
+					mockFunc: func(mock *repository.MockRepositoryInterface) {
+						mock.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Return(repository.ErrDuplicateData)
+						mock.EXPECT().GetIdempotencyKey(gomock.Any(), "ip:192.0.2.1", "mock-key").Return(repository.IdempotencyKey{
+							Fingerprint: mockFingerprint,
+							StatusCode:  http.StatusCreated,
+							ContentType: echo.MIMEApplicationJSON,
+							Headers:     map[string]string{echo.HeaderLocation: "/v2/users/1", "ETag": `"1"`},
+							Body:        []byte(`{"id":1}`),
+						}, nil)
+					},
+					wantStatus:  http.StatusCreated,
+					wantBody:    `{"id":1}`,
+					wantReplay:  true,
+					wantHeaders: map[string]string{echo.HeaderLocation: "/v2/users/1", "ETag": `"1"`},
+				},
+				{
+					testID:   15,
+					testDesc: "Success - response marked not storable releases the key",
+					args:     args{method: http.MethodPost, path: "/users/register", key: "mock-key", status: http.StatusCreated, noStore: true},
+					mockFunc: func(mock *repository.MockRepositoryInterface) {
+						mock.EXPECT().CreateIdempotencyKey(gomock.Any(), gomock.Any()).Return(nil)
+						mock.EXPECT().DeleteIdempotencyKey(gomock.Any(), "ip:192.0.2.1", "mock-key").Return(nil)
+					},
+					wantCalled: true,
+					wantStatus: http.StatusCreated,
+					wantBody:   `{"id":1}`,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					ctrl := gomock.NewController(t)
+					defer ctrl.Finish()
+					mock := repository.NewMockRepositoryInterface(ctrl)
+					tc.mockFunc(mock)
+
+					var stored *repository.IdempotencyKey
+					if tc.wantStored != nil {
+						mock.EXPECT().CompleteIdempotencyKey(gomock.Any(), gomock.Any()).
+							DoAndReturn(func(_ context.Context, input repository.IdempotencyKey) error {
+								So(input.ExpiresAt, ShouldHappenAfter, time.Now())
+								input.ExpiresAt = time.Time{}
+								stored = &input
+								return nil
+							})
+					}
+
+					called := false
+					status := tc.args.status
+					e := echo.New()
+					e.Use(Middleware(MiddlewareOptions{
+						Store: mock,
+						TTL:   time.Hour,
+						Skipper: func(c echo.Context) bool {
+							return c.Path() == "/login"
+						},
+					}))
+					headers, noStore := tc.args.headers, tc.args.noStore
+					handler := func(c echo.Context) error {
+						called = true
+						if noStore {
+							DoNotStore(c)
+						}
+						for name, value := range headers {
+							c.Response().Header().Set(name, value)
+						}
+						return c.JSON(status, map[string]int{"id": 1})
+					}
+					e.Add(tc.args.method, tc.args.path, handler)
+
+					req := httptest.NewRequest(tc.args.method, tc.args.path, strings.NewReader(mockBody))
+					req.RemoteAddr = "192.0.2.1:1234"
+					if tc.args.key != "" {
+						req.Header.Set(HeaderIdempotencyKey, tc.args.key)
+					}
+					rec := httptest.NewRecorder()
+					e.ServeHTTP(rec, req)
+
+					// assert
+					So(called, ShouldEqual, tc.wantCalled)
+					So(rec.Code, ShouldEqual, tc.wantStatus)
+					So(strings.TrimSpace(rec.Body.String()), ShouldEqual, tc.wantBody)
+					So(rec.Header().Get(HeaderIdempotentReplayed) == "true", ShouldEqual, tc.wantReplay)
+					for name, value := range tc.wantHeaders {
+						So(rec.Header().Get(name), ShouldEqual, value)
+					}
+					if tc.wantStored != nil {
+						So(stored, ShouldNotBeNil)
+						So(*stored, ShouldResemble, *tc.wantStored)
+					}
+				})
+			}
+		})
+	})
+}
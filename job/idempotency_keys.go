@@ -0,0 +1,56 @@
+// This file contains the job pruning expired idempotency keys.
+package job
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/logging"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+type IdempotencyKeyPruner struct {
+	Repository repository.RepositoryInterface
+	Interval   time.Duration
+	Now        func() time.Time
+}
+
+type NewIdempotencyKeyPrunerOptions struct {
+	Repository repository.RepositoryInterface
+	Interval   time.Duration
+}
+
+func NewIdempotencyKeyPruner(opts NewIdempotencyKeyPrunerOptions) *IdempotencyKeyPruner {
+	return &IdempotencyKeyPruner{
+		Repository: opts.Repository,
+		Interval:   opts.Interval,
+		Now:        time.Now,
+	}
+}
+
+// Prune delete idempotency keys past their expiry.
+func (p *IdempotencyKeyPruner) Prune(ctx context.Context) (deleted int64, err error) {
+	return p.Repository.DeleteIdempotencyKeysBefore(ctx, p.Now())
+}
+
+// Run prune idempotency keys every interval until ctx is done.
+func (p *IdempotencyKeyPruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := p.Prune(ctx)
+		if err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "prune idempotency keys", slog.Any("error", err))
+		} else if deleted > 0 {
+			logging.FromContext(ctx).InfoContext(ctx, "pruned idempotency keys", slog.Int64("deleted", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
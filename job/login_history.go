@@ -0,0 +1,59 @@
+// This file contains the job pruning login history past its retention.
+package job
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/logging"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+type LoginHistoryPruner struct {
+	Repository repository.RepositoryInterface
+	Retention  time.Duration
+	Interval   time.Duration
+	Now        func() time.Time
+}
+
+type NewLoginHistoryPrunerOptions struct {
+	Repository repository.RepositoryInterface
+	Retention  time.Duration
+	Interval   time.Duration
+}
+
+func NewLoginHistoryPruner(opts NewLoginHistoryPrunerOptions) *LoginHistoryPruner {
+	return &LoginHistoryPruner{
+		Repository: opts.Repository,
+		Retention:  opts.Retention,
+		Interval:   opts.Interval,
+		Now:        time.Now,
+	}
+}
+
+// Prune delete logins older than the retention period.
+func (p *LoginHistoryPruner) Prune(ctx context.Context) (deleted int64, err error) {
+	return p.Repository.DeleteUserLoginsBefore(ctx, p.Now().Add(-p.Retention))
+}
+
+// Run prune login history every interval until ctx is done.
+func (p *LoginHistoryPruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := p.Prune(ctx)
+		if err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "prune login history", slog.Any("error", err))
+		} else if deleted > 0 {
+			logging.FromContext(ctx).InfoContext(ctx, "pruned login history", slog.Int64("deleted", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// This file contains the job pruning rate limit buckets that are full again.
+package job
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/logging"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+type RateLimitBucketPruner struct {
+	Repository repository.RepositoryInterface
+	Interval   time.Duration
+	Now        func() time.Time
+}
+
+type NewRateLimitBucketPrunerOptions struct {
+	Repository repository.RepositoryInterface
+	Interval   time.Duration
+}
+
+func NewRateLimitBucketPruner(opts NewRateLimitBucketPrunerOptions) *RateLimitBucketPruner {
+	return &RateLimitBucketPruner{
+		Repository: opts.Repository,
+		Interval:   opts.Interval,
+		Now:        time.Now,
+	}
+}
+
+// Prune delete buckets past their expiry, a missing bucket is a full one.
+func (p *RateLimitBucketPruner) Prune(ctx context.Context) (deleted int64, err error) {
+	return p.Repository.DeleteRateLimitBucketsBefore(ctx, p.Now())
+}
+
+// Run prune rate limit buckets every interval until ctx is done.
+func (p *RateLimitBucketPruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := p.Prune(ctx)
+		if err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "prune rate limit buckets", slog.Any("error", err))
+		} else if deleted > 0 {
+			logging.FromContext(ctx).DebugContext(ctx, "pruned rate limit buckets", slog.Int64("deleted", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
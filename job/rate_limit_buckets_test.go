@@ -0,0 +1,68 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang/mock/gomock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRateLimitBucketPrunerPrune(t *testing.T) {
+	t.Run("TestRateLimitBucketPrunerPrune", func(t *testing.T) {
+		Convey("TestRateLimitBucketPrunerPrune", t, func(c C) {
+			mockTime := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+			testCases := []struct {
+				testID      int
+				testDesc    string
+				mockFunc    func(mockRepository *repository.MockRepositoryInterface)
+				wantDeleted int64
+				wantErr     bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error DeleteRateLimitBucketsBefore",
+					mockFunc: func(mockRepository *repository.MockRepositoryInterface) {
+						mockRepository.EXPECT().DeleteRateLimitBucketsBefore(gomock.Any(), mockTime).Return(int64(0), fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockRepository *repository.MockRepositoryInterface) {
+						mockRepository.EXPECT().DeleteRateLimitBucketsBefore(gomock.Any(), mockTime).Return(int64(5), nil)
+					},
+					wantDeleted: 5,
+					wantErr:     false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					ctrl := gomock.NewController(t)
+					defer ctrl.Finish()
+
+					mockRepository := repository.NewMockRepositoryInterface(ctrl)
+					tc.mockFunc(mockRepository)
+
+					pruner := NewRateLimitBucketPruner(NewRateLimitBucketPrunerOptions{
+						Repository: mockRepository,
+						Interval:   time.Hour,
+					})
+					pruner.Now = func() time.Time { return mockTime }
+
+					deleted, err := pruner.Prune(context.Background())
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(deleted, ShouldEqual, tc.wantDeleted)
+				})
+			}
+		})
+	})
+}
@@ -0,0 +1,177 @@
+// This file contains the process lifecycle manager used by cmd/main.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// A hook releases a resource once the HTTP server has stopped.
+type hook struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+// Manager runs the HTTP server until the context is done or a shutdown
+// signal is received, then drains in-flight requests and runs the shutdown
+// hooks within ShutdownTimeout.
+type Manager struct {
+	Server          *http.Server
+	Listener        net.Listener
+	DrainDelay      time.Duration
+	ShutdownTimeout time.Duration
+	Signals         []os.Signal
+
+	mu      sync.Mutex
+	extra   []*http.Server
+	onDrain []func()
+	hooks   []hook
+}
+
+type NewManagerOptions struct {
+	Server *http.Server
+	// Listener is served instead of listening on Server.Addr when set.
+	Listener net.Listener
+	// DrainDelay keeps serving after shutdown is requested so load balancers
+	// notice the failing readiness before the listener closes.
+	DrainDelay      time.Duration
+	ShutdownTimeout time.Duration
+}
+
+func NewManager(opts NewManagerOptions) *Manager {
+	return &Manager{
+		Server:          opts.Server,
+		Listener:        opts.Listener,
+		DrainDelay:      opts.DrainDelay,
+		ShutdownTimeout: opts.ShutdownTimeout,
+		Signals:         []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+	}
+}
+
+// AddServer register another server, e.g. an admin port, listening on its
+// own Addr and shut down together with Server.
+func (m *Manager) AddServer(server *http.Server) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.extra = append(m.extra, server)
+}
+
+// OnDrain register fn to run as soon as shutdown is requested, while the
+// server still accepts connections, e.g. to fail readiness.
+func (m *Manager) OnDrain(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onDrain = append(m.onDrain, fn)
+}
+
+// OnShutdown register fn to run after the HTTP server has been drained.
+// Hooks run in reverse registration order, so resources registered first,
+// such as the database pool, are released last.
+func (m *Manager) OnShutdown(name string, fn func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook{Name: name, Fn: fn})
+}
+
+// Run serve HTTP until ctx is done, a shutdown signal arrives or the server
+// fails, then shut down gracefully.
+// A clean shutdown returns nil.
+func (m *Manager) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, m.Signals...)
+	defer stop()
+
+	m.mu.Lock()
+	extra := m.extra
+	m.mu.Unlock()
+
+	serveErr := make(chan error, 1+len(extra))
+	go func() {
+		serveErr <- serve(m.Server, m.Listener)
+	}()
+	for _, server := range extra {
+		go func(server *http.Server) {
+			serveErr <- serve(server, nil)
+		}(server)
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+		slog.Info("shutdown requested, draining in-flight requests")
+	case err = <-serveErr:
+		err = fmt.Errorf("serve http: %w", err)
+	}
+	// a second signal falls back to the default behaviour and kills the process.
+	stop()
+
+	if shutdownErr := m.shutdown(); err == nil {
+		err = shutdownErr
+	}
+	return err
+}
+
+func serve(server *http.Server, listener net.Listener) error {
+	var err error
+	if listener != nil {
+		err = server.Serve(listener)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// shutdown stop accepting connections after DrainDelay, then wait for
+// in-flight requests and run hooks, both within ShutdownTimeout.
+// Hooks run even when draining did not finish in time, every failure is
+// logged and the first one is returned.
+func (m *Manager) shutdown() (err error) {
+	m.mu.Lock()
+	onDrain := m.onDrain
+	m.mu.Unlock()
+
+	for _, fn := range onDrain {
+		fn()
+	}
+	time.Sleep(m.DrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.ShutdownTimeout)
+	defer cancel()
+
+	fail := func(e error) {
+		slog.Error("shutdown", slog.Any("error", e))
+		if err == nil {
+			err = e
+		}
+	}
+
+	m.mu.Lock()
+	servers := append([]*http.Server{m.Server}, m.extra...)
+	hooks := m.hooks
+	m.mu.Unlock()
+
+	for _, server := range servers {
+		if e := server.Shutdown(ctx); e != nil {
+			fail(fmt.Errorf("drain http server %s: %w", server.Addr, e))
+		}
+	}
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if e := hooks[i].Fn(ctx); e != nil {
+			fail(fmt.Errorf("shutdown %s: %w", hooks[i].Name, e))
+		}
+	}
+
+	return err
+}
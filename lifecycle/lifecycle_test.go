@@ -0,0 +1,159 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// testServer start a manager serving handler on a random local port.
+// It returns the base url, a function triggering shutdown and the Run result.
+func testServer(manager *Manager, handler http.Handler) (string, context.CancelFunc, <-chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	So(err, ShouldBeNil)
+
+	manager.Server = &http.Server{Handler: handler}
+	manager.Listener = listener
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- manager.Run(ctx)
+	}()
+
+	return "http://" + listener.Addr().String(), cancel, result
+}
+
+func TestRun(t *testing.T) {
+	t.Run("TestRun", func(t *testing.T) {
+		Convey("TestRun", t, func(c C) {
+			testCases := []struct {
+				testID          int
+				testDesc        string
+				requestDuration time.Duration
+				hookErr         error
+				useSignal       bool
+				wantStatusCode  int
+				wantErr         bool
+			}{
+				{
+					testID:          1,
+					testDesc:        "Success - in-flight request drained before hooks run",
+					requestDuration: 200 * time.Millisecond,
+					wantStatusCode:  http.StatusOK,
+					wantErr:         false,
+				},
+				{
+					testID:          2,
+					testDesc:        "Success - SIGTERM triggers shutdown",
+					requestDuration: 200 * time.Millisecond,
+					useSignal:       true,
+					wantStatusCode:  http.StatusOK,
+					wantErr:         false,
+				},
+				{
+					testID:          3,
+					testDesc:        "Failed - request outlives shutdown timeout",
+					requestDuration: 2 * time.Second,
+					wantErr:         true,
+				},
+				{
+					testID:          4,
+					testDesc:        "Failed - hook error",
+					requestDuration: 200 * time.Millisecond,
+					hookErr:         errors.New("close failed"),
+					wantStatusCode:  http.StatusOK,
+					wantErr:         true,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					manager := NewManager(NewManagerOptions{
+						ShutdownTimeout: 500 * time.Millisecond,
+					})
+
+					var (
+						mu    sync.Mutex
+						order []string
+					)
+					record := func(name string) {
+						mu.Lock()
+						defer mu.Unlock()
+						order = append(order, name)
+					}
+					manager.OnDrain(func() {
+						record("drain")
+					})
+					manager.OnShutdown("database", func(context.Context) error {
+						record("database")
+						return tc.hookErr
+					})
+					manager.OnShutdown("worker", func(context.Context) error {
+						record("worker")
+						return nil
+					})
+
+					requestDuration := tc.requestDuration
+					started := make(chan struct{})
+					release := make(chan struct{})
+					url, shutdown, result := testServer(manager, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						close(started)
+						select {
+						case <-time.After(requestDuration):
+						case <-release:
+						}
+						record("request")
+						w.WriteHeader(http.StatusOK)
+					}))
+					defer close(release)
+
+					response := make(chan int, 1)
+					go func() {
+						resp, err := http.Get(url)
+						if err != nil {
+							response <- 0
+							return
+						}
+						resp.Body.Close()
+						response <- resp.StatusCode
+					}()
+					<-started
+
+					if tc.useSignal {
+						So(syscall.Kill(syscall.Getpid(), syscall.SIGTERM), ShouldBeNil)
+					} else {
+						shutdown()
+					}
+					defer shutdown()
+
+					err := <-result
+					So(err != nil, ShouldEqual, tc.wantErr)
+
+					mu.Lock()
+					defer mu.Unlock()
+					if tc.wantStatusCode != 0 {
+						So(<-response, ShouldEqual, tc.wantStatusCode)
+						So(order, ShouldResemble, []string{"drain", "request", "worker", "database"})
+					} else {
+						So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+						So(order, ShouldResemble, []string{"drain", "worker", "database"})
+					}
+
+					// listener no longer accepts connections.
+					_, err = http.Get(url)
+					So(err, ShouldNotBeNil)
+				})
+			}
+		})
+	})
+}
@@ -0,0 +1,125 @@
+// Package logging contains the structured JSON logger, its redaction rules
+// and the request scoped logger carried in the context.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Redacted replaces secret attribute values.
+const Redacted = "[REDACTED]"
+
+// secretKeys are attribute keys whose value is never written.
+var secretKeys = map[string]bool{
+	"password":      true,
+	"new_password":  true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"authorization": true,
+	"secret":        true,
+	"api_key":       true,
+}
+
+// phoneKeys are attribute keys whose value is masked with MaskPhone.
+var phoneKeys = map[string]bool{
+	"phone": true,
+}
+
+// identifierKeys are attribute keys whose value is masked with MaskIdentifier.
+var identifierKeys = map[string]bool{
+	"identifier": true,
+}
+
+type contextKey struct{}
+
+type NewOptions struct {
+	// Level is one of debug, info, warn or error, defaults to info.
+	Level string
+	// Writer defaults to os.Stdout.
+	Writer io.Writer
+}
+
+// New return JSON logger redacting secrets and masking phone numbers.
+func New(opts NewOptions) *slog.Logger {
+	w := opts.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       ParseLevel(opts.Level),
+		ReplaceAttr: redact,
+	}))
+}
+
+// ParseLevel map config log level to slog level, unknown levels are info.
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// redact is the slog ReplaceAttr hook, it applies to attributes in groups too.
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	switch {
+	case secretKeys[key]:
+		return slog.String(a.Key, Redacted)
+	case phoneKeys[key]:
+		return slog.String(a.Key, MaskPhone(a.Value.String()))
+	case identifierKeys[key]:
+		return slog.String(a.Key, MaskIdentifier(a.Value.String()))
+	}
+	return a
+}
+
+// MaskPhone keep the country and operator prefix and the last 3 digits,
+// e.g. +628123456890 becomes +62812****890.
+func MaskPhone(phone string) string {
+	const prefix, suffix = 6, 3
+	if len(phone) <= prefix+suffix {
+		if len(phone) <= suffix {
+			return strings.Repeat("*", len(phone))
+		}
+		return strings.Repeat("*", len(phone)-suffix) + phone[len(phone)-suffix:]
+	}
+	return phone[:prefix] + strings.Repeat("*", len(phone)-prefix-suffix) + phone[len(phone)-suffix:]
+}
+
+// MaskIdentifier mask a login identifier, emails keep the first letter and
+// the domain, e.g. budi@example.com becomes b***@example.com, anything else
+// is masked as a phone number.
+func MaskIdentifier(identifier string) string {
+	at := strings.LastIndex(identifier, "@")
+	if at < 0 {
+		return MaskPhone(identifier)
+	}
+	if at <= 1 {
+		return strings.Repeat("*", at) + identifier[at:]
+	}
+	return identifier[:1] + strings.Repeat("*", at-1) + identifier[at:]
+}
+
+// WithContext return ctx carrying logger.
+func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext return the logger carried by ctx, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
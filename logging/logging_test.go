@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("TestNew", func(t *testing.T) {
+		Convey("TestNew", t, func(c C) {
+			testCases := []struct {
+				testID      int
+				testDesc    string
+				level       string
+				log         func(logger *slog.Logger)
+				contains    []string
+				notContains []string
+			}{
+				{
+					testID:   1,
+					testDesc: "Success - secrets are redacted",
+					log: func(logger *slog.Logger) {
+						logger.Info("login", slog.String("password", "Secret123!"), slog.String("Authorization", "Bearer abc.def.ghi"), slog.String("token", "abc.def.ghi"))
+					},
+					contains:    []string{`"password":"[REDACTED]"`, `"Authorization":"[REDACTED]"`, `"token":"[REDACTED]"`},
+					notContains: []string{"Secret123!", "abc.def.ghi"},
+				},
+				{
+					testID:   2,
+					testDesc: "Success - secrets in groups are redacted",
+					log: func(logger *slog.Logger) {
+						logger.Info("register", slog.Group("payload", slog.String("new_password", "Secret123!"), slog.String("full_name", "Budi")))
+					},
+					contains:    []string{`"payload":{"new_password":"[REDACTED]","full_name":"Budi"}`},
+					notContains: []string{"Secret123!"},
+				},
+				{
+					testID:   3,
+					testDesc: "Success - phone is masked",
+					log: func(logger *slog.Logger) {
+						logger.Info("login failed", slog.String("phone", "+628123456890"))
+					},
+					contains:    []string{`"phone":"+62812****890"`},
+					notContains: []string{"+628123456890"},
+				},
+				{
+					testID:   4,
+					testDesc: "Success - login identifiers are masked",
+					log: func(logger *slog.Logger) {
+						logger.Info("login failed", slog.String("identifier", "+628123456890"))
+						logger.Info("login failed", slog.String("identifier", "budi@example.com"))
+					},
+					contains:    []string{`"identifier":"+62812****890"`, `"identifier":"b***@example.com"`},
+					notContains: []string{"+628123456890", "budi@"},
+				},
+				{
+					testID:   5,
+					testDesc: "Success - entries below level are dropped",
+					level:    "warn",
+					log: func(logger *slog.Logger) {
+						logger.Info("dropped")
+						logger.Warn("kept")
+					},
+					contains:    []string{`"msg":"kept"`},
+					notContains: []string{"dropped"},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					var buf bytes.Buffer
+					tc.log(New(NewOptions{Level: tc.level, Writer: &buf}))
+					// assert
+					for _, want := range tc.contains {
+						So(buf.String(), ShouldContainSubstring, want)
+					}
+					for _, unwanted := range tc.notContains {
+						So(buf.String(), ShouldNotContainSubstring, unwanted)
+					}
+				})
+			}
+		})
+	})
+}
+
+func TestMaskPhone(t *testing.T) {
+	t.Run("TestMaskPhone", func(t *testing.T) {
+		Convey("TestMaskPhone", t, func(c C) {
+			testCases := []struct {
+				testID   int
+				testDesc string
+				phone    string
+				want     string
+			}{
+				{testID: 1, testDesc: "Success - full number", phone: "+628123456890", want: "+62812****890"},
+				{testID: 2, testDesc: "Success - shortest valid number", phone: "+6281234567", want: "+62812**567"},
+				{testID: 3, testDesc: "Success - short value keeps last digits", phone: "12345", want: "**345"},
+				{testID: 4, testDesc: "Success - tiny value is fully masked", phone: "123", want: "***"},
+				{testID: 5, testDesc: "Success - empty value", phone: "", want: ""},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					So(MaskPhone(tc.phone), ShouldEqual, tc.want)
+				})
+			}
+		})
+	})
+}
+
+func TestFromContext(t *testing.T) {
+	t.Run("TestFromContext", func(t *testing.T) {
+		Convey("TestFromContext", t, func(c C) {
+			logger := New(NewOptions{})
+
+			So(FromContext(context.Background()), ShouldEqual, slog.Default())
+			So(FromContext(WithContext(context.Background(), logger)), ShouldEqual, logger)
+		})
+	})
+}
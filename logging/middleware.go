@@ -0,0 +1,94 @@
+// This file contains the echo middlewares tagging requests with an id and
+// writing access logs.
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxRequestIDLength bounds client supplied request ids.
+const maxRequestIDLength = 128
+
+// RequestID honor a valid X-Request-ID header or generate one, echo it in the
+// response and carry a logger tagged with it in the request context.
+func RequestID(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+
+			ctx := WithContext(req.Context(), logger.With(slog.String("request_id", id)))
+			c.SetRequest(req.WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
+
+// AccessLog write one entry per request with the logger from the request context.
+// Query strings and headers are not logged, they may carry credentials.
+func AccessLog() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// let the error handler write the response so the status is final.
+				c.Error(err)
+			}
+
+			req := c.Request()
+			status := c.Response().Status
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.String("route", c.Path()),
+				slog.Int("status", status),
+				slog.Duration("latency", time.Since(start)),
+				slog.String("ip", c.RealIP()),
+				slog.String("user_agent", req.UserAgent()),
+			}
+			if span := trace.SpanContextFromContext(req.Context()); span.HasTraceID() {
+				attrs = append(attrs, slog.String("trace_id", span.TraceID().String()))
+			}
+
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+			FromContext(req.Context()).LogAttrs(req.Context(), level, "http request", attrs...)
+
+			return nil
+		}
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRequestID(t *testing.T) {
+	t.Run("TestRequestID", func(t *testing.T) {
+		Convey("TestRequestID", t, func(c C) {
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				requestID string
+				handler   echo.HandlerFunc
+				wantID    string
+				// wantStatus is the status written in the access log.
+				wantStatus int
+			}{
+				{
+					testID:     1,
+					testDesc:   "Success - valid request id is honored",
+					requestID:  "req-123_abc.1",
+					handler:    func(c echo.Context) error { return c.NoContent(http.StatusOK) },
+					wantID:     "req-123_abc.1",
+					wantStatus: http.StatusOK,
+				},
+				{
+					testID:     2,
+					testDesc:   "Success - missing request id is generated",
+					handler:    func(c echo.Context) error { return c.NoContent(http.StatusOK) },
+					wantStatus: http.StatusOK,
+				},
+				{
+					testID:     3,
+					testDesc:   "Success - invalid request id is replaced",
+					requestID:  "<script>",
+					handler:    func(c echo.Context) error { return c.NoContent(http.StatusOK) },
+					wantStatus: http.StatusOK,
+				},
+				{
+					testID:     4,
+					testDesc:   "Success - too long request id is replaced",
+					requestID:  strings.Repeat("a", maxRequestIDLength+1),
+					handler:    func(c echo.Context) error { return c.NoContent(http.StatusOK) },
+					wantStatus: http.StatusOK,
+				},
+				{
+					testID:     5,
+					testDesc:   "Success - handler error status is logged",
+					requestID:  "req-500",
+					handler:    func(c echo.Context) error { return echo.ErrInternalServerError },
+					wantID:     "req-500",
+					wantStatus: http.StatusInternalServerError,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					var buf bytes.Buffer
+					e := echo.New()
+					e.Use(RequestID(New(NewOptions{Writer: &buf})))
+					e.Use(AccessLog())
+					e.GET("/users/:id", tc.handler)
+
+					req := httptest.NewRequest(http.MethodGet, "/users/1?token=abc.def.ghi", nil)
+					if tc.requestID != "" {
+						req.Header.Set(echo.HeaderXRequestID, tc.requestID)
+					}
+					rec := httptest.NewRecorder()
+					e.ServeHTTP(rec, req)
+
+					// assert
+					id := rec.Header().Get(echo.HeaderXRequestID)
+					if tc.wantID != "" {
+						So(id, ShouldEqual, tc.wantID)
+					} else {
+						So(id, ShouldHaveLength, 32)
+						So(id, ShouldNotEqual, tc.requestID)
+					}
+					So(rec.Code, ShouldEqual, tc.wantStatus)
+
+					var entry map[string]any
+					So(json.Unmarshal(buf.Bytes(), &entry), ShouldBeNil)
+					So(entry["msg"], ShouldEqual, "http request")
+					So(entry["request_id"], ShouldEqual, id)
+					So(entry["path"], ShouldEqual, "/users/1")
+					So(entry["route"], ShouldEqual, "/users/:id")
+					So(entry["status"], ShouldEqual, float64(tc.wantStatus))
+					So(buf.String(), ShouldNotContainSubstring, "abc.def.ghi")
+				})
+			}
+		})
+	})
+}
@@ -0,0 +1,192 @@
+// Package mail contains the senders of the transactional mails of the
+// service: SMTP for production, a log sender for development and a memory
+// sender for tests.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidHeader is returned for a recipient or subject spanning lines.
+var ErrInvalidHeader = errors.New("mail: invalid header")
+
+// Message is a plain text mail.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers a message.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender writes the messages to the log instead of sending them.
+type LogSender struct {
+	Logger *slog.Logger
+}
+
+type NewLogSenderOptions struct {
+	// Logger default to slog.Default.
+	Logger *slog.Logger
+}
+
+func NewLogSender(opts NewLogSenderOptions) *LogSender {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return &LogSender{Logger: opts.Logger}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	s.Logger.InfoContext(ctx, "mail",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+	return nil
+}
+
+// SMTPSender sends the messages through an SMTP relay, upgrading the
+// connection with STARTTLS when the relay offers it.
+type SMTPSender struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+type NewSMTPSenderOptions struct {
+	// Addr is the host:port of the relay.
+	Addr string
+	// Username and Password authenticate with PLAIN when Username is set.
+	Username string
+	Password string
+	// From is the sender, an address optionally with a name.
+	From string
+}
+
+func NewSMTPSender(opts NewSMTPSenderOptions) *SMTPSender {
+	return &SMTPSender{
+		Addr:     opts.Addr,
+		Username: opts.Username,
+		Password: opts.Password,
+		From:     opts.From,
+	}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	from, err := netmail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("parse from: %w", err)
+	}
+	data, err := format(s.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("dial smtp: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	host, _, _ := net.SplitHostPort(s.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return fmt.Errorf("smtp hello: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if s.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err = c.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp mail: %w", err)
+	}
+	if err = c.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("smtp rcpt: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err = w.Write(data); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	return c.Quit()
+}
+
+// format return msg as an RFC 5322 message with CRLF line endings.
+func format(from string, msg Message, now time.Time) ([]byte, error) {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, ErrInvalidHeader
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	if !strings.HasSuffix(body, "\n") {
+		b.WriteString("\r\n")
+	}
+	return b.Bytes(), nil
+}
+
+// MemorySender keeps the messages, for tests.
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (s *MemorySender) Send(_ context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Messages return the messages sent so far, oldest first.
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
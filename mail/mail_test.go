@@ -0,0 +1,135 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFormat(t *testing.T) {
+	t.Run("TestFormat", func(t *testing.T) {
+		Convey("TestFormat", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				msg      Message
+				want     string
+				wantErr  error
+			}{
+				{
+					testID:   1,
+					testDesc: "Success - body lines end with CRLF",
+					msg:      Message{To: "budi@example.com", Subject: "Verify", Body: "line 1\nline 2"},
+					want: "From: User Service <no-reply@example.com>\r\n" +
+						"To: budi@example.com\r\n" +
+						"Subject: Verify\r\n" +
+						"Date: Sun, 01 Jan 2023 23:59:59 +0000\r\n" +
+						"MIME-Version: 1.0\r\n" +
+						"Content-Type: text/plain; charset=utf-8\r\n" +
+						"Content-Transfer-Encoding: 8bit\r\n" +
+						"\r\n" +
+						"line 1\r\nline 2\r\n",
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - header injection in subject",
+					msg:      Message{To: "budi@example.com", Subject: "Verify\r\nBcc: andi@example.com"},
+					wantErr:  ErrInvalidHeader,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					got, err := format("User Service <no-reply@example.com>", tc.msg, mockTime)
+					// assert
+					if tc.wantErr != nil {
+						So(errors.Is(err, tc.wantErr), ShouldBeTrue)
+						return
+					}
+					So(err, ShouldBeNil)
+					So(string(got), ShouldEqual, tc.want)
+				})
+			}
+		})
+	})
+}
+
+func TestSMTPSender(t *testing.T) {
+	t.Run("TestSMTPSender", func(t *testing.T) {
+		Convey("TestSMTPSender", t, func(c C) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer ln.Close()
+
+			// a relay accepting one message and reporting the envelope and data.
+			received := make(chan []string, 1)
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+				var lines []string
+				reply("220 localhost")
+				data := false
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					line = strings.TrimRight(line, "\r\n")
+					if data {
+						if line == "." {
+							data = false
+							reply("250 ok")
+							continue
+						}
+						lines = append(lines, line)
+						continue
+					}
+					switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+					case "EHLO", "HELO":
+						reply("250 localhost")
+					case "MAIL", "RCPT":
+						lines = append(lines, line)
+						reply("250 ok")
+					case "DATA":
+						data = true
+						reply("354 go ahead")
+					case "QUIT":
+						reply("221 bye")
+						received <- lines
+						return
+					default:
+						reply("500 unknown")
+					}
+				}
+			}()
+
+			sender := NewSMTPSender(NewSMTPSenderOptions{
+				Addr: ln.Addr().String(),
+				From: "User Service <no-reply@example.com>",
+			})
+			err = sender.Send(context.Background(), Message{To: "budi@example.com", Subject: "Verify", Body: "hello"})
+			So(err, ShouldBeNil)
+
+			lines := <-received
+			So(lines[0], ShouldEqual, "MAIL FROM:<no-reply@example.com>")
+			So(lines[1], ShouldEqual, "RCPT TO:<budi@example.com>")
+			So(lines, ShouldContain, "To: budi@example.com")
+			So(lines[len(lines)-1], ShouldEqual, "hello")
+		})
+	})
+}
@@ -0,0 +1,171 @@
+// Package metrics contains the Prometheus metrics exported by the service.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/common"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "user_service"
+
+// unknownOperation labels requests not matching an operation of the spec,
+// so unrouted paths can not blow up label cardinality.
+const unknownOperation = "unknown"
+
+// Login outcomes besides the repository login failure reasons.
+const (
+	LoginFailedInvalidPayload = "invalid_payload"
+	LoginFailedError          = "error"
+	// LoginFailedInvalidIDToken is a social login with an invalid ID token.
+	LoginFailedInvalidIDToken = "invalid_id_token"
+	// LoginFailedIdentityConflict is a social login with the email of
+	// another user.
+	LoginFailedIdentityConflict = "identity_conflict"
+)
+
+// Token validation failure reasons.
+const (
+	TokenMalformed      = "malformed"
+	TokenInvalid        = "invalid"
+	TokenSessionRevoked = "session_revoked"
+	TokenError          = "error"
+)
+
+// Metrics holds the service collectors.
+// Methods are no-ops on a nil *Metrics, so callers need no metrics in tests.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	operations map[string]string
+
+	requests                *prometheus.CounterVec
+	requestDuration         *prometheus.HistogramVec
+	logins                  *prometheus.CounterVec
+	registrations           prometheus.Counter
+	tokenValidationFailures *prometheus.CounterVec
+}
+
+type NewMetricsOptions struct {
+	// Spec maps echo routes to operation ids.
+	Spec *openapi3.T
+	// Db exports connection pool stats when set.
+	Db *sql.DB
+}
+
+func NewMetrics(opts NewMetricsOptions) *Metrics {
+	m := &Metrics{
+		Registry:   prometheus.NewRegistry(),
+		operations: common.OperationIDs(opts.Spec),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by operation id and status code.",
+		}, []string{"operation", "method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by operation id.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "method"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by result and failure reason.",
+		}, []string{"result", "reason"}),
+		registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "registrations_total",
+			Help:      "Successful user registrations.",
+		}),
+		tokenValidationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "token_validation_failures_total",
+			Help:      "Rejected access tokens by reason.",
+		}, []string{"reason"}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.logins,
+		m.registrations,
+		m.tokenValidationFailures,
+	)
+	if opts.Db != nil {
+		m.Registry.MustRegister(collectors.NewDBStatsCollector(opts.Db, "users"))
+	}
+
+	return m
+}
+
+// Middleware record request count and latency labeled by operation id.
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if m == nil {
+			return next
+		}
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			status := c.Response().Status
+			if httpErr, ok := err.(*echo.HTTPError); ok && !c.Response().Committed {
+				status = httpErr.Code
+			}
+
+			method := c.Request().Method
+			operation, ok := m.operations[method+" "+c.Path()]
+			if !ok {
+				operation = unknownOperation
+			}
+			m.requests.WithLabelValues(operation, method, strconv.Itoa(status)).Inc()
+			m.requestDuration.WithLabelValues(operation, method).Observe(time.Since(start).Seconds())
+
+			return err
+		}
+	}
+}
+
+// Handler serve metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+func (m *Metrics) LoginSucceeded() {
+	if m == nil {
+		return
+	}
+	m.logins.WithLabelValues("success", "").Inc()
+}
+
+func (m *Metrics) LoginFailed(reason string) {
+	if m == nil {
+		return
+	}
+	m.logins.WithLabelValues("failure", reason).Inc()
+}
+
+func (m *Metrics) UserRegistered() {
+	if m == nil {
+		return
+	}
+	m.registrations.Inc()
+}
+
+func (m *Metrics) TokenValidationFailed(reason string) {
+	if m == nil {
+		return
+	}
+	m.tokenValidationFailures.WithLabelValues(reason).Inc()
+}
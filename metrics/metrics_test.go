@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Run("TestMiddleware", func(t *testing.T) {
+		Convey("TestMiddleware", t, func(c C) {
+			spec, err := generated.GetSwagger()
+			So(err, ShouldBeNil)
+
+			testCases := []struct {
+				testID        int
+				testDesc      string
+				method        string
+				path          string
+				wantOperation string
+				wantCode      string
+			}{
+				{
+					testID:        1,
+					testDesc:      "Success - static route",
+					method:        http.MethodGet,
+					path:          "/users/sessions",
+					wantOperation: "ListUserSessions",
+					wantCode:      "200",
+				},
+				{
+					testID:        2,
+					testDesc:      "Success - route with path parameter",
+					method:        http.MethodDelete,
+					path:          "/users/sessions/abc",
+					wantOperation: "RevokeUserSession",
+					wantCode:      "200",
+				},
+				{
+					testID:        3,
+					testDesc:      "Success - unknown route",
+					method:        http.MethodGet,
+					path:          "/wp-admin",
+					wantOperation: unknownOperation,
+					wantCode:      "404",
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					m := NewMetrics(NewMetricsOptions{Spec: spec})
+
+					e := echo.New()
+					e.Use(m.Middleware())
+					ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+					e.GET("/users/sessions", ok)
+					e.DELETE("/users/sessions/:id", ok)
+
+					rr := httptest.NewRecorder()
+					e.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+
+					// assert
+					So(testutil.ToFloat64(m.requests.WithLabelValues(tc.wantOperation, tc.method, tc.wantCode)), ShouldEqual, 1)
+					So(testutil.CollectAndCount(m.requestDuration), ShouldEqual, 1)
+				})
+			}
+		})
+	})
+}
+
+func TestAuthCounters(t *testing.T) {
+	t.Run("TestAuthCounters", func(t *testing.T) {
+		Convey("TestAuthCounters", t, func(c C) {
+			Convey("records outcomes", func() {
+				m := NewMetrics(NewMetricsOptions{})
+				m.LoginSucceeded()
+				m.LoginFailed("invalid_password")
+				m.LoginFailed("invalid_password")
+				m.UserRegistered()
+				m.TokenValidationFailed(TokenSessionRevoked)
+
+				So(testutil.ToFloat64(m.logins.WithLabelValues("success", "")), ShouldEqual, 1)
+				So(testutil.ToFloat64(m.logins.WithLabelValues("failure", "invalid_password")), ShouldEqual, 2)
+				So(testutil.ToFloat64(m.registrations), ShouldEqual, 1)
+				So(testutil.ToFloat64(m.tokenValidationFailures.WithLabelValues(TokenSessionRevoked)), ShouldEqual, 1)
+
+				expected := `
+# HELP user_service_registrations_total Successful user registrations.
+# TYPE user_service_registrations_total counter
+user_service_registrations_total 1
+`
+				So(testutil.GatherAndCompare(m.Registry, strings.NewReader(expected), "user_service_registrations_total"), ShouldBeNil)
+			})
+
+			Convey("nil metrics is a no-op", func() {
+				var m *Metrics
+				So(func() {
+					m.LoginSucceeded()
+					m.LoginFailed("invalid_password")
+					m.UserRegistered()
+					m.TokenValidationFailed(TokenInvalid)
+				}, ShouldNotPanic)
+
+				e := echo.New()
+				c := e.NewContext(httptest.NewRequest(http.MethodGet, "/users", nil), httptest.NewRecorder())
+				So(m.Middleware()(func(c echo.Context) error { return nil })(c), ShouldBeNil)
+			})
+		})
+	})
+}
@@ -1,5 +1,5 @@
 /**
-  This is the SQL script that will be used to initialize the database schema.
+  This is the initial database schema, later changes are added as new migrations.
   We will evaluate you based on how well you design your database.
   1. How you design the tables.
   2. How you choose the data types and keys.
@@ -0,0 +1,15 @@
+-- idempotency_keys stores the first response of a request sent with an Idempotency-Key
+-- header so retries replay it. status_code is NULL while the first request is in flight.
+CREATE TABLE idempotency_keys (
+  scope VARCHAR (100) NOT NULL,
+  key VARCHAR (255) NOT NULL,
+  fingerprint CHAR (64) NOT NULL,
+  status_code INT NULL,
+  content_type VARCHAR (255) NOT NULL DEFAULT '',
+  body BYTEA NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,10 @@
+-- rate_limit_buckets holds the token buckets of the rate limiter when several
+-- instances share the limits. A bucket is full again once expires_at is past.
+CREATE TABLE rate_limit_buckets (
+  key VARCHAR (255) PRIMARY KEY,
+  tokens DOUBLE PRECISION NOT NULL,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX rate_limit_buckets_expires_at_idx ON rate_limit_buckets (expires_at);
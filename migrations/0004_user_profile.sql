@@ -0,0 +1,24 @@
+-- Optional profile fields of users. email is stored normalized and is
+-- unverified until email_verified_at is set through email_verifications.
+ALTER TABLE users
+  ADD COLUMN email VARCHAR (254) NULL,
+  ADD COLUMN email_verified_at TIMESTAMP WITH TIME ZONE NULL,
+  ADD COLUMN display_name VARCHAR (60) NULL,
+  ADD COLUMN birth_date DATE NULL,
+  ADD COLUMN locale VARCHAR (35) NULL,
+  ADD COLUMN timezone VARCHAR (64) NULL,
+  ADD COLUMN avatar_url VARCHAR (2048) NULL;
+
+CREATE UNIQUE INDEX users_email_key ON users (email);
+
+-- email_verifications holds the pending confirmation of the email of a user,
+-- keyed by the hash of the token mailed to it. A user has at most one.
+CREATE TABLE email_verifications (
+  token_hash CHAR (64) PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  email VARCHAR (254) NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX email_verifications_user_id_idx ON email_verifications (user_id);
@@ -0,0 +1,5 @@
+-- Thumbnails of an uploaded avatar, a JSON array of {size, url, key} where key
+-- is the blob the thumbnail is stored as. They are cleared whenever
+-- avatar_url is changed without them.
+ALTER TABLE users
+  ADD COLUMN avatar_thumbnails JSONB NULL;
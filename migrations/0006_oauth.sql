@@ -0,0 +1,28 @@
+-- oauth_clients holds the applications logging users in through the OAuth
+-- authorization code flow. secret_hash is the hex SHA-256 of the client
+-- secret, NULL for public clients relying on PKCE alone. Users are not asked
+-- for consent by first party clients.
+CREATE TABLE oauth_clients (
+  id VARCHAR (64) PRIMARY KEY,
+  name VARCHAR (60) NOT NULL,
+  secret_hash CHAR (64) NULL,
+  redirect_uris TEXT[] NOT NULL,
+  first_party BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- oauth_authorization_codes holds the codes issued to clients, keyed by
+-- their hash, until they are exchanged for tokens.
+CREATE TABLE oauth_authorization_codes (
+  code_hash CHAR (64) PRIMARY KEY,
+  client_id VARCHAR (64) NOT NULL REFERENCES oauth_clients (id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  redirect_uri VARCHAR (2048) NOT NULL,
+  scope VARCHAR (255) NOT NULL,
+  nonce VARCHAR (255) NOT NULL DEFAULT '',
+  code_challenge VARCHAR (128) NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX oauth_authorization_codes_expires_at_idx ON oauth_authorization_codes (expires_at);
@@ -0,0 +1,17 @@
+-- Users signing up with an external identity provider have no phone and an
+-- empty password until they set them.
+ALTER TABLE users
+  ALTER COLUMN phone DROP NOT NULL;
+
+-- user_identities links the subject of an external OpenID Connect provider to
+-- a user. A user has at most one identity per provider. email is the one the
+-- provider asserted when the identity was linked.
+CREATE TABLE user_identities (
+  provider VARCHAR (64) NOT NULL,
+  subject VARCHAR (255) NOT NULL,
+  user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  email VARCHAR (254) NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (provider, subject),
+  CONSTRAINT user_identities_user_id_provider_key UNIQUE (user_id, provider)
+);
@@ -0,0 +1,21 @@
+-- api_keys holds the keys of service integrations, acting as user_id with
+-- the given scopes. Keys are stored as the hex SHA-256 of the key, prefix is
+-- its visible start identifying it in listings and logs. A key without
+-- rate_limit gets the configured default. Expired and revoked keys are kept
+-- so their use is still traceable.
+CREATE TABLE api_keys (
+  id VARCHAR (32) PRIMARY KEY,
+  name VARCHAR (60) NOT NULL,
+  prefix VARCHAR (16) NOT NULL UNIQUE,
+  key_hash CHAR (64) NOT NULL UNIQUE,
+  user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  scopes TEXT[] NOT NULL,
+  rate_limit INT NULL,
+  expires_at TIMESTAMP WITH TIME ZONE NULL,
+  last_used_at TIMESTAMP WITH TIME ZONE NULL,
+  created_by INT NULL REFERENCES users (id) ON DELETE SET NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  revoked_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
@@ -0,0 +1,57 @@
+-- Versions, login history, sessions and the audit log of users. Databases
+-- created from database.sql may have some of them already, so every
+-- statement is idempotent.
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS last_login_at TIMESTAMP WITH TIME ZONE NULL,
+  ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+-- user_events is an append-only audit log of security relevant user events.
+CREATE TABLE IF NOT EXISTS user_events (
+  id BIGSERIAL PRIMARY KEY,
+  user_id INT NULL REFERENCES users (id),
+  event_type VARCHAR (40) NOT NULL,
+  ip_address VARCHAR (45) NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  details JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS user_events_user_id_created_at_idx ON user_events (user_id, created_at DESC);
+
+CREATE OR REPLACE FUNCTION reject_user_events_change() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'user_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS user_events_append_only ON user_events;
+CREATE TRIGGER user_events_append_only
+  BEFORE UPDATE OR DELETE ON user_events
+  FOR EACH ROW EXECUTE FUNCTION reject_user_events_change();
+
+-- user_logins keeps the history of successful logins, pruned after the retention period.
+CREATE TABLE IF NOT EXISTS user_logins (
+  id BIGSERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users (id),
+  token_id VARCHAR (64) NOT NULL,
+  ip_address VARCHAR (45) NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS user_logins_user_id_id_idx ON user_logins (user_id, id DESC);
+CREATE INDEX IF NOT EXISTS user_logins_created_at_idx ON user_logins (created_at);
+
+-- user_sessions tracks the devices a user is logged in from, tokens carry the session id.
+CREATE TABLE IF NOT EXISTS user_sessions (
+  id VARCHAR (64) PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users (id),
+  device_name VARCHAR (100) NOT NULL DEFAULT '',
+  ip_address VARCHAR (45) NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  revoked_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+CREATE INDEX IF NOT EXISTS user_sessions_active_user_id_idx ON user_sessions (user_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,4 @@
+-- Response headers replayed with a stored response, a JSON object of the
+-- headers whitelisted by the idempotency middleware, e.g. Location and ETag.
+ALTER TABLE idempotency_keys
+  ADD COLUMN headers JSONB NULL;
@@ -0,0 +1,5 @@
+-- Only verified emails are unique. A pending email is not proven to be the
+-- user's, so it must not block the owner from setting and verifying it.
+DROP INDEX users_email_key;
+
+CREATE UNIQUE INDEX users_email_key ON users (email) WHERE email_verified_at IS NOT NULL;
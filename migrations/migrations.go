@@ -0,0 +1,258 @@
+// Package migrations contains the database schema as numbered SQL files
+// embedded into the binary, and the migrator applying them.
+//
+// A migration file is named <version>_<name>.sql, versions are applied in
+// ascending order and recorded in the schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockID is the advisory lock key serializing migrations across instances.
+const lockID = 4_261_372_001
+
+const (
+	createMigrationsTableQuery = `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version BIGINT PRIMARY KEY,
+  name VARCHAR (100) NOT NULL,
+  applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+)`
+	lockQuery                  = `SELECT pg_advisory_xact_lock($1)`
+	migrationsTableExistsQuery = `SELECT to_regclass('schema_migrations') IS NOT NULL`
+	baselineExistsQuery        = `SELECT to_regclass('users') IS NOT NULL`
+	listAppliedQuery           = `SELECT version FROM schema_migrations ORDER BY version`
+	insertAppliedQuery         = `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	SQL     string
+}
+
+// Status describes how far the database schema is behind the embedded migrations.
+type Status struct {
+	Current int64
+	Latest  int64
+	Pending []int64
+}
+
+// UpToDate report whether every migration has been applied.
+func (s Status) UpToDate() bool {
+	return len(s.Pending) == 0
+}
+
+func (s Status) String() string {
+	if s.UpToDate() {
+		return fmt.Sprintf("schema at version %d", s.Current)
+	}
+	return fmt.Sprintf("schema at version %d of %d, %d pending", s.Current, s.Latest, len(s.Pending))
+}
+
+type Migrator struct {
+	Db         *sql.DB
+	Migrations []Migration
+}
+
+type NewMigratorOptions struct {
+	Db *sql.DB
+}
+
+// NewMigrator return migrator for the embedded migrations.
+func NewMigrator(opts NewMigratorOptions) (*Migrator, error) {
+	migrations, err := load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		Db:         opts.Db,
+		Migrations: migrations,
+	}, nil
+}
+
+// load read embedded migrations sorted by version.
+func load() ([]Migration, error) {
+	entries, err := files.ReadDir(".")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	seen := map[int64]string{}
+	for _, entry := range entries {
+		version, name, err := parseName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migration version %d used by %s and %s", version, other, entry.Name())
+		}
+		seen[version] = entry.Name()
+
+		content, err := files.ReadFile(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// parseName split migration file name like 0001_init.sql into version and name.
+func parseName(file string) (version int64, name string, err error) {
+	base := strings.TrimSuffix(path.Base(file), ".sql")
+	prefix, name, ok := strings.Cut(base, "_")
+	if !ok || name == "" {
+		return 0, "", fmt.Errorf("migration %s must be named <version>_<name>.sql", file)
+	}
+	version, err = strconv.ParseInt(prefix, 10, 64)
+	if err != nil || version < 1 {
+		return 0, "", fmt.Errorf("migration %s must start with a positive version", file)
+	}
+	return version, name, nil
+}
+
+// Up apply pending migrations in a single transaction.
+// Concurrent instances wait on an advisory lock, so each migration runs once.
+func (m *Migrator) Up(ctx context.Context) (applied []int64, err error) {
+	tx, err := m.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, lockQuery, lockID); err != nil {
+		return nil, fmt.Errorf("lock migrations: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, createMigrationsTableQuery); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	done, err := appliedVersions(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(done) == 0 {
+		if err = m.adoptBaseline(ctx, tx, done); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, migration := range m.Migrations {
+		if done[migration.Version] {
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, migration.SQL); err != nil {
+			return nil, fmt.Errorf("apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if _, err = tx.ExecContext(ctx, insertAppliedQuery, migration.Version, migration.Name); err != nil {
+			return nil, fmt.Errorf("record migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		applied = append(applied, migration.Version)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return applied, nil
+}
+
+// adoptBaseline record the first migration as applied without running it
+// when the database was created from database.sql before migrations existed,
+// so its users table is kept and later migrations bring it up to date.
+func (m *Migrator) adoptBaseline(ctx context.Context, tx *sql.Tx, done map[int64]bool) error {
+	if len(m.Migrations) == 0 || m.Migrations[0].Version != 1 {
+		return nil
+	}
+	baseline := m.Migrations[0]
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, baselineExistsQuery).Scan(&exists); err != nil {
+		return fmt.Errorf("detect existing schema: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, insertAppliedQuery, baseline.Version, baseline.Name); err != nil {
+		return fmt.Errorf("record migration %d_%s: %w", baseline.Version, baseline.Name, err)
+	}
+	done[baseline.Version] = true
+	return nil
+}
+
+// Status compare applied migrations with the embedded ones.
+func (m *Migrator) Status(ctx context.Context) (status Status, err error) {
+	if len(m.Migrations) > 0 {
+		status.Latest = m.Migrations[len(m.Migrations)-1].Version
+	}
+
+	var exists bool
+	if err = m.Db.QueryRowContext(ctx, migrationsTableExistsQuery).Scan(&exists); err != nil {
+		return status, err
+	}
+
+	done := map[int64]bool{}
+	if exists {
+		if done, err = appliedVersions(ctx, m.Db); err != nil {
+			return status, err
+		}
+	}
+
+	for _, migration := range m.Migrations {
+		if done[migration.Version] {
+			status.Current = migration.Version
+			continue
+		}
+		status.Pending = append(status.Pending, migration.Version)
+	}
+	return status, nil
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func appliedVersions(ctx context.Context, q querier) (map[int64]bool, error) {
+	rows, err := q.QueryContext(ctx, listAppliedQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		if err = rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		done[version] = true
+	}
+	return done, rows.Err()
+}
+
+// Check report migration status for readiness, pending migrations fail the check.
+func (m *Migrator) Check(ctx context.Context) (details string, err error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !status.UpToDate() {
+		return status.String(), fmt.Errorf("%d pending migrations", len(status.Pending))
+	}
+	return status.String(), nil
+}
@@ -0,0 +1,293 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var mockMigrations = []Migration{
+	{Version: 1, Name: "init", SQL: "CREATE TABLE users (id serial PRIMARY KEY)"},
+	{Version: 2, Name: "add_email", SQL: "ALTER TABLE users ADD COLUMN email TEXT"},
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("TestLoad", func(t *testing.T) {
+		Convey("TestLoad", t, func(c C) {
+			migrations, err := load()
+			So(err, ShouldBeNil)
+			So(migrations, ShouldNotBeEmpty)
+			So(migrations[0].Version, ShouldEqual, 1)
+			So(migrations[0].Name, ShouldEqual, "init")
+			for i := 1; i < len(migrations); i++ {
+				So(migrations[i].Version, ShouldBeGreaterThan, migrations[i-1].Version)
+			}
+		})
+	})
+}
+
+func TestParseName(t *testing.T) {
+	t.Run("TestParseName", func(t *testing.T) {
+		Convey("TestParseName", t, func(c C) {
+			testCases := []struct {
+				testID      int
+				file        string
+				wantVersion int64
+				wantName    string
+				wantErr     bool
+			}{
+				{testID: 1, file: "0001_init.sql", wantVersion: 1, wantName: "init"},
+				{testID: 2, file: "0012_add_user_email.sql", wantVersion: 12, wantName: "add_user_email"},
+				{testID: 3, file: "init.sql", wantErr: true},
+				{testID: 4, file: "0001_.sql", wantErr: true},
+				{testID: 5, file: "0000_init.sql", wantErr: true},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.file), func() {
+					version, name, err := parseName(tc.file)
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(version, ShouldEqual, tc.wantVersion)
+					So(name, ShouldEqual, tc.wantName)
+				})
+			}
+		})
+	})
+}
+
+func TestUp(t *testing.T) {
+	t.Run("TestUp", func(t *testing.T) {
+		Convey("TestUp", t, func(c C) {
+			testCases := []struct {
+				testID      int
+				testDesc    string
+				mockFunc    func(mockSQL sqlmock.Sqlmock)
+				wantApplied []int64
+				wantErr     bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error lock",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("SELECT pg_advisory_xact_lock").
+							WithArgs(lockID).
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - error apply migration",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("SELECT pg_advisory_xact_lock").
+							WithArgs(lockID).
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectQuery("SELECT version FROM schema_migrations").
+							WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(int64(1)))
+						mockSQL.ExpectExec("ALTER TABLE users").
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr: true,
+				},
+				{
+					testID:   3,
+					testDesc: "Success - apply pending migrations",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("SELECT pg_advisory_xact_lock").
+							WithArgs(lockID).
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectQuery("SELECT version FROM schema_migrations").
+							WillReturnRows(sqlmock.NewRows([]string{"version"}))
+						mockSQL.ExpectQuery("SELECT to_regclass").
+							WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+						mockSQL.ExpectExec("CREATE TABLE users").
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectExec("INSERT INTO schema_migrations").
+							WithArgs(int64(1), "init").
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectExec("ALTER TABLE users").
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectExec("INSERT INTO schema_migrations").
+							WithArgs(int64(2), "add_email").
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectCommit()
+					},
+					wantApplied: []int64{1, 2},
+					wantErr:     false,
+				},
+				{
+					testID:   4,
+					testDesc: "Success - nothing pending",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("SELECT pg_advisory_xact_lock").
+							WithArgs(lockID).
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectQuery("SELECT version FROM schema_migrations").
+							WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(int64(1)).AddRow(int64(2)))
+						mockSQL.ExpectCommit()
+					},
+					wantErr: false,
+				},
+				{
+					testID:   5,
+					testDesc: "Success - adopt existing schema as baseline",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("SELECT pg_advisory_xact_lock").
+							WithArgs(lockID).
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectQuery("SELECT version FROM schema_migrations").
+							WillReturnRows(sqlmock.NewRows([]string{"version"}))
+						mockSQL.ExpectQuery("SELECT to_regclass").
+							WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+						mockSQL.ExpectExec("INSERT INTO schema_migrations").
+							WithArgs(int64(1), "init").
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectExec("ALTER TABLE users").
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectExec("INSERT INTO schema_migrations").
+							WithArgs(int64(2), "add_email").
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectCommit()
+					},
+					wantApplied: []int64{2},
+					wantErr:     false,
+				},
+				{
+					testID:   6,
+					testDesc: "Failed - error detect existing schema",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("SELECT pg_advisory_xact_lock").
+							WithArgs(lockID).
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectQuery("SELECT version FROM schema_migrations").
+							WillReturnRows(sqlmock.NewRows([]string{"version"}))
+						mockSQL.ExpectQuery("SELECT to_regclass").
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr: true,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					m := Migrator{
+						Db:         mockDB,
+						Migrations: mockMigrations,
+					}
+					tc.mockFunc(mockSQL)
+
+					applied, err := m.Up(context.Background())
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+					So(applied, ShouldResemble, tc.wantApplied)
+				})
+			}
+		})
+	})
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("TestCheck", func(t *testing.T) {
+		Convey("TestCheck", t, func(c C) {
+			testCases := []struct {
+				testID      int
+				testDesc    string
+				mockFunc    func(mockSQL sqlmock.Sqlmock)
+				wantDetails string
+				wantErr     bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error query",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT to_regclass").
+							WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - schema_migrations missing",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT to_regclass").
+							WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+					},
+					wantDetails: "schema at version 0 of 2, 2 pending",
+					wantErr:     true,
+				},
+				{
+					testID:   3,
+					testDesc: "Failed - pending migration",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT to_regclass").
+							WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+						mockSQL.ExpectQuery("SELECT version FROM schema_migrations").
+							WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(int64(1)))
+					},
+					wantDetails: "schema at version 1 of 2, 1 pending",
+					wantErr:     true,
+				},
+				{
+					testID:   4,
+					testDesc: "Success - up to date",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT to_regclass").
+							WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+						mockSQL.ExpectQuery("SELECT version FROM schema_migrations").
+							WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(int64(1)).AddRow(int64(2)))
+					},
+					wantDetails: "schema at version 2",
+					wantErr:     false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					m := Migrator{
+						Db:         mockDB,
+						Migrations: mockMigrations,
+					}
+					tc.mockFunc(mockSQL)
+
+					details, err := m.Check(context.Background())
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+					So(details, ShouldEqual, tc.wantDetails)
+				})
+			}
+		})
+	})
+}
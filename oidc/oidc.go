@@ -0,0 +1,311 @@
+// Package oidc verifies the ID tokens of external OpenID Connect providers,
+// like Google or Apple, so their users can log in to the service.
+//
+// Signatures are checked against the JSON Web Key Set of the issuer, which
+// is cached and fetched again when it expires or a token is signed by an
+// unknown key.
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// defaultCacheTTL is how long a key set is used before fetching it again.
+	defaultCacheTTL = time.Hour
+	// minRefreshInterval limits the fetches of tokens signed by unknown keys.
+	minRefreshInterval = time.Minute
+	// leeway is the clock skew allowed with the issuer.
+	leeway = time.Minute
+	// maxResponseBytes limits the discovery documents and key sets read.
+	maxResponseBytes = 1 << 20
+)
+
+var (
+	// ErrInvalidToken is returned for a malformed, forged or expired token
+	// and for a token issued to another client.
+	ErrInvalidToken = errors.New("oidc: invalid id token")
+	// ErrUnknownIssuer is returned for a token of an issuer that is not configured.
+	ErrUnknownIssuer = errors.New("oidc: unknown issuer")
+
+	errUnknownKey = errors.New("token is signed by an unknown key")
+)
+
+// A Provider is an OpenID Connect issuer the service accepts ID tokens of.
+type Provider struct {
+	// Name identifies the provider in the identities of users, like google.
+	Name   string
+	Issuer string
+	// ClientIDs are the client ids of the service at the provider, the aud
+	// of a token must be one of them.
+	ClientIDs []string
+	// JWKSURL is discovered from the configuration of the issuer when empty.
+	JWKSURL string
+}
+
+// Claims are the verified claims of an ID token.
+type Claims struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Nonce         string
+}
+
+// Verifier verifies the ID tokens of the configured providers.
+type Verifier struct {
+	HTTPClient *http.Client
+	CacheTTL   time.Duration
+
+	now func() time.Time
+	// keySets are the cached keys of the providers, by issuer.
+	keySets map[string]*keySet
+}
+
+type NewVerifierOptions struct {
+	Providers []Provider
+	// HTTPClient fetches the key sets, a client with a 10 seconds timeout
+	// when nil.
+	HTTPClient *http.Client
+	// CacheTTL is how long a key set is cached, 1 hour when zero.
+	CacheTTL time.Duration
+	// Now is the clock, time.Now when nil.
+	Now func() time.Time
+}
+
+func NewVerifier(opts NewVerifierOptions) *Verifier {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = defaultCacheTTL
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	v := &Verifier{
+		HTTPClient: opts.HTTPClient,
+		CacheTTL:   opts.CacheTTL,
+		now:        opts.Now,
+		keySets:    make(map[string]*keySet, len(opts.Providers)),
+	}
+	for _, provider := range opts.Providers {
+		provider.Issuer = strings.TrimSuffix(provider.Issuer, "/")
+		v.keySets[provider.Issuer] = &keySet{provider: provider}
+	}
+	return v
+}
+
+// Providers return the names of the configured providers.
+func (v *Verifier) Providers() []string {
+	names := make([]string, 0, len(v.keySets))
+	for _, set := range v.keySets {
+		names = append(names, set.provider.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// idTokenClaims are the claims read from an ID token.
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	AuthorizedParty string `json:"azp"`
+	Email           string `json:"email"`
+	// EmailVerified is a boolean, or the string "true" or "false" for
+	// some providers like Apple.
+	EmailVerified any    `json:"email_verified"`
+	Name          string `json:"name"`
+	Nonce         string `json:"nonce"`
+}
+
+// Verify check the signature, issuer, audience and expiry of the ID token
+// raw and return its claims. nonce, when not empty, must be the nonce of the
+// token. Return ErrUnknownIssuer for an issuer that is not configured and
+// ErrInvalidToken for any other invalid token.
+func (v *Verifier) Verify(ctx context.Context, raw string, nonce string) (Claims, error) {
+	var (
+		set      *keySet
+		fetchErr error
+	)
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+		jwt.WithTimeFunc(v.now),
+	)
+	var claims idTokenClaims
+	_, err := parser.ParseWithClaims(raw, &claims, func(token *jwt.Token) (interface{}, error) {
+		issuer, _ := token.Claims.GetIssuer()
+		var ok bool
+		if set, ok = v.keySets[issuer]; !ok {
+			return nil, ErrUnknownIssuer
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, err := set.key(ctx, v, kid)
+		if err != nil && !errors.Is(err, errUnknownKey) {
+			fetchErr = err
+		}
+		return key, err
+	})
+	switch {
+	case errors.Is(err, ErrUnknownIssuer):
+		return Claims{}, ErrUnknownIssuer
+	case fetchErr != nil:
+		return Claims{}, fmt.Errorf("oidc: keys of %s: %w", set.provider.Issuer, fetchErr)
+	case err != nil:
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	provider := set.provider
+	if !slices.ContainsFunc(claims.Audience, func(aud string) bool { return slices.Contains(provider.ClientIDs, aud) }) {
+		return Claims{}, fmt.Errorf("%w: token is not issued to the service", ErrInvalidToken)
+	}
+	// a token of several audiences is issued to its authorized party.
+	if claims.AuthorizedParty != "" && !slices.Contains(provider.ClientIDs, claims.AuthorizedParty) {
+		return Claims{}, fmt.Errorf("%w: token is not issued to the service", ErrInvalidToken)
+	}
+	if claims.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: token has no subject", ErrInvalidToken)
+	}
+	if nonce != "" && claims.Nonce != nonce {
+		return Claims{}, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+
+	verified, _ := claims.EmailVerified.(bool)
+	if s, ok := claims.EmailVerified.(string); ok {
+		verified = s == "true"
+	}
+	return Claims{
+		Provider:      provider.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: verified,
+		Name:          claims.Name,
+		Nonce:         claims.Nonce,
+	}, nil
+}
+
+// A keySet is the cached JSON Web Key Set of a provider.
+type keySet struct {
+	provider Provider
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+	jwksURL   string
+}
+
+// key return the key of kid, the key set is fetched again when it expired
+// or does not have kid. A key set that cannot be fetched again is still
+// used until a fetch succeeds.
+func (s *keySet) key(ctx context.Context, v *Verifier, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := v.now()
+	key, ok := s.keys[kid]
+	expired := s.keys == nil || now.Sub(s.fetchedAt) >= v.CacheTTL
+	if !expired && (ok || now.Sub(s.fetchedAt) < minRefreshInterval) {
+		if !ok {
+			return nil, errUnknownKey
+		}
+		return key, nil
+	}
+
+	keys, err := s.fetch(ctx, v.HTTPClient)
+	if err != nil {
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+	s.keys = keys
+	s.fetchedAt = now
+	if key, ok = keys[kid]; !ok {
+		return nil, errUnknownKey
+	}
+	return key, nil
+}
+
+// fetch the RSA signing keys of the key set, by kid. The URL of the key set
+// is discovered once from the configuration of the issuer.
+func (s *keySet) fetch(ctx context.Context, client *http.Client) (map[string]*rsa.PublicKey, error) {
+	if s.jwksURL == "" {
+		s.jwksURL = s.provider.JWKSURL
+	}
+	if s.jwksURL == "" {
+		var config struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := getJSON(ctx, client, s.provider.Issuer+"/.well-known/openid-configuration", &config); err != nil {
+			return nil, fmt.Errorf("discover: %w", err)
+		}
+		if strings.TrimSuffix(config.Issuer, "/") != s.provider.Issuer || config.JWKSURI == "" {
+			return nil, fmt.Errorf("discover: configuration of another issuer %q", config.Issuer)
+		}
+		s.jwksURL = config.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := getJSON(ctx, client, s.jwksURL, &jwks); err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// getJSON decode the JSON response of a GET of url into v.
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(v)
+}
@@ -0,0 +1,228 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/oidc"
+	"github.com/SawitProRecruitment/UserService/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVerify(t *testing.T) {
+	t.Run("TestVerify", func(t *testing.T) {
+		Convey("TestVerify", t, func(c C) {
+			issuer := oidctest.NewIssuer()
+			defer issuer.Close()
+			other := oidctest.NewIssuer()
+			defer other.Close()
+
+			verifier := oidc.NewVerifier(oidc.NewVerifierOptions{
+				Providers: []oidc.Provider{{Name: "mock", Issuer: issuer.URL, ClientIDs: []string{"mock-client", "mock-ios-client"}}},
+			})
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				token     func() string
+				nonce     string
+				want      oidc.Claims
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - issuer is not configured",
+					token: func() string {
+						return other.Sign(jwt.MapClaims{"sub": "mock-subject", "aud": "mock-client"})
+					},
+					wantErrIs: oidc.ErrUnknownIssuer,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - token expired",
+					token: func() string {
+						return issuer.Sign(jwt.MapClaims{"sub": "mock-subject", "aud": "mock-client", "exp": time.Now().Add(-time.Hour).Unix()})
+					},
+					wantErrIs: oidc.ErrInvalidToken,
+				},
+				{
+					testID:   3,
+					testDesc: "Failed - token without expiry",
+					token: func() string {
+						return issuer.Sign(jwt.MapClaims{"sub": "mock-subject", "aud": "mock-client", "exp": nil})
+					},
+					wantErrIs: oidc.ErrInvalidToken,
+				},
+				{
+					testID:   4,
+					testDesc: "Failed - token issued to another client",
+					token: func() string {
+						return issuer.Sign(jwt.MapClaims{"sub": "mock-subject", "aud": "other-client"})
+					},
+					wantErrIs: oidc.ErrInvalidToken,
+				},
+				{
+					testID:   5,
+					testDesc: "Failed - token authorized to another client",
+					token: func() string {
+						return issuer.Sign(jwt.MapClaims{"sub": "mock-subject", "aud": []string{"mock-client", "other-client"}, "azp": "other-client"})
+					},
+					wantErrIs: oidc.ErrInvalidToken,
+				},
+				{
+					testID:   6,
+					testDesc: "Failed - token signed by the key of another issuer",
+					token: func() string {
+						return other.Sign(jwt.MapClaims{"iss": issuer.URL, "sub": "mock-subject", "aud": "mock-client"})
+					},
+					wantErrIs: oidc.ErrInvalidToken,
+				},
+				{
+					testID:   7,
+					testDesc: "Failed - token signed with a shared secret",
+					token: func() string {
+						token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+							"iss": issuer.URL,
+							"sub": "mock-subject",
+							"aud": "mock-client",
+							"exp": time.Now().Add(time.Hour).Unix(),
+						}).SignedString([]byte("mock-secret"))
+						return token
+					},
+					wantErrIs: oidc.ErrInvalidToken,
+				},
+				{
+					testID:   8,
+					testDesc: "Failed - nonce does not match",
+					token: func() string {
+						return issuer.Sign(jwt.MapClaims{"sub": "mock-subject", "aud": "mock-client", "nonce": "other-nonce"})
+					},
+					nonce:     "mock-nonce",
+					wantErrIs: oidc.ErrInvalidToken,
+				},
+				{
+					testID:   9,
+					testDesc: "Failed - token without subject",
+					token: func() string {
+						return issuer.Sign(jwt.MapClaims{"aud": "mock-client"})
+					},
+					wantErrIs: oidc.ErrInvalidToken,
+				},
+				{
+					testID:   10,
+					testDesc: "Success",
+					token: func() string {
+						return issuer.Sign(jwt.MapClaims{
+							"sub":            "mock-subject",
+							"aud":            "mock-ios-client",
+							"email":          "budi@example.com",
+							"email_verified": true,
+							"name":           "Budi",
+							"nonce":          "mock-nonce",
+						})
+					},
+					nonce: "mock-nonce",
+					want: oidc.Claims{
+						Provider:      "mock",
+						Subject:       "mock-subject",
+						Email:         "budi@example.com",
+						EmailVerified: true,
+						Name:          "Budi",
+						Nonce:         "mock-nonce",
+					},
+				},
+				{
+					testID:   11,
+					testDesc: "Success - email_verified as a string",
+					token: func() string {
+						return issuer.Sign(jwt.MapClaims{"sub": "mock-subject", "aud": "mock-client", "email": "budi@example.com", "email_verified": "true"})
+					},
+					want: oidc.Claims{
+						Provider:      "mock",
+						Subject:       "mock-subject",
+						Email:         "budi@example.com",
+						EmailVerified: true,
+					},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					claims, err := verifier.Verify(context.Background(), tc.token(), tc.nonce)
+					// assert
+					if tc.wantErrIs == nil {
+						So(err, ShouldBeNil)
+					} else {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(claims, ShouldResemble, tc.want)
+				})
+			}
+		})
+	})
+}
+
+func TestVerifyKeyCache(t *testing.T) {
+	t.Run("TestVerifyKeyCache", func(t *testing.T) {
+		Convey("TestVerifyKeyCache", t, func(c C) {
+			issuer := oidctest.NewIssuer()
+			defer issuer.Close()
+			now := time.Now()
+			verifier := oidc.NewVerifier(oidc.NewVerifierOptions{
+				Providers: []oidc.Provider{{Name: "mock", Issuer: issuer.URL + "/", ClientIDs: []string{"mock-client"}}},
+				CacheTTL:  time.Hour,
+				Now:       func() time.Time { return now },
+			})
+			verify := func() error {
+				_, err := verifier.Verify(context.Background(), issuer.Sign(jwt.MapClaims{"sub": "mock-subject", "aud": "mock-client"}), "")
+				return err
+			}
+
+			Convey("the key set is fetched once until it expires", func() {
+				So(verify(), ShouldBeNil)
+				So(verify(), ShouldBeNil)
+				So(issuer.JWKSRequests(), ShouldEqual, 1)
+
+				now = now.Add(time.Hour)
+				So(verify(), ShouldBeNil)
+				So(issuer.JWKSRequests(), ShouldEqual, 2)
+			})
+
+			Convey("a rotated key is fetched at most once a minute", func() {
+				So(verify(), ShouldBeNil)
+				issuer.RotateKey()
+
+				now = now.Add(30 * time.Second)
+				So(errors.Is(verify(), oidc.ErrInvalidToken), ShouldBeTrue)
+				So(issuer.JWKSRequests(), ShouldEqual, 1)
+
+				now = now.Add(30 * time.Second)
+				So(verify(), ShouldBeNil)
+				So(issuer.JWKSRequests(), ShouldEqual, 2)
+			})
+
+			Convey("the cached key is used while the issuer is down", func() {
+				So(verify(), ShouldBeNil)
+				token := issuer.Sign(jwt.MapClaims{"sub": "mock-subject", "aud": "mock-client"})
+				issuer.Close()
+
+				now = now.Add(time.Hour)
+				_, err := verifier.Verify(context.Background(), token, "")
+				So(err, ShouldBeNil)
+			})
+
+			Convey("a key set that cannot be fetched is not an invalid token", func() {
+				issuer.Close()
+				err := verify()
+				So(err, ShouldNotBeNil)
+				So(errors.Is(err, oidc.ErrInvalidToken), ShouldBeFalse)
+			})
+		})
+	})
+}
@@ -0,0 +1,112 @@
+// Package oidctest runs a fake OpenID Connect issuer for tests, serving its
+// discovery document and key set and signing ID tokens.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer is an OpenID Connect issuer listening on a local address.
+type Issuer struct {
+	*httptest.Server
+
+	mu  sync.Mutex
+	key *rsa.PrivateKey
+	kid string
+	// jwksRequests counts the fetches of the key set.
+	jwksRequests int
+}
+
+// NewIssuer start an issuer with a new signing key, the caller should Close
+// it when finished.
+func NewIssuer() *Issuer {
+	i := &Issuer{}
+	i.RotateKey()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                                i.URL,
+			"jwks_uri":                              i.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		i.jwksRequests++
+		writeJSON(w, map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": i.kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(i.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(i.key.E)).Bytes()),
+			}},
+		})
+	})
+	i.Server = httptest.NewServer(mux)
+	return i
+}
+
+// RotateKey replace the signing key, tokens signed before no longer verify
+// against the key set.
+func (i *Issuer) RotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("oidctest: generate key: " + err.Error())
+	}
+	sum := sha256.Sum256(key.N.Bytes())
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.key = key
+	i.kid = base64.RawURLEncoding.EncodeToString(sum[:8])
+}
+
+// Sign return an ID token of claims signed with the current key. iss, iat
+// and exp default to the issuer, now and an hour from now.
+func (i *Issuer) Sign(claims jwt.MapClaims) string {
+	all := jwt.MapClaims{
+		"iss": i.URL,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		all[name] = value
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, all)
+	token.Header["kid"] = i.kid
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		panic("oidctest: sign token: " + err.Error())
+	}
+	return signed
+}
+
+// JWKSRequests return how many times the key set was fetched.
+func (i *Issuer) JWKSRequests() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.jwksRequests
+}
+
+// writeJSON write v as a JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,243 @@
+// This file contains the echo middleware applying rate limit policies.
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/common"
+	"github.com/SawitProRecruitment/UserService/logging"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+)
+
+// Response headers, see draft-ietf-httpapi-ratelimit-headers.
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+)
+
+// defaultScope prefixes the buckets of the default policy, they are shared by
+// every operation without a policy of its own.
+const defaultScope = "default"
+
+// apiKeyScope prefixes the buckets of API keys, one per key shared by every
+// operation.
+const apiKeyScope = "apikey"
+
+// Limiter applies a rate limit policy per operation id.
+type Limiter struct {
+	Store    Store
+	Policies map[string]Policy
+	Default  Policy
+	UserID   func(c echo.Context) string
+	APIKey   func(c echo.Context) (id string, limit int)
+	// APIKeyPolicy limits the requests of each API key.
+	APIKeyPolicy Policy
+	Now          func() time.Time
+
+	operations map[string]string
+	// keyOperations are the routes of the operations accepting API keys.
+	keyOperations map[string]bool
+}
+
+type NewLimiterOptions struct {
+	// Spec maps echo routes to operation ids.
+	Spec  *openapi3.T
+	Store Store
+	// Policies by operation id, in any case.
+	Policies map[string]Policy
+	// Default applies to operations without a policy, disabled when its Limit is zero.
+	Default Policy
+	// UserID return the user id of an authenticated request, empty otherwise.
+	// Requests without user are limited by client ip.
+	UserID func(c echo.Context) string
+	// APIKey return the id and rate limit of the API key authenticating the
+	// request, an empty id otherwise. Requests by key are limited per key by
+	// APIKeyPolicy instead of the policy of the operation, a positive limit
+	// of the key replaces the one of APIKeyPolicy.
+	APIKey func(c echo.Context) (id string, limit int)
+	// APIKeyScheme is the security scheme of API keys in the spec. Only the
+	// operations whose security accepts it limit requests per key, others
+	// apply their policy to requests sent with a key too.
+	APIKeyScheme string
+	// APIKeyPolicy applies to requests by API key, its window defaults to a
+	// minute.
+	APIKeyPolicy Policy
+}
+
+// NewLimiter return error when a policy names an operation the spec does not have.
+func NewLimiter(opts NewLimiterOptions) (*Limiter, error) {
+	l := &Limiter{
+		Store:         opts.Store,
+		Policies:      map[string]Policy{},
+		Default:       opts.Default,
+		UserID:        opts.UserID,
+		APIKey:        opts.APIKey,
+		APIKeyPolicy:  opts.APIKeyPolicy,
+		Now:           time.Now,
+		operations:    common.OperationIDs(opts.Spec),
+		keyOperations: map[string]bool{},
+	}
+	if l.UserID == nil {
+		l.UserID = func(echo.Context) string { return "" }
+	}
+	if l.APIKey == nil {
+		l.APIKey = func(echo.Context) (string, int) { return "", 0 }
+	}
+	if l.APIKeyPolicy.Window <= 0 {
+		l.APIKeyPolicy.Window = time.Minute
+	}
+	for route, schemes := range common.OperationSecurity(opts.Spec) {
+		if _, ok := schemes[opts.APIKeyScheme]; ok {
+			l.keyOperations[route] = true
+		}
+	}
+
+	// the generated spec capitalizes operation ids, policies are matched
+	// case-insensitively and keyed by the id of the spec.
+	known := map[string]string{}
+	for _, id := range l.operations {
+		known[strings.ToLower(id)] = id
+	}
+	for id, policy := range opts.Policies {
+		specID, ok := known[strings.ToLower(id)]
+		if !ok {
+			return nil, fmt.Errorf("rate limit policy for unknown operation %q", id)
+		}
+		l.Policies[specID] = policy
+	}
+
+	return l, nil
+}
+
+// Middleware reject requests over their policy with 429. Every limited
+// response carries the RateLimit-* headers. The limiter fails open when the
+// store is unavailable.
+func (l *Limiter) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scope, policy, key := l.policy(c)
+			if policy.Limit <= 0 {
+				return next(c)
+			}
+
+			ctx := c.Request().Context()
+			res, err := l.Store.Take(ctx, scope+":"+key, policy, l.Now())
+			if err != nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "take rate limit token", slog.Any("error", err))
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set(HeaderRateLimitLimit, strconv.Itoa(policy.Limit))
+			header.Set(HeaderRateLimitRemaining, strconv.Itoa(res.Remaining))
+			header.Set(HeaderRateLimitReset, ceilSeconds(res.Reset))
+			header.Set(HeaderRateLimitPolicy, fmt.Sprintf("%d;w=%s", policy.Limit, ceilSeconds(policy.Window)))
+			if !res.Allowed {
+				header.Set(echo.HeaderRetryAfter, ceilSeconds(res.RetryAfter))
+				return c.JSON(http.StatusTooManyRequests, common.ErrorResponse(c, "too many requests, retry later"))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// policy return the policy of the request, the scope of its buckets and the
+// key of its bucket. Requests by API key to operations accepting keys are
+// counted by key, others by the policy of their operation or else the default
+// policy.
+func (l *Limiter) policy(c echo.Context) (scope string, policy Policy, key string) {
+	route := c.Request().Method + " " + c.Path()
+	if l.keyOperations[route] {
+		if id, limit := l.APIKey(c); id != "" {
+			policy = l.APIKeyPolicy
+			if limit > 0 {
+				policy.Limit = limit
+			}
+			return apiKeyScope, policy, id
+		}
+	}
+
+	scope = l.operations[route]
+	policy, ok := l.Policies[scope]
+	if !ok {
+		scope, policy = defaultScope, l.Default
+	}
+	return scope, policy, l.key(c, policy.Key)
+}
+
+// key return what the request is counted by, falling back to the client ip
+// when the request has no user or login identifier.
+func (l *Limiter) key(c echo.Context, key string) string {
+	switch key {
+	case KeyUser:
+		if id := l.UserID(c); id != "" {
+			return "user:" + id
+		}
+	case KeyPhone:
+		if identifier := requestIdentifier(c); identifier != "" {
+			return identifier
+		}
+	}
+	return "ip:" + c.RealIP()
+}
+
+// requestIdentifier return the login identifier of a JSON or form body, its
+// identifier or else phone field, prefixed by its type and normalized so
+// every spelling of a phone number shares a bucket. The body is restored
+// for the handler.
+func requestIdentifier(c echo.Context) string {
+	req := c.Request()
+	if req.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		Identifier string `json:"identifier"`
+		Phone      string `json:"phone"`
+	}
+	if strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationForm) {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return ""
+		}
+		payload.Identifier, payload.Phone = form.Get("identifier"), form.Get("phone")
+	} else if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	raw := strings.TrimSpace(payload.Identifier)
+	if raw == "" {
+		raw = strings.TrimSpace(payload.Phone)
+	}
+	if raw == "" {
+		return ""
+	}
+	identifier, err := common.ParseIdentifier(raw)
+	if err != nil {
+		return string(common.IdentifierPhone) + ":" + raw
+	}
+	return string(identifier.Type) + ":" + identifier.Value
+}
+
+// ceilSeconds format d as whole seconds, rounded up.
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,348 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var mockSpec = &openapi3.T{
+	Paths: openapi3.Paths{
+		"/login": &openapi3.PathItem{
+			Post: &openapi3.Operation{OperationID: "login"},
+		},
+		"/users": &openapi3.PathItem{
+			Get: &openapi3.Operation{
+				OperationID: "getUser",
+				Security:    &openapi3.SecurityRequirements{{"bearerAuth": {}}, {"apiKeyAuth": {}}},
+			},
+		},
+		"/users/sessions": &openapi3.PathItem{
+			Get: &openapi3.Operation{
+				OperationID: "listUserSessions",
+				Security:    &openapi3.SecurityRequirements{{"bearerAuth": {}}, {"apiKeyAuth": {}}},
+			},
+		},
+		"/users/register": &openapi3.PathItem{
+			Post: &openapi3.Operation{OperationID: "userRegister"},
+		},
+		"/oauth/authorize": &openapi3.PathItem{
+			Post: &openapi3.Operation{OperationID: "submitAuthorization"},
+		},
+	},
+}
+
+// failingStore is a store that is always unavailable.
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Policy, time.Time) (Result, error) {
+	return Result{}, errors.New("error")
+}
+
+func TestNewLimiter(t *testing.T) {
+	t.Run("TestNewLimiter", func(t *testing.T) {
+		Convey("TestNewLimiter", t, func(c C) {
+			_, err := NewLimiter(NewLimiterOptions{
+				Spec:     mockSpec,
+				Policies: map[string]Policy{"logn": {Limit: 1, Window: time.Minute}},
+			})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, `"logn"`)
+
+			_, err = NewLimiter(NewLimiterOptions{
+				Spec:     mockSpec,
+				Policies: map[string]Policy{"login": {Limit: 1, Window: time.Minute}},
+			})
+			So(err, ShouldBeNil)
+
+			// the generated spec capitalizes operation ids.
+			limiter, err := NewLimiter(NewLimiterOptions{
+				Spec:     mockSpec,
+				Policies: map[string]Policy{"UserRegister": {Limit: 1, Window: time.Minute}},
+			})
+			So(err, ShouldBeNil)
+			So(limiter.Policies, ShouldContainKey, "userRegister")
+		})
+	})
+}
+
+func TestLimiterMiddleware(t *testing.T) {
+	t.Run("TestLimiterMiddleware", func(t *testing.T) {
+		Convey("TestLimiterMiddleware", t, func(c C) {
+			type (
+				request struct {
+					method        string
+					path          string
+					body          string
+					contentType   string
+					ip            string
+					authorization string
+				}
+			)
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				store    Store
+				policies map[string]Policy
+				def      Policy
+				// apiKeyPolicy limits requests authorized with "key-<id>[:<limit>]".
+				apiKeyPolicy Policy
+				requests     []request
+				// wantStatus is the status of each request.
+				wantStatus []int
+				// wantHeaders are the headers of the last response.
+				wantHeaders map[string]string
+			}{
+				{
+					testID:   1,
+					testDesc: "Success - operation without policy is not limited",
+					store:    NewMemoryStore(),
+					policies: map[string]Policy{"login": {Limit: 1, Window: time.Minute, Key: KeyIP}},
+					requests: []request{
+						{method: http.MethodGet, path: "/users", ip: "192.0.2.1"},
+						{method: http.MethodGet, path: "/users", ip: "192.0.2.1"},
+					},
+					wantStatus:  []int{http.StatusOK, http.StatusOK},
+					wantHeaders: map[string]string{HeaderRateLimitLimit: ""},
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - operation over its policy",
+					store:    NewMemoryStore(),
+					policies: map[string]Policy{"userRegister": {Limit: 2, Window: time.Minute, Key: KeyIP}},
+					requests: []request{
+						{method: http.MethodPost, path: "/users/register", ip: "192.0.2.1"},
+						{method: http.MethodPost, path: "/users/register", ip: "192.0.2.1"},
+						{method: http.MethodPost, path: "/users/register", ip: "192.0.2.1"},
+					},
+					wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+					wantHeaders: map[string]string{
+						HeaderRateLimitLimit:     "2",
+						HeaderRateLimitRemaining: "0",
+						HeaderRateLimitReset:     "60",
+						HeaderRateLimitPolicy:    "2;w=60",
+						echo.HeaderRetryAfter:    "30",
+					},
+				},
+				{
+					testID:   3,
+					testDesc: "Success - clients have their own bucket",
+					store:    NewMemoryStore(),
+					policies: map[string]Policy{"userRegister": {Limit: 1, Window: time.Minute, Key: KeyIP}},
+					requests: []request{
+						{method: http.MethodPost, path: "/users/register", ip: "192.0.2.1"},
+						{method: http.MethodPost, path: "/users/register", ip: "192.0.2.2"},
+					},
+					wantStatus: []int{http.StatusOK, http.StatusOK},
+					wantHeaders: map[string]string{
+						HeaderRateLimitRemaining: "0",
+						echo.HeaderRetryAfter:    "",
+					},
+				},
+				{
+					testID:   4,
+					testDesc: "Failed - phone is limited across ips",
+					store:    NewMemoryStore(),
+					policies: map[string]Policy{"login": {Limit: 1, Window: time.Minute, Key: KeyPhone}},
+					requests: []request{
+						{method: http.MethodPost, path: "/login", body: `{"phone":"+6281234567"}`, ip: "192.0.2.1"},
+						{method: http.MethodPost, path: "/login", body: `{"phone":"+6281234568"}`, ip: "192.0.2.1"},
+						{method: http.MethodPost, path: "/login", body: `{"phone":"+6281234567"}`, ip: "192.0.2.2"},
+					},
+					wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+				},
+				{
+					testID:   5,
+					testDesc: "Failed - identifier is limited with the phone it normalizes to",
+					store:    NewMemoryStore(),
+					policies: map[string]Policy{"login": {Limit: 1, Window: time.Minute, Key: KeyPhone}},
+					requests: []request{
+						{method: http.MethodPost, path: "/login", body: `{"phone":"+6281234567"}`, ip: "192.0.2.1"},
+						{method: http.MethodPost, path: "/login", body: `{"identifier":"Budi@Example.com"}`, ip: "192.0.2.1"},
+						{method: http.MethodPost, path: "/login", body: `{"identifier":"0812-34567"}`, ip: "192.0.2.2"},
+					},
+					wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+				},
+				{
+					testID:   6,
+					testDesc: "Failed - identifier of a form body is limited",
+					store:    NewMemoryStore(),
+					policies: map[string]Policy{"submitAuthorization": {Limit: 1, Window: time.Minute, Key: KeyPhone}},
+					requests: []request{
+						{method: http.MethodPost, path: "/oauth/authorize", body: "identifier=%2B6281234567", contentType: echo.MIMEApplicationForm, ip: "192.0.2.1"},
+						{method: http.MethodPost, path: "/oauth/authorize", body: "identifier=0812-34568", contentType: echo.MIMEApplicationForm, ip: "192.0.2.1"},
+						{method: http.MethodPost, path: "/oauth/authorize", body: "identifier=0812-34567", contentType: echo.MIMEApplicationForm, ip: "192.0.2.2"},
+					},
+					wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+				},
+				{
+					testID:   7,
+					testDesc: "Failed - user is limited across ips",
+					store:    NewMemoryStore(),
+					policies: map[string]Policy{"getUser": {Limit: 1, Window: time.Minute, Key: KeyUser}},
+					requests: []request{
+						{method: http.MethodGet, path: "/users", ip: "192.0.2.1", authorization: "17"},
+						{method: http.MethodGet, path: "/users", ip: "192.0.2.1", authorization: "18"},
+						{method: http.MethodGet, path: "/users", ip: "192.0.2.2", authorization: "17"},
+					},
+					wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+				},
+				{
+					testID:   8,
+					testDesc: "Failed - default policy is shared by operations",
+					store:    NewMemoryStore(),
+					def:      Policy{Limit: 1, Window: time.Second, Key: KeyIP},
+					requests: []request{
+						{method: http.MethodGet, path: "/users", ip: "192.0.2.1"},
+						{method: http.MethodPost, path: "/users/register", ip: "192.0.2.1"},
+					},
+					wantStatus: []int{http.StatusOK, http.StatusTooManyRequests},
+					wantHeaders: map[string]string{
+						HeaderRateLimitPolicy: "1;w=1",
+						echo.HeaderRetryAfter: "1",
+					},
+				},
+				{
+					testID:   9,
+					testDesc: "Success - unavailable store fails open",
+					store:    failingStore{},
+					policies: map[string]Policy{"userRegister": {Limit: 1, Window: time.Minute, Key: KeyIP}},
+					requests: []request{
+						{method: http.MethodPost, path: "/users/register", ip: "192.0.2.1"},
+						{method: http.MethodPost, path: "/users/register", ip: "192.0.2.1"},
+					},
+					wantStatus:  []int{http.StatusOK, http.StatusOK},
+					wantHeaders: map[string]string{HeaderRateLimitLimit: ""},
+				},
+				{
+					testID:       10,
+					testDesc:     "Failed - API key is limited by its policy across operations",
+					store:        NewMemoryStore(),
+					policies:     map[string]Policy{"getUser": {Limit: 5, Window: time.Minute, Key: KeyUser}},
+					apiKeyPolicy: Policy{Limit: 1, Window: time.Minute},
+					requests: []request{
+						{method: http.MethodGet, path: "/users", ip: "192.0.2.1", authorization: "key-a"},
+						{method: http.MethodGet, path: "/users", ip: "192.0.2.1", authorization: "key-b"},
+						{method: http.MethodGet, path: "/users/sessions", ip: "192.0.2.2", authorization: "key-a"},
+					},
+					wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+					wantHeaders: map[string]string{
+						HeaderRateLimitPolicy: "1;w=60",
+						echo.HeaderRetryAfter: "60",
+					},
+				},
+				{
+					testID:       11,
+					testDesc:     "Success - limit of the API key replaces the one of the policy",
+					store:        NewMemoryStore(),
+					apiKeyPolicy: Policy{Limit: 1, Window: time.Minute},
+					requests: []request{
+						{method: http.MethodGet, path: "/users", ip: "192.0.2.1", authorization: "key-a:2"},
+						{method: http.MethodGet, path: "/users", ip: "192.0.2.1", authorization: "key-a:2"},
+					},
+					wantStatus: []int{http.StatusOK, http.StatusOK},
+					wantHeaders: map[string]string{
+						HeaderRateLimitLimit:     "2",
+						HeaderRateLimitRemaining: "0",
+					},
+				},
+				{
+					testID:       12,
+					testDesc:     "Failed - API key is limited by the policy of an operation not accepting keys",
+					store:        NewMemoryStore(),
+					policies:     map[string]Policy{"login": {Limit: 1, Window: time.Minute, Key: KeyPhone}},
+					apiKeyPolicy: Policy{Limit: 100, Window: time.Minute},
+					requests: []request{
+						{method: http.MethodPost, path: "/login", body: `{"phone":"+6281234567"}`, ip: "192.0.2.1", authorization: "key-a"},
+						{method: http.MethodPost, path: "/login", body: `{"phone":"+6281234567"}`, ip: "192.0.2.2", authorization: "key-a"},
+					},
+					wantStatus: []int{http.StatusOK, http.StatusTooManyRequests},
+					wantHeaders: map[string]string{
+						HeaderRateLimitPolicy: "1;w=60",
+					},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+					limiter, err := NewLimiter(NewLimiterOptions{
+						Spec:     mockSpec,
+						Store:    tc.store,
+						Policies: tc.policies,
+						Default:  tc.def,
+						// the tests authorize with the bare user id.
+						UserID: func(c echo.Context) string { return c.Request().Header.Get(echo.HeaderAuthorization) },
+						APIKey: func(c echo.Context) (string, int) {
+							key, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "key-")
+							if !ok {
+								return "", 0
+							}
+							id, limit, _ := strings.Cut(key, ":")
+							n, _ := strconv.Atoi(limit)
+							return id, n
+						},
+						APIKeyScheme: "apiKeyAuth",
+						APIKeyPolicy: tc.apiKeyPolicy,
+					})
+					So(err, ShouldBeNil)
+					limiter.Now = func() time.Time { return mockTime }
+
+					e := echo.New()
+					e.Use(limiter.Middleware())
+					// handler echoes the body, so it must be restored after reading the phone.
+					handler := func(c echo.Context) error {
+						var payload map[string]string
+						_ = c.Bind(&payload)
+						return c.JSON(http.StatusOK, payload)
+					}
+					e.POST("/login", handler)
+					e.POST("/users/register", handler)
+					e.GET("/users", handler)
+					e.GET("/users/sessions", handler)
+					e.POST("/oauth/authorize", func(c echo.Context) error {
+						return c.String(http.StatusOK, c.FormValue("identifier"))
+					})
+
+					var rec *httptest.ResponseRecorder
+					for i, r := range tc.requests {
+						req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+						contentType := echo.MIMEApplicationJSON
+						if r.contentType != "" {
+							contentType = r.contentType
+						}
+						req.Header.Set(echo.HeaderContentType, contentType)
+						req.RemoteAddr = r.ip + ":1234"
+						if r.authorization != "" {
+							req.Header.Set(echo.HeaderAuthorization, r.authorization)
+						}
+						rec = httptest.NewRecorder()
+						e.ServeHTTP(rec, req)
+
+						// assert
+						So(rec.Code, ShouldEqual, tc.wantStatus[i])
+						if rec.Code == http.StatusOK && r.body != "" && r.contentType == "" {
+							So(rec.Body.String(), ShouldEqual, r.body+"\n")
+						}
+					}
+					for header, want := range tc.wantHeaders {
+						So(rec.Header().Get(header), ShouldEqual, want)
+					}
+				})
+			}
+		})
+	})
+}
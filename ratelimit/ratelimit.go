@@ -0,0 +1,154 @@
+// Package ratelimit contains the token bucket rate limiter, its stores and
+// the echo middleware applying a policy per operation id.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+// Rate limit keys, what a bucket is counted by.
+const (
+	KeyIP    = "ip"
+	KeyUser  = "user"
+	KeyPhone = "phone" // the login identifier, a phone number or an email.
+)
+
+// sweepInterval is how often the memory store drops full buckets.
+const sweepInterval = time.Minute
+
+// Policy allows Limit requests per Window for each key. It is a token bucket
+// of Limit tokens refilled continuously over Window, so bursts up to Limit
+// are allowed.
+type Policy struct {
+	Limit  int
+	Window time.Duration
+	// Key is one of ip, user or phone.
+	Key string
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until a token is available, when not allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets, Take must be atomic per key.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// take refill b for the time elapsed since its last update and take a token.
+// A bucket not found starts full.
+func (p Policy) take(b bucket, found bool, now time.Time) (bucket, Result) {
+	limit := float64(p.Limit)
+	rate := limit / p.Window.Seconds()
+
+	tokens := limit
+	if found {
+		elapsed := math.Max(now.Sub(b.updatedAt).Seconds(), 0)
+		tokens = math.Min(limit, b.tokens+elapsed*rate)
+	}
+
+	var res Result
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	res.Remaining = int(tokens)
+	res.Reset = seconds((limit - tokens) / rate)
+
+	return bucket{tokens: tokens, updatedAt: now}, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// MemoryStore keeps buckets in process, for a single instance.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	// expiresAt is when the bucket is full again and can be dropped.
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]memoryBucket{},
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, b := range s.buckets {
+			if !now.Before(b.expiresAt) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	b, found := s.buckets[key]
+	next, res := policy.take(b.bucket, found, now)
+	s.buckets[key] = memoryBucket{bucket: next, expiresAt: now.Add(res.Reset)}
+
+	return res, nil
+}
+
+// BucketRepository is implemented by the repository layer.
+type BucketRepository interface {
+	UpdateRateLimitBucket(ctx context.Context, key string, update func(bucket repository.RateLimitBucket, found bool) repository.RateLimitBucket) (err error)
+}
+
+// PostgresStore keeps buckets in Postgres, shared by every instance.
+type PostgresStore struct {
+	Repository BucketRepository
+}
+
+type NewPostgresStoreOptions struct {
+	Repository BucketRepository
+}
+
+func NewPostgresStore(opts NewPostgresStoreOptions) *PostgresStore {
+	return &PostgresStore{
+		Repository: opts.Repository,
+	}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (res Result, err error) {
+	err = s.Repository.UpdateRateLimitBucket(ctx, key, func(b repository.RateLimitBucket, found bool) repository.RateLimitBucket {
+		var next bucket
+		next, res = policy.take(bucket{tokens: b.Tokens, updatedAt: b.UpdatedAt}, found, now)
+		return repository.RateLimitBucket{
+			Key:       key,
+			Tokens:    next.tokens,
+			UpdatedAt: next.updatedAt,
+			ExpiresAt: now.Add(res.Reset),
+		}
+	})
+	return res, err
+}
@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang/mock/gomock"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPolicyTake(t *testing.T) {
+	t.Run("TestPolicyTake", func(t *testing.T) {
+		Convey("TestPolicyTake", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			// one token every 12 seconds.
+			policy := Policy{Limit: 5, Window: time.Minute}
+
+			testCases := []struct {
+				testID     int
+				testDesc   string
+				bucket     bucket
+				found      bool
+				wantTokens float64
+				wantResult Result
+			}{
+				{
+					testID:     1,
+					testDesc:   "Success - new bucket starts full",
+					found:      false,
+					wantTokens: 4,
+					wantResult: Result{Allowed: true, Remaining: 4, Reset: 12 * time.Second},
+				},
+				{
+					testID:     2,
+					testDesc:   "Success - bucket is refilled for elapsed time",
+					bucket:     bucket{tokens: 0, updatedAt: mockTime.Add(-30 * time.Second)},
+					found:      true,
+					wantTokens: 1.5,
+					wantResult: Result{Allowed: true, Remaining: 1, Reset: 42 * time.Second},
+				},
+				{
+					testID:     3,
+					testDesc:   "Success - refill is capped at limit",
+					bucket:     bucket{tokens: 3, updatedAt: mockTime.Add(-time.Hour)},
+					found:      true,
+					wantTokens: 4,
+					wantResult: Result{Allowed: true, Remaining: 4, Reset: 12 * time.Second},
+				},
+				{
+					testID:     4,
+					testDesc:   "Failed - empty bucket",
+					bucket:     bucket{tokens: 0.5, updatedAt: mockTime},
+					found:      true,
+					wantTokens: 0.5,
+					wantResult: Result{Allowed: false, Remaining: 0, Reset: 54 * time.Second, RetryAfter: 6 * time.Second},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					next, res := policy.take(tc.bucket, tc.found, mockTime)
+					// assert
+					So(next.tokens, ShouldAlmostEqual, tc.wantTokens)
+					So(next.updatedAt, ShouldEqual, mockTime)
+					So(res, ShouldResemble, tc.wantResult)
+				})
+			}
+		})
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Run("TestMemoryStore", func(t *testing.T) {
+		Convey("TestMemoryStore", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			policy := Policy{Limit: 2, Window: time.Minute}
+			store := NewMemoryStore()
+
+			take := func(key string, now time.Time) bool {
+				res, err := store.Take(context.Background(), key, policy, now)
+				So(err, ShouldBeNil)
+				return res.Allowed
+			}
+
+			So(take("ip:192.0.2.1", mockTime), ShouldBeTrue)
+			So(take("ip:192.0.2.1", mockTime), ShouldBeTrue)
+			So(take("ip:192.0.2.1", mockTime), ShouldBeFalse)
+			// other keys have their own bucket.
+			So(take("ip:192.0.2.2", mockTime), ShouldBeTrue)
+			// a token is back after window / limit.
+			So(take("ip:192.0.2.1", mockTime.Add(30*time.Second)), ShouldBeTrue)
+			So(take("ip:192.0.2.1", mockTime.Add(30*time.Second)), ShouldBeFalse)
+
+			// full buckets are dropped.
+			take("ip:192.0.2.3", mockTime.Add(time.Hour))
+			So(store.buckets, ShouldHaveLength, 1)
+		})
+	})
+}
+
+func TestPostgresStore(t *testing.T) {
+	t.Run("TestPostgresStore", func(t *testing.T) {
+		Convey("TestPostgresStore", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			policy := Policy{Limit: 5, Window: time.Minute}
+
+			testCases := []struct {
+				testID     int
+				testDesc   string
+				mockFunc   func(mock *repository.MockRepositoryInterface)
+				wantResult Result
+				wantErr    bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error UpdateRateLimitBucket",
+					mockFunc: func(mock *repository.MockRepositoryInterface) {
+						mock.EXPECT().UpdateRateLimitBucket(gomock.Any(), "login:phone:+6281234567", gomock.Any()).Return(errors.New("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Success - stored bucket is updated",
+					mockFunc: func(mock *repository.MockRepositoryInterface) {
+						mock.EXPECT().UpdateRateLimitBucket(gomock.Any(), "login:phone:+6281234567", gomock.Any()).
+							DoAndReturn(func(_ context.Context, key string, update func(repository.RateLimitBucket, bool) repository.RateLimitBucket) error {
+								next := update(repository.RateLimitBucket{Key: key, Tokens: 1, UpdatedAt: mockTime}, true)
+								So(next, ShouldResemble, repository.RateLimitBucket{
+									Key:       key,
+									Tokens:    0,
+									UpdatedAt: mockTime,
+									ExpiresAt: mockTime.Add(time.Minute),
+								})
+								return nil
+							})
+					},
+					wantResult: Result{Allowed: true, Remaining: 0, Reset: time.Minute},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					ctrl := gomock.NewController(t)
+					defer ctrl.Finish()
+					mock := repository.NewMockRepositoryInterface(ctrl)
+					tc.mockFunc(mock)
+
+					store := NewPostgresStore(NewPostgresStoreOptions{Repository: mock})
+					res, err := store.Take(context.Background(), "login:phone:+6281234567", policy, mockTime)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if !tc.wantErr {
+						So(res, ShouldResemble, tc.wantResult)
+					}
+				})
+			}
+		})
+	})
+}
@@ -2,133 +2,995 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/lib/pq"
 )
 
-var ErrDuplicateData = errors.New("duplicate data")
+var (
+	ErrDuplicateData   = errors.New("duplicate data")
+	ErrNotFound        = errors.New("data not found")
+	ErrVersionConflict = errors.New("data was modified by another request")
+	// ErrDuplicateEmail is the ErrDuplicateData of an email already used by another user.
+	ErrDuplicateEmail = fmt.Errorf("email: %w", ErrDuplicateData)
+)
 
 func (r *Repository) Createuser(ctx context.Context, input RegisterUser) (output User, err error) {
-	tx, err := r.Db.Begin()
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		query, err := tx.PrepareContext(ctx, InsertUserQuery)
+		if err != nil {
+			return fmt.Errorf("prepare insert user: %w", err)
+		}
+		defer query.Close()
+
+		err = query.QueryRowContext(ctx,
+			input.Phone,
+			input.Name,
+			input.Password,
+		).Scan(&output.ID)
+		if err != nil {
+			return fmt.Errorf("insert user: %w", translateError(err))
+		}
+
+		return insertUserEvent(ctx, tx, UserEvent{
+			UserID: &output.ID,
+			Type:   UserEventRegistered,
+		})
+	})
 	if err != nil {
-		return output, err
+		return User{}, err
 	}
 
-	query, err := tx.PrepareContext(ctx, InsertUserQuery)
+	return
+}
+
+func (r *Repository) GetUserByID(ctx context.Context, id int64) (output User, err error) {
+	err = scanUser(r.Db.QueryRowContext(ctx, GetUserByIDQuery, id), &output)
 	if err != nil {
-		return output, err
+		return User{}, fmt.Errorf("get user: %w", translateError(err))
 	}
-	defer query.Close()
+	return
+}
 
-	var id int64
-	err = query.QueryRowContext(ctx,
-		input.Phone,
-		input.Name,
-		input.Password,
-	).Scan(&id)
-	if err != nil {
-		pqErr, ok := err.(*pq.Error)
-		if ok {
-			if pqErr.Code.Name() == "unique_violation" {
-				return output, ErrDuplicateData
+func (r *Repository) UpdateUser(ctx context.Context, input UpdateUser) (output User, err error) {
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		// lock the row so the recorded before values are the ones replaced.
+		var before User
+		err := scanUser(tx.QueryRowContext(ctx, GetUserByIDForUpdateQuery, input.ID), &before)
+		if err != nil {
+			return fmt.Errorf("get user for update: %w", translateError(err))
+		}
+
+		query, err := tx.PrepareContext(ctx, UpdateUserQuery)
+		if err != nil {
+			return fmt.Errorf("prepare update user: %w", err)
+		}
+		defer query.Close()
+
+		// NULL clears the thumbnails.
+		var thumbnails interface{}
+		if len(input.AvatarThumbnails) > 0 {
+			if thumbnails, err = json.Marshal(input.AvatarThumbnails); err != nil {
+				return fmt.Errorf("encode avatar thumbnails: %w", err)
 			}
 		}
-		return output, err
+
+		err = scanUser(query.QueryRowContext(ctx,
+			input.Phone,
+			input.Name,
+			// WHERE
+			input.ID,
+			input.Version,
+			// optional fields
+			input.Email,
+			input.DisplayName,
+			input.BirthDate,
+			input.Locale,
+			input.Timezone,
+			input.AvatarURL,
+			thumbnails,
+		), &output)
+		if errors.Is(err, sql.ErrNoRows) {
+			// the row exists and is locked, only the version check can fail.
+			return fmt.Errorf("update user: %w", ErrVersionConflict)
+		}
+		if err != nil {
+			return fmt.Errorf("update user: %w", translateError(err))
+		}
+
+		changes := diffUser(before, output)
+		if len(changes) == 0 {
+			return nil
+		}
+
+		return insertUserEvent(ctx, tx, UserEvent{
+			UserID:  &output.ID,
+			Type:    UserEventProfileUpdated,
+			Details: UserEventDetails{Changes: changes},
+		})
+	})
+	if err != nil {
+		return User{}, err
 	}
 
-	err = tx.Commit()
+	return
+}
+
+func (r *Repository) GetUserByPhone(ctx context.Context, phone string) (output User, err error) {
+	err = scanUser(r.Db.QueryRowContext(ctx, GetUserByPhoneQuery, phone), &output)
 	if err != nil {
-		return output, err
+		return User{}, fmt.Errorf("get user by phone: %w", translateError(err))
 	}
+	return
+}
 
-	output.ID = id
+// GetUserByEmail return the user who verified a normalized email. Pending
+// emails are not unique, they are not found.
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (output User, err error) {
+	err = scanUser(r.Db.QueryRowContext(ctx, GetUserByEmailQuery, email), &output)
+	if err != nil {
+		return User{}, fmt.Errorf("get user by email: %w", translateError(err))
+	}
 	return
 }
 
-func (r *Repository) GetUserByID(ctx context.Context, id int64) (output User, err error) {
-	err = r.Db.QueryRowContext(ctx, GetUserByIDQuery, id).Scan(
-		&output.ID,
-		&output.Phone,
-		&output.Name,
-		&output.Password,
-		&output.CreatedAt,
-		&output.UpdateAt,
+// RecordLogin bump login count and last login of the user, open the login
+// session and append the login to the history and the audit log.
+func (r *Repository) RecordLogin(ctx context.Context, input UserLogin) (err error) {
+	client := clientInfoFromContext(ctx)
+	if input.IP == "" {
+		input.IP = client.IP
+	}
+	if input.UserAgent == "" {
+		input.UserAgent = client.UserAgent
+	}
+
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		query, err := tx.PrepareContext(ctx, UpdateLastLoginQuery)
+		if err != nil {
+			return fmt.Errorf("prepare update last login: %w", err)
+		}
+		defer query.Close()
+
+		_, err = query.ExecContext(ctx,
+			input.UserID,
+		)
+		if err != nil {
+			return fmt.Errorf("update last login: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, InsertUserLoginQuery,
+			input.UserID,
+			input.TokenID,
+			input.IP,
+			input.UserAgent,
+		)
+		if err != nil {
+			return fmt.Errorf("insert user login: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, InsertUserSessionQuery,
+			input.SessionID,
+			input.UserID,
+			input.DeviceName,
+			input.IP,
+			input.UserAgent,
+		)
+		if err != nil {
+			return fmt.Errorf("insert user session: %w", err)
+		}
+
+		return insertUserEvent(ctx, tx, UserEvent{
+			UserID:    &input.UserID,
+			Type:      UserEventLoginSucceeded,
+			IP:        input.IP,
+			UserAgent: input.UserAgent,
+		})
+	})
+}
+
+func (r *Repository) ListUserLogins(ctx context.Context, userID int64, before *int64, limit int) (output []UserLogin, err error) {
+	rows, err := r.Db.QueryContext(ctx, ListUserLoginsQuery, userID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list user logins: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var login UserLogin
+		err = rows.Scan(
+			&login.ID,
+			&login.UserID,
+			&login.TokenID,
+			&login.IP,
+			&login.UserAgent,
+			&login.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan user login: %w", err)
+		}
+		output = append(output, login)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("list user logins: %w", err)
+	}
+
+	return output, nil
+}
+
+// DeleteUserLoginsBefore prune login history created before the given time.
+func (r *Repository) DeleteUserLoginsBefore(ctx context.Context, before time.Time) (deleted int64, err error) {
+	result, err := r.Db.ExecContext(ctx, DeleteUserLoginsBeforeQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete user logins: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *Repository) ListActiveUserSessions(ctx context.Context, userID int64) (output []UserSession, err error) {
+	rows, err := r.Db.QueryContext(ctx, ListActiveUserSessionsQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list user sessions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var session UserSession
+		err = rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.DeviceName,
+			&session.IP,
+			&session.UserAgent,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.RevokedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan user session: %w", err)
+		}
+		output = append(output, session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("list user sessions: %w", err)
+	}
+
+	return output, nil
+}
+
+// TouchUserSession mark the session as seen now, unless it was seen in the
+// last minute. Return ErrNotFound when the session does not exist or has been
+// revoked.
+func (r *Repository) TouchUserSession(ctx context.Context, sessionID string, userID int64) (err error) {
+	var active int
+	err = r.Db.QueryRowContext(ctx, TouchUserSessionQuery, sessionID, userID).Scan(&active)
+	if err != nil {
+		return fmt.Errorf("touch user session: %w", err)
+	}
+	if active == 0 {
+		return fmt.Errorf("touch user session: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// RevokeUserSession revoke an active session of the user and record it in the audit log.
+// Return ErrNotFound when the session does not exist or has been revoked.
+func (r *Repository) RevokeUserSession(ctx context.Context, sessionID string, userID int64) (err error) {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, RevokeUserSessionQuery, sessionID, userID)
+		if err != nil {
+			return fmt.Errorf("revoke user session: %w", err)
+		}
+		if err = expectAffected(result, "revoke user session"); err != nil {
+			return err
+		}
+
+		return insertUserEvent(ctx, tx, UserEvent{
+			UserID:  &userID,
+			Type:    UserEventSessionRevoked,
+			Details: UserEventDetails{SessionID: sessionID},
+		})
+	})
+}
+
+// CreateUserEvent record an event that is not part of another write, like a failed login.
+func (r *Repository) CreateUserEvent(ctx context.Context, input UserEvent) (err error) {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		return insertUserEvent(ctx, tx, input)
+	})
+}
+
+func (r *Repository) ListUserEvents(ctx context.Context, userID int64, limit int) (output []UserEvent, err error) {
+	rows, err := r.Db.QueryContext(ctx, ListUserEventsQuery, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list user events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			event   UserEvent
+			details []byte
+		)
+		err = rows.Scan(
+			&event.ID,
+			&event.UserID,
+			&event.Type,
+			&event.IP,
+			&event.UserAgent,
+			&details,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan user event: %w", err)
+		}
+		if err = json.Unmarshal(details, &event.Details); err != nil {
+			return nil, fmt.Errorf("decode user event details: %w", err)
+		}
+		output = append(output, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("list user events: %w", err)
+	}
+
+	return output, nil
+}
+
+// insertUserEvent append event to the audit log within tx.
+// Client info is taken from ctx when the event does not carry it.
+func insertUserEvent(ctx context.Context, tx *sql.Tx, event UserEvent) error {
+	client := clientInfoFromContext(ctx)
+	if event.IP == "" {
+		event.IP = client.IP
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = client.UserAgent
+	}
+
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return fmt.Errorf("encode user event details: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, InsertUserEventQuery,
+		event.UserID,
+		event.Type,
+		event.IP,
+		event.UserAgent,
+		details,
 	)
-	return
+	if err != nil {
+		return fmt.Errorf("insert user event %s: %w", event.Type, err)
+	}
+
+	return nil
 }
 
-func (r *Repository) UpdateUser(ctx context.Context, input User) (output User, err error) {
-	tx, err := r.Db.Begin()
+// CreateIdempotencyKey reserve key for an in-flight request.
+// Return ErrDuplicateData when the key is already used and not expired.
+func (r *Repository) CreateIdempotencyKey(ctx context.Context, input IdempotencyKey) (err error) {
+	result, err := r.Db.ExecContext(ctx, InsertIdempotencyKeyQuery,
+		input.Scope,
+		input.Key,
+		input.Fingerprint,
+		input.ExpiresAt,
+	)
 	if err != nil {
-		return output, err
+		return fmt.Errorf("insert idempotency key: %w", err)
 	}
 
-	query, err := tx.PrepareContext(ctx, UpdateUserQuery)
+	affected, err := result.RowsAffected()
 	if err != nil {
-		return output, err
+		return fmt.Errorf("insert idempotency key: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("insert idempotency key: %w", ErrDuplicateData)
 	}
-	defer query.Close()
+	return nil
+}
 
-	_, err = query.ExecContext(ctx,
-		input.Phone,
-		input.Name,
-		// WHERE
-		input.ID,
+// GetIdempotencyKey return the unexpired key, ErrNotFound otherwise.
+func (r *Repository) GetIdempotencyKey(ctx context.Context, scope string, key string) (output IdempotencyKey, err error) {
+	var headers []byte
+	err = r.Db.QueryRowContext(ctx, GetIdempotencyKeyQuery, scope, key).Scan(
+		&output.Scope,
+		&output.Key,
+		&output.Fingerprint,
+		&output.StatusCode,
+		&output.ContentType,
+		&headers,
+		&output.Body,
+		&output.CreatedAt,
+		&output.ExpiresAt,
 	)
 	if err != nil {
-		pqErr, ok := err.(*pq.Error)
-		if ok {
-			if pqErr.Code.Name() == "unique_violation" {
-				return output, ErrDuplicateData
+		return output, fmt.Errorf("get idempotency key: %w", translateError(err))
+	}
+	if headers != nil {
+		if err = json.Unmarshal(headers, &output.Headers); err != nil {
+			return IdempotencyKey{}, fmt.Errorf("decode idempotency key headers: %w", err)
+		}
+	}
+
+	return output, nil
+}
+
+// CompleteIdempotencyKey store the response of the request holding the key.
+func (r *Repository) CompleteIdempotencyKey(ctx context.Context, input IdempotencyKey) (err error) {
+	// NULL when no header is replayed.
+	var headers interface{}
+	if len(input.Headers) > 0 {
+		if headers, err = json.Marshal(input.Headers); err != nil {
+			return fmt.Errorf("encode idempotency key headers: %w", err)
+		}
+	}
+
+	result, err := r.Db.ExecContext(ctx, CompleteIdempotencyKeyQuery,
+		input.Scope,
+		input.Key,
+		input.StatusCode,
+		input.ContentType,
+		headers,
+		input.Body,
+	)
+	if err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+
+	return expectAffected(result, "complete idempotency key")
+}
+
+// DeleteIdempotencyKey release an in-flight key so the request can be retried.
+func (r *Repository) DeleteIdempotencyKey(ctx context.Context, scope string, key string) (err error) {
+	result, err := r.Db.ExecContext(ctx, DeleteIdempotencyKeyQuery, scope, key)
+	if err != nil {
+		return fmt.Errorf("delete idempotency key: %w", err)
+	}
+
+	return expectAffected(result, "delete idempotency key")
+}
+
+// DeleteIdempotencyKeysBefore prune keys expired before the given time.
+func (r *Repository) DeleteIdempotencyKeysBefore(ctx context.Context, before time.Time) (deleted int64, err error) {
+	result, err := r.Db.ExecContext(ctx, DeleteIdempotencyKeysBeforeQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete idempotency keys: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// UpdateRateLimitBucket replace the bucket of key with the result of update,
+// holding a row lock so concurrent instances see each other's updates.
+// found is false when the key has no bucket yet.
+func (r *Repository) UpdateRateLimitBucket(ctx context.Context, key string, update func(bucket RateLimitBucket, found bool) RateLimitBucket) (err error) {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		bucket, found, err := getRateLimitBucketForUpdate(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			next := update(RateLimitBucket{Key: key}, false)
+			result, err := tx.ExecContext(ctx, InsertRateLimitBucketQuery,
+				key,
+				next.Tokens,
+				next.UpdatedAt,
+				next.ExpiresAt,
+			)
+			if err != nil {
+				return fmt.Errorf("insert rate limit bucket: %w", err)
+			}
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("insert rate limit bucket: %w", err)
+			}
+			if affected == 1 {
+				return nil
+			}
+
+			// another request created the bucket first, wait for its lock.
+			bucket, found, err = getRateLimitBucketForUpdate(ctx, tx, key)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return fmt.Errorf("get rate limit bucket: %w", ErrNotFound)
 			}
 		}
-		return output, err
+
+		next := update(bucket, true)
+		_, err = tx.ExecContext(ctx, UpdateRateLimitBucketQuery,
+			key,
+			next.Tokens,
+			next.UpdatedAt,
+			next.ExpiresAt,
+		)
+		if err != nil {
+			return fmt.Errorf("update rate limit bucket: %w", err)
+		}
+		return nil
+	})
+}
+
+func getRateLimitBucketForUpdate(ctx context.Context, tx *sql.Tx, key string) (bucket RateLimitBucket, found bool, err error) {
+	err = tx.QueryRowContext(ctx, GetRateLimitBucketForUpdateQuery, key).Scan(
+		&bucket.Key,
+		&bucket.Tokens,
+		&bucket.UpdatedAt,
+		&bucket.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return bucket, false, nil
+	}
+	if err != nil {
+		return bucket, false, fmt.Errorf("get rate limit bucket: %w", err)
+	}
+	return bucket, true, nil
+}
+
+// DeleteRateLimitBucketsBefore prune buckets that are full again before the given time.
+func (r *Repository) DeleteRateLimitBucketsBefore(ctx context.Context, before time.Time) (deleted int64, err error) {
+	result, err := r.Db.ExecContext(ctx, DeleteRateLimitBucketsBeforeQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete rate limit buckets: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// CreateEmailVerification store the verification of the email of a user,
+// replacing the pending one of the user, if any.
+func (r *Repository) CreateEmailVerification(ctx context.Context, input EmailVerification) (err error) {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, DeleteEmailVerificationsOfUserQuery, input.UserID)
+		if err != nil {
+			return fmt.Errorf("delete email verifications: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, InsertEmailVerificationQuery,
+			input.TokenHash,
+			input.UserID,
+			input.Email,
+			input.ExpiresAt,
+		)
+		if err != nil {
+			return fmt.Errorf("insert email verification: %w", translateError(err))
+		}
+		return nil
+	})
+}
+
+// ConfirmEmailVerification mark the email of the verification of tokenHash
+// as verified and record it in the audit log. The verification is used up.
+// Return ErrNotFound when the token is unknown or expired, or the user
+// changed email since, and ErrDuplicateEmail when another user verified the
+// email first.
+func (r *Repository) ConfirmEmailVerification(ctx context.Context, tokenHash string) (output User, err error) {
+	verified := false
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		var verification EmailVerification
+		err := tx.QueryRowContext(ctx, ConsumeEmailVerificationQuery, tokenHash).Scan(
+			&verification.UserID,
+			&verification.Email,
+			&verification.CreatedAt,
+			&verification.ExpiresAt,
+		)
+		if err != nil {
+			return fmt.Errorf("consume email verification: %w", translateError(err))
+		}
+		// commit, so the used up token is deleted.
+		if !verification.ExpiresAt.After(time.Now()) {
+			return nil
+		}
+
+		err = scanUser(tx.QueryRowContext(ctx, VerifyUserEmailQuery, verification.UserID, verification.Email), &output)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("verify user email: %w", translateError(err))
+		}
+		verified = true
+
+		return insertUserEvent(ctx, tx, UserEvent{
+			UserID: &output.ID,
+			Type:   UserEventEmailVerified,
+		})
+	})
+	if err != nil {
+		return User{}, err
+	}
+	if !verified {
+		return User{}, fmt.Errorf("confirm email verification: %w", ErrNotFound)
+	}
+
+	return output, nil
+}
+
+// CreateOAuthClient register a client of the authorization code flow.
+// Return ErrDuplicateData when the id is taken.
+func (r *Repository) CreateOAuthClient(ctx context.Context, input OAuthClient) (err error) {
+	_, err = r.Db.ExecContext(ctx, InsertOAuthClientQuery,
+		input.ID,
+		input.Name,
+		input.SecretHash,
+		pq.Array(input.RedirectURIs),
+		input.FirstParty,
+	)
+	if err != nil {
+		return fmt.Errorf("insert oauth client: %w", translateError(err))
 	}
+	return nil
+}
 
-	err = tx.Commit()
+// GetOAuthClient return the registered client, ErrNotFound otherwise.
+func (r *Repository) GetOAuthClient(ctx context.Context, id string) (output OAuthClient, err error) {
+	err = r.Db.QueryRowContext(ctx, GetOAuthClientQuery, id).Scan(
+		&output.ID,
+		&output.Name,
+		&output.SecretHash,
+		pq.Array(&output.RedirectURIs),
+		&output.FirstParty,
+		&output.CreatedAt,
+	)
 	if err != nil {
-		return output, err
+		return OAuthClient{}, fmt.Errorf("get oauth client: %w", translateError(err))
 	}
+	return output, nil
+}
 
+// CreateAPIKey store a key and return it with its creation time.
+// Return ErrDuplicateData when the id, prefix or hash is taken.
+func (r *Repository) CreateAPIKey(ctx context.Context, input APIKey) (output APIKey, err error) {
+	err = r.Db.QueryRowContext(ctx, InsertAPIKeyQuery,
+		input.ID,
+		input.Name,
+		input.Prefix,
+		input.KeyHash,
+		input.UserID,
+		pq.Array(input.Scopes),
+		input.RateLimit,
+		input.ExpiresAt,
+		input.CreatedBy,
+	).Scan(&input.CreatedAt)
+	if err != nil {
+		return APIKey{}, fmt.Errorf("insert api key: %w", translateError(err))
+	}
 	return input, nil
 }
 
-func (r *Repository) GetUserByPhone(ctx context.Context, phone string) (output User, err error) {
-	err = r.Db.QueryRowContext(ctx, GetUserByPhoneQuery, phone).Scan(
+// GetAPIKeyByHash return the key of the hash, expired and revoked keys
+// included. Return ErrNotFound when no key has the hash.
+func (r *Repository) GetAPIKeyByHash(ctx context.Context, keyHash string) (output APIKey, err error) {
+	output, err = scanAPIKey(r.Db.QueryRowContext(ctx, GetAPIKeyByHashQuery, keyHash))
+	if err != nil {
+		return APIKey{}, fmt.Errorf("get api key: %w", translateError(err))
+	}
+	return output, nil
+}
+
+// ListAPIKeys return the keys of the user, of every user when userID is
+// nil, newest first.
+func (r *Repository) ListAPIKeys(ctx context.Context, userID *int64) (output []APIKey, err error) {
+	rows, err := r.Db.QueryContext(ctx, ListAPIKeysQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		output = append(output, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+
+	return output, nil
+}
+
+// TouchAPIKey record the use of a key, at most once a minute.
+func (r *Repository) TouchAPIKey(ctx context.Context, id string) (err error) {
+	_, err = r.Db.ExecContext(ctx, TouchAPIKeyQuery, id)
+	if err != nil {
+		return fmt.Errorf("touch api key: %w", err)
+	}
+	return nil
+}
+
+// RevokeAPIKey revoke a key. Return ErrNotFound when the key does not exist
+// or has been revoked.
+func (r *Repository) RevokeAPIKey(ctx context.Context, id string) (err error) {
+	result, err := r.Db.ExecContext(ctx, RevokeAPIKeyQuery, id)
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	return expectAffected(result, "revoke api key")
+}
+
+// scanAPIKey scan a row of the api key queries.
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (output APIKey, err error) {
+	var (
+		rateLimit sql.NullInt32
+		createdBy sql.NullInt64
+	)
+	err = row.Scan(
 		&output.ID,
-		&output.Phone,
 		&output.Name,
-		&output.Password,
+		&output.Prefix,
+		&output.KeyHash,
+		&output.UserID,
+		pq.Array(&output.Scopes),
+		&rateLimit,
+		&output.ExpiresAt,
+		&output.LastUsedAt,
+		&createdBy,
 		&output.CreatedAt,
-		&output.UpdateAt,
+		&output.RevokedAt,
 	)
-	return
+	if err != nil {
+		return APIKey{}, err
+	}
+	if rateLimit.Valid {
+		limit := int(rateLimit.Int32)
+		output.RateLimit = &limit
+	}
+	if createdBy.Valid {
+		output.CreatedBy = &createdBy.Int64
+	}
+	return output, nil
 }
 
-func (r *Repository) IncreaseLoginCount(ctx context.Context, id int64) (err error) {
-	tx, err := r.Db.Begin()
+// CreateAuthorizationCode store a code issued to a client, expired codes
+// that were never exchanged are deleted on the way.
+func (r *Repository) CreateAuthorizationCode(ctx context.Context, input AuthorizationCode) (err error) {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, DeleteExpiredAuthorizationCodesQuery)
+		if err != nil {
+			return fmt.Errorf("delete expired authorization codes: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, InsertAuthorizationCodeQuery,
+			input.CodeHash,
+			input.ClientID,
+			input.UserID,
+			input.RedirectURI,
+			input.Scope,
+			input.Nonce,
+			input.CodeChallenge,
+			input.ExpiresAt,
+		)
+		if err != nil {
+			return fmt.Errorf("insert authorization code: %w", translateError(err))
+		}
+		return nil
+	})
+}
+
+// ConsumeAuthorizationCode return the code of codeHash and delete it, so it
+// is exchanged once. Return ErrNotFound when the code is unknown or expired.
+func (r *Repository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (output AuthorizationCode, err error) {
+	err = r.Db.QueryRowContext(ctx, ConsumeAuthorizationCodeQuery, codeHash).Scan(
+		&output.ClientID,
+		&output.UserID,
+		&output.RedirectURI,
+		&output.Scope,
+		&output.Nonce,
+		&output.CodeChallenge,
+		&output.CreatedAt,
+		&output.ExpiresAt,
+	)
 	if err != nil {
-		return err
+		return AuthorizationCode{}, fmt.Errorf("consume authorization code: %w", translateError(err))
+	}
+	if !output.ExpiresAt.After(time.Now()) {
+		return AuthorizationCode{}, fmt.Errorf("consume authorization code: %w", ErrNotFound)
 	}
+	output.CodeHash = codeHash
+	return output, nil
+}
+
+// CreateIdentityUser create a user signing up with an external identity and
+// link the identity to it. Return ErrDuplicateEmail when the email is used by
+// another user and ErrDuplicateData when the identity is already linked.
+func (r *Repository) CreateIdentityUser(ctx context.Context, input RegisterIdentityUser) (output User, err error) {
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, InsertIdentityUserQuery,
+			input.Name,
+			input.Email,
+		).Scan(&output.ID)
+		if err != nil {
+			return fmt.Errorf("insert identity user: %w", translateError(err))
+		}
 
-	query, err := tx.PrepareContext(ctx, UpdateLoginCount)
+		var createdAt time.Time
+		err = tx.QueryRowContext(ctx, InsertUserIdentityQuery,
+			input.Identity.Provider,
+			input.Identity.Subject,
+			output.ID,
+			input.Identity.Email,
+		).Scan(&createdAt)
+		if err != nil {
+			return fmt.Errorf("insert user identity: %w", translateError(err))
+		}
+
+		return insertUserEvent(ctx, tx, UserEvent{
+			UserID:  &output.ID,
+			Type:    UserEventRegistered,
+			Details: UserEventDetails{Provider: input.Identity.Provider},
+		})
+	})
 	if err != nil {
-		return err
+		return User{}, err
 	}
-	defer query.Close()
 
-	_, err = query.ExecContext(ctx,
-		id,
+	return
+}
+
+// GetUserIdentity return the identity of the provider subject, ErrNotFound
+// when it is not linked to a user.
+func (r *Repository) GetUserIdentity(ctx context.Context, provider string, subject string) (output UserIdentity, err error) {
+	err = r.Db.QueryRowContext(ctx, GetUserIdentityQuery, provider, subject).Scan(
+		&output.Provider,
+		&output.Subject,
+		&output.UserID,
+		&output.Email,
+		&output.CreatedAt,
 	)
 	if err != nil {
-		return err
+		return UserIdentity{}, fmt.Errorf("get user identity: %w", translateError(err))
 	}
+	return output, nil
+}
 
-	err = tx.Commit()
+func (r *Repository) ListUserIdentities(ctx context.Context, userID int64) (output []UserIdentity, err error) {
+	rows, err := r.Db.QueryContext(ctx, ListUserIdentitiesQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list user identities: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var identity UserIdentity
+		err = rows.Scan(
+			&identity.Provider,
+			&identity.Subject,
+			&identity.UserID,
+			&identity.Email,
+			&identity.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan user identity: %w", err)
+		}
+		output = append(output, identity)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("list user identities: %w", err)
+	}
+
+	return output, nil
+}
+
+// CreateUserIdentity link an identity to an existing user and record it in
+// the audit log. Return ErrDuplicateData when the subject is linked already
+// or the user has an identity of the provider.
+func (r *Repository) CreateUserIdentity(ctx context.Context, input UserIdentity) (output UserIdentity, err error) {
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, InsertUserIdentityQuery,
+			input.Provider,
+			input.Subject,
+			input.UserID,
+			input.Email,
+		).Scan(&input.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("insert user identity: %w", translateError(err))
+		}
+
+		return insertUserEvent(ctx, tx, UserEvent{
+			UserID:  &input.UserID,
+			Type:    UserEventIdentityLinked,
+			Details: UserEventDetails{Provider: input.Provider},
+		})
+	})
+	if err != nil {
+		return UserIdentity{}, err
+	}
+
+	return input, nil
+}
+
+// DeleteUserIdentity unlink the identity of the provider from the user and
+// record it in the audit log. Return ErrNotFound when none is linked.
+func (r *Repository) DeleteUserIdentity(ctx context.Context, userID int64, provider string) (err error) {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, DeleteUserIdentityQuery, userID, provider)
+		if err != nil {
+			return fmt.Errorf("delete user identity: %w", err)
+		}
+		if err = expectAffected(result, "delete user identity"); err != nil {
+			return err
+		}
+
+		return insertUserEvent(ctx, tx, UserEvent{
+			UserID:  &userID,
+			Type:    UserEventIdentityUnlinked,
+			Details: UserEventDetails{Provider: provider},
+		})
+	})
+}
+
+// scanUser scan a row of the user columns into user, users without phone
+// have an empty Phone.
+func scanUser(row *sql.Row, user *User) error {
+	var (
+		phone      sql.NullString
+		thumbnails []byte
+	)
+	err := row.Scan(
+		&user.ID,
+		&phone,
+		&user.Name,
+		&user.Password,
+		&user.CreatedAt,
+		&user.UpdateAt,
+		&user.Version,
+		&user.LoginCount,
+		&user.LastLoginAt,
+		&user.Email,
+		&user.EmailVerifiedAt,
+		&user.DisplayName,
+		&user.BirthDate,
+		&user.Locale,
+		&user.Timezone,
+		&user.AvatarURL,
+		&thumbnails,
+	)
 	if err != nil {
 		return err
 	}
+	user.Phone = phone.String
+	if thumbnails == nil {
+		return nil
+	}
+	if err = json.Unmarshal(thumbnails, &user.AvatarThumbnails); err != nil {
+		return fmt.Errorf("decode avatar thumbnails: %w", err)
+	}
+	return nil
+}
 
+// expectAffected return ErrNotFound when the statement changed no rows.
+func expectAffected(result sql.Result, op string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrNotFound)
+	}
 	return nil
 }
+
+// translateError maps driver specific errors to repository errors.
+func translateError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		if pqErr.Constraint == "users_email_key" {
+			return ErrDuplicateEmail
+		}
+		return ErrDuplicateData
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -27,12 +28,13 @@ func TestCreateuser(t *testing.T) {
 			)
 
 			testCases := []struct {
-				testID   int
-				testDesc string
-				args     args
-				mockFunc func(mockSQL sqlmock.Sqlmock)
-				wantResp User
-				wantErr  bool
+				testID    int
+				testDesc  string
+				args      args
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				wantResp  User
+				wantErr   bool
+				wantErrIs error
 			}{
 				{
 					testID:   1,
@@ -55,6 +57,7 @@ func TestCreateuser(t *testing.T) {
 					mockFunc: func(mockSQL sqlmock.Sqlmock) {
 						mockSQL.ExpectBegin()
 						mockSQL.ExpectPrepare(`INSERT INTO users (.+)`).WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
 					},
 					wantErr:  true,
 					wantResp: User{},
@@ -71,9 +74,11 @@ func TestCreateuser(t *testing.T) {
 						mockSQL.ExpectQuery("INSERT INTO users (.+)").
 							WithArgs("mock-phone", "mock-name", "mock-password").
 							WillReturnError(&pq.Error{Code: "23505"})
+						mockSQL.ExpectRollback()
 					},
-					wantErr:  true,
-					wantResp: User{},
+					wantErr:   true,
+					wantErrIs: ErrDuplicateData,
+					wantResp:  User{},
 				},
 				{
 					testID:   4,
@@ -87,12 +92,35 @@ func TestCreateuser(t *testing.T) {
 						mockSQL.ExpectQuery("INSERT INTO users (.+)").
 							WithArgs("mock-phone", "mock-name", "mock-password").
 							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
 					},
 					wantErr:  true,
 					wantResp: User{},
 				},
 				{
 					testID:   5,
+					testDesc: "Failed - error insert user event",
+					args: args{
+						payload: mockUser,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectPrepare(`INSERT INTO users (.+)`)
+						mockSQL.ExpectQuery("INSERT INTO users (.+)").
+							WithArgs("mock-phone", "mock-name", "mock-password").
+							WillReturnRows(
+								sqlmock.NewRows([]string{"id"}).
+									AddRow(1))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr:  true,
+					wantResp: User{},
+				},
+				{
+					testID:   6,
 					testDesc: "Failed - error commit",
 					args: args{
 						payload: mockUser,
@@ -105,13 +133,16 @@ func TestCreateuser(t *testing.T) {
 							WillReturnRows(
 								sqlmock.NewRows([]string{"id"}).
 									AddRow(1))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(1, UserEventRegistered, "127.0.0.1", "mock-agent", []byte(`{}`)).
+							WillReturnResult(sqlmock.NewResult(1, 1))
 						mockSQL.ExpectCommit().WillReturnError(fmt.Errorf("error"))
 					},
 					wantErr:  true,
 					wantResp: User{},
 				},
 				{
-					testID:   6,
+					testID:   7,
 					testDesc: "Success",
 					args: args{
 						payload: mockUser,
@@ -124,6 +155,9 @@ func TestCreateuser(t *testing.T) {
 							WillReturnRows(
 								sqlmock.NewRows([]string{"id"}).
 									AddRow(1))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(1, UserEventRegistered, "127.0.0.1", "mock-agent", []byte(`{}`)).
+							WillReturnResult(sqlmock.NewResult(1, 1))
 						mockSQL.ExpectCommit()
 					},
 					wantErr: false,
@@ -144,9 +178,14 @@ func TestCreateuser(t *testing.T) {
 					}
 					tc.mockFunc(mockSQL)
 
-					output, err := r.Createuser(context.Background(), tc.args.payload)
+					ctx := WithClientInfo(context.Background(), ClientInfo{IP: "127.0.0.1", UserAgent: "mock-agent"})
+					output, err := r.Createuser(ctx, tc.args.payload)
 					// assert
 					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
 					So(output, ShouldEqual, tc.wantResp)
 				})
 			}
@@ -197,8 +236,8 @@ func TestGetUserByID(t *testing.T) {
 						mockSQL.ExpectQuery("SELECT (.+)").
 							WithArgs(int64(1)).
 							WillReturnRows(
-								sqlmock.NewRows([]string{"id", "phone", "name", "password", "created_at", "updated_at"}).
-									AddRow(int64(1), "mock-phone", "mock-name", "mock-password", mockTime, nil))
+								sqlmock.NewRows([]string{"id", "phone", "name", "password", "created_at", "updated_at", "version", "login_count", "last_login_at", "email", "email_verified_at", "display_name", "birth_date", "locale", "timezone", "avatar_url", "avatar_thumbnails"}).
+									AddRow(int64(1), "mock-phone", "mock-name", "mock-password", mockTime, nil, int64(1), int64(0), nil, nil, nil, nil, nil, nil, nil, nil, nil))
 					},
 					wantErr: false,
 					wantResp: User{
@@ -208,6 +247,28 @@ func TestGetUserByID(t *testing.T) {
 						Password:  "mock-password",
 						CreatedAt: mockTime,
 						UpdateAt:  nil,
+						Version:   1,
+					},
+				},
+				{
+					testID:   3,
+					testDesc: "Success - user without phone",
+					args: args{
+						id: 1,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+)").
+							WithArgs(int64(1)).
+							WillReturnRows(
+								sqlmock.NewRows([]string{"id", "phone", "name", "password", "created_at", "updated_at", "version", "login_count", "last_login_at", "email", "email_verified_at", "display_name", "birth_date", "locale", "timezone", "avatar_url", "avatar_thumbnails"}).
+									AddRow(int64(1), nil, "mock-name", "", mockTime, nil, int64(1), int64(0), nil, nil, nil, nil, nil, nil, nil, nil, nil))
+					},
+					wantErr: false,
+					wantResp: User{
+						ID:        1,
+						Name:      "mock-name",
+						CreatedAt: mockTime,
+						Version:   1,
 					},
 				},
 			}
@@ -226,6 +287,7 @@ func TestGetUserByID(t *testing.T) {
 					output, err := r.GetUserByID(context.Background(), tc.args.id)
 					// assert
 					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
 					So(output, ShouldEqual, tc.wantResp)
 				})
 			}
@@ -236,24 +298,38 @@ func TestGetUserByID(t *testing.T) {
 func TestUpdateUser(t *testing.T) {
 	t.Run("TestUpdateUser", func(t *testing.T) {
 		Convey("TestUpdateUser", t, func(c C) {
-			mockUser := User{
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			mockPhone := "mock-phone"
+			mockName := "mock-name"
+			mockVersion := int64(1)
+			mockAvatarURL := "/blobs/avatars/1/512.jpg"
+			mockThumbnails := []AvatarThumbnail{{Size: 64, URL: "/blobs/avatars/1/64.jpg", Key: "avatars/1/64.jpg"}}
+			mockThumbnailsJSON := `[{"size":64,"url":"/blobs/avatars/1/64.jpg","key":"avatars/1/64.jpg"}]`
+			mockUser := UpdateUser{
 				ID:    1,
-				Name:  "mock-name",
-				Phone: "mock-phone",
+				Name:  &mockName,
+				Phone: &mockPhone,
+			}
+			mockColumns := []string{"id", "phone", "name", "password", "created_at", "updated_at", "version", "login_count", "last_login_at", "email", "email_verified_at", "display_name", "birth_date", "locale", "timezone", "avatar_url", "avatar_thumbnails"}
+			mockBeforeRows := func() *sqlmock.Rows {
+				return sqlmock.NewRows(mockColumns).
+					AddRow(int64(1), "stored-phone", "stored-name", "mock-password", mockTime, nil, int64(1), int64(0), nil, nil, nil, nil, nil, nil, nil, nil, nil)
 			}
 
 			type (
 				args struct {
-					payload User
+					payload UpdateUser
 				}
 			)
 
 			testCases := []struct {
-				testID   int
-				testDesc string
-				args     args
-				mockFunc func(mockSQL sqlmock.Sqlmock)
-				wantErr  bool
+				testID    int
+				testDesc  string
+				args      args
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				wantResp  User
+				wantErr   bool
+				wantErrIs error
 			}{
 				{
 					testID:   1,
@@ -268,77 +344,256 @@ func TestUpdateUser(t *testing.T) {
 				},
 				{
 					testID:   2,
+					testDesc: "Failed - error get user for update - user not found",
+					args: args{
+						payload: mockUser,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FOR UPDATE").
+							WithArgs(1).
+							WillReturnRows(sqlmock.NewRows(mockColumns))
+						mockSQL.ExpectRollback()
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   3,
 					testDesc: "Failed - error prepare",
 					args: args{
 						payload: mockUser,
 					},
 					mockFunc: func(mockSQL sqlmock.Sqlmock) {
 						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FOR UPDATE").
+							WithArgs(1).
+							WillReturnRows(mockBeforeRows())
 						mockSQL.ExpectPrepare(`UPDATE users(.+)`).WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
 					},
 					wantErr: true,
 				},
 				{
-					testID:   3,
-					testDesc: "Failed - error exec - unique constraint",
+					testID:   4,
+					testDesc: "Failed - error query - unique constraint",
 					args: args{
 						payload: mockUser,
 					},
 					mockFunc: func(mockSQL sqlmock.Sqlmock) {
 						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FOR UPDATE").
+							WithArgs(1).
+							WillReturnRows(mockBeforeRows())
 						mockSQL.ExpectPrepare(`UPDATE users(.+)`)
-						mockSQL.ExpectExec("UPDATE users(.+)").
-							WithArgs("mock-phone", "mock-name", 1).
+						mockSQL.ExpectQuery("UPDATE users(.+)").
+							WithArgs("mock-phone", "mock-name", 1, nil, nil, nil, nil, nil, nil, nil, nil).
 							WillReturnError(&pq.Error{Code: "23505"})
+						mockSQL.ExpectRollback()
+					},
+					wantErr:   true,
+					wantErrIs: ErrDuplicateData,
+				},
+				{
+					testID:   5,
+					testDesc: "Failed - error query - version conflict",
+					args: args{
+						payload: UpdateUser{
+							ID:      1,
+							Name:    &mockName,
+							Version: &mockVersion,
+						},
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FOR UPDATE").
+							WithArgs(1).
+							WillReturnRows(mockBeforeRows())
+						mockSQL.ExpectPrepare(`UPDATE users(.+)`)
+						mockSQL.ExpectQuery("UPDATE users(.+)").
+							WithArgs(nil, "mock-name", 1, 1, nil, nil, nil, nil, nil, nil, nil).
+							WillReturnRows(sqlmock.NewRows(mockColumns))
+						mockSQL.ExpectRollback()
+					},
+					wantErr:   true,
+					wantErrIs: ErrVersionConflict,
+				},
+				{
+					testID:   6,
+					testDesc: "Failed - error query",
+					args: args{
+						payload: mockUser,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FOR UPDATE").
+							WithArgs(1).
+							WillReturnRows(mockBeforeRows())
+						mockSQL.ExpectPrepare(`UPDATE users(.+)`)
+						mockSQL.ExpectQuery("UPDATE users(.+)").
+							WithArgs("mock-phone", "mock-name", 1, nil, nil, nil, nil, nil, nil, nil, nil).
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
 					},
 					wantErr: true,
 				},
 				{
-					testID:   4,
-					testDesc: "Failed - error exec",
+					testID:   7,
+					testDesc: "Failed - error insert user event",
 					args: args{
 						payload: mockUser,
 					},
 					mockFunc: func(mockSQL sqlmock.Sqlmock) {
 						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FOR UPDATE").
+							WithArgs(1).
+							WillReturnRows(mockBeforeRows())
 						mockSQL.ExpectPrepare(`UPDATE users(.+)`)
-						mockSQL.ExpectExec("UPDATE users(.+)").
-							WithArgs("mock-phone", "mock-name", 1).
+						mockSQL.ExpectQuery("UPDATE users(.+)").
+							WithArgs("mock-phone", "mock-name", 1, nil, nil, nil, nil, nil, nil, nil, nil).
+							WillReturnRows(
+								sqlmock.NewRows(mockColumns).
+									AddRow(int64(1), "mock-phone", "mock-name", "mock-password", mockTime, mockTime, int64(2), int64(0), nil, nil, nil, nil, nil, nil, nil, nil, nil))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
 							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
 					},
 					wantErr: true,
 				},
 				{
-					testID:   5,
+					testID:   8,
 					testDesc: "Failed - error commit",
 					args: args{
 						payload: mockUser,
 					},
 					mockFunc: func(mockSQL sqlmock.Sqlmock) {
 						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FOR UPDATE").
+							WithArgs(1).
+							WillReturnRows(mockBeforeRows())
 						mockSQL.ExpectPrepare(`UPDATE users(.+)`)
-						mockSQL.ExpectExec("UPDATE users(.+)").
-							WithArgs("mock-phone", "mock-name", 1).
-							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectQuery("UPDATE users(.+)").
+							WithArgs("mock-phone", "mock-name", 1, nil, nil, nil, nil, nil, nil, nil, nil).
+							WillReturnRows(
+								sqlmock.NewRows(mockColumns).
+									AddRow(int64(1), "mock-phone", "mock-name", "mock-password", mockTime, mockTime, int64(2), int64(0), nil, nil, nil, nil, nil, nil, nil, nil, nil))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+							WillReturnResult(sqlmock.NewResult(1, 1))
 						mockSQL.ExpectCommit().WillReturnError(fmt.Errorf("error"))
 					},
 					wantErr: true,
 				},
 				{
-					testID:   6,
-					testDesc: "Success",
+					testID:   9,
+					testDesc: "Success - partial update",
 					args: args{
-						payload: mockUser,
+						payload: UpdateUser{
+							ID:   1,
+							Name: &mockName,
+						},
 					},
 					mockFunc: func(mockSQL sqlmock.Sqlmock) {
 						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FOR UPDATE").
+							WithArgs(1).
+							WillReturnRows(mockBeforeRows())
 						mockSQL.ExpectPrepare(`UPDATE users(.+)`)
-						mockSQL.ExpectExec("UPDATE users(.+)").
-							WithArgs("mock-phone", "mock-name", 1).
-							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectQuery("UPDATE users(.+)").
+							WithArgs(nil, "mock-name", 1, nil, nil, nil, nil, nil, nil, nil, nil).
+							WillReturnRows(
+								sqlmock.NewRows(mockColumns).
+									AddRow(int64(1), "stored-phone", "mock-name", "mock-password", mockTime, mockTime, int64(2), int64(0), nil, nil, nil, nil, nil, nil, nil, nil, nil))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(1, UserEventProfileUpdated, "", "", []byte(`{"changes":{"name":{"before":"stored-name","after":"mock-name"}}}`)).
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectCommit()
+					},
+					wantErr: false,
+					wantResp: User{
+						ID:        1,
+						Phone:     "stored-phone",
+						Name:      "mock-name",
+						Password:  "mock-password",
+						CreatedAt: mockTime,
+						UpdateAt:  &mockTime,
+						Version:   2,
+					},
+				},
+				{
+					testID:   10,
+					testDesc: "Success - nothing changed",
+					args: args{
+						payload: UpdateUser{
+							ID:   1,
+							Name: &mockName,
+						},
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FOR UPDATE").
+							WithArgs(1).
+							WillReturnRows(
+								sqlmock.NewRows(mockColumns).
+									AddRow(int64(1), "stored-phone", "mock-name", "mock-password", mockTime, nil, int64(1), int64(0), nil, nil, nil, nil, nil, nil, nil, nil, nil))
+						mockSQL.ExpectPrepare(`UPDATE users(.+)`)
+						mockSQL.ExpectQuery("UPDATE users(.+)").
+							WithArgs(nil, "mock-name", 1, nil, nil, nil, nil, nil, nil, nil, nil).
+							WillReturnRows(
+								sqlmock.NewRows(mockColumns).
+									AddRow(int64(1), "stored-phone", "mock-name", "mock-password", mockTime, mockTime, int64(2), int64(0), nil, nil, nil, nil, nil, nil, nil, nil, nil))
+						mockSQL.ExpectCommit()
+					},
+					wantErr: false,
+					wantResp: User{
+						ID:        1,
+						Phone:     "stored-phone",
+						Name:      "mock-name",
+						Password:  "mock-password",
+						CreatedAt: mockTime,
+						UpdateAt:  &mockTime,
+						Version:   2,
+					},
+				},
+				{
+					testID:   11,
+					testDesc: "Success - uploaded avatar with thumbnails",
+					args: args{
+						payload: UpdateUser{
+							ID:               1,
+							AvatarURL:        &mockAvatarURL,
+							AvatarThumbnails: mockThumbnails,
+						},
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FOR UPDATE").
+							WithArgs(1).
+							WillReturnRows(mockBeforeRows())
+						mockSQL.ExpectPrepare(`UPDATE users(.+)`)
+						mockSQL.ExpectQuery("UPDATE users(.+)").
+							WithArgs(nil, nil, 1, nil, nil, nil, nil, nil, nil, mockAvatarURL, []byte(mockThumbnailsJSON)).
+							WillReturnRows(
+								sqlmock.NewRows(mockColumns).
+									AddRow(int64(1), "stored-phone", "stored-name", "mock-password", mockTime, mockTime, int64(2), int64(0), nil, nil, nil, nil, nil, nil, nil, mockAvatarURL, []byte(mockThumbnailsJSON)))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(1, UserEventProfileUpdated, "", "", []byte(`{"changes":{"avatar_url":{"before":"","after":"/blobs/avatars/1/512.jpg"}}}`)).
+							WillReturnResult(sqlmock.NewResult(1, 1))
 						mockSQL.ExpectCommit()
 					},
 					wantErr: false,
+					wantResp: User{
+						ID:               1,
+						Phone:            "stored-phone",
+						Name:             "stored-name",
+						Password:         "mock-password",
+						CreatedAt:        mockTime,
+						UpdateAt:         &mockTime,
+						Version:          2,
+						AvatarURL:        &mockAvatarURL,
+						AvatarThumbnails: mockThumbnails,
+					},
 				},
 			}
 
@@ -353,9 +608,14 @@ func TestUpdateUser(t *testing.T) {
 					}
 					tc.mockFunc(mockSQL)
 
-					_, err := r.UpdateUser(context.Background(), tc.args.payload)
+					output, err := r.UpdateUser(context.Background(), tc.args.payload)
 					// assert
 					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+					So(output, ShouldResemble, tc.wantResp)
 				})
 			}
 		})
@@ -405,8 +665,8 @@ func TestGetUserByPhone(t *testing.T) {
 						mockSQL.ExpectQuery("SELECT (.+)").
 							WithArgs("mock-phone").
 							WillReturnRows(
-								sqlmock.NewRows([]string{"id", "phone", "name", "password", "created_at", "updated_at"}).
-									AddRow(int64(1), "mock-phone", "mock-name", "mock-password", mockTime, nil))
+								sqlmock.NewRows([]string{"id", "phone", "name", "password", "created_at", "updated_at", "version", "login_count", "last_login_at", "email", "email_verified_at", "display_name", "birth_date", "locale", "timezone", "avatar_url", "avatar_thumbnails"}).
+									AddRow(int64(1), "mock-phone", "mock-name", "mock-password", mockTime, nil, int64(1), int64(0), nil, nil, nil, nil, nil, nil, nil, nil, nil))
 					},
 					wantErr: false,
 					wantResp: User{
@@ -416,6 +676,7 @@ func TestGetUserByPhone(t *testing.T) {
 						Password:  "mock-password",
 						CreatedAt: mockTime,
 						UpdateAt:  nil,
+						Version:   1,
 					},
 				},
 			}
@@ -434,6 +695,7 @@ func TestGetUserByPhone(t *testing.T) {
 					output, err := r.GetUserByPhone(context.Background(), tc.args.phone)
 					// assert
 					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
 					So(output, ShouldEqual, tc.wantResp)
 				})
 			}
@@ -441,13 +703,104 @@ func TestGetUserByPhone(t *testing.T) {
 	})
 }
 
-func TestIncreaseLoginCount(t *testing.T) {
-	t.Run("TestIncreaseLoginCount", func(t *testing.T) {
-		Convey("TestIncreaseLoginCount", t, func(c C) {
+func TestGetUserByEmail(t *testing.T) {
+	t.Run("TestGetUserByEmail", func(t *testing.T) {
+		Convey("TestGetUserByEmail", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			mockEmail := "budi@example.com"
 
 			type (
 				args struct {
-					id int64
+					email string
+				}
+			)
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				args     args
+				mockFunc func(mockSQL sqlmock.Sqlmock)
+				wantResp User
+				wantErr  bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed",
+					args: args{
+						email: "budi@example.com",
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+)").
+							WithArgs("budi@example.com").
+							WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr:  true,
+					wantResp: User{},
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					args: args{
+						email: "budi@example.com",
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+)").
+							WithArgs("budi@example.com").
+							WillReturnRows(
+								sqlmock.NewRows([]string{"id", "phone", "name", "password", "created_at", "updated_at", "version", "login_count", "last_login_at", "email", "email_verified_at", "display_name", "birth_date", "locale", "timezone", "avatar_url", "avatar_thumbnails"}).
+									AddRow(int64(1), "mock-phone", "mock-name", "mock-password", mockTime, nil, int64(1), int64(0), nil, "budi@example.com", mockTime, nil, nil, nil, nil, nil, nil))
+					},
+					wantErr: false,
+					wantResp: User{
+						ID:        1,
+						Name:      "mock-name",
+						Phone:     "mock-phone",
+						Password:  "mock-password",
+						CreatedAt: mockTime,
+						UpdateAt:  nil,
+						Version:   1,
+
+						Email:           &mockEmail,
+						EmailVerifiedAt: &mockTime,
+					},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					output, err := r.GetUserByEmail(context.Background(), tc.args.email)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+					So(output, ShouldEqual, tc.wantResp)
+				})
+			}
+		})
+	})
+}
+
+func TestRecordLogin(t *testing.T) {
+	t.Run("TestRecordLogin", func(t *testing.T) {
+		Convey("TestRecordLogin", t, func(c C) {
+			mockLogin := UserLogin{
+				UserID:     1,
+				TokenID:    "mock-token-id",
+				SessionID:  "mock-session-id",
+				DeviceName: "mock-device",
+			}
+
+			type (
+				args struct {
+					payload UserLogin
 				}
 			)
 
@@ -462,7 +815,7 @@ func TestIncreaseLoginCount(t *testing.T) {
 					testID:   1,
 					testDesc: "Failed - error begin",
 					args: args{
-						id: 1,
+						payload: mockLogin,
 					},
 					mockFunc: func(mockSQL sqlmock.Sqlmock) {
 						mockSQL.ExpectBegin().WillReturnError(fmt.Errorf("error"))
@@ -473,11 +826,12 @@ func TestIncreaseLoginCount(t *testing.T) {
 					testID:   2,
 					testDesc: "Failed - error prepare",
 					args: args{
-						id: 1,
+						payload: mockLogin,
 					},
 					mockFunc: func(mockSQL sqlmock.Sqlmock) {
 						mockSQL.ExpectBegin()
 						mockSQL.ExpectPrepare(`UPDATE users(.+)`).WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
 					},
 					wantErr: true,
 				},
@@ -485,7 +839,7 @@ func TestIncreaseLoginCount(t *testing.T) {
 					testID:   3,
 					testDesc: "Failed - error exec",
 					args: args{
-						id: 1,
+						payload: mockLogin,
 					},
 					mockFunc: func(mockSQL sqlmock.Sqlmock) {
 						mockSQL.ExpectBegin()
@@ -493,14 +847,15 @@ func TestIncreaseLoginCount(t *testing.T) {
 						mockSQL.ExpectExec("UPDATE users(.+)").
 							WithArgs(1).
 							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
 					},
 					wantErr: true,
 				},
 				{
 					testID:   4,
-					testDesc: "Failed - error commit",
+					testDesc: "Failed - error insert user login",
 					args: args{
-						id: 1,
+						payload: mockLogin,
 					},
 					mockFunc: func(mockSQL sqlmock.Sqlmock) {
 						mockSQL.ExpectBegin()
@@ -508,15 +863,65 @@ func TestIncreaseLoginCount(t *testing.T) {
 						mockSQL.ExpectExec("UPDATE users(.+)").
 							WithArgs(1).
 							WillReturnResult(sqlmock.NewResult(0, 1))
-						mockSQL.ExpectCommit().WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectExec("INSERT INTO user_logins (.+)").
+							WithArgs(1, "mock-token-id", "127.0.0.1", "mock-agent").
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
 					},
 					wantErr: true,
 				},
 				{
 					testID:   5,
-					testDesc: "Success",
+					testDesc: "Failed - error insert user session",
 					args: args{
-						id: 1,
+						payload: mockLogin,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectPrepare(`UPDATE users(.+)`)
+						mockSQL.ExpectExec("UPDATE users(.+)").
+							WithArgs(1).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectExec("INSERT INTO user_logins (.+)").
+							WithArgs(1, "mock-token-id", "127.0.0.1", "mock-agent").
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectExec("INSERT INTO user_sessions (.+)").
+							WithArgs("mock-session-id", 1, "mock-device", "127.0.0.1", "mock-agent").
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr: true,
+				},
+				{
+					testID:   6,
+					testDesc: "Failed - error insert user event",
+					args: args{
+						payload: mockLogin,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectPrepare(`UPDATE users(.+)`)
+						mockSQL.ExpectExec("UPDATE users(.+)").
+							WithArgs(1).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectExec("INSERT INTO user_logins (.+)").
+							WithArgs(1, "mock-token-id", "127.0.0.1", "mock-agent").
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectExec("INSERT INTO user_sessions (.+)").
+							WithArgs("mock-session-id", 1, "mock-device", "127.0.0.1", "mock-agent").
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr: true,
+				},
+				{
+					testID:   7,
+					testDesc: "Failed - error commit",
+					args: args{
+						payload: mockLogin,
 					},
 					mockFunc: func(mockSQL sqlmock.Sqlmock) {
 						mockSQL.ExpectBegin()
@@ -524,6 +929,2128 @@ func TestIncreaseLoginCount(t *testing.T) {
 						mockSQL.ExpectExec("UPDATE users(.+)").
 							WithArgs(1).
 							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectExec("INSERT INTO user_logins (.+)").
+							WithArgs(1, "mock-token-id", "127.0.0.1", "mock-agent").
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectExec("INSERT INTO user_sessions (.+)").
+							WithArgs("mock-session-id", 1, "mock-device", "127.0.0.1", "mock-agent").
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(1, UserEventLoginSucceeded, "127.0.0.1", "mock-agent", []byte(`{}`)).
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectCommit().WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   8,
+					testDesc: "Success",
+					args: args{
+						payload: mockLogin,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectPrepare(`UPDATE users(.+)`)
+						mockSQL.ExpectExec("UPDATE users(.+)").
+							WithArgs(1).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectExec("INSERT INTO user_logins (.+)").
+							WithArgs(1, "mock-token-id", "127.0.0.1", "mock-agent").
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectExec("INSERT INTO user_sessions (.+)").
+							WithArgs("mock-session-id", 1, "mock-device", "127.0.0.1", "mock-agent").
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(1, UserEventLoginSucceeded, "127.0.0.1", "mock-agent", []byte(`{}`)).
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectCommit()
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					ctx := WithClientInfo(context.Background(), ClientInfo{IP: "127.0.0.1", UserAgent: "mock-agent"})
+					err := r.RecordLogin(ctx, tc.args.payload)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestListUserLogins(t *testing.T) {
+	t.Run("TestListUserLogins", func(t *testing.T) {
+		Convey("TestListUserLogins", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			mockBefore := int64(10)
+			mockColumns := []string{"id", "user_id", "token_id", "ip_address", "user_agent", "created_at"}
+
+			type (
+				args struct {
+					userID int64
+					before *int64
+					limit  int
+				}
+			)
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				args     args
+				mockFunc func(mockSQL sqlmock.Sqlmock)
+				wantResp []UserLogin
+				wantErr  bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error query",
+					args: args{
+						userID: 1,
+						limit:  20,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM user_logins").
+							WithArgs(1, nil, 20).
+							WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					args: args{
+						userID: 1,
+						before: &mockBefore,
+						limit:  20,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM user_logins").
+							WithArgs(1, 10, 20).
+							WillReturnRows(
+								sqlmock.NewRows(mockColumns).
+									AddRow(int64(9), int64(1), "mock-token-id", "127.0.0.1", "mock-agent", mockTime))
+					},
+					wantErr: false,
+					wantResp: []UserLogin{
+						{
+							ID:        9,
+							UserID:    1,
+							TokenID:   "mock-token-id",
+							IP:        "127.0.0.1",
+							UserAgent: "mock-agent",
+							CreatedAt: mockTime,
+						},
+					},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					output, err := r.ListUserLogins(context.Background(), tc.args.userID, tc.args.before, tc.args.limit)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+					So(output, ShouldResemble, tc.wantResp)
+				})
+			}
+		})
+	})
+}
+
+func TestDeleteUserLoginsBefore(t *testing.T) {
+	t.Run("TestDeleteUserLoginsBefore", func(t *testing.T) {
+		Convey("TestDeleteUserLoginsBefore", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+
+			testCases := []struct {
+				testID      int
+				testDesc    string
+				mockFunc    func(mockSQL sqlmock.Sqlmock)
+				wantDeleted int64
+				wantErr     bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error exec",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectExec("DELETE FROM user_logins (.+)").
+							WithArgs(mockTime).
+							WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectExec("DELETE FROM user_logins (.+)").
+							WithArgs(mockTime).
+							WillReturnResult(sqlmock.NewResult(0, 3))
+					},
+					wantDeleted: 3,
+					wantErr:     false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					deleted, err := r.DeleteUserLoginsBefore(context.Background(), mockTime)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+					So(deleted, ShouldEqual, tc.wantDeleted)
+				})
+			}
+		})
+	})
+}
+
+func TestCreateUserEvent(t *testing.T) {
+	t.Run("TestCreateUserEvent", func(t *testing.T) {
+		Convey("TestCreateUserEvent", t, func(c C) {
+			mockEvent := UserEvent{
+				Type:    UserEventLoginFailed,
+				Details: UserEventDetails{Reason: LoginFailedUnknownUser},
+			}
+
+			type (
+				args struct {
+					payload UserEvent
+				}
+			)
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				args     args
+				mockFunc func(mockSQL sqlmock.Sqlmock)
+				wantErr  bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error begin",
+					args: args{
+						payload: mockEvent,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin().WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - error exec",
+					args: args{
+						payload: mockEvent,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(nil, UserEventLoginFailed, "127.0.0.1", "mock-agent", []byte(`{"reason":"unknown_user"}`)).
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr: true,
+				},
+				{
+					testID:   3,
+					testDesc: "Success",
+					args: args{
+						payload: mockEvent,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(nil, UserEventLoginFailed, "127.0.0.1", "mock-agent", []byte(`{"reason":"unknown_user"}`)).
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectCommit()
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					ctx := WithClientInfo(context.Background(), ClientInfo{IP: "127.0.0.1", UserAgent: "mock-agent"})
+					err := r.CreateUserEvent(ctx, tc.args.payload)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestListUserEvents(t *testing.T) {
+	t.Run("TestListUserEvents", func(t *testing.T) {
+		Convey("TestListUserEvents", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			mockUserID := int64(1)
+			mockColumns := []string{"id", "user_id", "event_type", "ip_address", "user_agent", "details", "created_at"}
+
+			type (
+				args struct {
+					userID int64
+					limit  int
+				}
+			)
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				args     args
+				mockFunc func(mockSQL sqlmock.Sqlmock)
+				wantResp []UserEvent
+				wantErr  bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error query",
+					args: args{
+						userID: 1,
+						limit:  20,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM user_events").
+							WithArgs(1, 20).
+							WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - error decode details",
+					args: args{
+						userID: 1,
+						limit:  20,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM user_events").
+							WithArgs(1, 20).
+							WillReturnRows(
+								sqlmock.NewRows(mockColumns).
+									AddRow(int64(1), int64(1), UserEventRegistered, "", "", []byte(`{`), mockTime))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   3,
+					testDesc: "Success",
+					args: args{
+						userID: 1,
+						limit:  20,
+					},
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM user_events").
+							WithArgs(1, 20).
+							WillReturnRows(
+								sqlmock.NewRows(mockColumns).
+									AddRow(int64(2), int64(1), UserEventProfileUpdated, "127.0.0.1", "mock-agent", []byte(`{"changes":{"name":{"before":"a","after":"b"}}}`), mockTime).
+									AddRow(int64(1), int64(1), UserEventRegistered, "127.0.0.1", "mock-agent", []byte(`{}`), mockTime))
+					},
+					wantErr: false,
+					wantResp: []UserEvent{
+						{
+							ID:        2,
+							UserID:    &mockUserID,
+							Type:      UserEventProfileUpdated,
+							IP:        "127.0.0.1",
+							UserAgent: "mock-agent",
+							Details: UserEventDetails{
+								Changes: map[string]FieldChange{"name": {Before: "a", After: "b"}},
+							},
+							CreatedAt: mockTime,
+						},
+						{
+							ID:        1,
+							UserID:    &mockUserID,
+							Type:      UserEventRegistered,
+							IP:        "127.0.0.1",
+							UserAgent: "mock-agent",
+							CreatedAt: mockTime,
+						},
+					},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					output, err := r.ListUserEvents(context.Background(), tc.args.userID, tc.args.limit)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+					So(output, ShouldResemble, tc.wantResp)
+				})
+			}
+		})
+	})
+}
+
+func TestListActiveUserSessions(t *testing.T) {
+	t.Run("TestListActiveUserSessions", func(t *testing.T) {
+		Convey("TestListActiveUserSessions", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			mockColumns := []string{"id", "user_id", "device_name", "ip_address", "user_agent", "created_at", "last_seen_at", "revoked_at"}
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				mockFunc func(mockSQL sqlmock.Sqlmock)
+				wantResp []UserSession
+				wantErr  bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error query",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM user_sessions").
+							WithArgs(1).
+							WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM user_sessions").
+							WithArgs(1).
+							WillReturnRows(
+								sqlmock.NewRows(mockColumns).
+									AddRow("mock-session-id", int64(1), "mock-device", "127.0.0.1", "mock-agent", mockTime, mockTime, nil))
+					},
+					wantErr: false,
+					wantResp: []UserSession{
+						{
+							ID:         "mock-session-id",
+							UserID:     1,
+							DeviceName: "mock-device",
+							IP:         "127.0.0.1",
+							UserAgent:  "mock-agent",
+							CreatedAt:  mockTime,
+							LastSeenAt: mockTime,
+						},
+					},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					output, err := r.ListActiveUserSessions(context.Background(), 1)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+					So(output, ShouldResemble, tc.wantResp)
+				})
+			}
+		})
+	})
+}
+
+func TestTouchUserSession(t *testing.T) {
+	t.Run("TestTouchUserSession", func(t *testing.T) {
+		Convey("TestTouchUserSession", t, func(c C) {
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error query",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("WITH active AS (.+) UPDATE user_sessions (.+)").
+							WithArgs("mock-session-id", 1).
+							WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - session revoked",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("WITH active AS (.+) UPDATE user_sessions (.+)").
+							WithArgs("mock-session-id", 1).
+							WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   3,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("WITH active AS (.+) UPDATE user_sessions (.+)").
+							WithArgs("mock-session-id", 1).
+							WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					err := r.TouchUserSession(context.Background(), "mock-session-id", 1)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestRevokeUserSession(t *testing.T) {
+	t.Run("TestRevokeUserSession", func(t *testing.T) {
+		Convey("TestRevokeUserSession", t, func(c C) {
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error begin",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin().WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - session not found",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("UPDATE user_sessions (.+)").
+							WithArgs("mock-session-id", 1).
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectRollback()
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   3,
+					testDesc: "Failed - error insert user event",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("UPDATE user_sessions (.+)").
+							WithArgs("mock-session-id", 1).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr: true,
+				},
+				{
+					testID:   4,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("UPDATE user_sessions (.+)").
+							WithArgs("mock-session-id", 1).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(1, UserEventSessionRevoked, "", "", []byte(`{"session_id":"mock-session-id"}`)).
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectCommit()
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					err := r.RevokeUserSession(context.Background(), "mock-session-id", 1)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestCreateEmailVerification(t *testing.T) {
+	t.Run("TestCreateEmailVerification", func(t *testing.T) {
+		Convey("TestCreateEmailVerification", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			input := EmailVerification{TokenHash: "mock-token-hash", UserID: 1, Email: "budi@example.com", ExpiresAt: mockTime}
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				mockFunc func(mockSQL sqlmock.Sqlmock)
+				wantErr  bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error delete pending verification",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("DELETE FROM email_verifications (.+)").
+							WithArgs(1).
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - error insert",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("DELETE FROM email_verifications (.+)").
+							WithArgs(1).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectExec("INSERT INTO email_verifications (.+)").
+							WithArgs("mock-token-hash", 1, "budi@example.com", mockTime).
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr: true,
+				},
+				{
+					testID:   3,
+					testDesc: "Success - pending verification replaced",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("DELETE FROM email_verifications (.+)").
+							WithArgs(1).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectExec("INSERT INTO email_verifications (.+)").
+							WithArgs("mock-token-hash", 1, "budi@example.com", mockTime).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectCommit()
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					err := r.CreateEmailVerification(context.Background(), input)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestConfirmEmailVerification(t *testing.T) {
+	t.Run("TestConfirmEmailVerification", func(t *testing.T) {
+		Convey("TestConfirmEmailVerification", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			expiresAt := time.Now().Add(time.Hour)
+			email := "budi@example.com"
+			verificationColumns := []string{"user_id", "email", "created_at", "expires_at"}
+			userColumns := []string{"id", "phone", "name", "password", "created_at", "updated_at", "version", "login_count", "last_login_at", "email", "email_verified_at", "display_name", "birth_date", "locale", "timezone", "avatar_url", "avatar_thumbnails"}
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				want      User
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - unknown token",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("DELETE FROM email_verifications (.+)").
+							WithArgs("mock-token-hash").
+							WillReturnRows(sqlmock.NewRows(verificationColumns))
+						mockSQL.ExpectRollback()
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - expired token is used up",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("DELETE FROM email_verifications (.+)").
+							WithArgs("mock-token-hash").
+							WillReturnRows(sqlmock.NewRows(verificationColumns).
+								AddRow(int64(1), email, mockTime, mockTime))
+						mockSQL.ExpectCommit()
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   3,
+					testDesc: "Failed - email changed since the token was mailed",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("DELETE FROM email_verifications (.+)").
+							WithArgs("mock-token-hash").
+							WillReturnRows(sqlmock.NewRows(verificationColumns).
+								AddRow(int64(1), email, mockTime, expiresAt))
+						mockSQL.ExpectQuery("UPDATE users (.+)").
+							WithArgs(int64(1), email).
+							WillReturnRows(sqlmock.NewRows(userColumns))
+						mockSQL.ExpectCommit()
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   4,
+					testDesc: "Failed - email verified by another user first",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("DELETE FROM email_verifications (.+)").
+							WithArgs("mock-token-hash").
+							WillReturnRows(sqlmock.NewRows(verificationColumns).
+								AddRow(int64(1), email, mockTime, expiresAt))
+						mockSQL.ExpectQuery("UPDATE users (.+)").
+							WithArgs(int64(1), email).
+							WillReturnError(&pq.Error{Code: "23505", Constraint: "users_email_key"})
+						mockSQL.ExpectRollback()
+					},
+					wantErr:   true,
+					wantErrIs: ErrDuplicateEmail,
+				},
+				{
+					testID:   5,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("DELETE FROM email_verifications (.+)").
+							WithArgs("mock-token-hash").
+							WillReturnRows(sqlmock.NewRows(verificationColumns).
+								AddRow(int64(1), email, mockTime, expiresAt))
+						mockSQL.ExpectQuery("UPDATE users (.+)").
+							WithArgs(int64(1), email).
+							WillReturnRows(sqlmock.NewRows(userColumns).
+								AddRow(int64(1), "mock-phone", "mock-name", "mock-password", mockTime, mockTime, int64(2), int64(0), nil, email, mockTime, nil, nil, nil, nil, nil, nil))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(1, UserEventEmailVerified, "", "", []byte(`{}`)).
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectCommit()
+					},
+					want: User{
+						ID:              1,
+						Phone:           "mock-phone",
+						Name:            "mock-name",
+						Password:        "mock-password",
+						CreatedAt:       mockTime,
+						UpdateAt:        &mockTime,
+						Version:         2,
+						Email:           &email,
+						EmailVerifiedAt: &mockTime,
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					got, err := r.ConfirmEmailVerification(context.Background(), "mock-token-hash")
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(got, ShouldResemble, tc.want)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestCreateOAuthClient(t *testing.T) {
+	t.Run("TestCreateOAuthClient", func(t *testing.T) {
+		Convey("TestCreateOAuthClient", t, func(c C) {
+			input := OAuthClient{ID: "mock-client", Name: "Mock App", RedirectURIs: []string{"https://app.example.com/callback"}, FirstParty: true}
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - id taken",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectExec("INSERT INTO oauth_clients (.+)").
+							WithArgs("mock-client", "Mock App", "", pq.Array(input.RedirectURIs), true).
+							WillReturnError(&pq.Error{Code: "23505"})
+					},
+					wantErr:   true,
+					wantErrIs: ErrDuplicateData,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectExec("INSERT INTO oauth_clients (.+)").
+							WithArgs("mock-client", "Mock App", "", pq.Array(input.RedirectURIs), true).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					err := r.CreateOAuthClient(context.Background(), input)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestGetOAuthClient(t *testing.T) {
+	t.Run("TestGetOAuthClient", func(t *testing.T) {
+		Convey("TestGetOAuthClient", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			clientColumns := []string{"id", "name", "secret_hash", "redirect_uris", "first_party", "created_at"}
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				want      OAuthClient
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - unknown client",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM oauth_clients (.+)").
+							WithArgs("mock-client").
+							WillReturnRows(sqlmock.NewRows(clientColumns))
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM oauth_clients (.+)").
+							WithArgs("mock-client").
+							WillReturnRows(sqlmock.NewRows(clientColumns).
+								AddRow("mock-client", "Mock App", "mock-secret-hash", "{https://app.example.com/callback,https://app.example.com/other}", false, mockTime))
+					},
+					want: OAuthClient{
+						ID:           "mock-client",
+						Name:         "Mock App",
+						SecretHash:   "mock-secret-hash",
+						RedirectURIs: []string{"https://app.example.com/callback", "https://app.example.com/other"},
+						CreatedAt:    mockTime,
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					got, err := r.GetOAuthClient(context.Background(), "mock-client")
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(got, ShouldResemble, tc.want)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestCreateAuthorizationCode(t *testing.T) {
+	t.Run("TestCreateAuthorizationCode", func(t *testing.T) {
+		Convey("TestCreateAuthorizationCode", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			input := AuthorizationCode{
+				CodeHash:      "mock-code-hash",
+				ClientID:      "mock-client",
+				UserID:        1,
+				RedirectURI:   "https://app.example.com/callback",
+				Scope:         "openid",
+				Nonce:         "mock-nonce",
+				CodeChallenge: "mock-challenge",
+				ExpiresAt:     mockTime,
+			}
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				mockFunc func(mockSQL sqlmock.Sqlmock)
+				wantErr  bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error delete expired codes",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("DELETE FROM oauth_authorization_codes (.+)").
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - error insert",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("DELETE FROM oauth_authorization_codes (.+)").
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectExec("INSERT INTO oauth_authorization_codes (.+)").
+							WithArgs("mock-code-hash", "mock-client", 1, "https://app.example.com/callback", "openid", "mock-nonce", "mock-challenge", mockTime).
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr: true,
+				},
+				{
+					testID:   3,
+					testDesc: "Success - expired codes deleted",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("DELETE FROM oauth_authorization_codes (.+)").
+							WillReturnResult(sqlmock.NewResult(0, 2))
+						mockSQL.ExpectExec("INSERT INTO oauth_authorization_codes (.+)").
+							WithArgs("mock-code-hash", "mock-client", 1, "https://app.example.com/callback", "openid", "mock-nonce", "mock-challenge", mockTime).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectCommit()
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					err := r.CreateAuthorizationCode(context.Background(), input)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestConsumeAuthorizationCode(t *testing.T) {
+	t.Run("TestConsumeAuthorizationCode", func(t *testing.T) {
+		Convey("TestConsumeAuthorizationCode", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			expiresAt := time.Now().Add(time.Minute)
+			codeColumns := []string{"client_id", "user_id", "redirect_uri", "scope", "nonce", "code_challenge", "created_at", "expires_at"}
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				want      AuthorizationCode
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - unknown code",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("DELETE FROM oauth_authorization_codes (.+)").
+							WithArgs("mock-code-hash").
+							WillReturnRows(sqlmock.NewRows(codeColumns))
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - expired code is used up",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("DELETE FROM oauth_authorization_codes (.+)").
+							WithArgs("mock-code-hash").
+							WillReturnRows(sqlmock.NewRows(codeColumns).
+								AddRow("mock-client", int64(1), "https://app.example.com/callback", "openid", "", "mock-challenge", mockTime, mockTime))
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   3,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("DELETE FROM oauth_authorization_codes (.+)").
+							WithArgs("mock-code-hash").
+							WillReturnRows(sqlmock.NewRows(codeColumns).
+								AddRow("mock-client", int64(1), "https://app.example.com/callback", "openid", "mock-nonce", "mock-challenge", mockTime, expiresAt))
+					},
+					want: AuthorizationCode{
+						CodeHash:      "mock-code-hash",
+						ClientID:      "mock-client",
+						UserID:        1,
+						RedirectURI:   "https://app.example.com/callback",
+						Scope:         "openid",
+						Nonce:         "mock-nonce",
+						CodeChallenge: "mock-challenge",
+						CreatedAt:     mockTime,
+						ExpiresAt:     expiresAt,
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					got, err := r.ConsumeAuthorizationCode(context.Background(), "mock-code-hash")
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(got, ShouldResemble, tc.want)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestCreateIdentityUser(t *testing.T) {
+	t.Run("TestCreateIdentityUser", func(t *testing.T) {
+		Convey("TestCreateIdentityUser", t, func(c C) {
+			email := "budi@example.com"
+			input := RegisterIdentityUser{
+				Name:  "Budi",
+				Email: &email,
+				Identity: UserIdentity{
+					Provider: "google",
+					Subject:  "mock-subject",
+					Email:    &email,
+				},
+			}
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				wantResp  User
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - email used by another user",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("INSERT INTO users (.+)").
+							WithArgs("Budi", &email).
+							WillReturnError(&pq.Error{Code: "23505", Constraint: "users_email_key"})
+						mockSQL.ExpectRollback()
+					},
+					wantErr:   true,
+					wantErrIs: ErrDuplicateEmail,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - identity already linked",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("INSERT INTO users (.+)").
+							WithArgs("Budi", &email).
+							WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+						mockSQL.ExpectQuery("INSERT INTO user_identities (.+)").
+							WithArgs("google", "mock-subject", 1, &email).
+							WillReturnError(&pq.Error{Code: "23505"})
+						mockSQL.ExpectRollback()
+					},
+					wantErr:   true,
+					wantErrIs: ErrDuplicateData,
+				},
+				{
+					testID:   3,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("INSERT INTO users (.+)").
+							WithArgs("Budi", &email).
+							WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+						mockSQL.ExpectQuery("INSERT INTO user_identities (.+)").
+							WithArgs("google", "mock-subject", 1, &email).
+							WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(1, UserEventRegistered, "", "", []byte(`{"provider":"google"}`)).
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectCommit()
+					},
+					wantErr:  false,
+					wantResp: User{ID: 1},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					output, err := r.CreateIdentityUser(context.Background(), input)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+					So(output, ShouldResemble, tc.wantResp)
+				})
+			}
+		})
+	})
+}
+
+func TestGetUserIdentity(t *testing.T) {
+	t.Run("TestGetUserIdentity", func(t *testing.T) {
+		Convey("TestGetUserIdentity", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			identityColumns := []string{"provider", "subject", "user_id", "email", "created_at"}
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				want      UserIdentity
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - identity not linked",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM user_identities (.+)").
+							WithArgs("google", "mock-subject").
+							WillReturnRows(sqlmock.NewRows(identityColumns))
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM user_identities (.+)").
+							WithArgs("google", "mock-subject").
+							WillReturnRows(sqlmock.NewRows(identityColumns).
+								AddRow("google", "mock-subject", int64(1), nil, mockTime))
+					},
+					want:    UserIdentity{Provider: "google", Subject: "mock-subject", UserID: 1, CreatedAt: mockTime},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					output, err := r.GetUserIdentity(context.Background(), "google", "mock-subject")
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+					So(output, ShouldResemble, tc.want)
+				})
+			}
+		})
+	})
+}
+
+func TestListUserIdentities(t *testing.T) {
+	t.Run("TestListUserIdentities", func(t *testing.T) {
+		Convey("TestListUserIdentities", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			email := "budi@example.com"
+			identityColumns := []string{"provider", "subject", "user_id", "email", "created_at"}
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				mockFunc func(mockSQL sqlmock.Sqlmock)
+				want     []UserIdentity
+				wantErr  bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error query",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM user_identities (.+)").
+							WithArgs(int64(1)).
+							WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM user_identities (.+)").
+							WithArgs(int64(1)).
+							WillReturnRows(sqlmock.NewRows(identityColumns).
+								AddRow("apple", "mock-apple-subject", int64(1), nil, mockTime).
+								AddRow("google", "mock-subject", int64(1), email, mockTime))
+					},
+					want: []UserIdentity{
+						{Provider: "apple", Subject: "mock-apple-subject", UserID: 1, CreatedAt: mockTime},
+						{Provider: "google", Subject: "mock-subject", UserID: 1, Email: &email, CreatedAt: mockTime},
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					output, err := r.ListUserIdentities(context.Background(), 1)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+					So(output, ShouldResemble, tc.want)
+				})
+			}
+		})
+	})
+}
+
+func TestCreateUserIdentity(t *testing.T) {
+	t.Run("TestCreateUserIdentity", func(t *testing.T) {
+		Convey("TestCreateUserIdentity", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			input := UserIdentity{Provider: "google", Subject: "mock-subject", UserID: 1}
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				want      UserIdentity
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - identity already linked",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("INSERT INTO user_identities (.+)").
+							WithArgs("google", "mock-subject", 1, nil).
+							WillReturnError(&pq.Error{Code: "23505"})
+						mockSQL.ExpectRollback()
+					},
+					wantErr:   true,
+					wantErrIs: ErrDuplicateData,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("INSERT INTO user_identities (.+)").
+							WithArgs("google", "mock-subject", 1, nil).
+							WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(mockTime))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(1, UserEventIdentityLinked, "", "", []byte(`{"provider":"google"}`)).
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectCommit()
+					},
+					want:    UserIdentity{Provider: "google", Subject: "mock-subject", UserID: 1, CreatedAt: mockTime},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					output, err := r.CreateUserIdentity(context.Background(), input)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+					So(output, ShouldResemble, tc.want)
+				})
+			}
+		})
+	})
+}
+
+func TestDeleteUserIdentity(t *testing.T) {
+	t.Run("TestDeleteUserIdentity", func(t *testing.T) {
+		Convey("TestDeleteUserIdentity", t, func(c C) {
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - identity not linked",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("DELETE FROM user_identities (.+)").
+							WithArgs(1, "google").
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectRollback()
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectExec("DELETE FROM user_identities (.+)").
+							WithArgs(1, "google").
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectExec("INSERT INTO user_events (.+)").
+							WithArgs(1, UserEventIdentityUnlinked, "", "", []byte(`{"provider":"google"}`)).
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mockSQL.ExpectCommit()
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					err := r.DeleteUserIdentity(context.Background(), 1, "google")
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	t.Run("TestCreateAPIKey", func(t *testing.T) {
+		Convey("TestCreateAPIKey", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			rateLimit := 100
+			admin := int64(1)
+			input := APIKey{
+				ID:        "mock-key-id",
+				Name:      "Billing export",
+				Prefix:    "usk_mockpref",
+				KeyHash:   "mock-key-hash",
+				UserID:    17,
+				Scopes:    []string{"profile:read"},
+				RateLimit: &rateLimit,
+				ExpiresAt: &mockTime,
+				CreatedBy: &admin,
+			}
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				want      APIKey
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - prefix taken",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("INSERT INTO api_keys (.+)").
+							WithArgs("mock-key-id", "Billing export", "usk_mockpref", "mock-key-hash", 17, pq.Array(input.Scopes), 100, mockTime, 1).
+							WillReturnError(&pq.Error{Code: "23505"})
+					},
+					wantErr:   true,
+					wantErrIs: ErrDuplicateData,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("INSERT INTO api_keys (.+)").
+							WithArgs("mock-key-id", "Billing export", "usk_mockpref", "mock-key-hash", 17, pq.Array(input.Scopes), 100, mockTime, 1).
+							WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(mockTime))
+					},
+					want: APIKey{
+						ID:        "mock-key-id",
+						Name:      "Billing export",
+						Prefix:    "usk_mockpref",
+						KeyHash:   "mock-key-hash",
+						UserID:    17,
+						Scopes:    []string{"profile:read"},
+						RateLimit: &rateLimit,
+						ExpiresAt: &mockTime,
+						CreatedBy: &admin,
+						CreatedAt: mockTime,
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					got, err := r.CreateAPIKey(context.Background(), input)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(got, ShouldResemble, tc.want)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestGetAPIKeyByHash(t *testing.T) {
+	t.Run("TestGetAPIKeyByHash", func(t *testing.T) {
+		Convey("TestGetAPIKeyByHash", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			apiKeyColumns := []string{"id", "name", "prefix", "key_hash", "user_id", "scopes", "rate_limit", "expires_at", "last_used_at", "created_by", "created_at", "revoked_at"}
+			rateLimit := 100
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				want      APIKey
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - unknown key",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM api_keys (.+)").
+							WithArgs("mock-key-hash").
+							WillReturnRows(sqlmock.NewRows(apiKeyColumns))
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM api_keys (.+)").
+							WithArgs("mock-key-hash").
+							WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+								AddRow("mock-key-id", "Billing export", "usk_mockpref", "mock-key-hash", 17, "{profile:read,sessions:manage}", 100, nil, mockTime, nil, mockTime, nil))
+					},
+					want: APIKey{
+						ID:         "mock-key-id",
+						Name:       "Billing export",
+						Prefix:     "usk_mockpref",
+						KeyHash:    "mock-key-hash",
+						UserID:     17,
+						Scopes:     []string{"profile:read", "sessions:manage"},
+						RateLimit:  &rateLimit,
+						LastUsedAt: &mockTime,
+						CreatedAt:  mockTime,
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					got, err := r.GetAPIKeyByHash(context.Background(), "mock-key-hash")
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(got, ShouldResemble, tc.want)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestListAPIKeys(t *testing.T) {
+	t.Run("TestListAPIKeys", func(t *testing.T) {
+		Convey("TestListAPIKeys", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			apiKeyColumns := []string{"id", "name", "prefix", "key_hash", "user_id", "scopes", "rate_limit", "expires_at", "last_used_at", "created_by", "created_at", "revoked_at"}
+			userID := int64(17)
+			admin := int64(1)
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				userID   *int64
+				mockFunc func(mockSQL sqlmock.Sqlmock)
+				want     []APIKey
+				wantErr  bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error query",
+					userID:   &userID,
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM api_keys (.+)").
+							WithArgs(17).
+							WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Success - keys of every user",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM api_keys (.+)").
+							WithArgs(nil).
+							WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+								AddRow("mock-key-id", "Billing export", "usk_mockpref", "mock-key-hash", 17, "{profile:read}", nil, mockTime, nil, 1, mockTime, mockTime))
+					},
+					want: []APIKey{{
+						ID:        "mock-key-id",
+						Name:      "Billing export",
+						Prefix:    "usk_mockpref",
+						KeyHash:   "mock-key-hash",
+						UserID:    17,
+						Scopes:    []string{"profile:read"},
+						ExpiresAt: &mockTime,
+						CreatedBy: &admin,
+						CreatedAt: mockTime,
+						RevokedAt: &mockTime,
+					}},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					got, err := r.ListAPIKeys(context.Background(), tc.userID)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					So(got, ShouldResemble, tc.want)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	t.Run("TestRevokeAPIKey", func(t *testing.T) {
+		Convey("TestRevokeAPIKey", t, func(c C) {
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - unknown or revoked key",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectExec("UPDATE api_keys (.+)").
+							WithArgs("mock-key-id").
+							WillReturnResult(sqlmock.NewResult(0, 0))
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectExec("UPDATE api_keys (.+)").
+							WithArgs("mock-key-id").
+							WillReturnResult(sqlmock.NewResult(0, 1))
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					err := r.RevokeAPIKey(context.Background(), "mock-key-id")
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	t.Run("TestCreateIdempotencyKey", func(t *testing.T) {
+		Convey("TestCreateIdempotencyKey", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			input := IdempotencyKey{Scope: "ip:127.0.0.1", Key: "mock-key", Fingerprint: "mock-fingerprint", ExpiresAt: mockTime}
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error exec",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectExec("INSERT INTO idempotency_keys (.+)").
+							WithArgs("ip:127.0.0.1", "mock-key", "mock-fingerprint", mockTime).
+							WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - key already used",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectExec("INSERT INTO idempotency_keys (.+)").
+							WithArgs("ip:127.0.0.1", "mock-key", "mock-fingerprint", mockTime).
+							WillReturnResult(sqlmock.NewResult(0, 0))
+					},
+					wantErr:   true,
+					wantErrIs: ErrDuplicateData,
+				},
+				{
+					testID:   3,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectExec("INSERT INTO idempotency_keys (.+)").
+							WithArgs("ip:127.0.0.1", "mock-key", "mock-fingerprint", mockTime).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					err := r.CreateIdempotencyKey(context.Background(), input)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestGetIdempotencyKey(t *testing.T) {
+	t.Run("TestGetIdempotencyKey", func(t *testing.T) {
+		Convey("TestGetIdempotencyKey", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			columns := []string{"scope", "key", "fingerprint", "status_code", "content_type", "headers", "body", "created_at", "expires_at"}
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				wantResp  IdempotencyKey
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - not found or expired",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM idempotency_keys (.+)").
+							WithArgs("ip:127.0.0.1", "mock-key").
+							WillReturnRows(sqlmock.NewRows(columns))
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM idempotency_keys (.+)").
+							WithArgs("ip:127.0.0.1", "mock-key").
+							WillReturnRows(sqlmock.NewRows(columns).
+								AddRow("ip:127.0.0.1", "mock-key", "mock-fingerprint", 200, "application/json", nil, []byte(`{"id":1}`), mockTime, mockTime.Add(time.Hour)))
+					},
+					wantResp: IdempotencyKey{
+						Scope:       "ip:127.0.0.1",
+						Key:         "mock-key",
+						Fingerprint: "mock-fingerprint",
+						StatusCode:  200,
+						ContentType: "application/json",
+						Body:        []byte(`{"id":1}`),
+						CreatedAt:   mockTime,
+						ExpiresAt:   mockTime.Add(time.Hour),
+					},
+					wantErr: false,
+				},
+				{
+					testID:   3,
+					testDesc: "Success - with headers",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectQuery("SELECT (.+) FROM idempotency_keys (.+)").
+							WithArgs("ip:127.0.0.1", "mock-key").
+							WillReturnRows(sqlmock.NewRows(columns).
+								AddRow("ip:127.0.0.1", "mock-key", "mock-fingerprint", 201, "application/json", []byte(`{"Location":"/v2/users/1"}`), []byte(`{"id":1}`), mockTime, mockTime.Add(time.Hour)))
+					},
+					wantResp: IdempotencyKey{
+						Scope:       "ip:127.0.0.1",
+						Key:         "mock-key",
+						Fingerprint: "mock-fingerprint",
+						StatusCode:  201,
+						ContentType: "application/json",
+						Headers:     map[string]string{"Location": "/v2/users/1"},
+						Body:        []byte(`{"id":1}`),
+						CreatedAt:   mockTime,
+						ExpiresAt:   mockTime.Add(time.Hour),
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					output, err := r.GetIdempotencyKey(context.Background(), "ip:127.0.0.1", "mock-key")
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+					So(output, ShouldResemble, tc.wantResp)
+				})
+			}
+		})
+	})
+}
+
+func TestCompleteIdempotencyKey(t *testing.T) {
+	t.Run("TestCompleteIdempotencyKey", func(t *testing.T) {
+		Convey("TestCompleteIdempotencyKey", t, func(c C) {
+			input := IdempotencyKey{Scope: "ip:127.0.0.1", Key: "mock-key", StatusCode: 200, ContentType: "application/json", Headers: map[string]string{"ETag": `"2"`}, Body: []byte(`{"id":1}`)}
+
+			testCases := []struct {
+				testID    int
+				testDesc  string
+				mockFunc  func(mockSQL sqlmock.Sqlmock)
+				wantErr   bool
+				wantErrIs error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - key not in flight",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectExec("UPDATE idempotency_keys (.+)").
+							WithArgs("ip:127.0.0.1", "mock-key", 200, "application/json", []byte(`{"ETag":"\"2\""}`), []byte(`{"id":1}`)).
+							WillReturnResult(sqlmock.NewResult(0, 0))
+					},
+					wantErr:   true,
+					wantErrIs: ErrNotFound,
+				},
+				{
+					testID:   2,
+					testDesc: "Success",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectExec("UPDATE idempotency_keys (.+)").
+							WithArgs("ip:127.0.0.1", "mock-key", 200, "application/json", []byte(`{"ETag":"\"2\""}`), []byte(`{"id":1}`)).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+					},
+					wantErr: false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					mockDB, mockSQL, _ := sqlmock.New()
+					defer mockDB.Close()
+
+					r := Repository{
+						Db: mockDB,
+					}
+					tc.mockFunc(mockSQL)
+
+					err := r.CompleteIdempotencyKey(context.Background(), input)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErrIs != nil {
+						So(errors.Is(err, tc.wantErrIs), ShouldBeTrue)
+					}
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestUpdateRateLimitBucket(t *testing.T) {
+	t.Run("TestUpdateRateLimitBucket", func(t *testing.T) {
+		Convey("TestUpdateRateLimitBucket", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			columns := []string{"key", "tokens", "updated_at", "expires_at"}
+			// update take one token, a new bucket starts with 5.
+			update := func(bucket RateLimitBucket, found bool) RateLimitBucket {
+				if !found {
+					bucket.Tokens = 5
+				}
+				bucket.Tokens--
+				bucket.UpdatedAt = mockTime
+				bucket.ExpiresAt = mockTime.Add(time.Minute)
+				return bucket
+			}
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				mockFunc func(mockSQL sqlmock.Sqlmock)
+				wantErr  bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error begin",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin().WillReturnError(fmt.Errorf("error"))
+					},
+					wantErr: true,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - error get bucket",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FROM rate_limit_buckets (.+) FOR UPDATE").
+							WithArgs("login:phone:+6281234567").
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr: true,
+				},
+				{
+					testID:   3,
+					testDesc: "Success - new bucket is inserted",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FROM rate_limit_buckets (.+) FOR UPDATE").
+							WithArgs("login:phone:+6281234567").
+							WillReturnRows(sqlmock.NewRows(columns))
+						mockSQL.ExpectExec("INSERT INTO rate_limit_buckets (.+)").
+							WithArgs("login:phone:+6281234567", float64(4), mockTime, mockTime.Add(time.Minute)).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectCommit()
+					},
+					wantErr: false,
+				},
+				{
+					testID:   4,
+					testDesc: "Success - bucket created concurrently is updated",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FROM rate_limit_buckets (.+) FOR UPDATE").
+							WithArgs("login:phone:+6281234567").
+							WillReturnRows(sqlmock.NewRows(columns))
+						mockSQL.ExpectExec("INSERT INTO rate_limit_buckets (.+)").
+							WithArgs("login:phone:+6281234567", float64(4), mockTime, mockTime.Add(time.Minute)).
+							WillReturnResult(sqlmock.NewResult(0, 0))
+						mockSQL.ExpectQuery("SELECT (.+) FROM rate_limit_buckets (.+) FOR UPDATE").
+							WithArgs("login:phone:+6281234567").
+							WillReturnRows(sqlmock.NewRows(columns).AddRow("login:phone:+6281234567", float64(4), mockTime, mockTime.Add(time.Minute)))
+						mockSQL.ExpectExec("UPDATE rate_limit_buckets (.+)").
+							WithArgs("login:phone:+6281234567", float64(3), mockTime, mockTime.Add(time.Minute)).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mockSQL.ExpectCommit()
+					},
+					wantErr: false,
+				},
+				{
+					testID:   5,
+					testDesc: "Failed - error update bucket",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FROM rate_limit_buckets (.+) FOR UPDATE").
+							WithArgs("login:phone:+6281234567").
+							WillReturnRows(sqlmock.NewRows(columns).AddRow("login:phone:+6281234567", float64(2), mockTime, mockTime.Add(time.Minute)))
+						mockSQL.ExpectExec("UPDATE rate_limit_buckets (.+)").
+							WithArgs("login:phone:+6281234567", float64(1), mockTime, mockTime.Add(time.Minute)).
+							WillReturnError(fmt.Errorf("error"))
+						mockSQL.ExpectRollback()
+					},
+					wantErr: true,
+				},
+				{
+					testID:   6,
+					testDesc: "Success - existing bucket is updated",
+					mockFunc: func(mockSQL sqlmock.Sqlmock) {
+						mockSQL.ExpectBegin()
+						mockSQL.ExpectQuery("SELECT (.+) FROM rate_limit_buckets (.+) FOR UPDATE").
+							WithArgs("login:phone:+6281234567").
+							WillReturnRows(sqlmock.NewRows(columns).AddRow("login:phone:+6281234567", float64(2), mockTime, mockTime.Add(time.Minute)))
+						mockSQL.ExpectExec("UPDATE rate_limit_buckets (.+)").
+							WithArgs("login:phone:+6281234567", float64(1), mockTime, mockTime.Add(time.Minute)).
+							WillReturnResult(sqlmock.NewResult(0, 1))
 						mockSQL.ExpectCommit()
 					},
 					wantErr: false,
@@ -541,9 +3068,10 @@ func TestIncreaseLoginCount(t *testing.T) {
 					}
 					tc.mockFunc(mockSQL)
 
-					err := r.IncreaseLoginCount(context.Background(), tc.args.id)
+					err := r.UpdateRateLimitBucket(context.Background(), "login:phone:+6281234567", update)
 					// assert
 					So(err != nil, ShouldEqual, tc.wantErr)
+					So(mockSQL.ExpectationsWereMet(), ShouldBeNil)
 				})
 			}
 		})
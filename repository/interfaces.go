@@ -4,13 +4,65 @@
 // interfaces using mockgen. See the Makefile for more information.
 package repository
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type RepositoryInterface interface {
 	// User
 	Createuser(ctx context.Context, input RegisterUser) (output User, err error)
 	GetUserByID(ctx context.Context, id int64) (output User, err error)
 	GetUserByPhone(ctx context.Context, phone string) (output User, err error)
-	UpdateUser(ctx context.Context, input User) (output User, err error)
-	IncreaseLoginCount(ctx context.Context, id int64) (err error)
+	GetUserByEmail(ctx context.Context, email string) (output User, err error)
+	UpdateUser(ctx context.Context, input UpdateUser) (output User, err error)
+
+	// External identities
+	CreateIdentityUser(ctx context.Context, input RegisterIdentityUser) (output User, err error)
+	GetUserIdentity(ctx context.Context, provider string, subject string) (output UserIdentity, err error)
+	ListUserIdentities(ctx context.Context, userID int64) (output []UserIdentity, err error)
+	CreateUserIdentity(ctx context.Context, input UserIdentity) (output UserIdentity, err error)
+	DeleteUserIdentity(ctx context.Context, userID int64, provider string) (err error)
+
+	// Logins
+	RecordLogin(ctx context.Context, input UserLogin) (err error)
+	ListUserLogins(ctx context.Context, userID int64, before *int64, limit int) (output []UserLogin, err error)
+	DeleteUserLoginsBefore(ctx context.Context, before time.Time) (deleted int64, err error)
+
+	// Sessions
+	ListActiveUserSessions(ctx context.Context, userID int64) (output []UserSession, err error)
+	TouchUserSession(ctx context.Context, sessionID string, userID int64) (err error)
+	RevokeUserSession(ctx context.Context, sessionID string, userID int64) (err error)
+
+	// User events
+	CreateUserEvent(ctx context.Context, input UserEvent) (err error)
+	ListUserEvents(ctx context.Context, userID int64, limit int) (output []UserEvent, err error)
+
+	// Email verifications
+	CreateEmailVerification(ctx context.Context, input EmailVerification) (err error)
+	ConfirmEmailVerification(ctx context.Context, tokenHash string) (output User, err error)
+
+	// OAuth clients and authorization codes
+	CreateOAuthClient(ctx context.Context, input OAuthClient) (err error)
+	GetOAuthClient(ctx context.Context, id string) (output OAuthClient, err error)
+	CreateAuthorizationCode(ctx context.Context, input AuthorizationCode) (err error)
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (output AuthorizationCode, err error)
+
+	// API keys
+	CreateAPIKey(ctx context.Context, input APIKey) (output APIKey, err error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (output APIKey, err error)
+	ListAPIKeys(ctx context.Context, userID *int64) (output []APIKey, err error)
+	TouchAPIKey(ctx context.Context, id string) (err error)
+	RevokeAPIKey(ctx context.Context, id string) (err error)
+
+	// Idempotency keys
+	CreateIdempotencyKey(ctx context.Context, input IdempotencyKey) (err error)
+	GetIdempotencyKey(ctx context.Context, scope string, key string) (output IdempotencyKey, err error)
+	CompleteIdempotencyKey(ctx context.Context, input IdempotencyKey) (err error)
+	DeleteIdempotencyKey(ctx context.Context, scope string, key string) (err error)
+	DeleteIdempotencyKeysBefore(ctx context.Context, before time.Time) (deleted int64, err error)
+
+	// Rate limits
+	UpdateRateLimitBucket(ctx context.Context, key string, update func(bucket RateLimitBucket, found bool) RateLimitBucket) (err error)
+	DeleteRateLimitBucketsBefore(ctx context.Context, before time.Time) (deleted int64, err error)
 }
@@ -7,6 +7,7 @@ package repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,6 +35,165 @@ func (m *MockRepositoryInterface) EXPECT() *MockRepositoryInterfaceMockRecorder
 	return m.recorder
 }
 
+// CompleteIdempotencyKey mocks base method.
+func (m *MockRepositoryInterface) CompleteIdempotencyKey(ctx context.Context, input IdempotencyKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteIdempotencyKey", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteIdempotencyKey indicates an expected call of CompleteIdempotencyKey.
+func (mr *MockRepositoryInterfaceMockRecorder) CompleteIdempotencyKey(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteIdempotencyKey", reflect.TypeOf((*MockRepositoryInterface)(nil).CompleteIdempotencyKey), ctx, input)
+}
+
+// ConfirmEmailVerification mocks base method.
+func (m *MockRepositoryInterface) ConfirmEmailVerification(ctx context.Context, tokenHash string) (User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmEmailVerification", ctx, tokenHash)
+	ret0, _ := ret[0].(User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmEmailVerification indicates an expected call of ConfirmEmailVerification.
+func (mr *MockRepositoryInterfaceMockRecorder) ConfirmEmailVerification(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmEmailVerification", reflect.TypeOf((*MockRepositoryInterface)(nil).ConfirmEmailVerification), ctx, tokenHash)
+}
+
+// ConsumeAuthorizationCode mocks base method.
+func (m *MockRepositoryInterface) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (AuthorizationCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeAuthorizationCode", ctx, codeHash)
+	ret0, _ := ret[0].(AuthorizationCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeAuthorizationCode indicates an expected call of ConsumeAuthorizationCode.
+func (mr *MockRepositoryInterfaceMockRecorder) ConsumeAuthorizationCode(ctx, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeAuthorizationCode", reflect.TypeOf((*MockRepositoryInterface)(nil).ConsumeAuthorizationCode), ctx, codeHash)
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockRepositoryInterface) CreateAPIKey(ctx context.Context, input APIKey) (APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, input)
+	ret0, _ := ret[0].(APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateAPIKey(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateAPIKey), ctx, input)
+}
+
+// CreateAuthorizationCode mocks base method.
+func (m *MockRepositoryInterface) CreateAuthorizationCode(ctx context.Context, input AuthorizationCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuthorizationCode", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuthorizationCode indicates an expected call of CreateAuthorizationCode.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateAuthorizationCode(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthorizationCode", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateAuthorizationCode), ctx, input)
+}
+
+// CreateEmailVerification mocks base method.
+func (m *MockRepositoryInterface) CreateEmailVerification(ctx context.Context, input EmailVerification) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEmailVerification", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateEmailVerification indicates an expected call of CreateEmailVerification.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateEmailVerification(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEmailVerification", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateEmailVerification), ctx, input)
+}
+
+// CreateIdempotencyKey mocks base method.
+func (m *MockRepositoryInterface) CreateIdempotencyKey(ctx context.Context, input IdempotencyKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIdempotencyKey", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateIdempotencyKey indicates an expected call of CreateIdempotencyKey.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateIdempotencyKey(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIdempotencyKey", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateIdempotencyKey), ctx, input)
+}
+
+// CreateIdentityUser mocks base method.
+func (m *MockRepositoryInterface) CreateIdentityUser(ctx context.Context, input RegisterIdentityUser) (User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIdentityUser", ctx, input)
+	ret0, _ := ret[0].(User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateIdentityUser indicates an expected call of CreateIdentityUser.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateIdentityUser(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIdentityUser", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateIdentityUser), ctx, input)
+}
+
+// CreateOAuthClient mocks base method.
+func (m *MockRepositoryInterface) CreateOAuthClient(ctx context.Context, input OAuthClient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOAuthClient", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOAuthClient indicates an expected call of CreateOAuthClient.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateOAuthClient(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOAuthClient", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateOAuthClient), ctx, input)
+}
+
+// CreateUserEvent mocks base method.
+func (m *MockRepositoryInterface) CreateUserEvent(ctx context.Context, input UserEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUserEvent", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateUserEvent indicates an expected call of CreateUserEvent.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateUserEvent(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserEvent", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateUserEvent), ctx, input)
+}
+
+// CreateUserIdentity mocks base method.
+func (m *MockRepositoryInterface) CreateUserIdentity(ctx context.Context, input UserIdentity) (UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUserIdentity", ctx, input)
+	ret0, _ := ret[0].(UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUserIdentity indicates an expected call of CreateUserIdentity.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateUserIdentity(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserIdentity", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateUserIdentity), ctx, input)
+}
+
 // Createuser mocks base method.
 func (m *MockRepositoryInterface) Createuser(ctx context.Context, input RegisterUser) (User, error) {
 	m.ctrl.T.Helper()
@@ -49,6 +209,139 @@ func (mr *MockRepositoryInterfaceMockRecorder) Createuser(ctx, input interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Createuser", reflect.TypeOf((*MockRepositoryInterface)(nil).Createuser), ctx, input)
 }
 
+// DeleteIdempotencyKey mocks base method.
+func (m *MockRepositoryInterface) DeleteIdempotencyKey(ctx context.Context, scope, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIdempotencyKey", ctx, scope, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteIdempotencyKey indicates an expected call of DeleteIdempotencyKey.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteIdempotencyKey(ctx, scope, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdempotencyKey", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteIdempotencyKey), ctx, scope, key)
+}
+
+// DeleteIdempotencyKeysBefore mocks base method.
+func (m *MockRepositoryInterface) DeleteIdempotencyKeysBefore(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIdempotencyKeysBefore", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteIdempotencyKeysBefore indicates an expected call of DeleteIdempotencyKeysBefore.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteIdempotencyKeysBefore(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdempotencyKeysBefore", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteIdempotencyKeysBefore), ctx, before)
+}
+
+// DeleteRateLimitBucketsBefore mocks base method.
+func (m *MockRepositoryInterface) DeleteRateLimitBucketsBefore(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRateLimitBucketsBefore", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteRateLimitBucketsBefore indicates an expected call of DeleteRateLimitBucketsBefore.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteRateLimitBucketsBefore(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRateLimitBucketsBefore", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteRateLimitBucketsBefore), ctx, before)
+}
+
+// DeleteUserIdentity mocks base method.
+func (m *MockRepositoryInterface) DeleteUserIdentity(ctx context.Context, userID int64, provider string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserIdentity", ctx, userID, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserIdentity indicates an expected call of DeleteUserIdentity.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteUserIdentity(ctx, userID, provider interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserIdentity", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteUserIdentity), ctx, userID, provider)
+}
+
+// DeleteUserLoginsBefore mocks base method.
+func (m *MockRepositoryInterface) DeleteUserLoginsBefore(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserLoginsBefore", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteUserLoginsBefore indicates an expected call of DeleteUserLoginsBefore.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteUserLoginsBefore(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserLoginsBefore", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteUserLoginsBefore), ctx, before)
+}
+
+// GetAPIKeyByHash mocks base method.
+func (m *MockRepositoryInterface) GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeyByHash", ctx, keyHash)
+	ret0, _ := ret[0].(APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeyByHash indicates an expected call of GetAPIKeyByHash.
+func (mr *MockRepositoryInterfaceMockRecorder) GetAPIKeyByHash(ctx, keyHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeyByHash", reflect.TypeOf((*MockRepositoryInterface)(nil).GetAPIKeyByHash), ctx, keyHash)
+}
+
+// GetIdempotencyKey mocks base method.
+func (m *MockRepositoryInterface) GetIdempotencyKey(ctx context.Context, scope, key string) (IdempotencyKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdempotencyKey", ctx, scope, key)
+	ret0, _ := ret[0].(IdempotencyKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdempotencyKey indicates an expected call of GetIdempotencyKey.
+func (mr *MockRepositoryInterfaceMockRecorder) GetIdempotencyKey(ctx, scope, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdempotencyKey", reflect.TypeOf((*MockRepositoryInterface)(nil).GetIdempotencyKey), ctx, scope, key)
+}
+
+// GetOAuthClient mocks base method.
+func (m *MockRepositoryInterface) GetOAuthClient(ctx context.Context, id string) (OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthClient", ctx, id)
+	ret0, _ := ret[0].(OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthClient indicates an expected call of GetOAuthClient.
+func (mr *MockRepositoryInterfaceMockRecorder) GetOAuthClient(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthClient", reflect.TypeOf((*MockRepositoryInterface)(nil).GetOAuthClient), ctx, id)
+}
+
+// GetUserByEmail mocks base method.
+func (m *MockRepositoryInterface) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmail", ctx, email)
+	ret0, _ := ret[0].(User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmail indicates an expected call of GetUserByEmail.
+func (mr *MockRepositoryInterfaceMockRecorder) GetUserByEmail(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUserByEmail), ctx, email)
+}
+
 // GetUserByID mocks base method.
 func (m *MockRepositoryInterface) GetUserByID(ctx context.Context, id int64) (User, error) {
 	m.ctrl.T.Helper()
@@ -79,22 +372,182 @@ func (mr *MockRepositoryInterfaceMockRecorder) GetUserByPhone(ctx, phone interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByPhone", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUserByPhone), ctx, phone)
 }
 
-// IncreaseLoginCount mocks base method.
-func (m *MockRepositoryInterface) IncreaseLoginCount(ctx context.Context, id int64) error {
+// GetUserIdentity mocks base method.
+func (m *MockRepositoryInterface) GetUserIdentity(ctx context.Context, provider, subject string) (UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserIdentity", ctx, provider, subject)
+	ret0, _ := ret[0].(UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserIdentity indicates an expected call of GetUserIdentity.
+func (mr *MockRepositoryInterfaceMockRecorder) GetUserIdentity(ctx, provider, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserIdentity", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUserIdentity), ctx, provider, subject)
+}
+
+// ListAPIKeys mocks base method.
+func (m *MockRepositoryInterface) ListAPIKeys(ctx context.Context, userID *int64) ([]APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", ctx, userID)
+	ret0, _ := ret[0].([]APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys.
+func (mr *MockRepositoryInterfaceMockRecorder) ListAPIKeys(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockRepositoryInterface)(nil).ListAPIKeys), ctx, userID)
+}
+
+// ListActiveUserSessions mocks base method.
+func (m *MockRepositoryInterface) ListActiveUserSessions(ctx context.Context, userID int64) ([]UserSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActiveUserSessions", ctx, userID)
+	ret0, _ := ret[0].([]UserSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListActiveUserSessions indicates an expected call of ListActiveUserSessions.
+func (mr *MockRepositoryInterfaceMockRecorder) ListActiveUserSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActiveUserSessions", reflect.TypeOf((*MockRepositoryInterface)(nil).ListActiveUserSessions), ctx, userID)
+}
+
+// ListUserEvents mocks base method.
+func (m *MockRepositoryInterface) ListUserEvents(ctx context.Context, userID int64, limit int) ([]UserEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUserEvents", ctx, userID, limit)
+	ret0, _ := ret[0].([]UserEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUserEvents indicates an expected call of ListUserEvents.
+func (mr *MockRepositoryInterfaceMockRecorder) ListUserEvents(ctx, userID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUserEvents", reflect.TypeOf((*MockRepositoryInterface)(nil).ListUserEvents), ctx, userID, limit)
+}
+
+// ListUserIdentities mocks base method.
+func (m *MockRepositoryInterface) ListUserIdentities(ctx context.Context, userID int64) ([]UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUserIdentities", ctx, userID)
+	ret0, _ := ret[0].([]UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUserIdentities indicates an expected call of ListUserIdentities.
+func (mr *MockRepositoryInterfaceMockRecorder) ListUserIdentities(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUserIdentities", reflect.TypeOf((*MockRepositoryInterface)(nil).ListUserIdentities), ctx, userID)
+}
+
+// ListUserLogins mocks base method.
+func (m *MockRepositoryInterface) ListUserLogins(ctx context.Context, userID int64, before *int64, limit int) ([]UserLogin, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUserLogins", ctx, userID, before, limit)
+	ret0, _ := ret[0].([]UserLogin)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUserLogins indicates an expected call of ListUserLogins.
+func (mr *MockRepositoryInterfaceMockRecorder) ListUserLogins(ctx, userID, before, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUserLogins", reflect.TypeOf((*MockRepositoryInterface)(nil).ListUserLogins), ctx, userID, before, limit)
+}
+
+// RecordLogin mocks base method.
+func (m *MockRepositoryInterface) RecordLogin(ctx context.Context, input UserLogin) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordLogin", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordLogin indicates an expected call of RecordLogin.
+func (mr *MockRepositoryInterfaceMockRecorder) RecordLogin(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLogin", reflect.TypeOf((*MockRepositoryInterface)(nil).RecordLogin), ctx, input)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockRepositoryInterface) RevokeAPIKey(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeAPIKey(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeAPIKey), ctx, id)
+}
+
+// RevokeUserSession mocks base method.
+func (m *MockRepositoryInterface) RevokeUserSession(ctx context.Context, sessionID string, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserSession", ctx, sessionID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserSession indicates an expected call of RevokeUserSession.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeUserSession(ctx, sessionID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserSession", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeUserSession), ctx, sessionID, userID)
+}
+
+// TouchAPIKey mocks base method.
+func (m *MockRepositoryInterface) TouchAPIKey(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchAPIKey", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchAPIKey indicates an expected call of TouchAPIKey.
+func (mr *MockRepositoryInterfaceMockRecorder) TouchAPIKey(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchAPIKey", reflect.TypeOf((*MockRepositoryInterface)(nil).TouchAPIKey), ctx, id)
+}
+
+// TouchUserSession mocks base method.
+func (m *MockRepositoryInterface) TouchUserSession(ctx context.Context, sessionID string, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchUserSession", ctx, sessionID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchUserSession indicates an expected call of TouchUserSession.
+func (mr *MockRepositoryInterfaceMockRecorder) TouchUserSession(ctx, sessionID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchUserSession", reflect.TypeOf((*MockRepositoryInterface)(nil).TouchUserSession), ctx, sessionID, userID)
+}
+
+// UpdateRateLimitBucket mocks base method.
+func (m *MockRepositoryInterface) UpdateRateLimitBucket(ctx context.Context, key string, update func(RateLimitBucket, bool) RateLimitBucket) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "IncreaseLoginCount", ctx, id)
+	ret := m.ctrl.Call(m, "UpdateRateLimitBucket", ctx, key, update)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// IncreaseLoginCount indicates an expected call of IncreaseLoginCount.
-func (mr *MockRepositoryInterfaceMockRecorder) IncreaseLoginCount(ctx, id interface{}) *gomock.Call {
+// UpdateRateLimitBucket indicates an expected call of UpdateRateLimitBucket.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateRateLimitBucket(ctx, key, update interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncreaseLoginCount", reflect.TypeOf((*MockRepositoryInterface)(nil).IncreaseLoginCount), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRateLimitBucket", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateRateLimitBucket), ctx, key, update)
 }
 
 // UpdateUser mocks base method.
-func (m *MockRepositoryInterface) UpdateUser(ctx context.Context, input User) (User, error) {
+func (m *MockRepositoryInterface) UpdateUser(ctx context.Context, input UpdateUser) (User, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateUser", ctx, input)
 	ret0, _ := ret[0].(User)
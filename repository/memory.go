@@ -0,0 +1,854 @@
+// This file contains an in-process implementation of the repository layer.
+// It keeps data in memory with the same semantics as the Postgres
+// repository, for end-to-end tests and local runs without a database.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MemoryRepository struct {
+	mu sync.Mutex
+	// now is the clock, time.Now outside of tests.
+	now func() time.Time
+
+	users           map[int64]User
+	logins          []UserLogin
+	sessions        map[string]UserSession
+	events          []UserEvent
+	identities      map[[2]string]UserIdentity
+	verifications   map[string]EmailVerification
+	oauthClients    map[string]OAuthClient
+	codes           map[string]AuthorizationCode
+	apiKeys         map[string]APIKey
+	idempotencyKeys map[[2]string]IdempotencyKey
+	buckets         map[string]RateLimitBucket
+
+	lastUserID  int64
+	lastLoginID int64
+	lastEventID int64
+}
+
+type NewMemoryRepositoryOptions struct {
+	// Now is the clock, time.Now when nil.
+	Now func() time.Time
+}
+
+func NewMemoryRepository(opts NewMemoryRepositoryOptions) *MemoryRepository {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &MemoryRepository{
+		now:             opts.Now,
+		users:           map[int64]User{},
+		sessions:        map[string]UserSession{},
+		identities:      map[[2]string]UserIdentity{},
+		verifications:   map[string]EmailVerification{},
+		oauthClients:    map[string]OAuthClient{},
+		codes:           map[string]AuthorizationCode{},
+		apiKeys:         map[string]APIKey{},
+		idempotencyKeys: map[[2]string]IdempotencyKey{},
+		buckets:         map[string]RateLimitBucket{},
+	}
+}
+
+func (r *MemoryRepository) Createuser(ctx context.Context, input RegisterUser) (output User, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.userByPhone(input.Phone); ok {
+		return User{}, fmt.Errorf("insert user: %w", ErrDuplicateData)
+	}
+
+	r.lastUserID++
+	output = User{
+		ID:        r.lastUserID,
+		Phone:     input.Phone,
+		Name:      input.Name,
+		Password:  input.Password,
+		CreatedAt: r.now(),
+		Version:   1,
+	}
+	r.users[output.ID] = output
+	r.insertUserEvent(ctx, UserEvent{
+		UserID: &output.ID,
+		Type:   UserEventRegistered,
+	})
+
+	return User{ID: output.ID}, nil
+}
+
+func (r *MemoryRepository) GetUserByID(ctx context.Context, id int64) (output User, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	output, ok := r.users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return output, nil
+}
+
+func (r *MemoryRepository) GetUserByPhone(ctx context.Context, phone string) (output User, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	output, ok := r.userByPhone(phone)
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return output, nil
+}
+
+func (r *MemoryRepository) GetUserByEmail(ctx context.Context, email string) (output User, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	output, ok := r.userByEmail(email)
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return output, nil
+}
+
+func (r *MemoryRepository) UpdateUser(ctx context.Context, input UpdateUser) (output User, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before, ok := r.users[input.ID]
+	if !ok {
+		return User{}, fmt.Errorf("get user for update: %w", ErrNotFound)
+	}
+	if input.Version != nil && *input.Version != before.Version {
+		return User{}, fmt.Errorf("update user: %w", ErrVersionConflict)
+	}
+
+	output = before
+	if input.Phone != nil {
+		if other, ok := r.userByPhone(*input.Phone); ok && other.ID != input.ID {
+			return User{}, fmt.Errorf("update user: %w", ErrDuplicateData)
+		}
+		output.Phone = *input.Phone
+	}
+	if input.Name != nil {
+		output.Name = *input.Name
+	}
+	if input.Email != nil {
+		if valueOf(output.Email) != *input.Email {
+			output.EmailVerifiedAt = nil
+		}
+		output.Email = nullIfEmpty(*input.Email)
+	}
+	if input.DisplayName != nil {
+		output.DisplayName = nullIfEmpty(*input.DisplayName)
+	}
+	if input.BirthDate != nil {
+		output.BirthDate = nil
+		if *input.BirthDate != "" {
+			birthDate, err := time.Parse(time.DateOnly, *input.BirthDate)
+			if err != nil {
+				return User{}, fmt.Errorf("update user: %w", err)
+			}
+			output.BirthDate = &birthDate
+		}
+	}
+	if input.Locale != nil {
+		output.Locale = nullIfEmpty(*input.Locale)
+	}
+	if input.Timezone != nil {
+		output.Timezone = nullIfEmpty(*input.Timezone)
+	}
+	if input.AvatarURL != nil {
+		output.AvatarURL = nullIfEmpty(*input.AvatarURL)
+		output.AvatarThumbnails = nil
+		if len(input.AvatarThumbnails) > 0 {
+			output.AvatarThumbnails = append([]AvatarThumbnail(nil), input.AvatarThumbnails...)
+		}
+	}
+	now := r.now()
+	output.UpdateAt = &now
+	output.Version++
+	r.users[output.ID] = output
+
+	if changes := diffUser(before, output); len(changes) > 0 {
+		r.insertUserEvent(ctx, UserEvent{
+			UserID:  &output.ID,
+			Type:    UserEventProfileUpdated,
+			Details: UserEventDetails{Changes: changes},
+		})
+	}
+
+	return output, nil
+}
+
+// CreateIdentityUser create a user signing up with an external identity and
+// link the identity to it. Return ErrDuplicateEmail when the email is used by
+// another user and ErrDuplicateData when the identity is already linked.
+func (r *MemoryRepository) CreateIdentityUser(ctx context.Context, input RegisterIdentityUser) (output User, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if input.Email != nil {
+		if _, ok := r.userByEmail(*input.Email); ok {
+			return User{}, fmt.Errorf("insert identity user: %w", ErrDuplicateEmail)
+		}
+	}
+	key := [2]string{input.Identity.Provider, input.Identity.Subject}
+	if _, ok := r.identities[key]; ok {
+		return User{}, fmt.Errorf("insert user identity: %w", ErrDuplicateData)
+	}
+
+	r.lastUserID++
+	now := r.now()
+	output = User{
+		ID:        r.lastUserID,
+		Name:      input.Name,
+		CreatedAt: now,
+		Version:   1,
+	}
+	if input.Email != nil {
+		email := *input.Email
+		output.Email = &email
+		output.EmailVerifiedAt = &now
+	}
+	r.users[output.ID] = output
+	input.Identity.UserID = output.ID
+	input.Identity.CreatedAt = now
+	r.identities[key] = input.Identity
+	r.insertUserEvent(ctx, UserEvent{
+		UserID:  &output.ID,
+		Type:    UserEventRegistered,
+		Details: UserEventDetails{Provider: input.Identity.Provider},
+	})
+
+	return User{ID: output.ID}, nil
+}
+
+// GetUserIdentity return the identity of the provider subject, ErrNotFound
+// when it is not linked to a user.
+func (r *MemoryRepository) GetUserIdentity(ctx context.Context, provider string, subject string) (output UserIdentity, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	output, ok := r.identities[[2]string{provider, subject}]
+	if !ok {
+		return UserIdentity{}, fmt.Errorf("get user identity: %w", ErrNotFound)
+	}
+	return output, nil
+}
+
+func (r *MemoryRepository) ListUserIdentities(ctx context.Context, userID int64) (output []UserIdentity, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			output = append(output, identity)
+		}
+	}
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].Provider < output[j].Provider
+	})
+	return output, nil
+}
+
+// CreateUserIdentity link an identity to an existing user and record it in
+// the audit log. Return ErrDuplicateData when the subject is linked already
+// or the user has an identity of the provider.
+func (r *MemoryRepository) CreateUserIdentity(ctx context.Context, input UserIdentity) (output UserIdentity, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[input.UserID]; !ok {
+		return UserIdentity{}, fmt.Errorf("insert user identity: unknown user %d", input.UserID)
+	}
+	key := [2]string{input.Provider, input.Subject}
+	if _, ok := r.identities[key]; ok {
+		return UserIdentity{}, fmt.Errorf("insert user identity: %w", ErrDuplicateData)
+	}
+	if _, ok := r.identityOf(input.UserID, input.Provider); ok {
+		return UserIdentity{}, fmt.Errorf("insert user identity: %w", ErrDuplicateData)
+	}
+	input.CreatedAt = r.now()
+	r.identities[key] = input
+	r.insertUserEvent(ctx, UserEvent{
+		UserID:  &input.UserID,
+		Type:    UserEventIdentityLinked,
+		Details: UserEventDetails{Provider: input.Provider},
+	})
+	return input, nil
+}
+
+// DeleteUserIdentity unlink the identity of the provider from the user and
+// record it in the audit log. Return ErrNotFound when none is linked.
+func (r *MemoryRepository) DeleteUserIdentity(ctx context.Context, userID int64, provider string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	identity, ok := r.identityOf(userID, provider)
+	if !ok {
+		return fmt.Errorf("delete user identity: %w", ErrNotFound)
+	}
+	delete(r.identities, [2]string{identity.Provider, identity.Subject})
+	r.insertUserEvent(ctx, UserEvent{
+		UserID:  &userID,
+		Type:    UserEventIdentityUnlinked,
+		Details: UserEventDetails{Provider: provider},
+	})
+	return nil
+}
+
+// RecordLogin bump login count and last login of the user, open the login
+// session and append the login to the history and the audit log.
+func (r *MemoryRepository) RecordLogin(ctx context.Context, input UserLogin) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	client := clientInfoFromContext(ctx)
+	if input.IP == "" {
+		input.IP = client.IP
+	}
+	if input.UserAgent == "" {
+		input.UserAgent = client.UserAgent
+	}
+	if _, ok := r.sessions[input.SessionID]; ok {
+		return fmt.Errorf("insert user session: %w", ErrDuplicateData)
+	}
+
+	now := r.now()
+	if user, ok := r.users[input.UserID]; ok {
+		user.LoginCount++
+		user.LastLoginAt = &now
+		r.users[input.UserID] = user
+	}
+
+	r.lastLoginID++
+	r.logins = append(r.logins, UserLogin{
+		ID:        r.lastLoginID,
+		UserID:    input.UserID,
+		TokenID:   input.TokenID,
+		IP:        input.IP,
+		UserAgent: input.UserAgent,
+		CreatedAt: now,
+	})
+	r.sessions[input.SessionID] = UserSession{
+		ID:         input.SessionID,
+		UserID:     input.UserID,
+		DeviceName: input.DeviceName,
+		IP:         input.IP,
+		UserAgent:  input.UserAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	r.insertUserEvent(ctx, UserEvent{
+		UserID:    &input.UserID,
+		Type:      UserEventLoginSucceeded,
+		IP:        input.IP,
+		UserAgent: input.UserAgent,
+	})
+
+	return nil
+}
+
+func (r *MemoryRepository) ListUserLogins(ctx context.Context, userID int64, before *int64, limit int) (output []UserLogin, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// newest first, ids grow with time.
+	for i := len(r.logins) - 1; i >= 0 && len(output) < limit; i-- {
+		login := r.logins[i]
+		if login.UserID != userID || (before != nil && login.ID >= *before) {
+			continue
+		}
+		output = append(output, login)
+	}
+	return output, nil
+}
+
+// DeleteUserLoginsBefore prune login history created before the given time.
+func (r *MemoryRepository) DeleteUserLoginsBefore(ctx context.Context, before time.Time) (deleted int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.logins[:0]
+	for _, login := range r.logins {
+		if login.CreatedAt.Before(before) {
+			deleted++
+			continue
+		}
+		kept = append(kept, login)
+	}
+	r.logins = kept
+	return deleted, nil
+}
+
+func (r *MemoryRepository) ListActiveUserSessions(ctx context.Context, userID int64) (output []UserSession, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			output = append(output, session)
+		}
+	}
+	sort.Slice(output, func(i, j int) bool {
+		if !output[i].LastSeenAt.Equal(output[j].LastSeenAt) {
+			return output[i].LastSeenAt.After(output[j].LastSeenAt)
+		}
+		return output[i].ID < output[j].ID
+	})
+	return output, nil
+}
+
+// TouchUserSession mark the session as seen now, unless it was seen in the
+// last minute. Return ErrNotFound when the session does not exist or has been
+// revoked.
+func (r *MemoryRepository) TouchUserSession(ctx context.Context, sessionID string, userID int64) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.activeSession(sessionID, userID)
+	if !ok {
+		return fmt.Errorf("touch user session: %w", ErrNotFound)
+	}
+	if now := r.now(); now.Sub(session.LastSeenAt) > time.Minute {
+		session.LastSeenAt = now
+		r.sessions[sessionID] = session
+	}
+	return nil
+}
+
+// RevokeUserSession revoke an active session of the user and record it in the audit log.
+// Return ErrNotFound when the session does not exist or has been revoked.
+func (r *MemoryRepository) RevokeUserSession(ctx context.Context, sessionID string, userID int64) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.activeSession(sessionID, userID)
+	if !ok {
+		return fmt.Errorf("revoke user session: %w", ErrNotFound)
+	}
+	now := r.now()
+	session.RevokedAt = &now
+	r.sessions[sessionID] = session
+
+	r.insertUserEvent(ctx, UserEvent{
+		UserID:  &userID,
+		Type:    UserEventSessionRevoked,
+		Details: UserEventDetails{SessionID: sessionID},
+	})
+	return nil
+}
+
+// CreateUserEvent record an event that is not part of another write, like a failed login.
+func (r *MemoryRepository) CreateUserEvent(ctx context.Context, input UserEvent) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.insertUserEvent(ctx, input)
+	return nil
+}
+
+func (r *MemoryRepository) ListUserEvents(ctx context.Context, userID int64, limit int) (output []UserEvent, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// newest first, ids grow with time.
+	for i := len(r.events) - 1; i >= 0 && len(output) < limit; i-- {
+		event := r.events[i]
+		if event.UserID == nil || *event.UserID != userID {
+			continue
+		}
+		output = append(output, event)
+	}
+	return output, nil
+}
+
+// CreateEmailVerification store the verification of the email of a user,
+// replacing the pending one of the user, if any.
+func (r *MemoryRepository) CreateEmailVerification(ctx context.Context, input EmailVerification) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.verifications[input.TokenHash]; ok {
+		return fmt.Errorf("insert email verification: %w", ErrDuplicateData)
+	}
+	for tokenHash, verification := range r.verifications {
+		if verification.UserID == input.UserID {
+			delete(r.verifications, tokenHash)
+		}
+	}
+	input.CreatedAt = r.now()
+	r.verifications[input.TokenHash] = input
+	return nil
+}
+
+// ConfirmEmailVerification mark the email of the verification of tokenHash
+// as verified and record it in the audit log. The verification is used up.
+// Return ErrNotFound when the token is unknown or expired, or the user
+// changed email since, and ErrDuplicateEmail when another user verified the
+// email first.
+func (r *MemoryRepository) ConfirmEmailVerification(ctx context.Context, tokenHash string) (output User, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	verification, ok := r.verifications[tokenHash]
+	delete(r.verifications, tokenHash)
+	if !ok || !verification.ExpiresAt.After(r.now()) {
+		return User{}, fmt.Errorf("confirm email verification: %w", ErrNotFound)
+	}
+	output, ok = r.users[verification.UserID]
+	if !ok || valueOf(output.Email) != verification.Email {
+		return User{}, fmt.Errorf("confirm email verification: %w", ErrNotFound)
+	}
+	if other, ok := r.userByEmail(verification.Email); ok && other.ID != output.ID {
+		return User{}, fmt.Errorf("verify user email: %w", ErrDuplicateEmail)
+	}
+
+	now := r.now()
+	output.EmailVerifiedAt = &now
+	output.UpdateAt = &now
+	output.Version++
+	r.users[output.ID] = output
+	r.insertUserEvent(ctx, UserEvent{
+		UserID: &output.ID,
+		Type:   UserEventEmailVerified,
+	})
+
+	return output, nil
+}
+
+// CreateOAuthClient register a client of the authorization code flow.
+// Return ErrDuplicateData when the id is taken.
+func (r *MemoryRepository) CreateOAuthClient(ctx context.Context, input OAuthClient) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.oauthClients[input.ID]; ok {
+		return fmt.Errorf("insert oauth client: %w", ErrDuplicateData)
+	}
+	input.RedirectURIs = append([]string(nil), input.RedirectURIs...)
+	input.CreatedAt = r.now()
+	r.oauthClients[input.ID] = input
+	return nil
+}
+
+// GetOAuthClient return the registered client, ErrNotFound otherwise.
+func (r *MemoryRepository) GetOAuthClient(ctx context.Context, id string) (output OAuthClient, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	output, ok := r.oauthClients[id]
+	if !ok {
+		return OAuthClient{}, fmt.Errorf("get oauth client: %w", ErrNotFound)
+	}
+	output.RedirectURIs = append([]string(nil), output.RedirectURIs...)
+	return output, nil
+}
+
+// CreateAuthorizationCode store a code issued to a client, expired codes
+// that were never exchanged are deleted on the way.
+func (r *MemoryRepository) CreateAuthorizationCode(ctx context.Context, input AuthorizationCode) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	for codeHash, code := range r.codes {
+		if code.ExpiresAt.Before(now) {
+			delete(r.codes, codeHash)
+		}
+	}
+	if _, ok := r.oauthClients[input.ClientID]; !ok {
+		return fmt.Errorf("insert authorization code: unknown client %s", input.ClientID)
+	}
+	if _, ok := r.codes[input.CodeHash]; ok {
+		return fmt.Errorf("insert authorization code: %w", ErrDuplicateData)
+	}
+	input.CreatedAt = now
+	r.codes[input.CodeHash] = input
+	return nil
+}
+
+// ConsumeAuthorizationCode return the code of codeHash and delete it, so it
+// is exchanged once. Return ErrNotFound when the code is unknown or expired.
+func (r *MemoryRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (output AuthorizationCode, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	output, ok := r.codes[codeHash]
+	delete(r.codes, codeHash)
+	if !ok || !output.ExpiresAt.After(r.now()) {
+		return AuthorizationCode{}, fmt.Errorf("consume authorization code: %w", ErrNotFound)
+	}
+	return output, nil
+}
+
+// CreateAPIKey store a key and return it with its creation time.
+// Return ErrDuplicateData when the id, prefix or hash is taken.
+func (r *MemoryRepository) CreateAPIKey(ctx context.Context, input APIKey) (output APIKey, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[input.UserID]; !ok {
+		return APIKey{}, fmt.Errorf("insert api key: unknown user %d", input.UserID)
+	}
+	for _, key := range r.apiKeys {
+		if key.ID == input.ID || key.Prefix == input.Prefix || key.KeyHash == input.KeyHash {
+			return APIKey{}, fmt.Errorf("insert api key: %w", ErrDuplicateData)
+		}
+	}
+	input.Scopes = append([]string(nil), input.Scopes...)
+	input.CreatedAt = r.now()
+	r.apiKeys[input.ID] = input
+	return copyAPIKey(input), nil
+}
+
+// GetAPIKeyByHash return the key of the hash, expired and revoked keys
+// included. Return ErrNotFound when no key has the hash.
+func (r *MemoryRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (output APIKey, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.apiKeys {
+		if key.KeyHash == keyHash {
+			return copyAPIKey(key), nil
+		}
+	}
+	return APIKey{}, fmt.Errorf("get api key: %w", ErrNotFound)
+}
+
+// ListAPIKeys return the keys of the user, of every user when userID is
+// nil, newest first.
+func (r *MemoryRepository) ListAPIKeys(ctx context.Context, userID *int64) (output []APIKey, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.apiKeys {
+		if userID == nil || key.UserID == *userID {
+			output = append(output, copyAPIKey(key))
+		}
+	}
+	sort.Slice(output, func(i, j int) bool {
+		if !output[i].CreatedAt.Equal(output[j].CreatedAt) {
+			return output[i].CreatedAt.After(output[j].CreatedAt)
+		}
+		return output[i].ID < output[j].ID
+	})
+	return output, nil
+}
+
+// TouchAPIKey record the use of a key, at most once a minute.
+func (r *MemoryRepository) TouchAPIKey(ctx context.Context, id string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.apiKeys[id]
+	now := r.now()
+	if ok && (key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > time.Minute) {
+		key.LastUsedAt = &now
+		r.apiKeys[id] = key
+	}
+	return nil
+}
+
+// RevokeAPIKey revoke a key. Return ErrNotFound when the key does not exist
+// or has been revoked.
+func (r *MemoryRepository) RevokeAPIKey(ctx context.Context, id string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.apiKeys[id]
+	if !ok || key.RevokedAt != nil {
+		return fmt.Errorf("revoke api key: %w", ErrNotFound)
+	}
+	now := r.now()
+	key.RevokedAt = &now
+	r.apiKeys[id] = key
+	return nil
+}
+
+// copyAPIKey return key with its own scopes, callers can not change the
+// stored key.
+func copyAPIKey(key APIKey) APIKey {
+	key.Scopes = append([]string(nil), key.Scopes...)
+	return key
+}
+
+// CreateIdempotencyKey reserve key for an in-flight request.
+// Return ErrDuplicateData when the key is already used and not expired.
+func (r *MemoryRepository) CreateIdempotencyKey(ctx context.Context, input IdempotencyKey) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := [2]string{input.Scope, input.Key}
+	if existing, ok := r.idempotencyKeys[id]; ok && existing.ExpiresAt.After(r.now()) {
+		return fmt.Errorf("insert idempotency key: %w", ErrDuplicateData)
+	}
+	r.idempotencyKeys[id] = IdempotencyKey{
+		Scope:       input.Scope,
+		Key:         input.Key,
+		Fingerprint: input.Fingerprint,
+		CreatedAt:   r.now(),
+		ExpiresAt:   input.ExpiresAt,
+	}
+	return nil
+}
+
+// GetIdempotencyKey return the unexpired key, ErrNotFound otherwise.
+func (r *MemoryRepository) GetIdempotencyKey(ctx context.Context, scope string, key string) (output IdempotencyKey, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	output, ok := r.idempotencyKeys[[2]string{scope, key}]
+	if !ok || !output.ExpiresAt.After(r.now()) {
+		return IdempotencyKey{}, fmt.Errorf("get idempotency key: %w", ErrNotFound)
+	}
+	return output, nil
+}
+
+// CompleteIdempotencyKey store the response of the request holding the key.
+func (r *MemoryRepository) CompleteIdempotencyKey(ctx context.Context, input IdempotencyKey) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := [2]string{input.Scope, input.Key}
+	stored, ok := r.idempotencyKeys[id]
+	if !ok || stored.StatusCode != 0 {
+		return fmt.Errorf("complete idempotency key: %w", ErrNotFound)
+	}
+	stored.StatusCode = input.StatusCode
+	stored.ContentType = input.ContentType
+	stored.Headers = input.Headers
+	stored.Body = input.Body
+	r.idempotencyKeys[id] = stored
+	return nil
+}
+
+// DeleteIdempotencyKey release an in-flight key so the request can be retried.
+func (r *MemoryRepository) DeleteIdempotencyKey(ctx context.Context, scope string, key string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := [2]string{scope, key}
+	stored, ok := r.idempotencyKeys[id]
+	if !ok || stored.StatusCode != 0 {
+		return fmt.Errorf("delete idempotency key: %w", ErrNotFound)
+	}
+	delete(r.idempotencyKeys, id)
+	return nil
+}
+
+// DeleteIdempotencyKeysBefore prune keys expired before the given time.
+func (r *MemoryRepository) DeleteIdempotencyKeysBefore(ctx context.Context, before time.Time) (deleted int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, key := range r.idempotencyKeys {
+		if key.ExpiresAt.Before(before) {
+			delete(r.idempotencyKeys, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// UpdateRateLimitBucket replace the bucket of key with the result of update.
+// found is false when the key has no bucket yet.
+func (r *MemoryRepository) UpdateRateLimitBucket(ctx context.Context, key string, update func(bucket RateLimitBucket, found bool) RateLimitBucket) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bucket, found := r.buckets[key]
+	if !found {
+		bucket = RateLimitBucket{Key: key}
+	}
+	next := update(bucket, found)
+	next.Key = key
+	r.buckets[key] = next
+	return nil
+}
+
+// DeleteRateLimitBucketsBefore prune buckets that are full again before the given time.
+func (r *MemoryRepository) DeleteRateLimitBucketsBefore(ctx context.Context, before time.Time) (deleted int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, bucket := range r.buckets {
+		if bucket.ExpiresAt.Before(before) {
+			delete(r.buckets, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// userByPhone return the user of phone, r.mu must be held.
+func (r *MemoryRepository) userByPhone(phone string) (User, bool) {
+	for _, user := range r.users {
+		if phone != "" && user.Phone == phone {
+			return user, true
+		}
+	}
+	return User{}, false
+}
+
+// userByEmail return the user who verified email, r.mu must be held.
+func (r *MemoryRepository) userByEmail(email string) (User, bool) {
+	for _, user := range r.users {
+		if email != "" && valueOf(user.Email) == email && user.EmailVerifiedAt != nil {
+			return user, true
+		}
+	}
+	return User{}, false
+}
+
+// identityOf return the identity of the provider linked to the user, r.mu
+// must be held.
+func (r *MemoryRepository) identityOf(userID int64, provider string) (UserIdentity, bool) {
+	for _, identity := range r.identities {
+		if identity.UserID == userID && identity.Provider == provider {
+			return identity, true
+		}
+	}
+	return UserIdentity{}, false
+}
+
+// activeSession return the unrevoked session of the user, r.mu must be held.
+func (r *MemoryRepository) activeSession(sessionID string, userID int64) (UserSession, bool) {
+	session, ok := r.sessions[sessionID]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return UserSession{}, false
+	}
+	return session, true
+}
+
+// nullIfEmpty return nil for an empty optional field, like NULLIF.
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// insertUserEvent append event to the audit log, r.mu must be held.
+// Client info is taken from ctx when the event does not carry it.
+func (r *MemoryRepository) insertUserEvent(ctx context.Context, event UserEvent) {
+	client := clientInfoFromContext(ctx)
+	if event.IP == "" {
+		event.IP = client.IP
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = client.UserAgent
+	}
+
+	r.lastEventID++
+	event.ID = r.lastEventID
+	event.CreatedAt = r.now()
+	r.events = append(r.events, event)
+}
+
+var _ RepositoryInterface = (*MemoryRepository)(nil)
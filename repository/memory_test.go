@@ -0,0 +1,343 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	t.Run("TestMemoryRepository", func(t *testing.T) {
+		Convey("TestMemoryRepository", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			ctx := context.Background()
+			phone := "+6281234568"
+			version := int64(1)
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				// run is given a repository holding user 1 with phone +6281234567.
+				run     func(r *MemoryRepository) error
+				wantErr error
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - duplicate phone on create",
+					run: func(r *MemoryRepository) error {
+						_, err := r.Createuser(ctx, RegisterUser{Phone: "+6281234567"})
+						return err
+					},
+					wantErr: ErrDuplicateData,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - unknown user",
+					run: func(r *MemoryRepository) error {
+						_, err := r.GetUserByID(ctx, 2)
+						return err
+					},
+					wantErr: ErrNotFound,
+				},
+				{
+					testID:   3,
+					testDesc: "Success - update bumps version and records changes",
+					run: func(r *MemoryRepository) error {
+						user, err := r.UpdateUser(ctx, UpdateUser{ID: 1, Phone: &phone, Version: &version})
+						if err != nil {
+							return err
+						}
+						events, _ := r.ListUserEvents(ctx, 1, 1)
+						if user.Version != 2 || user.Phone != phone || events[0].Details.Changes["phone"].After != phone {
+							return fmt.Errorf("unexpected update %+v %+v", user, events)
+						}
+						return nil
+					},
+				},
+				{
+					testID:   4,
+					testDesc: "Failed - update of a stale version",
+					run: func(r *MemoryRepository) error {
+						stale := int64(0)
+						_, err := r.UpdateUser(ctx, UpdateUser{ID: 1, Phone: &phone, Version: &stale})
+						return err
+					},
+					wantErr: ErrVersionConflict,
+				},
+				{
+					testID:   5,
+					testDesc: "Failed - revoked session cannot be touched",
+					run: func(r *MemoryRepository) error {
+						if err := r.RecordLogin(ctx, UserLogin{UserID: 1, SessionID: "mock-session-id"}); err != nil {
+							return err
+						}
+						if err := r.RevokeUserSession(ctx, "mock-session-id", 1); err != nil {
+							return err
+						}
+						return r.TouchUserSession(ctx, "mock-session-id", 1)
+					},
+					wantErr: ErrNotFound,
+				},
+				{
+					testID:   6,
+					testDesc: "Success - expired idempotency key is replaced",
+					run: func(r *MemoryRepository) error {
+						err := r.CreateIdempotencyKey(ctx, IdempotencyKey{Scope: "ip:127.0.0.1", Key: "mock-key", ExpiresAt: mockTime})
+						if err != nil {
+							return err
+						}
+						if _, err = r.GetIdempotencyKey(ctx, "ip:127.0.0.1", "mock-key"); !errors.Is(err, ErrNotFound) {
+							return fmt.Errorf("expired key returned: %v", err)
+						}
+						return r.CreateIdempotencyKey(ctx, IdempotencyKey{Scope: "ip:127.0.0.1", Key: "mock-key", ExpiresAt: mockTime.Add(time.Hour)})
+					},
+				},
+				{
+					testID:   7,
+					testDesc: "Failed - idempotency key in use",
+					run: func(r *MemoryRepository) error {
+						key := IdempotencyKey{Scope: "ip:127.0.0.1", Key: "mock-key", ExpiresAt: mockTime.Add(time.Hour)}
+						if err := r.CreateIdempotencyKey(ctx, key); err != nil {
+							return err
+						}
+						return r.CreateIdempotencyKey(ctx, key)
+					},
+					wantErr: ErrDuplicateData,
+				},
+				{
+					testID:   8,
+					testDesc: "Failed - email verified by another user first",
+					run: func(r *MemoryRepository) error {
+						email := "budi@example.com"
+						if _, err := r.UpdateUser(ctx, UpdateUser{ID: 1, Email: &email}); err != nil {
+							return err
+						}
+						// a pending email does not block other users.
+						other, err := r.Createuser(ctx, RegisterUser{Phone: phone})
+						if err != nil {
+							return err
+						}
+						if _, err = r.UpdateUser(ctx, UpdateUser{ID: other.ID, Email: &email}); err != nil {
+							return err
+						}
+						err = r.CreateEmailVerification(ctx, EmailVerification{TokenHash: "mock-token-hash", UserID: other.ID, Email: email, ExpiresAt: mockTime.Add(time.Hour)})
+						if err != nil {
+							return err
+						}
+						if _, err = r.ConfirmEmailVerification(ctx, "mock-token-hash"); err != nil {
+							return err
+						}
+						err = r.CreateEmailVerification(ctx, EmailVerification{TokenHash: "mock-other-token-hash", UserID: 1, Email: email, ExpiresAt: mockTime.Add(time.Hour)})
+						if err != nil {
+							return err
+						}
+						_, err = r.ConfirmEmailVerification(ctx, "mock-other-token-hash")
+						return err
+					},
+					wantErr: ErrDuplicateEmail,
+				},
+				{
+					testID:   9,
+					testDesc: "Success - email verified once, then token is used up",
+					run: func(r *MemoryRepository) error {
+						email := "budi@example.com"
+						if _, err := r.UpdateUser(ctx, UpdateUser{ID: 1, Email: &email}); err != nil {
+							return err
+						}
+						err := r.CreateEmailVerification(ctx, EmailVerification{TokenHash: "mock-token-hash", UserID: 1, Email: email, ExpiresAt: mockTime.Add(time.Hour)})
+						if err != nil {
+							return err
+						}
+						user, err := r.ConfirmEmailVerification(ctx, "mock-token-hash")
+						if err != nil {
+							return err
+						}
+						if user.EmailVerifiedAt == nil || user.Version != 3 {
+							return fmt.Errorf("unexpected user %+v", user)
+						}
+						if _, err = r.ConfirmEmailVerification(ctx, "mock-token-hash"); !errors.Is(err, ErrNotFound) {
+							return fmt.Errorf("token used twice: %v", err)
+						}
+						return nil
+					},
+				},
+				{
+					testID:   10,
+					testDesc: "Success - authorization code exchanged once",
+					run: func(r *MemoryRepository) error {
+						err := r.CreateOAuthClient(ctx, OAuthClient{ID: "mock-client", RedirectURIs: []string{"https://app.example.com/callback"}})
+						if err != nil {
+							return err
+						}
+						err = r.CreateAuthorizationCode(ctx, AuthorizationCode{CodeHash: "mock-code-hash", ClientID: "mock-client", UserID: 1, ExpiresAt: mockTime.Add(time.Minute)})
+						if err != nil {
+							return err
+						}
+						code, err := r.ConsumeAuthorizationCode(ctx, "mock-code-hash")
+						if err != nil {
+							return err
+						}
+						if code.UserID != 1 || !code.CreatedAt.Equal(mockTime) {
+							return fmt.Errorf("unexpected code %+v", code)
+						}
+						if _, err = r.ConsumeAuthorizationCode(ctx, "mock-code-hash"); !errors.Is(err, ErrNotFound) {
+							return fmt.Errorf("code exchanged twice: %v", err)
+						}
+						return nil
+					},
+				},
+				{
+					testID:   11,
+					testDesc: "Failed - expired authorization code",
+					run: func(r *MemoryRepository) error {
+						err := r.CreateOAuthClient(ctx, OAuthClient{ID: "mock-client"})
+						if err != nil {
+							return err
+						}
+						err = r.CreateAuthorizationCode(ctx, AuthorizationCode{CodeHash: "mock-code-hash", ClientID: "mock-client", UserID: 1, ExpiresAt: mockTime})
+						if err != nil {
+							return err
+						}
+						_, err = r.ConsumeAuthorizationCode(ctx, "mock-code-hash")
+						return err
+					},
+					wantErr: ErrNotFound,
+				},
+				{
+					testID:   12,
+					testDesc: "Success - identity user has no phone",
+					run: func(r *MemoryRepository) error {
+						email := "budi@example.com"
+						user, err := r.CreateIdentityUser(ctx, RegisterIdentityUser{
+							Name:     "Budi",
+							Email:    &email,
+							Identity: UserIdentity{Provider: "google", Subject: "mock-subject"},
+						})
+						if err != nil {
+							return err
+						}
+						identity, err := r.GetUserIdentity(ctx, "google", "mock-subject")
+						if err != nil {
+							return err
+						}
+						if identity.UserID != user.ID {
+							return fmt.Errorf("unexpected identity %+v", identity)
+						}
+						// a user without phone is not found by an empty one.
+						if _, err = r.GetUserByPhone(ctx, ""); !errors.Is(err, ErrNotFound) {
+							return fmt.Errorf("found user by empty phone: %v", err)
+						}
+						stored, err := r.GetUserByEmail(ctx, email)
+						if err != nil {
+							return err
+						}
+						if stored.EmailVerifiedAt == nil {
+							return errors.New("email of identity user is not verified")
+						}
+						return nil
+					},
+				},
+				{
+					testID:   13,
+					testDesc: "Failed - user already has an identity of the provider",
+					run: func(r *MemoryRepository) error {
+						_, err := r.CreateUserIdentity(ctx, UserIdentity{Provider: "google", Subject: "mock-subject", UserID: 1})
+						if err != nil {
+							return err
+						}
+						_, err = r.CreateUserIdentity(ctx, UserIdentity{Provider: "google", Subject: "mock-other-subject", UserID: 1})
+						return err
+					},
+					wantErr: ErrDuplicateData,
+				},
+				{
+					testID:   14,
+					testDesc: "Success - identity unlinked",
+					run: func(r *MemoryRepository) error {
+						_, err := r.CreateUserIdentity(ctx, UserIdentity{Provider: "google", Subject: "mock-subject", UserID: 1})
+						if err != nil {
+							return err
+						}
+						if err = r.DeleteUserIdentity(ctx, 1, "google"); err != nil {
+							return err
+						}
+						identities, err := r.ListUserIdentities(ctx, 1)
+						if err != nil {
+							return err
+						}
+						if len(identities) != 0 {
+							return fmt.Errorf("unexpected identities %+v", identities)
+						}
+						if err = r.DeleteUserIdentity(ctx, 1, "google"); !errors.Is(err, ErrNotFound) {
+							return fmt.Errorf("identity unlinked twice: %v", err)
+						}
+						return nil
+					},
+				},
+				{
+					testID:   15,
+					testDesc: "Failed - duplicate api key prefix",
+					run: func(r *MemoryRepository) error {
+						_, err := r.CreateAPIKey(ctx, APIKey{ID: "mock-key-id", Prefix: "usk_mockpref", KeyHash: "mock-key-hash", UserID: 1})
+						if err != nil {
+							return err
+						}
+						_, err = r.CreateAPIKey(ctx, APIKey{ID: "mock-other-key-id", Prefix: "usk_mockpref", KeyHash: "mock-other-key-hash", UserID: 1})
+						return err
+					},
+					wantErr: ErrDuplicateData,
+				},
+				{
+					testID:   16,
+					testDesc: "Success - api key revoked once",
+					run: func(r *MemoryRepository) error {
+						_, err := r.CreateAPIKey(ctx, APIKey{ID: "mock-key-id", Prefix: "usk_mockpref", KeyHash: "mock-key-hash", UserID: 1, Scopes: []string{"profile:read"}})
+						if err != nil {
+							return err
+						}
+						if err = r.TouchAPIKey(ctx, "mock-key-id"); err != nil {
+							return err
+						}
+						if err = r.RevokeAPIKey(ctx, "mock-key-id"); err != nil {
+							return err
+						}
+						key, err := r.GetAPIKeyByHash(ctx, "mock-key-hash")
+						if err != nil {
+							return err
+						}
+						if key.LastUsedAt == nil || key.RevokedAt == nil {
+							return fmt.Errorf("unexpected api key %+v", key)
+						}
+						if err = r.RevokeAPIKey(ctx, "mock-key-id"); !errors.Is(err, ErrNotFound) {
+							return fmt.Errorf("api key revoked twice: %v", err)
+						}
+						return nil
+					},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					r := NewMemoryRepository(NewMemoryRepositoryOptions{
+						Now: func() time.Time { return mockTime },
+					})
+					_, err := r.Createuser(ctx, RegisterUser{Phone: "+6281234567", Name: "mock-name", Password: "mock-password"})
+					So(err, ShouldBeNil)
+
+					err = tc.run(r)
+					// assert
+					if tc.wantErr == nil {
+						So(err, ShouldBeNil)
+					} else {
+						So(errors.Is(err, tc.wantErr), ShouldBeTrue)
+					}
+				})
+			}
+		})
+	})
+}
@@ -6,6 +6,13 @@ const (
 		VALUES ($1, $2, $3)
 		RETURNING id`
 
+	// InsertIdentityUserQuery inserts a user without phone and password, the
+	// email asserted by the identity provider is verified.
+	InsertIdentityUserQuery = `
+		INSERT INTO users (name, password, email, email_verified_at)
+		VALUES ($1, '', $2, CASE WHEN $2::VARCHAR IS NOT NULL THEN now() END)
+		RETURNING id`
+
 	GetUserByIDQuery = `
 		SELECT
 			id,
@@ -13,7 +20,18 @@ const (
 			name,
 			password,
 			created_at,
-			updated_at
+			updated_at,
+			version,
+			login_count,
+			last_login_at,
+			email,
+			email_verified_at,
+			display_name,
+			birth_date,
+			locale,
+			timezone,
+			avatar_url,
+			avatar_thumbnails
 		FROM
 			users
 		WHERE id = $1`
@@ -25,22 +43,460 @@ const (
 			name,
 			password,
 			created_at,
-			updated_at
+			updated_at,
+			version,
+			login_count,
+			last_login_at,
+			email,
+			email_verified_at,
+			display_name,
+			birth_date,
+			locale,
+			timezone,
+			avatar_url,
+			avatar_thumbnails
 		FROM
 			users
 		WHERE phone = $1`
 
+	GetUserByEmailQuery = `
+		SELECT
+			id,
+			phone,
+			name,
+			password,
+			created_at,
+			updated_at,
+			version,
+			login_count,
+			last_login_at,
+			email,
+			email_verified_at,
+			display_name,
+			birth_date,
+			locale,
+			timezone,
+			avatar_url,
+			avatar_thumbnails
+		FROM
+			users
+		WHERE email = $1
+			AND email_verified_at IS NOT NULL`
+
 	UpdateUserQuery = `
 		UPDATE users
 		SET
-			phone = CASE WHEN $1 != '' THEN $1 ELSE phone END,
-			name = CASE WHEN $2 != '' THEN $2 ELSE name END,
-			updated_at = now()
-		WHERE id = $3`
+			phone = COALESCE($1, phone),
+			name = COALESCE($2, name),
+			-- optional fields: NULL leaves the value unchanged, '' clears it.
+			email = CASE WHEN $5::VARCHAR IS NULL THEN email ELSE NULLIF($5, '') END,
+			email_verified_at = CASE WHEN $5::VARCHAR IS NULL OR NULLIF($5, '') IS NOT DISTINCT FROM email THEN email_verified_at END,
+			display_name = CASE WHEN $6::VARCHAR IS NULL THEN display_name ELSE NULLIF($6, '') END,
+			birth_date = CASE WHEN $7::VARCHAR IS NULL THEN birth_date ELSE NULLIF($7, '')::DATE END,
+			locale = CASE WHEN $8::VARCHAR IS NULL THEN locale ELSE NULLIF($8, '') END,
+			timezone = CASE WHEN $9::VARCHAR IS NULL THEN timezone ELSE NULLIF($9, '') END,
+			avatar_url = CASE WHEN $10::VARCHAR IS NULL THEN avatar_url ELSE NULLIF($10, '') END,
+			-- thumbnails only belong to the avatar_url they were set with.
+			avatar_thumbnails = CASE WHEN $10::VARCHAR IS NULL THEN avatar_thumbnails ELSE $11::JSONB END,
+			updated_at = now(),
+			version = version + 1
+		WHERE id = $3
+			AND ($4::BIGINT IS NULL OR version = $4)
+		RETURNING
+			id,
+			phone,
+			name,
+			password,
+			created_at,
+			updated_at,
+			version,
+			login_count,
+			last_login_at,
+			email,
+			email_verified_at,
+			display_name,
+			birth_date,
+			locale,
+			timezone,
+			avatar_url,
+			avatar_thumbnails`
+
+	GetUserByIDForUpdateQuery = `
+		SELECT
+			id,
+			phone,
+			name,
+			password,
+			created_at,
+			updated_at,
+			version,
+			login_count,
+			last_login_at,
+			email,
+			email_verified_at,
+			display_name,
+			birth_date,
+			locale,
+			timezone,
+			avatar_url,
+			avatar_thumbnails
+		FROM
+			users
+		WHERE id = $1
+		FOR UPDATE`
 
-	UpdateLoginCount = `
+	UpdateLastLoginQuery = `
 		UPDATE users
 		SET
-			login_count = login_count + 1
+			login_count = login_count + 1,
+			last_login_at = now()
+		WHERE id = $1`
+
+	InsertUserLoginQuery = `
+		INSERT INTO user_logins (user_id, token_id, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4)`
+
+	ListUserLoginsQuery = `
+		SELECT
+			id,
+			user_id,
+			token_id,
+			ip_address,
+			user_agent,
+			created_at
+		FROM
+			user_logins
+		WHERE user_id = $1
+			AND ($2::BIGINT IS NULL OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3`
+
+	DeleteUserLoginsBeforeQuery = `
+		DELETE FROM user_logins
+		WHERE created_at < $1`
+
+	InsertUserEventQuery = `
+		INSERT INTO user_events (user_id, event_type, ip_address, user_agent, details)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	ListUserEventsQuery = `
+		SELECT
+			id,
+			user_id,
+			event_type,
+			ip_address,
+			user_agent,
+			details,
+			created_at
+		FROM
+			user_events
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+	InsertUserSessionQuery = `
+		INSERT INTO user_sessions (id, user_id, device_name, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	ListActiveUserSessionsQuery = `
+		SELECT
+			id,
+			user_id,
+			device_name,
+			ip_address,
+			user_agent,
+			created_at,
+			last_seen_at,
+			revoked_at
+		FROM
+			user_sessions
+		WHERE user_id = $1
+			AND revoked_at IS NULL
+		ORDER BY last_seen_at DESC, id`
+
+	// TouchUserSessionQuery counts the active session and records it as seen
+	// at most once a minute, so authenticated requests do not all write.
+	TouchUserSessionQuery = `
+		WITH active AS (
+			SELECT id, last_seen_at
+			FROM user_sessions
+			WHERE id = $1
+				AND user_id = $2
+				AND revoked_at IS NULL
+		), touched AS (
+			UPDATE user_sessions
+			SET
+				last_seen_at = now()
+			WHERE id IN (SELECT id FROM active WHERE last_seen_at < now() - INTERVAL '1 minute')
+		)
+		SELECT count(*) FROM active`
+
+	RevokeUserSessionQuery = `
+		UPDATE user_sessions
+		SET
+			revoked_at = now()
+		WHERE id = $1
+			AND user_id = $2
+			AND revoked_at IS NULL`
+
+	DeleteEmailVerificationsOfUserQuery = `
+		DELETE FROM email_verifications
+		WHERE user_id = $1`
+
+	InsertEmailVerificationQuery = `
+		INSERT INTO email_verifications (token_hash, user_id, email, expires_at)
+		VALUES ($1, $2, $3, $4)`
+
+	// ConsumeEmailVerificationQuery deletes the verification even when expired,
+	// a token is only tried once.
+	ConsumeEmailVerificationQuery = `
+		DELETE FROM email_verifications
+		WHERE token_hash = $1
+		RETURNING
+			user_id,
+			email,
+			created_at,
+			expires_at`
+
+	// VerifyUserEmailQuery only verifies the email the token was mailed to.
+	VerifyUserEmailQuery = `
+		UPDATE users
+		SET
+			email_verified_at = now(),
+			updated_at = now(),
+			version = version + 1
+		WHERE id = $1
+			AND email = $2
+		RETURNING
+			id,
+			phone,
+			name,
+			password,
+			created_at,
+			updated_at,
+			version,
+			login_count,
+			last_login_at,
+			email,
+			email_verified_at,
+			display_name,
+			birth_date,
+			locale,
+			timezone,
+			avatar_url,
+			avatar_thumbnails`
+
+	// InsertIdempotencyKeyQuery only replaces an existing key once it expired.
+	InsertIdempotencyKeyQuery = `
+		INSERT INTO idempotency_keys (scope, key, fingerprint, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET
+			fingerprint = EXCLUDED.fingerprint,
+			status_code = NULL,
+			content_type = '',
+			headers = NULL,
+			body = NULL,
+			created_at = now(),
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= now()`
+
+	GetIdempotencyKeyQuery = `
+		SELECT
+			scope,
+			key,
+			fingerprint,
+			COALESCE(status_code, 0),
+			content_type,
+			headers,
+			body,
+			created_at,
+			expires_at
+		FROM
+			idempotency_keys
+		WHERE scope = $1
+			AND key = $2
+			AND expires_at > now()`
+
+	CompleteIdempotencyKeyQuery = `
+		UPDATE idempotency_keys
+		SET
+			status_code = $3,
+			content_type = $4,
+			headers = $5,
+			body = $6
+		WHERE scope = $1
+			AND key = $2
+			AND status_code IS NULL`
+
+	DeleteIdempotencyKeyQuery = `
+		DELETE FROM idempotency_keys
+		WHERE scope = $1
+			AND key = $2
+			AND status_code IS NULL`
+
+	DeleteIdempotencyKeysBeforeQuery = `
+		DELETE FROM idempotency_keys
+		WHERE expires_at < $1`
+
+	GetRateLimitBucketForUpdateQuery = `
+		SELECT
+			key,
+			tokens,
+			updated_at,
+			expires_at
+		FROM
+			rate_limit_buckets
+		WHERE key = $1
+		FOR UPDATE`
+
+	InsertRateLimitBucketQuery = `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO NOTHING`
+
+	UpdateRateLimitBucketQuery = `
+		UPDATE rate_limit_buckets
+		SET
+			tokens = $2,
+			updated_at = $3,
+			expires_at = $4
+		WHERE key = $1`
+
+	DeleteRateLimitBucketsBeforeQuery = `
+		DELETE FROM rate_limit_buckets
+		WHERE expires_at < $1`
+
+	InsertOAuthClientQuery = `
+		INSERT INTO oauth_clients (id, name, secret_hash, redirect_uris, first_party)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)`
+
+	GetOAuthClientQuery = `
+		SELECT
+			id,
+			name,
+			COALESCE(secret_hash, ''),
+			redirect_uris,
+			first_party,
+			created_at
+		FROM
+			oauth_clients
 		WHERE id = $1`
+
+	DeleteExpiredAuthorizationCodesQuery = `
+		DELETE FROM oauth_authorization_codes
+		WHERE expires_at < now()`
+
+	InsertAuthorizationCodeQuery = `
+		INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scope, nonce, code_challenge, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	// ConsumeAuthorizationCodeQuery deletes the code even when expired, a
+	// code is only exchanged once.
+	ConsumeAuthorizationCodeQuery = `
+		DELETE FROM oauth_authorization_codes
+		WHERE code_hash = $1
+		RETURNING
+			client_id,
+			user_id,
+			redirect_uri,
+			scope,
+			nonce,
+			code_challenge,
+			created_at,
+			expires_at`
+
+	InsertUserIdentityQuery = `
+		INSERT INTO user_identities (provider, subject, user_id, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at`
+
+	GetUserIdentityQuery = `
+		SELECT
+			provider,
+			subject,
+			user_id,
+			email,
+			created_at
+		FROM
+			user_identities
+		WHERE provider = $1
+			AND subject = $2`
+
+	ListUserIdentitiesQuery = `
+		SELECT
+			provider,
+			subject,
+			user_id,
+			email,
+			created_at
+		FROM
+			user_identities
+		WHERE user_id = $1
+		ORDER BY provider`
+
+	DeleteUserIdentityQuery = `
+		DELETE FROM user_identities
+		WHERE user_id = $1
+			AND provider = $2`
+
+	InsertAPIKeyQuery = `
+		INSERT INTO api_keys (id, name, prefix, key_hash, user_id, scopes, rate_limit, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at`
+
+	GetAPIKeyByHashQuery = `
+		SELECT
+			id,
+			name,
+			prefix,
+			key_hash,
+			user_id,
+			scopes,
+			rate_limit,
+			expires_at,
+			last_used_at,
+			created_by,
+			created_at,
+			revoked_at
+		FROM
+			api_keys
+		WHERE key_hash = $1`
+
+	// ListAPIKeysQuery lists the keys of the user $1, of every user when NULL.
+	ListAPIKeysQuery = `
+		SELECT
+			id,
+			name,
+			prefix,
+			key_hash,
+			user_id,
+			scopes,
+			rate_limit,
+			expires_at,
+			last_used_at,
+			created_by,
+			created_at,
+			revoked_at
+		FROM
+			api_keys
+		WHERE $1::INT IS NULL
+			OR user_id = $1
+		ORDER BY created_at DESC, id`
+
+	// TouchAPIKeyQuery records the use of a key at most once a minute, so
+	// busy keys do not write on every request.
+	TouchAPIKeyQuery = `
+		UPDATE api_keys
+		SET
+			last_used_at = now()
+		WHERE id = $1
+			AND (last_used_at IS NULL OR last_used_at < now() - INTERVAL '1 minute')`
+
+	RevokeAPIKeyQuery = `
+		UPDATE api_keys
+		SET
+			revoked_at = now()
+		WHERE id = $1
+			AND revoked_at IS NULL`
 )
@@ -3,6 +3,7 @@ package repository
 
 import (
 	"database/sql"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -13,6 +14,10 @@ type Repository struct {
 
 type NewRepositoryOptions struct {
 	Dsn string
+	// Connection pool settings, zero keeps the database/sql default.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
 }
 
 func NewRepository(opts NewRepositoryOptions) *Repository {
@@ -20,6 +25,15 @@ func NewRepository(opts NewRepositoryOptions) *Repository {
 	if err != nil {
 		panic(err)
 	}
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
 	return &Repository{
 		Db: db,
 	}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SawitProRecruitment/UserService/common"
+)
+
+// ErrUnsupportedIdentifier is returned by a resolver for identifier types it
+// does not resolve.
+var ErrUnsupportedIdentifier = errors.New("unsupported identifier")
+
+// An IdentifierResolver looks up the user of a login identifier.
+type IdentifierResolver interface {
+	ResolveUser(ctx context.Context, identifier common.Identifier) (output User, err error)
+}
+
+// IdentifierResolverFunc adapts a function to IdentifierResolver.
+type IdentifierResolverFunc func(ctx context.Context, identifier common.Identifier) (User, error)
+
+func (f IdentifierResolverFunc) ResolveUser(ctx context.Context, identifier common.Identifier) (User, error) {
+	return f(ctx, identifier)
+}
+
+// A ResolverChain asks its resolvers in order, the first one supporting the
+// identifier type resolves it. An identifier of no known user is ErrNotFound
+// whatever its type, so failures do not tell the types apart.
+type ResolverChain []IdentifierResolver
+
+// NewResolverChain return the chain resolving phone numbers and verified emails.
+func NewResolverChain(repo RepositoryInterface) ResolverChain {
+	return ResolverChain{PhoneResolver(repo), EmailResolver(repo)}
+}
+
+func (c ResolverChain) ResolveUser(ctx context.Context, identifier common.Identifier) (User, error) {
+	for _, resolver := range c {
+		user, err := resolver.ResolveUser(ctx, identifier)
+		switch {
+		case errors.Is(err, ErrUnsupportedIdentifier):
+			continue
+		case errors.Is(err, ErrNotFound):
+			return User{}, ErrNotFound
+		}
+		return user, err
+	}
+	return User{}, ErrNotFound
+}
+
+// PhoneResolver resolve phone numbers with GetUserByPhone.
+func PhoneResolver(repo RepositoryInterface) IdentifierResolver {
+	return IdentifierResolverFunc(func(ctx context.Context, identifier common.Identifier) (User, error) {
+		if identifier.Type != common.IdentifierPhone {
+			return User{}, ErrUnsupportedIdentifier
+		}
+		return repo.GetUserByPhone(ctx, identifier.Value)
+	})
+}
+
+// EmailResolver resolve emails with GetUserByEmail. An email that is not
+// verified yet is not found, it may not belong to the user.
+func EmailResolver(repo RepositoryInterface) IdentifierResolver {
+	return IdentifierResolverFunc(func(ctx context.Context, identifier common.Identifier) (User, error) {
+		if identifier.Type != common.IdentifierEmail {
+			return User{}, ErrUnsupportedIdentifier
+		}
+		user, err := repo.GetUserByEmail(ctx, identifier.Value)
+		if err != nil {
+			return User{}, err
+		}
+		if user.EmailVerifiedAt == nil {
+			return User{}, ErrNotFound
+		}
+		return user, nil
+	})
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/common"
+	"github.com/golang/mock/gomock"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResolverChain(t *testing.T) {
+	t.Run("TestResolverChain", func(t *testing.T) {
+		Convey("TestResolverChain", t, func(c C) {
+			mockTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+			mockEmail := "budi@example.com"
+
+			testCases := []struct {
+				testID     int
+				testDesc   string
+				identifier common.Identifier
+				mockFunc   func(mock *MockRepositoryInterface)
+				wantResp   User
+				wantErr    error
+			}{
+				{
+					testID:     1,
+					testDesc:   "Failed - unknown phone",
+					identifier: common.Identifier{Type: common.IdentifierPhone, Value: "+6281234567"},
+					mockFunc: func(mock *MockRepositoryInterface) {
+						mock.EXPECT().GetUserByPhone(gomock.Any(), "+6281234567").Return(User{}, fmt.Errorf("get user by phone: %w", ErrNotFound))
+					},
+					wantErr: ErrNotFound,
+				},
+				{
+					testID:     2,
+					testDesc:   "Failed - unknown email",
+					identifier: common.Identifier{Type: common.IdentifierEmail, Value: mockEmail},
+					mockFunc: func(mock *MockRepositoryInterface) {
+						mock.EXPECT().GetUserByEmail(gomock.Any(), mockEmail).Return(User{}, fmt.Errorf("get user by email: %w", ErrNotFound))
+					},
+					wantErr: ErrNotFound,
+				},
+				{
+					testID:     3,
+					testDesc:   "Failed - unverified email",
+					identifier: common.Identifier{Type: common.IdentifierEmail, Value: mockEmail},
+					mockFunc: func(mock *MockRepositoryInterface) {
+						mock.EXPECT().GetUserByEmail(gomock.Any(), mockEmail).Return(User{ID: 1, Email: &mockEmail}, nil)
+					},
+					wantErr: ErrNotFound,
+				},
+				{
+					testID:     4,
+					testDesc:   "Failed - no resolver for type",
+					identifier: common.Identifier{Type: "username", Value: "budi"},
+					mockFunc:   func(mock *MockRepositoryInterface) {},
+					wantErr:    ErrNotFound,
+				},
+				{
+					testID:     5,
+					testDesc:   "Success - phone",
+					identifier: common.Identifier{Type: common.IdentifierPhone, Value: "+6281234567"},
+					mockFunc: func(mock *MockRepositoryInterface) {
+						mock.EXPECT().GetUserByPhone(gomock.Any(), "+6281234567").Return(User{ID: 1, Phone: "+6281234567"}, nil)
+					},
+					wantResp: User{ID: 1, Phone: "+6281234567"},
+				},
+				{
+					testID:     6,
+					testDesc:   "Success - verified email",
+					identifier: common.Identifier{Type: common.IdentifierEmail, Value: mockEmail},
+					mockFunc: func(mock *MockRepositoryInterface) {
+						mock.EXPECT().GetUserByEmail(gomock.Any(), mockEmail).Return(User{ID: 1, Email: &mockEmail, EmailVerifiedAt: &mockTime}, nil)
+					},
+					wantResp: User{ID: 1, Email: &mockEmail, EmailVerifiedAt: &mockTime},
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					ctrl := gomock.NewController(t)
+					defer ctrl.Finish()
+					mock := NewMockRepositoryInterface(ctrl)
+					tc.mockFunc(mock)
+
+					output, err := NewResolverChain(mock).ResolveUser(context.Background(), tc.identifier)
+					// assert
+					if tc.wantErr != nil {
+						// the same error whatever the identifier type.
+						So(err, ShouldEqual, tc.wantErr)
+						return
+					}
+					So(err, ShouldBeNil)
+					So(output, ShouldResemble, tc.wantResp)
+				})
+			}
+		})
+	})
+}
@@ -0,0 +1,276 @@
+// This file contains the tracing decorator of the repository layer.
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/SawitProRecruitment/UserService/repository"
+
+// TracedRepository wraps a repository with one span per call.
+// Spans carry the names of the SQL statements the call runs, keep them in
+// sync with implementations.go.
+type TracedRepository struct {
+	Next   RepositoryInterface
+	Tracer trace.Tracer
+}
+
+type NewTracedRepositoryOptions struct {
+	Next RepositoryInterface
+	// TracerProvider defaults to the global provider.
+	TracerProvider trace.TracerProvider
+}
+
+func NewTracedRepository(opts NewTracedRepositoryOptions) *TracedRepository {
+	provider := opts.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &TracedRepository{
+		Next:   opts.Next,
+		Tracer: provider.Tracer(tracerName),
+	}
+}
+
+// start open a span for method running the named statements.
+func (r *TracedRepository) start(ctx context.Context, method string, statements ...string) (context.Context, trace.Span) {
+	return r.Tracer.Start(ctx, "repository."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			attribute.StringSlice("db.statement.names", statements),
+		),
+	)
+}
+
+// end record err on span and end it.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (r *TracedRepository) Createuser(ctx context.Context, input RegisterUser) (output User, err error) {
+	ctx, span := r.start(ctx, "Createuser", "InsertUserQuery", "InsertUserEventQuery")
+	defer func() { end(span, err) }()
+	return r.Next.Createuser(ctx, input)
+}
+
+func (r *TracedRepository) GetUserByID(ctx context.Context, id int64) (output User, err error) {
+	ctx, span := r.start(ctx, "GetUserByID", "GetUserByIDQuery")
+	defer func() { end(span, err) }()
+	return r.Next.GetUserByID(ctx, id)
+}
+
+func (r *TracedRepository) GetUserByPhone(ctx context.Context, phone string) (output User, err error) {
+	ctx, span := r.start(ctx, "GetUserByPhone", "GetUserByPhoneQuery")
+	defer func() { end(span, err) }()
+	return r.Next.GetUserByPhone(ctx, phone)
+}
+
+func (r *TracedRepository) GetUserByEmail(ctx context.Context, email string) (output User, err error) {
+	ctx, span := r.start(ctx, "GetUserByEmail", "GetUserByEmailQuery")
+	defer func() { end(span, err) }()
+	return r.Next.GetUserByEmail(ctx, email)
+}
+
+func (r *TracedRepository) UpdateUser(ctx context.Context, input UpdateUser) (output User, err error) {
+	ctx, span := r.start(ctx, "UpdateUser", "GetUserByIDForUpdateQuery", "UpdateUserQuery", "InsertUserEventQuery")
+	defer func() { end(span, err) }()
+	return r.Next.UpdateUser(ctx, input)
+}
+
+func (r *TracedRepository) CreateIdentityUser(ctx context.Context, input RegisterIdentityUser) (output User, err error) {
+	ctx, span := r.start(ctx, "CreateIdentityUser", "InsertIdentityUserQuery", "InsertUserIdentityQuery", "InsertUserEventQuery")
+	defer func() { end(span, err) }()
+	return r.Next.CreateIdentityUser(ctx, input)
+}
+
+func (r *TracedRepository) GetUserIdentity(ctx context.Context, provider string, subject string) (output UserIdentity, err error) {
+	ctx, span := r.start(ctx, "GetUserIdentity", "GetUserIdentityQuery")
+	defer func() { end(span, err) }()
+	return r.Next.GetUserIdentity(ctx, provider, subject)
+}
+
+func (r *TracedRepository) ListUserIdentities(ctx context.Context, userID int64) (output []UserIdentity, err error) {
+	ctx, span := r.start(ctx, "ListUserIdentities", "ListUserIdentitiesQuery")
+	defer func() { end(span, err) }()
+	return r.Next.ListUserIdentities(ctx, userID)
+}
+
+func (r *TracedRepository) CreateUserIdentity(ctx context.Context, input UserIdentity) (output UserIdentity, err error) {
+	ctx, span := r.start(ctx, "CreateUserIdentity", "InsertUserIdentityQuery", "InsertUserEventQuery")
+	defer func() { end(span, err) }()
+	return r.Next.CreateUserIdentity(ctx, input)
+}
+
+func (r *TracedRepository) DeleteUserIdentity(ctx context.Context, userID int64, provider string) (err error) {
+	ctx, span := r.start(ctx, "DeleteUserIdentity", "DeleteUserIdentityQuery", "InsertUserEventQuery")
+	defer func() { end(span, err) }()
+	return r.Next.DeleteUserIdentity(ctx, userID, provider)
+}
+
+func (r *TracedRepository) RecordLogin(ctx context.Context, input UserLogin) (err error) {
+	ctx, span := r.start(ctx, "RecordLogin", "UpdateLastLoginQuery", "InsertUserLoginQuery", "InsertUserSessionQuery", "InsertUserEventQuery")
+	defer func() { end(span, err) }()
+	return r.Next.RecordLogin(ctx, input)
+}
+
+func (r *TracedRepository) ListUserLogins(ctx context.Context, userID int64, before *int64, limit int) (output []UserLogin, err error) {
+	ctx, span := r.start(ctx, "ListUserLogins", "ListUserLoginsQuery")
+	defer func() { end(span, err) }()
+	return r.Next.ListUserLogins(ctx, userID, before, limit)
+}
+
+func (r *TracedRepository) DeleteUserLoginsBefore(ctx context.Context, before time.Time) (deleted int64, err error) {
+	ctx, span := r.start(ctx, "DeleteUserLoginsBefore", "DeleteUserLoginsBeforeQuery")
+	defer func() { end(span, err) }()
+	return r.Next.DeleteUserLoginsBefore(ctx, before)
+}
+
+func (r *TracedRepository) ListActiveUserSessions(ctx context.Context, userID int64) (output []UserSession, err error) {
+	ctx, span := r.start(ctx, "ListActiveUserSessions", "ListActiveUserSessionsQuery")
+	defer func() { end(span, err) }()
+	return r.Next.ListActiveUserSessions(ctx, userID)
+}
+
+func (r *TracedRepository) TouchUserSession(ctx context.Context, sessionID string, userID int64) (err error) {
+	ctx, span := r.start(ctx, "TouchUserSession", "TouchUserSessionQuery")
+	defer func() { end(span, err) }()
+	return r.Next.TouchUserSession(ctx, sessionID, userID)
+}
+
+func (r *TracedRepository) RevokeUserSession(ctx context.Context, sessionID string, userID int64) (err error) {
+	ctx, span := r.start(ctx, "RevokeUserSession", "RevokeUserSessionQuery", "InsertUserEventQuery")
+	defer func() { end(span, err) }()
+	return r.Next.RevokeUserSession(ctx, sessionID, userID)
+}
+
+func (r *TracedRepository) CreateUserEvent(ctx context.Context, input UserEvent) (err error) {
+	ctx, span := r.start(ctx, "CreateUserEvent", "InsertUserEventQuery")
+	defer func() { end(span, err) }()
+	return r.Next.CreateUserEvent(ctx, input)
+}
+
+func (r *TracedRepository) ListUserEvents(ctx context.Context, userID int64, limit int) (output []UserEvent, err error) {
+	ctx, span := r.start(ctx, "ListUserEvents", "ListUserEventsQuery")
+	defer func() { end(span, err) }()
+	return r.Next.ListUserEvents(ctx, userID, limit)
+}
+
+func (r *TracedRepository) CreateEmailVerification(ctx context.Context, input EmailVerification) (err error) {
+	ctx, span := r.start(ctx, "CreateEmailVerification", "DeleteEmailVerificationsOfUserQuery", "InsertEmailVerificationQuery")
+	defer func() { end(span, err) }()
+	return r.Next.CreateEmailVerification(ctx, input)
+}
+
+func (r *TracedRepository) ConfirmEmailVerification(ctx context.Context, tokenHash string) (output User, err error) {
+	ctx, span := r.start(ctx, "ConfirmEmailVerification", "ConsumeEmailVerificationQuery", "VerifyUserEmailQuery", "InsertUserEventQuery")
+	defer func() { end(span, err) }()
+	return r.Next.ConfirmEmailVerification(ctx, tokenHash)
+}
+
+func (r *TracedRepository) CreateOAuthClient(ctx context.Context, input OAuthClient) (err error) {
+	ctx, span := r.start(ctx, "CreateOAuthClient", "InsertOAuthClientQuery")
+	defer func() { end(span, err) }()
+	return r.Next.CreateOAuthClient(ctx, input)
+}
+
+func (r *TracedRepository) GetOAuthClient(ctx context.Context, id string) (output OAuthClient, err error) {
+	ctx, span := r.start(ctx, "GetOAuthClient", "GetOAuthClientQuery")
+	defer func() { end(span, err) }()
+	return r.Next.GetOAuthClient(ctx, id)
+}
+
+func (r *TracedRepository) CreateAuthorizationCode(ctx context.Context, input AuthorizationCode) (err error) {
+	ctx, span := r.start(ctx, "CreateAuthorizationCode", "DeleteExpiredAuthorizationCodesQuery", "InsertAuthorizationCodeQuery")
+	defer func() { end(span, err) }()
+	return r.Next.CreateAuthorizationCode(ctx, input)
+}
+
+func (r *TracedRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (output AuthorizationCode, err error) {
+	ctx, span := r.start(ctx, "ConsumeAuthorizationCode", "ConsumeAuthorizationCodeQuery")
+	defer func() { end(span, err) }()
+	return r.Next.ConsumeAuthorizationCode(ctx, codeHash)
+}
+
+func (r *TracedRepository) CreateAPIKey(ctx context.Context, input APIKey) (output APIKey, err error) {
+	ctx, span := r.start(ctx, "CreateAPIKey", "InsertAPIKeyQuery")
+	defer func() { end(span, err) }()
+	return r.Next.CreateAPIKey(ctx, input)
+}
+
+func (r *TracedRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (output APIKey, err error) {
+	ctx, span := r.start(ctx, "GetAPIKeyByHash", "GetAPIKeyByHashQuery")
+	defer func() { end(span, err) }()
+	return r.Next.GetAPIKeyByHash(ctx, keyHash)
+}
+
+func (r *TracedRepository) ListAPIKeys(ctx context.Context, userID *int64) (output []APIKey, err error) {
+	ctx, span := r.start(ctx, "ListAPIKeys", "ListAPIKeysQuery")
+	defer func() { end(span, err) }()
+	return r.Next.ListAPIKeys(ctx, userID)
+}
+
+func (r *TracedRepository) TouchAPIKey(ctx context.Context, id string) (err error) {
+	ctx, span := r.start(ctx, "TouchAPIKey", "TouchAPIKeyQuery")
+	defer func() { end(span, err) }()
+	return r.Next.TouchAPIKey(ctx, id)
+}
+
+func (r *TracedRepository) RevokeAPIKey(ctx context.Context, id string) (err error) {
+	ctx, span := r.start(ctx, "RevokeAPIKey", "RevokeAPIKeyQuery")
+	defer func() { end(span, err) }()
+	return r.Next.RevokeAPIKey(ctx, id)
+}
+
+func (r *TracedRepository) CreateIdempotencyKey(ctx context.Context, input IdempotencyKey) (err error) {
+	ctx, span := r.start(ctx, "CreateIdempotencyKey", "InsertIdempotencyKeyQuery")
+	defer func() { end(span, err) }()
+	return r.Next.CreateIdempotencyKey(ctx, input)
+}
+
+func (r *TracedRepository) GetIdempotencyKey(ctx context.Context, scope string, key string) (output IdempotencyKey, err error) {
+	ctx, span := r.start(ctx, "GetIdempotencyKey", "GetIdempotencyKeyQuery")
+	defer func() { end(span, err) }()
+	return r.Next.GetIdempotencyKey(ctx, scope, key)
+}
+
+func (r *TracedRepository) CompleteIdempotencyKey(ctx context.Context, input IdempotencyKey) (err error) {
+	ctx, span := r.start(ctx, "CompleteIdempotencyKey", "CompleteIdempotencyKeyQuery")
+	defer func() { end(span, err) }()
+	return r.Next.CompleteIdempotencyKey(ctx, input)
+}
+
+func (r *TracedRepository) DeleteIdempotencyKey(ctx context.Context, scope string, key string) (err error) {
+	ctx, span := r.start(ctx, "DeleteIdempotencyKey", "DeleteIdempotencyKeyQuery")
+	defer func() { end(span, err) }()
+	return r.Next.DeleteIdempotencyKey(ctx, scope, key)
+}
+
+func (r *TracedRepository) DeleteIdempotencyKeysBefore(ctx context.Context, before time.Time) (deleted int64, err error) {
+	ctx, span := r.start(ctx, "DeleteIdempotencyKeysBefore", "DeleteIdempotencyKeysBeforeQuery")
+	defer func() { end(span, err) }()
+	return r.Next.DeleteIdempotencyKeysBefore(ctx, before)
+}
+
+func (r *TracedRepository) UpdateRateLimitBucket(ctx context.Context, key string, update func(bucket RateLimitBucket, found bool) RateLimitBucket) (err error) {
+	ctx, span := r.start(ctx, "UpdateRateLimitBucket", "GetRateLimitBucketForUpdateQuery", "InsertRateLimitBucketQuery", "UpdateRateLimitBucketQuery")
+	defer func() { end(span, err) }()
+	return r.Next.UpdateRateLimitBucket(ctx, key, update)
+}
+
+func (r *TracedRepository) DeleteRateLimitBucketsBefore(ctx context.Context, before time.Time) (deleted int64, err error) {
+	ctx, span := r.start(ctx, "DeleteRateLimitBucketsBefore", "DeleteRateLimitBucketsBeforeQuery")
+	defer func() { end(span, err) }()
+	return r.Next.DeleteRateLimitBucketsBefore(ctx, before)
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTracedRepository(t *testing.T) {
+	t.Run("TestTracedRepository", func(t *testing.T) {
+		Convey("TestTracedRepository", t, func(c C) {
+			testCases := []struct {
+				testID         int
+				testDesc       string
+				call           func(ctx context.Context, r RepositoryInterface) error
+				mockFunc       func(mock *MockRepositoryInterface)
+				wantSpan       string
+				wantStatements []string
+				wantErr        bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - error is recorded on span",
+					call: func(ctx context.Context, r RepositoryInterface) error {
+						_, err := r.GetUserByPhone(ctx, "+6281234567")
+						return err
+					},
+					mockFunc: func(mock *MockRepositoryInterface) {
+						mock.EXPECT().GetUserByPhone(gomock.Any(), "+6281234567").Return(User{}, ErrNotFound)
+					},
+					wantSpan:       "repository.GetUserByPhone",
+					wantStatements: []string{"GetUserByPhoneQuery"},
+					wantErr:        true,
+				},
+				{
+					testID:   2,
+					testDesc: "Success - transaction lists every statement",
+					call: func(ctx context.Context, r RepositoryInterface) error {
+						return r.RecordLogin(ctx, UserLogin{UserID: 1})
+					},
+					mockFunc: func(mock *MockRepositoryInterface) {
+						mock.EXPECT().RecordLogin(gomock.Any(), UserLogin{UserID: 1}).Return(nil)
+					},
+					wantSpan:       "repository.RecordLogin",
+					wantStatements: []string{"UpdateLastLoginQuery", "InsertUserLoginQuery", "InsertUserSessionQuery", "InsertUserEventQuery"},
+					wantErr:        false,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					ctrl := gomock.NewController(t)
+					defer ctrl.Finish()
+					mock := NewMockRepositoryInterface(ctrl)
+					tc.mockFunc(mock)
+
+					recorder := tracetest.NewSpanRecorder()
+					r := NewTracedRepository(NewTracedRepositoryOptions{
+						Next:           mock,
+						TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+					})
+
+					err := tc.call(context.Background(), r)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+
+					spans := recorder.Ended()
+					So(spans, ShouldHaveLength, 1)
+					So(spans[0].Name(), ShouldEqual, tc.wantSpan)
+					So(spans[0].Attributes(), ShouldContain, attribute.StringSlice("db.statement.names", tc.wantStatements))
+					if tc.wantErr {
+						So(spans[0].Status().Code, ShouldEqual, codes.Error)
+					} else {
+						So(spans[0].Status().Code, ShouldEqual, codes.Unset)
+					}
+				})
+			}
+		})
+	})
+}
@@ -0,0 +1,33 @@
+// This file contains the transaction helper shared by the write methods.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/SawitProRecruitment/UserService/logging"
+)
+
+// withTx runs fn inside a transaction bound to ctx.
+// The transaction is committed when fn returns nil, otherwise it is rolled back.
+// The deferred rollback is a no-op once the transaction has been committed.
+func (r *Repository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		logging.FromContext(ctx).DebugContext(ctx, "transaction rolled back", slog.Any("error", err))
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
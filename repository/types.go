@@ -2,6 +2,7 @@
 package repository
 
 import (
+	"context"
 	"time"
 
 	"github.com/SawitProRecruitment/UserService/common"
@@ -26,19 +27,326 @@ func (t *RegisterUser) Validate() (result []string) {
 
 // An User represents an user data in this application.
 type User struct {
-	ID        int64
-	Phone     string
-	Name      string
-	Password  string
-	CreatedAt time.Time
-	UpdateAt  *time.Time
+	ID          int64
+	Phone       string
+	Name        string
+	Password    string
+	CreatedAt   time.Time
+	UpdateAt    *time.Time
+	Version     int64
+	LoginCount  int64
+	LastLoginAt *time.Time
+	// Optional profile fields, nil when not set.
+	Email           *string
+	EmailVerifiedAt *time.Time
+	DisplayName     *string
+	BirthDate       *time.Time
+	Locale          *string
+	Timezone        *string
+	AvatarURL       *string
+	// AvatarThumbnails are the resized copies of an uploaded avatar, nil
+	// when AvatarURL was not uploaded.
+	AvatarThumbnails []AvatarThumbnail
+}
+
+// An AvatarThumbnail is a square resized copy of an uploaded avatar.
+type AvatarThumbnail struct {
+	// Size is the width and height in pixels.
+	Size int    `json:"size"`
+	URL  string `json:"url"`
+	// Key is the blob the thumbnail is stored as.
+	Key string `json:"key"`
+}
+
+// An UpdateUser represents a partial update of user data.
+// Nil fields are left unchanged and an empty optional profile field is
+// cleared. Changing Email marks it unverified. When Version is set the
+// update only applies if the stored user still has that version.
+type UpdateUser struct {
+	ID          int64
+	Phone       *string
+	Name        *string
+	Email       *string
+	DisplayName *string
+	// BirthDate is formatted as YYYY-MM-DD.
+	BirthDate *string
+	Locale    *string
+	Timezone  *string
+	AvatarURL *string
+	// AvatarThumbnails replace the thumbnails when AvatarURL is set, they
+	// are cleared when AvatarURL is set without them.
+	AvatarThumbnails []AvatarThumbnail
+	Version          *int64
+}
+
+// IsEmpty reports whether the update has no fields to change.
+func (t *UpdateUser) IsEmpty() bool {
+	return t.Phone == nil && t.Name == nil && t.Email == nil && t.DisplayName == nil &&
+		t.BirthDate == nil && t.Locale == nil && t.Timezone == nil && t.AvatarURL == nil
 }
 
-// Validate validate user update input.
+// Validate validate user update input, only fields present are checked.
 // Return empty result if comply with given rules.
-func (t *User) Validate() (result []string) {
-	result = append(result, common.ValidatePhone(t.Phone)...)
-	result = append(result, common.ValidateName(t.Name)...)
+func (t *UpdateUser) Validate() (result []string) {
+	if t.Phone != nil {
+		result = append(result, common.ValidatePhone(*t.Phone)...)
+	}
+	if t.Name != nil {
+		result = append(result, common.ValidateName(*t.Name)...)
+	}
+	// empty optional fields clear the stored value.
+	if t.Email != nil && *t.Email != "" {
+		result = append(result, common.ValidateEmail(*t.Email)...)
+	}
+	if t.DisplayName != nil && *t.DisplayName != "" {
+		result = append(result, common.ValidateDisplayName(*t.DisplayName)...)
+	}
+	if t.BirthDate != nil && *t.BirthDate != "" {
+		result = append(result, common.ValidateBirthDate(*t.BirthDate)...)
+	}
+	if t.Locale != nil && *t.Locale != "" {
+		result = append(result, common.ValidateLocale(*t.Locale)...)
+	}
+	if t.Timezone != nil && *t.Timezone != "" {
+		result = append(result, common.ValidateTimezone(*t.Timezone)...)
+	}
+	if t.AvatarURL != nil && *t.AvatarURL != "" {
+		result = append(result, common.ValidateAvatarURL(*t.AvatarURL)...)
+	}
 
 	return result
 }
+
+// A UserLogin represents a successful login of an user.
+// Empty IP and UserAgent are taken from the request client info.
+// SessionID and DeviceName are only used to open the login session.
+type UserLogin struct {
+	ID         int64
+	UserID     int64
+	TokenID    string
+	SessionID  string
+	DeviceName string
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+}
+
+// A UserSession represents a device the user is logged in from.
+type UserSession struct {
+	ID         string
+	UserID     int64
+	DeviceName string
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	RevokedAt  *time.Time
+}
+
+// A ClientInfo represents the client issuing the current request.
+// It is recorded on user events written during the request.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo returns a copy of ctx carrying client info.
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// clientInfoFromContext returns client info stored in ctx, if any.
+func clientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+// User event types recorded in user_events.
+const (
+	UserEventRegistered     = "registered"
+	UserEventLoginSucceeded = "login_succeeded"
+	UserEventLoginFailed    = "login_failed"
+	UserEventProfileUpdated = "profile_updated"
+	UserEventSessionRevoked = "session_revoked"
+	UserEventEmailVerified  = "email_verified"
+	// An external identity was linked to or unlinked from the user, the
+	// registration of a user signing up with one is UserEventRegistered.
+	UserEventIdentityLinked   = "identity_linked"
+	UserEventIdentityUnlinked = "identity_unlinked"
+)
+
+// Reasons of a failed login recorded in user event details.
+const (
+	LoginFailedUnknownUser     = "unknown_user"
+	LoginFailedInvalidPassword = "invalid_password"
+)
+
+// A FieldChange represents the value of a field before and after a change.
+type FieldChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// A UserEventDetails represents event specific data of a user event.
+type UserEventDetails struct {
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+	Reason    string                 `json:"reason,omitempty"`
+	SessionID string                 `json:"session_id,omitempty"`
+	Provider  string                 `json:"provider,omitempty"`
+}
+
+// A UserEvent represents an entry of the user audit log.
+type UserEvent struct {
+	ID        int64
+	UserID    *int64
+	Type      string
+	IP        string
+	UserAgent string
+	Details   UserEventDetails
+	CreatedAt time.Time
+}
+
+// An IdempotencyKey represents the response stored for a request sent with
+// an Idempotency-Key header. StatusCode is zero while the first request is
+// still in flight. Headers are the response headers replayed with the body.
+type IdempotencyKey struct {
+	Scope       string
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Headers     map[string]string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// An EmailVerification represents a pending confirmation of the email of a
+// user, identified by the hash of the token mailed to it.
+type EmailVerification struct {
+	TokenHash string
+	UserID    int64
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// An OAuthClient represents an application logging users in through the
+// OAuth authorization code flow. SecretHash is empty for public clients,
+// which rely on PKCE alone. Users are not asked for consent by FirstParty
+// clients.
+type OAuthClient struct {
+	ID           string
+	Name         string
+	SecretHash   string
+	RedirectURIs []string
+	FirstParty   bool
+	CreatedAt    time.Time
+}
+
+// An AuthorizationCode represents a code issued to a client on behalf of a
+// user, identified by its hash. CodeChallenge is the PKCE challenge the
+// code verifier must match. It is exchanged once, CreatedAt is when the user
+// logged in.
+type AuthorizationCode struct {
+	CodeHash      string
+	ClientID      string
+	UserID        int64
+	RedirectURI   string
+	Scope         string
+	Nonce         string
+	CodeChallenge string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+// A UserIdentity represents the subject of an external OpenID Connect
+// provider linked to a user. Email is the one the provider asserted when the
+// identity was linked, nil when it asserted none.
+type UserIdentity struct {
+	Provider  string
+	Subject   string
+	UserID    int64
+	Email     *string
+	CreatedAt time.Time
+}
+
+// A RegisterIdentityUser represents input for creating a user signing up
+// with an external identity. The user has no phone and no password, Email
+// is stored as verified when set.
+type RegisterIdentityUser struct {
+	Name     string
+	Email    *string
+	Identity UserIdentity
+}
+
+// An APIKey represents the key of a service integration, acting as UserID
+// with Scopes. Only the hex SHA-256 of the key is stored, Prefix is its
+// visible start. A key without RateLimit gets the configured default.
+type APIKey struct {
+	ID         string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	UserID     int64
+	Scopes     []string
+	RateLimit  *int
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedBy  *int64
+	CreatedAt  time.Time
+	RevokedAt  *time.Time
+}
+
+// A RateLimitBucket represents the token bucket of a rate limit key.
+// The bucket is full again once ExpiresAt is past.
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// diffUser returns changed profile fields between before and after.
+func diffUser(before User, after User) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+	if before.Phone != after.Phone {
+		changes["phone"] = FieldChange{Before: before.Phone, After: after.Phone}
+	}
+	if before.Name != after.Name {
+		changes["name"] = FieldChange{Before: before.Name, After: after.Name}
+	}
+	optional := map[string][2]*string{
+		"email":        {before.Email, after.Email},
+		"display_name": {before.DisplayName, after.DisplayName},
+		"birth_date":   {formatDate(before.BirthDate), formatDate(after.BirthDate)},
+		"locale":       {before.Locale, after.Locale},
+		"timezone":     {before.Timezone, after.Timezone},
+		"avatar_url":   {before.AvatarURL, after.AvatarURL},
+	}
+	for field, values := range optional {
+		if b, a := valueOf(values[0]), valueOf(values[1]); b != a {
+			changes[field] = FieldChange{Before: b, After: a}
+		}
+	}
+	return changes
+}
+
+// valueOf return the value of an optional field, empty when not set.
+func valueOf(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// formatDate format an optional date as YYYY-MM-DD.
+func formatDate(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	date := t.Format(time.DateOnly)
+	return &date
+}
@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3Service is the service name in the signing scope.
+const s3Service = "s3"
+
+// S3Store keeps blobs as objects of a bucket of S3 or an S3-compatible
+// service such as MinIO. Objects are addressed path style,
+// Endpoint/Bucket/key, and requests are signed with AWS Signature Version 4.
+type S3Store struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	BaseURL         string
+	HTTPClient      *http.Client
+
+	now func() time.Time
+}
+
+type NewS3StoreOptions struct {
+	// Endpoint is the URL of the service, like https://s3.ap-southeast-3.amazonaws.com.
+	Endpoint string
+	// Region default to us-east-1.
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// BaseURL is where the objects are served, Endpoint/Bucket when empty.
+	BaseURL string
+	// HTTPClient default to a client with a 30 seconds timeout.
+	HTTPClient *http.Client
+}
+
+func NewS3Store(opts NewS3StoreOptions) *S3Store {
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = joinURL(opts.Endpoint, opts.Bucket)
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &S3Store{
+		Endpoint:        opts.Endpoint,
+		Region:          opts.Region,
+		Bucket:          opts.Bucket,
+		AccessKeyID:     opts.AccessKeyID,
+		SecretAccessKey: opts.SecretAccessKey,
+		BaseURL:         opts.BaseURL,
+		HTTPClient:      opts.HTTPClient,
+		now:             time.Now,
+	}
+}
+
+// Put upload data as a public, immutable object, keys are not reused.
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	req, err := s.newRequest(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Cache-Control", "public, max-age=31536000, immutable")
+
+	return s.do(req, data, "put object", http.StatusOK)
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	// deleting a missing object succeeds, some services answer 404.
+	return s.do(req, nil, "delete object", http.StatusNoContent, http.StatusOK, http.StatusNotFound)
+}
+
+func (s *S3Store) URL(key string) string {
+	return joinURL(s.BaseURL, key)
+}
+
+// newRequest return a request on the object of key carrying body.
+func (s *S3Store) newRequest(ctx context.Context, method string, key string, body []byte) (*http.Request, error) {
+	endpoint, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse s3 endpoint: %w", err)
+	}
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + "/" + s.Bucket + "/" + key
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	return req, nil
+}
+
+// do sign and send req carrying body, a status other than ok is an error.
+func (s *S3Store) do(req *http.Request, body []byte, op string, ok ...int) error {
+	s.sign(req, body)
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3 %s: %w", op, err)
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	for _, status := range ok {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("s3 %s: status %d: %s", op, resp.StatusCode, strings.TrimSpace(string(message)))
+}
+
+// sign add the AWS Signature Version 4 Authorization header to req, signing
+// the host, every header set on req and payload.
+func (s *S3Store) sign(req *http.Request, payload []byte) {
+	payloadHash := sha256Hex(payload)
+
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	scope := date + "/" + s.Region + "/" + s3Service + "/aws4_request"
+	signedHeaders, canonicalRequest := canonicalRequest(req, payloadHash)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalRequest return the signed header names and the canonical form of
+// req as defined by Signature Version 4.
+func canonicalRequest(req *http.Request, payloadHash string) (string, string) {
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "authorization" {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[name] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	return signedHeaders, strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+}
+
+// canonicalURI URI-encode each segment of path, keeping the slashes.
+func canonicalURI(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery return query sorted by name with encoded names and values.
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name)+"="+uriEncode(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encode every byte of s but the unreserved characters.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,158 @@
+// Package storage contains the blob stores user uploads are kept in: the
+// local filesystem, and S3 or any S3-compatible service.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidKey is returned for a key that is empty, absolute or escapes the
+// store with "..".
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// BlobStore keeps blobs by key, keys are slash separated paths like
+// avatars/1/64.jpg.
+type BlobStore interface {
+	// Put store data under key, replacing the blob stored before.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete remove the blob of key, a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL return where the blob of key is served.
+	URL(key string) string
+}
+
+// validateKey reject keys that are not a clean relative path.
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
+
+// joinURL join base and key with a single slash.
+func joinURL(base string, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
+
+// FileStore keeps blobs as files under Dir, served under BaseURL.
+type FileStore struct {
+	Dir     string
+	BaseURL string
+}
+
+type NewFileStoreOptions struct {
+	// Dir is created when missing.
+	Dir string
+	// BaseURL is where Dir is served, like /blobs or https://cdn.example.com.
+	BaseURL string
+}
+
+func NewFileStore(opts NewFileStoreOptions) *FileStore {
+	return &FileStore{
+		Dir:     opts.Dir,
+		BaseURL: opts.BaseURL,
+	}
+}
+
+// Put write data to a temporary file renamed into place, so a blob is never
+// served half written.
+func (s *FileStore) Put(_ context.Context, key string, data []byte, _ string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	name := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("create blob dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write blob: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("write blob: %w", err)
+	}
+	if err = os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("write blob: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("write blob: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) Delete(_ context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(s.Dir, filepath.FromSlash(key)))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete blob: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) URL(key string) string {
+	return joinURL(s.BaseURL, key)
+}
+
+// MemoryStore keeps blobs in memory, for tests.
+type MemoryStore struct {
+	BaseURL string
+
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func NewMemoryStore(baseURL string) *MemoryStore {
+	return &MemoryStore{BaseURL: baseURL, blobs: map[string][]byte{}}
+}
+
+func (s *MemoryStore) Put(_ context.Context, key string, data []byte, _ string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
+
+func (s *MemoryStore) URL(key string) string {
+	return joinURL(s.BaseURL, key)
+}
+
+// Keys return the keys of the stored blobs, sorted.
+func (s *MemoryStore) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.blobs))
+	for key := range s.blobs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,109 @@
+// Package tracing contains the OpenTelemetry tracer provider setup.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+// Supported span exporters.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+// Provider is a tracer provider owning its exporter output.
+type Provider struct {
+	*sdktrace.TracerProvider
+	closer io.Closer
+}
+
+type NewProviderOptions struct {
+	ServiceName string
+	// Exporter is one of none, stdout, file or otlp.
+	// The otlp exporter is configured with the standard OTEL_EXPORTER_OTLP_* variables.
+	Exporter string
+	// File receives spans as JSON lines when Exporter is file.
+	File string
+	// SampleRatio is the share of new traces recorded, parent decisions are honored.
+	SampleRatio float64
+	// Stdout receives spans when Exporter is stdout, defaults to os.Stdout.
+	Stdout io.Writer
+}
+
+func NewProvider(ctx context.Context, opts NewProviderOptions) (*Provider, error) {
+	p := &Provider{}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case ExporterNone, "":
+	case ExporterStdout:
+		w := opts.Stdout
+		if w == nil {
+			w = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case ExporterFile:
+		var f *os.File
+		f, err = os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open trace file: %w", err)
+		}
+		p.closer = f
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s trace exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	}
+	if exporter != nil {
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	}
+	p.TracerProvider = sdktrace.NewTracerProvider(providerOpts...)
+
+	return p, nil
+}
+
+// Propagator return the propagator reading and writing W3C traceparent and baggage headers.
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+}
+
+// Shutdown flush pending spans and release the exporter output.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	err := p.TracerProvider.Shutdown(ctx)
+	if p.closer != nil {
+		if closeErr := p.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewProvider(t *testing.T) {
+	t.Run("TestNewProvider", func(t *testing.T) {
+		Convey("TestNewProvider", t, func(c C) {
+			traceFile := filepath.Join(t.TempDir(), "spans.json")
+
+			testCases := []struct {
+				testID   int
+				testDesc string
+				opts     NewProviderOptions
+				output   func(stdout *bytes.Buffer) string
+				wantErr  bool
+			}{
+				{
+					testID:   1,
+					testDesc: "Failed - unknown exporter",
+					opts:     NewProviderOptions{Exporter: "jaeger"},
+					wantErr:  true,
+				},
+				{
+					testID:   2,
+					testDesc: "Failed - file not writable",
+					opts:     NewProviderOptions{Exporter: ExporterFile, File: filepath.Join(t.TempDir(), "missing", "spans.json")},
+					wantErr:  true,
+				},
+				{
+					testID:   3,
+					testDesc: "Success - stdout exporter",
+					opts:     NewProviderOptions{Exporter: ExporterStdout, ServiceName: "user-service", SampleRatio: 1},
+					output:   func(stdout *bytes.Buffer) string { return stdout.String() },
+				},
+				{
+					testID:   4,
+					testDesc: "Success - file exporter",
+					opts:     NewProviderOptions{Exporter: ExporterFile, File: traceFile, ServiceName: "user-service", SampleRatio: 1},
+					output: func(*bytes.Buffer) string {
+						content, _ := os.ReadFile(traceFile)
+						return string(content)
+					},
+				},
+				{
+					testID:   5,
+					testDesc: "Success - none exporter",
+					opts:     NewProviderOptions{Exporter: ExporterNone, SampleRatio: 1},
+					output:   func(stdout *bytes.Buffer) string { return stdout.String() },
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					var stdout bytes.Buffer
+					opts := tc.opts
+					opts.Stdout = &stdout
+
+					provider, err := NewProvider(context.Background(), opts)
+					// assert
+					So(err != nil, ShouldEqual, tc.wantErr)
+					if tc.wantErr {
+						return
+					}
+
+					_, span := provider.Tracer("test").Start(context.Background(), "ComparePassword")
+					span.End()
+					So(provider.Shutdown(context.Background()), ShouldBeNil)
+
+					if tc.opts.Exporter == ExporterNone {
+						So(tc.output(&stdout), ShouldBeEmpty)
+						return
+					}
+					So(tc.output(&stdout), ShouldContainSubstring, `"Name":"ComparePassword"`)
+					So(tc.output(&stdout), ShouldContainSubstring, `"Value":"user-service"`)
+				})
+			}
+		})
+	})
+}
+
+func TestPropagator(t *testing.T) {
+	t.Run("TestPropagator", func(t *testing.T) {
+		Convey("TestPropagator", t, func(c C) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			e := echo.New()
+			e.Use(otelecho.Middleware("user-service",
+				otelecho.WithTracerProvider(provider),
+				otelecho.WithPropagators(Propagator()),
+			))
+			e.GET("/users", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			e.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()
+			So(spans, ShouldHaveLength, 1)
+			So(spans[0].Name(), ShouldEqual, "/users")
+			So(spans[0].SpanContext().TraceID().String(), ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+			So(spans[0].Parent().SpanID().String(), ShouldEqual, "00f067aa0ba902b7")
+		})
+	})
+}
@@ -0,0 +1,242 @@
+// Package validation contains the echo middleware validating requests, and
+// optionally responses, against the OpenAPI spec.
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/SawitProRecruitment/UserService/common"
+	"github.com/SawitProRecruitment/UserService/logging"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/labstack/echo/v4"
+)
+
+func init() {
+	// pages, like the login page of the OpenID Connect provider, are
+	// validated as strings.
+	openapi3filter.RegisterBodyDecoder("text/html", htmlBodyDecoder)
+	openapi3filter.RegisterBodyDecoder(echo.MIMEApplicationForm, formBodyDecoder(openapi3filter.RegisteredBodyDecoder(echo.MIMEApplicationForm)))
+}
+
+// formBodyDecoder wrap the form decoder of kin-openapi, which decodes the
+// properties missing from the form as null, failing the optional ones that
+// are not nullable. They are left out instead.
+func formBodyDecoder(decode openapi3filter.BodyDecoder) openapi3filter.BodyDecoder {
+	return func(body io.Reader, header http.Header, schema *openapi3.SchemaRef, encFn openapi3filter.EncodingFn) (interface{}, error) {
+		value, err := decode(body, header, schema, encFn)
+		if obj, ok := value.(map[string]interface{}); ok {
+			for name, prop := range obj {
+				if prop == nil {
+					delete(obj, name)
+				}
+			}
+		}
+		return value, err
+	}
+}
+
+// htmlBodyDecoder decode a text/html body as a string.
+func htmlBodyDecoder(body io.Reader, _ http.Header, _ *openapi3.SchemaRef, _ openapi3filter.EncodingFn) (interface{}, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Validator matches echo routes to the operations of the spec.
+type Validator struct {
+	// ValidateResponses replaces responses not matching the spec with 500.
+	// Responses are buffered, meant for tests.
+	ValidateResponses bool
+
+	routes  map[string]*routers.Route
+	options *openapi3filter.Options
+}
+
+type NewValidatorOptions struct {
+	Spec              *openapi3.T
+	ValidateResponses bool
+}
+
+// NewValidator return error when the spec is invalid.
+func NewValidator(opts NewValidatorOptions) (*Validator, error) {
+	if err := opts.Spec.Validate(openapi3.NewLoader().Context); err != nil {
+		return nil, fmt.Errorf("validate spec: %w", err)
+	}
+
+	v := &Validator{
+		ValidateResponses: opts.ValidateResponses,
+		routes:            map[string]*routers.Route{},
+		options: &openapi3filter.Options{
+			// tokens are checked by the handlers.
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		},
+	}
+	for path, item := range opts.Spec.Paths {
+		for method, operation := range item.Operations() {
+			v.routes[method+" "+common.EchoRoute(path)] = &routers.Route{
+				Spec:      opts.Spec,
+				Path:      path,
+				PathItem:  item,
+				Method:    method,
+				Operation: operation,
+			}
+		}
+	}
+
+	return v, nil
+}
+
+// Middleware reject requests not matching their operation with 400.
+// Routes missing from the spec, such as /metrics, are not validated.
+func (v *Validator) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			route, ok := v.routes[req.Method+" "+c.Path()]
+			if !ok {
+				return next(c)
+			}
+
+			params := map[string]string{}
+			for i, name := range c.ParamNames() {
+				params[name] = c.ParamValues()[i]
+			}
+			input := &openapi3filter.RequestValidationInput{
+				Request:     req,
+				PathParams:  params,
+				QueryParams: c.QueryParams(),
+				Route:       route,
+				Options:     v.options,
+			}
+			if err := openapi3filter.ValidateRequest(req.Context(), input); err != nil {
+				return c.JSON(http.StatusBadRequest, common.ErrorResponse(c, requestErrorMessage(err)))
+			}
+
+			if !v.ValidateResponses {
+				return next(c)
+			}
+			return v.validateResponse(c, next, input)
+		}
+	}
+}
+
+// validateResponse buffer the response of next and write it only when it
+// matches the spec.
+func (v *Validator) validateResponse(c echo.Context, next echo.HandlerFunc, input *openapi3filter.RequestValidationInput) error {
+	res := c.Response()
+	w := res.Writer
+	buf := &buffer{ResponseWriter: w}
+	res.Writer = buf
+	if err := next(c); err != nil {
+		// let the error handler write the response so it can be validated.
+		c.Error(err)
+	}
+	res.Writer = w
+
+	status := buf.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	output := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 status,
+		Header:                 res.Header(),
+		Options:                v.options,
+	}
+	output.SetBodyBytes(buf.body.Bytes())
+
+	ctx := c.Request().Context()
+	if err := openapi3filter.ValidateResponse(ctx, output); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "response does not match spec",
+			slog.String("operation", input.Route.Operation.OperationID),
+			slog.Int("status", status),
+			slog.String("error", responseErrorMessage(err)),
+		)
+		body, _ := json.Marshal(common.ErrorResponse(c, "response does not match spec"))
+		status = http.StatusInternalServerError
+		res.Status = status
+		res.Header().Del(echo.HeaderContentLength)
+		res.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+		buf.body.Reset()
+		buf.body.Write(append(body, '\n'))
+	}
+
+	// the response is committed, write it past echo.
+	w.WriteHeader(status)
+	_, err := w.Write(buf.body.Bytes())
+	return err
+}
+
+// requestErrorMessage describe err without the rejected value.
+func requestErrorMessage(err error) string {
+	var reqErr *openapi3filter.RequestError
+	if !errors.As(err, &reqErr) {
+		return "invalid request"
+	}
+
+	reason := errorReason(reqErr.Reason, reqErr.Err)
+	switch {
+	case reqErr.Parameter != nil:
+		return fmt.Sprintf("invalid %s parameter %q: %s", reqErr.Parameter.In, reqErr.Parameter.Name, reason)
+	case reqErr.RequestBody != nil:
+		return "invalid request body: " + reason
+	}
+	return reason
+}
+
+// responseErrorMessage describe err without the response value, which may
+// carry personal data.
+func responseErrorMessage(err error) string {
+	var resErr *openapi3filter.ResponseError
+	if !errors.As(err, &resErr) {
+		return err.Error()
+	}
+	return errorReason(resErr.Reason, resErr.Err)
+}
+
+// errorReason return the reason of a schema error, located by its JSON
+// pointer, or reason followed by err.
+func errorReason(reason string, err error) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		pointer := schemaErr.JSONPointer()
+		// the pointer of a missing property already names it.
+		if len(pointer) == 0 || schemaErr.SchemaField == "required" {
+			return schemaErr.Reason
+		}
+		return fmt.Sprintf("/%s: %s", strings.Join(pointer, "/"), schemaErr.Reason)
+	}
+	if err == nil {
+		return reason
+	}
+	if reason == "" {
+		return err.Error()
+	}
+	return reason + ": " + err.Error()
+}
+
+// buffer holds the response until it is validated.
+type buffer struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *buffer) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
@@ -0,0 +1,248 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/generated/oauth"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewValidator(t *testing.T) {
+	t.Run("TestNewValidator", func(t *testing.T) {
+		Convey("TestNewValidator", t, func(c C) {
+			spec, err := generated.GetSwagger()
+			So(err, ShouldBeNil)
+			_, err = NewValidator(NewValidatorOptions{Spec: spec})
+			So(err, ShouldBeNil)
+
+			_, err = NewValidator(NewValidatorOptions{Spec: &openapi3.T{}})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("TestMiddleware", func(t *testing.T) {
+		Convey("TestMiddleware", t, func(c C) {
+			spec, err := generated.GetSwagger()
+			So(err, ShouldBeNil)
+
+			type (
+				args struct {
+					method string
+					path   string
+					body   string
+				}
+			)
+
+			testCases := []struct {
+				testID            int
+				testDesc          string
+				args              args
+				validateResponses bool
+				// response is written by the handler.
+				response    interface{}
+				wantCalled  bool
+				wantStatus  int
+				wantMessage string
+			}{
+				{
+					testID:     1,
+					testDesc:   "Success - valid request",
+					args:       args{method: http.MethodPost, path: "/users/register", body: `{"phone":"+6281234567","name":"Budi","password":"Secret1!"}`},
+					response:   generated.RegisterResponse{Id: 1},
+					wantCalled: true,
+					wantStatus: http.StatusOK,
+				},
+				{
+					testID:      2,
+					testDesc:    "Failed - missing property",
+					args:        args{method: http.MethodPost, path: "/users/register", body: `{"phone":"+6281234567","name":"Budi"}`},
+					wantStatus:  http.StatusBadRequest,
+					wantMessage: `invalid request body: property "password" is missing`,
+				},
+				{
+					testID:      3,
+					testDesc:    "Failed - unknown property",
+					args:        args{method: http.MethodPost, path: "/login", body: `{"phone":"+6281234567","password":"Secret1!","admin":true}`},
+					wantStatus:  http.StatusBadRequest,
+					wantMessage: `invalid request body: property "admin" is unsupported`,
+				},
+				{
+					testID:      4,
+					testDesc:    "Failed - wrong type",
+					args:        args{method: http.MethodPatch, path: "/users", body: `{"name":1}`},
+					wantStatus:  http.StatusBadRequest,
+					wantMessage: `invalid request body: /name: value must be a string`,
+				},
+				{
+					testID:      5,
+					testDesc:    "Failed - invalid query parameter",
+					args:        args{method: http.MethodGet, path: "/users/events?limit=1000"},
+					wantStatus:  http.StatusBadRequest,
+					wantMessage: `invalid query parameter "limit": number must be at most 100`,
+				},
+				{
+					testID:     6,
+					testDesc:   "Success - route missing from spec is not validated",
+					args:       args{method: http.MethodGet, path: "/metrics"},
+					response:   map[string]string{},
+					wantCalled: true,
+					wantStatus: http.StatusOK,
+				},
+				{
+					testID:            7,
+					testDesc:          "Success - response matches spec",
+					args:              args{method: http.MethodPost, path: "/users/register", body: `{"phone":"+6281234567","name":"Budi","password":"Secret1!"}`},
+					validateResponses: true,
+					response:          generated.RegisterResponse{Id: 1},
+					wantCalled:        true,
+					wantStatus:        http.StatusOK,
+				},
+				{
+					testID:            8,
+					testDesc:          "Failed - response drifted from spec",
+					args:              args{method: http.MethodGet, path: "/users"},
+					validateResponses: true,
+					response:          map[string]interface{}{"phone": "+6281234567", "name": "Budi"},
+					wantCalled:        true,
+					wantStatus:        http.StatusInternalServerError,
+					wantMessage:       "response does not match spec",
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					v, err := NewValidator(NewValidatorOptions{Spec: spec, ValidateResponses: tc.validateResponses})
+					So(err, ShouldBeNil)
+
+					e := echo.New()
+					e.Use(v.Middleware())
+					called := false
+					handler := func(c echo.Context) error {
+						called = true
+						return c.JSON(http.StatusOK, tc.response)
+					}
+					e.POST("/users/register", handler)
+					e.POST("/login", handler)
+					e.GET("/users", handler)
+					e.PATCH("/users", handler)
+					e.GET("/users/events", handler)
+					e.GET("/metrics", handler)
+
+					req := httptest.NewRequest(tc.args.method, tc.args.path, strings.NewReader(tc.args.body))
+					if tc.args.body != "" {
+						req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+					}
+					rec := httptest.NewRecorder()
+					e.ServeHTTP(rec, req)
+
+					// assert
+					So(called, ShouldEqual, tc.wantCalled)
+					So(rec.Code, ShouldEqual, tc.wantStatus)
+					if tc.wantMessage != "" {
+						var resp generated.ErrorResponse
+						So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+						So(resp.Message, ShouldEqual, tc.wantMessage)
+					} else {
+						want, _ := json.Marshal(tc.response)
+						So(rec.Body.String(), ShouldEqual, string(want)+"\n")
+					}
+				})
+			}
+		})
+	})
+}
+
+func TestMiddlewareFormsAndPages(t *testing.T) {
+	t.Run("TestMiddlewareFormsAndPages", func(t *testing.T) {
+		Convey("TestMiddlewareFormsAndPages", t, func(c C) {
+			spec, err := oauth.GetSwagger()
+			So(err, ShouldBeNil)
+
+			testCases := []struct {
+				testID      int
+				testDesc    string
+				method      string
+				path        string
+				form        string
+				wantCalled  bool
+				wantStatus  int
+				wantMessage string
+			}{
+				{
+					testID:     1,
+					testDesc:   "Success - form without optional properties",
+					method:     http.MethodPost,
+					path:       "/oauth/token",
+					form:       "grant_type=authorization_code&code=mock-code",
+					wantCalled: true,
+					wantStatus: http.StatusOK,
+				},
+				{
+					testID:      2,
+					testDesc:    "Failed - form missing property",
+					method:      http.MethodPost,
+					path:        "/oauth/token",
+					form:        "code=mock-code",
+					wantStatus:  http.StatusBadRequest,
+					wantMessage: `invalid request body: property "grant_type" is missing`,
+				},
+				{
+					testID:     3,
+					testDesc:   "Success - html page matches spec",
+					method:     http.MethodGet,
+					path:       "/oauth/authorize?response_type=code&client_id=mock-app&redirect_uri=https://app.example.com",
+					wantCalled: true,
+					wantStatus: http.StatusOK,
+				},
+			}
+
+			for _, tc := range testCases {
+
+				Convey(fmt.Sprintf("%d : %s", tc.testID, tc.testDesc), func() {
+					v, err := NewValidator(NewValidatorOptions{Spec: spec, ValidateResponses: true})
+					So(err, ShouldBeNil)
+
+					e := echo.New()
+					e.Use(v.Middleware())
+					called := false
+					e.POST("/oauth/token", func(c echo.Context) error {
+						called = true
+						return c.JSON(http.StatusOK, oauth.TokenResponse{AccessToken: c.FormValue("code"), TokenType: "Bearer"})
+					})
+					e.GET("/oauth/authorize", func(c echo.Context) error {
+						called = true
+						return c.HTML(http.StatusOK, "<!DOCTYPE html><title>Log in</title>")
+					})
+
+					req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.form))
+					if tc.form != "" {
+						req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+					}
+					rec := httptest.NewRecorder()
+					e.ServeHTTP(rec, req)
+
+					// assert
+					So(called, ShouldEqual, tc.wantCalled)
+					So(rec.Code, ShouldEqual, tc.wantStatus)
+					if tc.wantMessage != "" {
+						var resp generated.ErrorResponse
+						So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+						So(resp.Message, ShouldEqual, tc.wantMessage)
+					}
+				})
+			}
+		})
+	})
+}